@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	"person-service/auth"
+	"person-service/config"
+	db "person-service/internal/db/generated"
+	"person-service/metrics"
+	"person-service/tracing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Entry is a request log entry recorded on behalf of the caller of a request
+type Entry struct {
+	// Kind prefixes the generated trace_id of the entry, such as purpose or policy
+	Kind string
+	// Reason is the reason column of the request log
+	Reason string
+	// Purpose is the declared purpose of use, empty when none was declared
+	Purpose string
+	// Details are stored encrypted as the request body with the trace ID of the request
+	Details map[string]interface{}
+}
+
+// Writer adds audit entries to the request log, encrypted with the current key
+type Writer struct {
+	queries       *db.Queries
+	encryptionKey string
+	keyVersion    int64
+}
+
+// NewWriter creates a new instance of Writer with injected queries that
+// encrypts entries with the key in encryption
+func NewWriter(queries *db.Queries, encryption config.Encryption) *Writer {
+	return &Writer{
+		queries:       queries,
+		encryptionKey: encryption.Key,
+		keyVersion:    encryption.KeyVersion,
+	}
+}
+
+// Write adds entry to the request log of the tenant of ctx for principal
+func (w *Writer) Write(ctx context.Context, principal auth.Principal, entry Entry) error {
+	details := map[string]interface{}{"trace_id": tracing.TraceIDFromContext(ctx)}
+	for name, value := range entry.Details {
+		details[name] = value
+	}
+	requestBody, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	caller := principal.Name
+	if caller == "" {
+		caller = "anonymous"
+	}
+	var principalID pgtype.Text
+	if principal.ID != "" {
+		principalID = pgtype.Text{String: principal.ID, Valid: true}
+	}
+
+	// Each entry gets its own trace_id, which is unique in the request log of
+	// the tenant; the trace ID of the request is kept in the details
+	_, err = w.queries.InsertRequestLog(ctx, db.InsertRequestLogParams{
+		TenantID:              auth.TenantFromContext(ctx),
+		TraceID:               entry.Kind + "-" + uuid.NewString(),
+		CallerInfo:            caller,
+		PrincipalID:           principalID,
+		Reason:                entry.Reason,
+		EncryptedRequestBody:  string(requestBody),
+		EncryptedResponseBody: "",
+		EncKey:                w.encryptionKey,
+		KeyVersion:            w.keyVersion,
+		Purpose:               pgtype.Text{String: entry.Purpose, Valid: entry.Purpose != ""},
+	})
+	if err != nil {
+		return err
+	}
+	metrics.RecordEncryption(metrics.ResourceRequestLog, 1)
+	return nil
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+)
+
+// contextKey is a custom type for context keys to avoid collisions
+type contextKey string
+
+const (
+	// PrincipalKey is the context key for storing the authenticated principal
+	PrincipalKey contextKey = "principal"
+
+	// DefaultNamespace is the key-value namespace used when a credential has none configured
+	DefaultNamespace = "default"
+
+	// DefaultTenant is the tenant used when a credential has none configured
+	DefaultTenant = "default"
+)
+
+// Principal sources, naming how the caller was authenticated
+const (
+	SourceBootstrapKey = "bootstrap_key"
+	SourceCredential   = "credential"
+	SourceJWT          = "jwt"
+	SourceCertificate  = "certificate"
+)
+
+// IsValidSource reports whether source is one of the Source* values
+func IsValidSource(source string) bool {
+	switch source {
+	case SourceBootstrapKey, SourceCredential, SourceJWT, SourceCertificate:
+		return true
+	}
+	return false
+}
+
+// Principal identifies the authenticated caller of a request.
+// Namespace scopes the caller's key-value entries, Tenant scopes the persons
+// the caller can access and Scopes limits the routes the caller may use.
+// RedactionProfile, when set, is applied to every attribute value the caller
+// reads, and Purposes, when set, limits the purposes of use it may declare.
+// Source is one of the Source* values; IDs are only unique within a source.
+type Principal struct {
+	ID               string
+	Source           string
+	Name             string
+	Namespace        string
+	Tenant           string
+	Scopes           []string
+	RedactionProfile string
+	Purposes         []string
+}
+
+// ContextWithPrincipal creates a new context with the principal stored.
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, PrincipalKey, principal)
+}
+
+// PrincipalFromContext retrieves the principal from the context.
+// Returns false if no principal is found.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	if ctx == nil {
+		return Principal{}, false
+	}
+
+	principal, ok := ctx.Value(PrincipalKey).(Principal)
+	return principal, ok
+}
+
+// NamespaceFromContext returns the namespace of the principal stored in the context.
+// Returns DefaultNamespace if no principal is found or it has no namespace.
+func NamespaceFromContext(ctx context.Context) string {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok || principal.Namespace == "" {
+		return DefaultNamespace
+	}
+	return principal.Namespace
+}
+
+// TenantFromContext returns the tenant of the principal stored in the context.
+// Returns DefaultTenant if no principal is found or it has no tenant.
+func TenantFromContext(ctx context.Context) string {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok || principal.Tenant == "" {
+		return DefaultTenant
+	}
+	return principal.Tenant
+}
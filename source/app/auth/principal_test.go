@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextWithPrincipal_And_PrincipalFromContext(t *testing.T) {
+	principal := Principal{ID: "blue", Namespace: "team-a"}
+
+	ctx := ContextWithPrincipal(context.Background(), principal)
+	result, ok := PrincipalFromContext(ctx)
+
+	assert.True(t, ok)
+	assert.Equal(t, principal, result)
+}
+
+func TestPrincipalFromContext_NilContext(t *testing.T) {
+	_, ok := PrincipalFromContext(nil)
+	assert.False(t, ok)
+}
+
+func TestPrincipalFromContext_NoPrincipal(t *testing.T) {
+	_, ok := PrincipalFromContext(context.Background())
+	assert.False(t, ok)
+}
+
+func TestNamespaceFromContext(t *testing.T) {
+	assert.Equal(t, DefaultNamespace, NamespaceFromContext(context.Background()))
+
+	ctx := ContextWithPrincipal(context.Background(), Principal{ID: "blue"})
+	assert.Equal(t, DefaultNamespace, NamespaceFromContext(ctx))
+
+	ctx = ContextWithPrincipal(context.Background(), Principal{ID: "green", Namespace: "team-b"})
+	assert.Equal(t, "team-b", NamespaceFromContext(ctx))
+}
+
+func TestTenantFromContext(t *testing.T) {
+	assert.Equal(t, DefaultTenant, TenantFromContext(context.Background()))
+
+	ctx := ContextWithPrincipal(context.Background(), Principal{ID: "blue"})
+	assert.Equal(t, DefaultTenant, TenantFromContext(ctx))
+
+	ctx = ContextWithPrincipal(context.Background(), Principal{ID: "green", Tenant: "acme"})
+	assert.Equal(t, "acme", TenantFromContext(ctx))
+}
+
+func TestPrincipal_HasScope(t *testing.T) {
+	principal := Principal{ID: "reader", Scopes: []string{ScopePersonRead, ScopeKVRead}}
+
+	assert.True(t, principal.HasScope(ScopePersonRead))
+	assert.True(t, principal.HasScope(ScopeKVRead))
+	assert.False(t, principal.HasScope(ScopePersonWrite))
+	assert.False(t, Principal{}.HasScope(ScopeAdmin))
+}
+
+func TestIsValidScope(t *testing.T) {
+	for _, scope := range AllScopes {
+		assert.True(t, IsValidScope(scope), scope)
+	}
+	assert.False(t, IsValidScope("person:delete"))
+	assert.False(t, IsValidScope(""))
+	assert.True(t, IsValidScope("attributes:read:pii"))
+	assert.False(t, IsValidScope(AttributeScopePrefix))
+}
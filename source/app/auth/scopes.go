@@ -0,0 +1,57 @@
+package auth
+
+import "strings"
+
+// Scopes granted to API credentials. Each route requires one scope.
+const (
+	ScopePersonRead      = "person:read"
+	ScopePersonWrite     = "person:write"
+	ScopeAttributesRead  = "attributes:read"
+	ScopeAttributesWrite = "attributes:write"
+	ScopeAuditRead       = "audit:read"
+	ScopeKVRead          = "kv:read"
+	ScopeKVWrite         = "kv:write"
+	ScopeMetricsRead     = "metrics:read"
+	ScopeAdmin           = "admin"
+)
+
+// AttributeScopePrefix starts the scopes that attribute policies require to
+// read protected attributes, such as attributes:read:pii
+const AttributeScopePrefix = "attributes:read:"
+
+// AllScopes lists every known scope, in the order they are documented
+var AllScopes = []string{
+	ScopePersonRead,
+	ScopePersonWrite,
+	ScopeAttributesRead,
+	ScopeAttributesWrite,
+	ScopeAuditRead,
+	ScopeKVRead,
+	ScopeKVWrite,
+	ScopeMetricsRead,
+	ScopeAdmin,
+}
+
+// IsValidScope reports whether scope is one of AllScopes or an attribute
+// scope with a non-empty name after AttributeScopePrefix
+func IsValidScope(scope string) bool {
+	if name, ok := strings.CutPrefix(scope, AttributeScopePrefix); ok {
+		return name != ""
+	}
+	for _, known := range AllScopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the principal was granted scope
+func (p Principal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
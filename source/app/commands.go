@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"person-service/auth"
+	"person-service/config"
+	"person-service/credentials"
+	errs "person-service/errors"
+	dbpkg "person-service/internal/db"
+	db "person-service/internal/db/generated"
+	"person-service/logging"
+	"person-service/maintenance"
+)
+
+// ============================================================================
+// COMMANDS - Operational subcommands of the binary
+// ============================================================================
+
+// command is an operation selected by the first argument of the binary
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(ctx context.Context, cfg config.Config, args []string) error
+	// databaseOnly commands are run with only the database and logging
+	// settings validated
+	databaseOnly bool
+}
+
+// commands lists every command; serve runs when none is given
+var commands = []command{
+	{
+		name:    "serve",
+		usage:   "serve",
+		summary: "Run the HTTP server (default)",
+		run:     serve,
+	},
+	{
+		name:         "migrate",
+		usage:        "migrate up | down [N|all] | status | force VERSION",
+		summary:      "Apply, revert or inspect the embedded database migrations",
+		run:          migrateCommand,
+		databaseOnly: true,
+	},
+	{
+		name:    "rotate-keys",
+		usage:   "rotate-keys [-batch-size N] [-dry-run]",
+		summary: "Re-encrypt values stored with older keys with the ENCRYPTION_KEY_VERSION key",
+		run:     rotateKeysCommand,
+	},
+	{
+		name:    "verify-encryption",
+		usage:   "verify-encryption",
+		summary: "Check that every encrypted value decrypts with the key of its key version",
+		run:     verifyEncryptionCommand,
+	},
+	{
+		name:    "create-api-key",
+		usage:   "create-api-key -name NAME -owner OWNER -scopes SCOPE,... [-namespace NS] [-tenant T] [-redaction-profile P] [-purposes P,...] [-expires-in DURATION]",
+		summary: "Issue an API credential and print its key once",
+		run:     createAPIKeyCommand,
+	},
+	{
+		name:    "seed",
+		usage:   "seed [-tenant TENANT] [-persons N]",
+		summary: "Create sample persons with attributes for development",
+		run:     seedCommand,
+	},
+}
+
+// usageError reports invalid arguments; the command exits with status 2
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+// mismatchError reports that verify-encryption found values that do not decrypt
+type mismatchError struct {
+	failed int64
+}
+
+func (e *mismatchError) Error() string {
+	return fmt.Sprintf("%d encrypted values do not decrypt with the key of their key version", e.failed)
+}
+
+// findCommand returns the command called name
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// printUsage lists the commands on w
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: person-service [command] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-18s %s\n", cmd.name, cmd.summary)
+		fmt.Fprintf(w, "  %-18s   %s\n", "", cmd.usage)
+	}
+}
+
+// runCommand runs cmd and returns the exit status. Failures are logged with
+// the error code of their kind.
+func runCommand(ctx context.Context, cmd command, cfg config.Config, args []string) int {
+	err := cmd.run(ctx, cfg, args)
+	var usageErr *usageError
+	var mismatchErr *mismatchError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &usageErr):
+		logging.Error("Invalid arguments",
+			"command", cmd.name,
+			"error", err,
+			"usage", cmd.usage,
+			"error_code", errs.ErrInvalidUsage)
+		return 2
+	case errors.As(err, &mismatchErr):
+		logging.Error("Encryption verification failed",
+			"command", cmd.name,
+			"error", err,
+			"error_code", errs.ErrEncryptionMismatch)
+		return 1
+	default:
+		logging.Error("Command failed",
+			"command", cmd.name,
+			"error", err,
+			"error_code", errs.ErrCommandFailed)
+		return 1
+	}
+}
+
+// parseFlags parses the flags of the command called name. define registers
+// them on the flag set and may be nil when the command takes none. Positional
+// arguments are not accepted.
+func parseFlags(name string, args []string, define func(flags *flag.FlagSet)) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	if define != nil {
+		define(flags)
+	}
+	if err := flags.Parse(args); err != nil {
+		return &usageError{err.Error()}
+	}
+	if flags.NArg() > 0 {
+		return &usageError{fmt.Sprintf("unexpected argument %q", flags.Arg(0))}
+	}
+	return nil
+}
+
+// printJSON writes result to stdout as indented JSON
+func printJSON(result any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// migrateCommand applies, reverts or inspects the migrations and prints the
+// resulting status. It connects with DATABASE_URL, so a migration job can use
+// a role with DDL privileges while the service runs with MIGRATE_ON_START=false.
+// Only the database and logging settings are required, so the job does not
+// need the encryption keys or other secrets of the server.
+func migrateCommand(ctx context.Context, cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return &usageError{"missing subcommand: use up, down, status or force"}
+	}
+	action, args := args[0], args[1:]
+
+	// Validate the arguments before connecting
+	var steps, version int
+	switch action {
+	case "up", "status":
+		if len(args) > 0 {
+			return &usageError{fmt.Sprintf("unexpected argument %q", args[0])}
+		}
+	case "down":
+		steps = 1
+		if len(args) > 1 {
+			return &usageError{fmt.Sprintf("unexpected argument %q", args[1])}
+		}
+		if len(args) == 1 {
+			if args[0] == "all" {
+				steps = 0
+			} else if parsed, err := strconv.Atoi(args[0]); err == nil && parsed > 0 {
+				steps = parsed
+			} else {
+				return &usageError{fmt.Sprintf("invalid number of steps %q: use a positive number or all", args[0])}
+			}
+		}
+	case "force":
+		if len(args) != 1 {
+			return &usageError{"force needs the VERSION to record"}
+		}
+		parsed, err := strconv.Atoi(args[0])
+		if err != nil || parsed < -1 {
+			return &usageError{fmt.Sprintf("invalid version %q", args[0])}
+		}
+		version = parsed
+	default:
+		return &usageError{fmt.Sprintf("unknown subcommand %q: use up, down, status or force", action)}
+	}
+
+	migrator, err := dbpkg.NewMigrator(cfg.Database.URL)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	// Changes wait for instances migrating on start, and the other way round
+	if action != "status" {
+		lockCtx, cancel := context.WithTimeout(ctx, cfg.Database.MigrationLockTimeout)
+		defer cancel()
+		err = dbpkg.WithMigrationLock(lockCtx, cfg.Database.URL, func() error {
+			switch action {
+			case "up":
+				logging.Info("Applying migrations")
+				return migrator.Up()
+			case "down":
+				logging.Warn("Reverting migrations", "steps", steps)
+				return migrator.Down(steps)
+			default:
+				logging.Warn("Forcing migration version", "version", version)
+				return migrator.Force(version)
+			}
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("migrate %s failed: %w", action, err)
+	}
+
+	status, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+	return printJSON(status)
+}
+
+// rotateKeysCommand re-encrypts values stored with older key versions with
+// the current key and prints the rows moved per table and version
+func rotateKeysCommand(ctx context.Context, cfg config.Config, args []string) error {
+	batchSize := maintenance.DefaultBatchSize
+	var dryRun bool
+	err := parseFlags("rotate-keys", args, func(flags *flag.FlagSet) {
+		flags.IntVar(&batchSize, "batch-size", batchSize, "rows re-encrypted per statement")
+		flags.BoolVar(&dryRun, "dry-run", false, "only count the rows to re-encrypt")
+	})
+	if err != nil {
+		return err
+	}
+	if batchSize <= 0 {
+		return &usageError{"-batch-size must be positive"}
+	}
+
+	pool := connectDb(cfg.Database)
+	defer pool.Close()
+
+	results, err := maintenance.RotateKeys(ctx, pool, cfg.Encryption, batchSize, dryRun)
+	if printErr := printJSON(map[string]interface{}{
+		"key_version": cfg.Encryption.KeyVersion,
+		"dry_run":     dryRun,
+		"rotated":     results,
+	}); printErr != nil && err == nil {
+		err = printErr
+	}
+	return err
+}
+
+// verifyEncryptionCommand checks every encrypted value and prints the rows
+// that do not decrypt. It fails when there is any.
+func verifyEncryptionCommand(ctx context.Context, cfg config.Config, args []string) error {
+	if err := parseFlags("verify-encryption", args, nil); err != nil {
+		return err
+	}
+
+	pool := connectDb(cfg.Database)
+	defer pool.Close()
+
+	results, err := maintenance.VerifyEncryption(ctx, pool, cfg.Encryption)
+	if err != nil {
+		return err
+	}
+	if err := printJSON(map[string]interface{}{"tables": results}); err != nil {
+		return err
+	}
+
+	var failed int64
+	for _, result := range results {
+		failed += result.Failed
+	}
+	if failed > 0 {
+		return &mismatchError{failed: failed}
+	}
+	return nil
+}
+
+// createAPIKeyCommand issues an API credential like POST /admin/api-keys and
+// prints it with its key, which cannot be shown again
+func createAPIKeyCommand(ctx context.Context, cfg config.Config, args []string) error {
+	var req credentials.CreateCredentialRequest
+	var scopes, purposes string
+	var expiresIn time.Duration
+	err := parseFlags("create-api-key", args, func(flags *flag.FlagSet) {
+		flags.StringVar(&req.Name, "name", "", "name of the credential")
+		flags.StringVar(&req.Owner, "owner", "", "team or person responsible for the credential")
+		flags.StringVar(&scopes, "scopes", "", "comma-separated scopes")
+		flags.StringVar(&req.Namespace, "namespace", "", "key-value namespace")
+		flags.StringVar(&req.Tenant, "tenant", "", "tenant whose persons the credential can access")
+		flags.StringVar(&req.RedactionProfile, "redaction-profile", "", "redaction profile applied to every read")
+		flags.StringVar(&purposes, "purposes", "", "comma-separated purposes of use the credential may declare")
+		flags.DurationVar(&expiresIn, "expires-in", 0, "lifetime of the credential; it never expires when 0")
+	})
+	if err != nil {
+		return err
+	}
+	req.Scopes = splitList(scopes)
+	req.Purposes = splitList(purposes)
+	if expiresIn < 0 {
+		return &usageError{"-expires-in must not be negative"}
+	}
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(expiresIn)
+		req.ExpiresAt = &expiresAt
+	}
+
+	pool := connectDb(cfg.Database)
+	defer pool.Close()
+
+	store := credentials.NewStore(db.New(pool))
+	store.SetRedactionProfiles(setupRedactionProfiles(cfg.Access))
+	credential, key, err := store.Issue(ctx, req)
+	var issueErr *credentials.IssueError
+	if errors.As(err, &issueErr) {
+		return &usageError{issueErr.Message}
+	}
+	if err != nil {
+		return err
+	}
+
+	logging.Info("API credential issued",
+		"name", credential.Name,
+		"owner", credential.Owner)
+
+	response := credentials.CredentialResponse(credential)
+	response["api_key"] = key
+	return printJSON(response)
+}
+
+// seedCommand creates sample persons for development and prints how many
+// were created
+func seedCommand(ctx context.Context, cfg config.Config, args []string) error {
+	tenant := auth.DefaultTenant
+	persons := 10
+	err := parseFlags("seed", args, func(flags *flag.FlagSet) {
+		flags.StringVar(&tenant, "tenant", tenant, "tenant of the seeded persons")
+		flags.IntVar(&persons, "persons", persons, "number of persons")
+	})
+	if err != nil {
+		return err
+	}
+	if persons <= 0 {
+		return &usageError{"-persons must be positive"}
+	}
+
+	pool := connectDb(cfg.Database)
+	defer pool.Close()
+
+	result, err := maintenance.Seed(ctx, db.New(pool), cfg.Encryption, tenant, persons)
+	if printErr := printJSON(result); printErr != nil && err == nil {
+		err = printErr
+	}
+	return err
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
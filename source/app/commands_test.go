@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"person-service/config"
+)
+
+func TestFindCommand(t *testing.T) {
+	for _, name := range []string{"serve", "migrate", "rotate-keys", "verify-encryption", "create-api-key", "seed"} {
+		cmd, ok := findCommand(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, name, cmd.name)
+	}
+
+	_, ok := findCommand("unknown")
+	assert.False(t, ok)
+}
+
+func TestPrintUsage_ListsCommands(t *testing.T) {
+	var out bytes.Buffer
+	printUsage(&out)
+	for _, cmd := range commands {
+		assert.Contains(t, out.String(), cmd.usage)
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	var dryRun bool
+	err := parseFlags("rotate-keys", []string{"-dry-run"}, func(flags *flag.FlagSet) {
+		flags.BoolVar(&dryRun, "dry-run", false, "")
+	})
+	assert.NoError(t, err)
+	assert.True(t, dryRun)
+
+	var usageErr *usageError
+	err = parseFlags("rotate-keys", []string{"-unknown"}, nil)
+	assert.True(t, errors.As(err, &usageErr))
+
+	err = parseFlags("verify-encryption", []string{"extra"}, nil)
+	assert.True(t, errors.As(err, &usageErr))
+	assert.Contains(t, err.Error(), `"extra"`)
+}
+
+func TestMigrateCommand_InvalidArguments(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"sideways"},
+		{"up", "2"},
+		{"down", "0"},
+		{"down", "two"},
+		{"force"},
+		{"force", "-2"},
+	}
+	for _, args := range tests {
+		var usageErr *usageError
+		err := migrateCommand(context.Background(), config.Default(), args)
+		assert.True(t, errors.As(err, &usageErr), "%v", args)
+	}
+}
+
+func TestRunCommand_ExitStatus(t *testing.T) {
+	run := func(err error) int {
+		cmd := command{name: "test", run: func(context.Context, config.Config, []string) error { return err }}
+		return runCommand(context.Background(), cmd, config.Default(), nil)
+	}
+
+	assert.Equal(t, 0, run(nil))
+	assert.Equal(t, 2, run(&usageError{"bad"}))
+	assert.Equal(t, 1, run(&mismatchError{failed: 1}))
+	assert.Equal(t, 1, run(errors.New("boom")))
+}
+
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"persons:read", "persons:write"}, splitList(" persons:read, ,persons:write"))
+	assert.Nil(t, splitList(""))
+}
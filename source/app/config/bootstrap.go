@@ -0,0 +1,41 @@
+package config
+
+import "strings"
+
+// BootstrapColors are the deployment slots that can hold a bootstrap key
+var BootstrapColors = []string{"blue", "green"}
+
+// BootstrapKey is an API key with every scope, used to issue the first
+// credentials. Namespace and Tenant are empty when not configured.
+type BootstrapKey struct {
+	Name      string
+	Key       string
+	Namespace string
+	Tenant    string
+}
+
+// LoadBootstrapKeys reads the bootstrap keys that are set. Each key is a
+// secret read from PERSON_API_KEY_<COLOR> or PERSON_API_KEY_<COLOR>_FILE, with
+// its key-value namespace and tenant in PERSON_API_KEY_<COLOR>_NAMESPACE and
+// PERSON_API_KEY_<COLOR>_TENANT. The files are read on every call so mounted
+// secrets can be rotated without a restart.
+func LoadBootstrapKeys() ([]BootstrapKey, error) {
+	var keys []BootstrapKey
+	for _, color := range BootstrapColors {
+		name := "PERSON_API_KEY_" + strings.ToUpper(color)
+		key, err := ReadSecret(name)
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			continue
+		}
+
+		e := &env{}
+		bootstrapKey := BootstrapKey{Name: color, Key: key}
+		e.string(name+"_NAMESPACE", &bootstrapKey.Namespace)
+		e.string(name+"_TENANT", &bootstrapKey.Tenant)
+		keys = append(keys, bootstrapKey)
+	}
+	return keys, nil
+}
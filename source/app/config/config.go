@@ -0,0 +1,385 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultEncryptionKey is used when no encryption key is configured. It is
+// only suitable for local development.
+const DefaultEncryptionKey = "default-key-for-dev"
+
+// Config is the complete service configuration. It is loaded once at startup
+// by Load and passed to the components that need it.
+type Config struct {
+	Server     Server     `yaml:"server"`
+	Database   Database   `yaml:"database"`
+	Encryption Encryption `yaml:"encryption"`
+	Logging    Logging    `yaml:"logging"`
+	AccessLog  AccessLog  `yaml:"access_log"`
+	KeyValue   KeyValue   `yaml:"key_value"`
+	JWT        JWT        `yaml:"jwt"`
+	TLS        TLS        `yaml:"tls"`
+	Signing    Signing    `yaml:"request_signing"`
+	RateLimit  RateLimit  `yaml:"rate_limit"`
+	Access     Access     `yaml:"access"`
+}
+
+// Server configures the HTTP server
+type Server struct {
+	Port            int           `yaml:"port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	ReadinessDelay  time.Duration `yaml:"readiness_delay"`
+}
+
+// Database configures the connection pool. URL is a secret and is only read
+// from DATABASE_URL or DATABASE_URL_FILE.
+type Database struct {
+	URL               string        `yaml:"-"`
+	MaxConns          int32         `yaml:"max_conns"`
+	MinConns          int32         `yaml:"min_conns"`
+	MaxConnLifetime   time.Duration `yaml:"max_conn_lifetime"`
+	MaxConnIdleTime   time.Duration `yaml:"max_conn_idle_time"`
+	HealthCheckPeriod time.Duration `yaml:"health_check_period"`
+	ConnectTimeout    time.Duration `yaml:"connect_timeout"`
+	// TenantRLS sets the tenant on every statement so row-level security applies
+	TenantRLS bool `yaml:"tenant_rls"`
+	// MigrateOnStart applies pending migrations before serving; turn it off
+	// when migrations run as a separate job
+	MigrateOnStart bool `yaml:"migrate_on_start"`
+	// MigrationLockTimeout bounds the wait for another instance that holds
+	// the migration lock
+	MigrationLockTimeout time.Duration `yaml:"migration_lock_timeout"`
+	// ExpectSchemaVersion, when set, turns off migrations on start; the
+	// service refuses to start unless the schema is at this version
+	ExpectSchemaVersion int64 `yaml:"expect_schema_version"`
+}
+
+// Encryption configures the keys used for values encrypted at rest. Keys maps
+// each key version to its key; they are secrets and are only read from
+// ENCRYPTION_KEY_<VERSION> or ENCRYPTION_KEY_<VERSION>_FILE. New values are
+// encrypted with Key, the key of KeyVersion. Key-value entries encrypted
+// before key versions were recorded have none; UnversionedKeyVersion names
+// the key they were encrypted with and defaults to KeyVersion.
+type Encryption struct {
+	Key                   string           `yaml:"-"`
+	KeyVersion            int64            `yaml:"key_version"`
+	UnversionedKeyVersion int64            `yaml:"unversioned_key_version"`
+	Keys                  map[int64]string `yaml:"-"`
+}
+
+// KeyFor returns the key of version and whether it is configured
+func (e Encryption) KeyFor(version int64) (string, bool) {
+	key, ok := e.Keys[version]
+	return key, ok
+}
+
+// UnversionedVersion returns the key version of values stored without one
+func (e Encryption) UnversionedVersion() int64 {
+	if e.UnversionedKeyVersion > 0 {
+		return e.UnversionedKeyVersion
+	}
+	return e.KeyVersion
+}
+
+// Logging configures the structured logger
+type Logging struct {
+	Level        string   `yaml:"level"`
+	Format       string   `yaml:"format"`
+	RedactKeys   []string `yaml:"redact_keys"`
+	GCPProjectID string   `yaml:"gcp_project_id"`
+}
+
+// AccessLog configures the per-request access log
+type AccessLog struct {
+	Enabled           bool          `yaml:"enabled"`
+	SlowThreshold     time.Duration `yaml:"slow_threshold"`
+	HealthSampleEvery int           `yaml:"health_sample_every"`
+}
+
+// KeyValue configures the key-value store
+type KeyValue struct {
+	EncryptValues bool          `yaml:"encrypt_values"`
+	SweepInterval time.Duration `yaml:"sweep_interval"`
+}
+
+// JWT configures bearer token authentication; it is enabled when JWKS is set
+type JWT struct {
+	JWKS           string        `yaml:"jwks"`
+	Issuer         string        `yaml:"issuer"`
+	Audience       string        `yaml:"audience"`
+	ScopesClaim    string        `yaml:"scopes_claim"`
+	ScopePrefix    string        `yaml:"scope_prefix"`
+	NamespaceClaim string        `yaml:"namespace_claim"`
+	TenantClaim    string        `yaml:"tenant_claim"`
+	ClockSkew      time.Duration `yaml:"clock_skew"`
+}
+
+// TLS configures TLS serving; it is enabled when CertFile and KeyFile are set
+type TLS struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"`
+	IdentityFile string `yaml:"client_identity_file"`
+	// ClientAuth is require or optional
+	ClientAuth string `yaml:"client_auth"`
+}
+
+// Enabled reports whether TLS serving is configured
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// RequireClientCert reports whether handshakes without a verified client
+// certificate are rejected
+func (t TLS) RequireClientCert() bool {
+	return t.ClientCAFile != "" && t.ClientAuth == "require"
+}
+
+// Signing configures HMAC request signing. Keys is a secret and is only read
+// from REQUEST_SIGNING_KEYS or REQUEST_SIGNING_KEYS_FILE. Principals binds
+// each key id to the principal allowed to sign with it.
+type Signing struct {
+	Routes     []string      `yaml:"routes"`
+	Keys       string        `yaml:"-"`
+	Principals string        `yaml:"principals"`
+	Window     time.Duration `yaml:"window"`
+	NonceStore string        `yaml:"nonce_store"`
+}
+
+// RateLimit configures per-class rate limits such as 600/m; classes left
+// empty are not limited
+type RateLimit struct {
+	Read    string `yaml:"read"`
+	Write   string `yaml:"write"`
+	Search  string `yaml:"search"`
+	Backend string `yaml:"backend"`
+}
+
+// Access configures attribute access policy, redaction and purpose of use
+type Access struct {
+	AttributePolicyFile   string   `yaml:"attribute_policy_file"`
+	RedactionProfilesFile string   `yaml:"redaction_profiles_file"`
+	Purposes              []string `yaml:"purposes"`
+}
+
+// Default returns the configuration used for settings that are not set
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port:            3000,
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 10 * time.Second,
+		},
+		Database: Database{
+			MaxConns:             25,
+			MinConns:             5,
+			MaxConnLifetime:      5 * time.Minute,
+			MaxConnIdleTime:      time.Minute,
+			HealthCheckPeriod:    time.Minute,
+			ConnectTimeout:       30 * time.Second,
+			MigrateOnStart:       true,
+			MigrationLockTimeout: 5 * time.Minute,
+		},
+		Encryption: Encryption{
+			Key:        DefaultEncryptionKey,
+			KeyVersion: 1,
+			Keys:       map[int64]string{1: DefaultEncryptionKey},
+		},
+		Logging: Logging{
+			Level:  "info",
+			Format: "json",
+		},
+		AccessLog: AccessLog{
+			Enabled:           true,
+			SlowThreshold:     time.Second,
+			HealthSampleEvery: 100,
+		},
+		KeyValue: KeyValue{
+			SweepInterval: time.Minute,
+		},
+		TLS: TLS{
+			ClientAuth: "require",
+		},
+		Signing: Signing{
+			Window:     5 * time.Minute,
+			NonceStore: "postgres",
+		},
+		RateLimit: RateLimit{
+			Backend: "memory",
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the YAML file named by
+// CONFIG_FILE when set and then the environment, so environment variables
+// override the file. Secrets are read from the environment only, either
+// directly or from the file named by the variable with a _FILE suffix. All
+// problems found are returned together.
+func Load() (Config, error) {
+	return load(Config.Validate)
+}
+
+// LoadDatabase builds the configuration like Load but only validates the
+// database and logging settings, so commands that only touch the schema, such
+// as a migration job, do not need the secrets of the server.
+func LoadDatabase() (Config, error) {
+	return load(Config.ValidateDatabase)
+}
+
+// load builds the configuration and checks it with validate
+func load(validate func(Config) error) (Config, error) {
+	config := Default()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadFile(path, &config); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if err := loadEnv(&config); err != nil {
+		return Config{}, err
+	}
+	config.Encryption.Key = config.Encryption.Keys[config.Encryption.KeyVersion]
+	config.Logging.Level = strings.ToLower(config.Logging.Level)
+	config.Logging.Format = strings.ToLower(config.Logging.Format)
+
+	if err := validate(config); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// loadFile decodes the YAML file at path over config. Unknown keys are
+// rejected so typos are not silently ignored.
+func loadFile(path string, config *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read CONFIG_FILE: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse CONFIG_FILE %s: %w", path, err)
+	}
+	return nil
+}
+
+// checker collects the problems found while validating settings
+type checker struct {
+	problems []error
+}
+
+// check records a problem described by format and args unless ok
+func (c *checker) check(ok bool, format string, args ...any) {
+	if !ok {
+		c.problems = append(c.problems, fmt.Errorf(format, args...))
+	}
+}
+
+// Validate checks that every setting is in range and that related settings
+// are consistent. Settings parsed by the components themselves, such as the
+// signing secrets and rate limits, are validated when those are built.
+func (c Config) Validate() error {
+	v := &checker{}
+	check := v.check
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.Server.ReadTimeout > 0, "server.read_timeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.ReadinessDelay >= 0, "server.readiness_delay must not be negative")
+
+	c.checkDatabase(v)
+
+	_, ok := c.Encryption.KeyFor(c.Encryption.KeyVersion)
+	check(ok && c.Encryption.Key != "", "ENCRYPTION_KEY_%d is not set for encryption.key_version %d",
+		c.Encryption.KeyVersion, c.Encryption.KeyVersion)
+	check(c.Encryption.UnversionedKeyVersion >= 0, "encryption.unversioned_key_version must not be negative")
+
+	c.checkLogging(v)
+
+	check(c.AccessLog.SlowThreshold >= 0, "access_log.slow_threshold must not be negative")
+	check(c.AccessLog.HealthSampleEvery >= 0, "access_log.health_sample_every must not be negative")
+
+	check(c.KeyValue.SweepInterval > 0, "key_value.sweep_interval must be positive")
+
+	check(c.JWT.ClockSkew >= 0, "jwt.clock_skew must not be negative")
+
+	if c.TLS.Enabled() {
+		check(c.TLS.CertFile != "" && c.TLS.KeyFile != "", "tls.cert_file and tls.key_file must be set together")
+	} else {
+		check(c.TLS.ClientCAFile == "", "tls.client_ca_file requires tls.cert_file and tls.key_file")
+	}
+	check(c.TLS.ClientAuth == "require" || c.TLS.ClientAuth == "optional",
+		"tls.client_auth must be require or optional, got %q", c.TLS.ClientAuth)
+	check(c.TLS.IdentityFile == "" || c.TLS.ClientCAFile != "", "tls.client_identity_file requires tls.client_ca_file")
+
+	check(c.Signing.Window > 0, "request_signing.window must be positive")
+	check(c.Signing.NonceStore == "postgres" || c.Signing.NonceStore == "memory",
+		"request_signing.nonce_store must be postgres or memory, got %q", c.Signing.NonceStore)
+
+	check(c.RateLimit.Backend == "memory" || c.RateLimit.Backend == "postgres",
+		"rate_limit.backend must be memory or postgres, got %q", c.RateLimit.Backend)
+
+	return errors.Join(v.problems...)
+}
+
+// ValidateDatabase checks the database and logging settings only, which is
+// all the migrate command uses
+func (c Config) ValidateDatabase() error {
+	v := &checker{}
+	c.checkDatabase(v)
+	c.checkLogging(v)
+	return errors.Join(v.problems...)
+}
+
+// checkDatabase checks the database settings
+func (c Config) checkDatabase(v *checker) {
+	v.check(c.Database.URL != "", "DATABASE_URL is not set")
+	v.check(c.Database.MaxConns > 0, "database.max_conns must be positive")
+	v.check(c.Database.MinConns >= 0 && c.Database.MinConns <= c.Database.MaxConns,
+		"database.min_conns must be between 0 and database.max_conns")
+	v.check(c.Database.MaxConnLifetime > 0, "database.max_conn_lifetime must be positive")
+	v.check(c.Database.MaxConnIdleTime > 0, "database.max_conn_idle_time must be positive")
+	v.check(c.Database.HealthCheckPeriod > 0, "database.health_check_period must be positive")
+	v.check(c.Database.ConnectTimeout > 0, "database.connect_timeout must be positive")
+	v.check(c.Database.MigrationLockTimeout > 0, "database.migration_lock_timeout must be positive")
+	v.check(c.Database.ExpectSchemaVersion >= 0, "database.expect_schema_version must not be negative")
+}
+
+// checkLogging checks the logging settings
+func (c Config) checkLogging(v *checker) {
+	_, err := parseLevel(c.Logging.Level)
+	v.check(err == nil, "logging.level must be debug, info, warn or error, got %q", c.Logging.Level)
+	v.check(c.Logging.Format == "json" || c.Logging.Format == "text",
+		"logging.format must be json or text, got %q", c.Logging.Format)
+}
+
+// LogLevel returns the parsed logging level; Validate ensures it is valid
+func (l Logging) LogLevel() slog.Level {
+	level, _ := parseLevel(l.Level)
+	return level
+}
+
+// parseLevel parses a log level name such as debug, info, warn or error
+func parseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(strings.TrimSpace(name)))
+	return level, err
+}
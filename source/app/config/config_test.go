@@ -0,0 +1,363 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupEnv clears every variable Load reads and sets DATABASE_URL, the only
+// setting without a default
+func setupEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{
+		"CONFIG_FILE", "PORT", "SERVER_READ_TIMEOUT", "SERVER_WRITE_TIMEOUT", "SERVER_IDLE_TIMEOUT", "SHUTDOWN_TIMEOUT",
+		"SHUTDOWN_READINESS_DELAY", "DATABASE_URL_FILE", "DB_MAX_CONNS", "DB_MIN_CONNS", "DB_MAX_CONN_LIFETIME", "DB_MAX_CONN_IDLE_TIME",
+		"DB_HEALTH_CHECK_PERIOD", "DB_CONNECT_TIMEOUT", "TENANT_RLS", "MIGRATE_ON_START",
+		"DB_MIGRATION_LOCK_TIMEOUT", "EXPECT_SCHEMA_VERSION",
+		"ENCRYPTION_KEY_1", "ENCRYPTION_KEY_1_FILE", "ENCRYPTION_KEY_2", "ENCRYPTION_KEY_2_FILE", "ENCRYPTION_KEY_VERSION",
+		"ENCRYPTION_UNVERSIONED_KEY_VERSION", "LOG_LEVEL", "LOG_FORMAT", "LOG_REDACT_KEYS", "GCP_PROJECT_ID",
+		"ACCESS_LOG", "ACCESS_LOG_SLOW_THRESHOLD", "ACCESS_LOG_HEALTH_SAMPLE",
+		"KV_ENCRYPT_VALUES", "KV_SWEEP_INTERVAL",
+		"JWT_JWKS", "JWT_ISSUER", "JWT_AUDIENCE", "JWT_SCOPES_CLAIM", "JWT_SCOPE_PREFIX",
+		"JWT_NAMESPACE_CLAIM", "JWT_TENANT_CLAIM", "JWT_CLOCK_SKEW",
+		"TLS_CERT_FILE", "TLS_KEY_FILE", "TLS_CLIENT_CA_FILE", "TLS_CLIENT_IDENTITY_FILE", "TLS_CLIENT_AUTH",
+		"REQUEST_SIGNING_ROUTES", "REQUEST_SIGNING_KEYS", "REQUEST_SIGNING_KEYS_FILE",
+		"REQUEST_SIGNING_PRINCIPALS", "REQUEST_SIGNING_WINDOW", "REQUEST_SIGNING_NONCE_STORE",
+		"RATE_LIMIT_READ", "RATE_LIMIT_WRITE", "RATE_LIMIT_SEARCH", "RATE_LIMIT_BACKEND",
+		"ATTRIBUTE_POLICY_FILE", "REDACTION_PROFILES_FILE", "PURPOSE_REGISTRY",
+	} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("DATABASE_URL", "postgres://localhost/person")
+}
+
+// writeFile writes content to a file in a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	setupEnv(t)
+
+	config, err := Load()
+
+	assert.NoError(t, err)
+	expected := Default()
+	expected.Database.URL = "postgres://localhost/person"
+	assert.Equal(t, expected, config)
+}
+
+func TestLoad_DatabaseURLRequired(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("DATABASE_URL", "")
+
+	_, err := Load()
+
+	assert.ErrorContains(t, err, "DATABASE_URL is not set")
+}
+
+func TestLoad_Environment(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("PORT", "8080")
+	t.Setenv("SHUTDOWN_TIMEOUT", "30s")
+	t.Setenv("SHUTDOWN_READINESS_DELAY", "5s")
+	t.Setenv("DB_MAX_CONNS", "50")
+	t.Setenv("TENANT_RLS", "true")
+	t.Setenv("ENCRYPTION_KEY_1", "secret-key")
+	t.Setenv("LOG_LEVEL", "DEBUG")
+	t.Setenv("LOG_REDACT_KEYS", "ssn, , passport")
+	t.Setenv("ACCESS_LOG", "false")
+	t.Setenv("REQUEST_SIGNING_ROUTES", "admin,person")
+	t.Setenv("RATE_LIMIT_READ", "600/m")
+	t.Setenv("PURPOSE_REGISTRY", "treatment,billing")
+
+	config, err := Load()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, config.Server.Port)
+	assert.Equal(t, 30*time.Second, config.Server.ShutdownTimeout)
+	assert.Equal(t, 5*time.Second, config.Server.ReadinessDelay)
+	assert.Equal(t, int32(50), config.Database.MaxConns)
+	assert.True(t, config.Database.TenantRLS)
+	assert.Equal(t, "secret-key", config.Encryption.Key)
+	assert.Equal(t, "debug", config.Logging.Level)
+	assert.Equal(t, []string{"ssn", "passport"}, config.Logging.RedactKeys)
+	assert.False(t, config.AccessLog.Enabled)
+	assert.Equal(t, []string{"admin", "person"}, config.Signing.Routes)
+	assert.Equal(t, "600/m", config.RateLimit.Read)
+	assert.Equal(t, []string{"treatment", "billing"}, config.Access.Purposes)
+}
+
+func TestLoad_File(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", `
+server:
+  port: 8080
+  shutdown_timeout: 20s
+logging:
+  level: warn
+database:
+  expect_schema_version: 14
+rate_limit:
+  read: 100/s
+  backend: postgres
+`))
+	// The environment overrides the file
+	t.Setenv("PORT", "9090")
+
+	config, err := Load()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 9090, config.Server.Port)
+	assert.Equal(t, 20*time.Second, config.Server.ShutdownTimeout)
+	assert.Equal(t, "warn", config.Logging.Level)
+	assert.Equal(t, "100/s", config.RateLimit.Read)
+	assert.Equal(t, "postgres", config.RateLimit.Backend)
+	assert.Equal(t, int64(14), config.Database.ExpectSchemaVersion)
+	// Settings missing from the file keep their defaults
+	assert.Equal(t, 15*time.Second, config.Server.ReadTimeout)
+}
+
+func TestLoad_FileEmpty(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", ""))
+
+	_, err := Load()
+
+	assert.NoError(t, err)
+}
+
+func TestLoad_FileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{name: "unknown key", content: "server:\n  prot: 8080\n", err: "field prot not found"},
+		{name: "secret in file", content: "database:\n  url: postgres://localhost\n", err: "field url not found"},
+		{name: "invalid duration", content: "server:\n  read_timeout: soon\n", err: "failed to parse CONFIG_FILE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupEnv(t)
+			t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", tt.content))
+
+			_, err := Load()
+
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestLoad_FileMissing(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+
+	_, err := Load()
+
+	assert.ErrorContains(t, err, "failed to read CONFIG_FILE")
+}
+
+func TestLoad_SecretFiles(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("DATABASE_URL", "postgres://ignored")
+	t.Setenv("DATABASE_URL_FILE", writeFile(t, "database_url", "postgres://db/person\n"))
+	t.Setenv("ENCRYPTION_KEY_1_FILE", writeFile(t, "encryption_key", "file-key\n"))
+
+	config, err := Load()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://db/person", config.Database.URL, "the file takes precedence")
+	assert.Equal(t, "file-key", config.Encryption.Key, "trailing newline is trimmed")
+}
+
+func TestLoad_EncryptionKeys(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("ENCRYPTION_KEY_1", "old-key")
+	t.Setenv("ENCRYPTION_KEY_2_FILE", writeFile(t, "encryption_key_2", "new-key\n"))
+
+	config, err := Load()
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]string{1: "old-key", 2: "new-key"}, config.Encryption.Keys)
+	assert.Equal(t, int64(1), config.Encryption.KeyVersion, "version 1 stays current until changed")
+	assert.Equal(t, "old-key", config.Encryption.Key)
+	assert.Equal(t, int64(1), config.Encryption.UnversionedVersion())
+
+	t.Setenv("ENCRYPTION_KEY_VERSION", "2")
+
+	config, err = Load()
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), config.Encryption.KeyVersion)
+	assert.Equal(t, "new-key", config.Encryption.Key)
+	key, ok := config.Encryption.KeyFor(1)
+	assert.True(t, ok)
+	assert.Equal(t, "old-key", key)
+	assert.Equal(t, int64(2), config.Encryption.UnversionedVersion(), "unversioned values default to the current version")
+
+	t.Setenv("ENCRYPTION_UNVERSIONED_KEY_VERSION", "1")
+
+	config, err = Load()
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), config.Encryption.UnversionedVersion())
+}
+
+func TestLoad_EncryptionKeyVersionNotConfigured(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("ENCRYPTION_KEY_1", "old-key")
+	t.Setenv("ENCRYPTION_KEY_VERSION", "3")
+
+	_, err := Load()
+
+	assert.ErrorContains(t, err, "ENCRYPTION_KEY_3 is not set")
+}
+
+func TestLoadDatabase(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("ENCRYPTION_KEY_VERSION", "3")
+	t.Setenv("PORT", "0")
+	t.Setenv("REQUEST_SIGNING_NONCE_STORE", "redis")
+
+	// Settings the migrate command does not use are not validated
+	config, err := LoadDatabase()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://localhost/person", config.Database.URL)
+
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("DB_MIGRATION_LOCK_TIMEOUT", "0s")
+	t.Setenv("LOG_LEVEL", "verbose")
+
+	_, err = LoadDatabase()
+
+	assert.ErrorContains(t, err, "DATABASE_URL is not set")
+	assert.ErrorContains(t, err, "database.migration_lock_timeout must be positive")
+	assert.ErrorContains(t, err, "logging.level must be debug, info, warn or error")
+	assert.NotContains(t, err.Error(), "ENCRYPTION_KEY_3")
+}
+
+func TestLoad_SecretFileMissing(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("ENCRYPTION_KEY_1_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	_, err := Load()
+
+	assert.ErrorContains(t, err, "failed to read ENCRYPTION_KEY_1_FILE")
+}
+
+func TestLoad_InvalidValuesReportedTogether(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("PORT", "http")
+	t.Setenv("SHUTDOWN_TIMEOUT", "ten")
+	t.Setenv("TENANT_RLS", "yes please")
+
+	_, err := Load()
+
+	assert.ErrorContains(t, err, `invalid PORT "http"`)
+	assert.ErrorContains(t, err, `invalid SHUTDOWN_TIMEOUT "ten"`)
+	assert.ErrorContains(t, err, `invalid TENANT_RLS "yes please"`)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		err    string
+	}{
+		{name: "port out of range", modify: func(c *Config) { c.Server.Port = 70000 }, err: "server.port"},
+		{name: "zero shutdown timeout", modify: func(c *Config) { c.Server.ShutdownTimeout = 0 }, err: "server.shutdown_timeout"},
+		{name: "negative readiness delay", modify: func(c *Config) { c.Server.ReadinessDelay = -time.Second }, err: "server.readiness_delay"},
+		{name: "min conns above max", modify: func(c *Config) { c.Database.MinConns = 30 }, err: "database.min_conns"},
+		{name: "zero migration lock timeout", modify: func(c *Config) { c.Database.MigrationLockTimeout = 0 }, err: "database.migration_lock_timeout"},
+		{name: "negative schema version", modify: func(c *Config) { c.Database.ExpectSchemaVersion = -1 }, err: "database.expect_schema_version"},
+		{name: "key version without key", modify: func(c *Config) { c.Encryption.KeyVersion = 2 }, err: "ENCRYPTION_KEY_2 is not set"},
+		{name: "negative unversioned key version", modify: func(c *Config) { c.Encryption.UnversionedKeyVersion = -1 }, err: "encryption.unversioned_key_version"},
+		{name: "unknown log level", modify: func(c *Config) { c.Logging.Level = "loud" }, err: "logging.level"},
+		{name: "unknown log format", modify: func(c *Config) { c.Logging.Format = "xml" }, err: "logging.format"},
+		{name: "negative slow threshold", modify: func(c *Config) { c.AccessLog.SlowThreshold = -time.Second }, err: "access_log.slow_threshold"},
+		{name: "zero sweep interval", modify: func(c *Config) { c.KeyValue.SweepInterval = 0 }, err: "key_value.sweep_interval"},
+		{name: "zero signing window", modify: func(c *Config) { c.Signing.Window = 0 }, err: "request_signing.window"},
+		{name: "unknown nonce store", modify: func(c *Config) { c.Signing.NonceStore = "redis" }, err: "request_signing.nonce_store"},
+		{name: "unknown rate limit backend", modify: func(c *Config) { c.RateLimit.Backend = "redis" }, err: "rate_limit.backend"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Default()
+			config.Database.URL = "postgres://localhost/person"
+			tt.modify(&config)
+
+			assert.ErrorContains(t, config.Validate(), tt.err)
+		})
+	}
+}
+
+func TestValidate_TLS(t *testing.T) {
+	config := Default()
+	config.Database.URL = "postgres://localhost/person"
+	assert.NoError(t, config.Validate())
+	assert.False(t, config.TLS.Enabled())
+
+	config.TLS.CertFile = "tls.crt"
+	assert.Error(t, config.Validate(), "Certificate and key must be set together")
+
+	config.TLS.KeyFile = "tls.key"
+	assert.NoError(t, config.Validate())
+	assert.True(t, config.TLS.Enabled())
+	assert.False(t, config.TLS.RequireClientCert())
+
+	config.TLS.IdentityFile = "identities.json"
+	assert.Error(t, config.Validate(), "Identities require a client CA bundle")
+
+	config.TLS.ClientCAFile = "ca.crt"
+	assert.NoError(t, config.Validate())
+	assert.True(t, config.TLS.RequireClientCert())
+
+	config.TLS.ClientAuth = "optional"
+	assert.NoError(t, config.Validate())
+	assert.False(t, config.TLS.RequireClientCert())
+
+	config.TLS.ClientAuth = "sometimes"
+	assert.Error(t, config.Validate())
+}
+
+func TestLoadBootstrapKeys(t *testing.T) {
+	for _, name := range []string{"PERSON_API_KEY_BLUE", "PERSON_API_KEY_BLUE_FILE", "PERSON_API_KEY_BLUE_NAMESPACE", "PERSON_API_KEY_BLUE_TENANT", "PERSON_API_KEY_GREEN", "PERSON_API_KEY_GREEN_FILE"} {
+		t.Setenv(name, "")
+	}
+
+	keys, err := LoadBootstrapKeys()
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	t.Setenv("PERSON_API_KEY_BLUE", "blue-key")
+	t.Setenv("PERSON_API_KEY_BLUE_NAMESPACE", "billing")
+	t.Setenv("PERSON_API_KEY_BLUE_TENANT", "acme")
+	t.Setenv("PERSON_API_KEY_GREEN_FILE", writeFile(t, "green", "green-key\n"))
+
+	keys, err = LoadBootstrapKeys()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []BootstrapKey{
+		{Name: "blue", Key: "blue-key", Namespace: "billing", Tenant: "acme"},
+		{Name: "green", Key: "green-key"},
+	}, keys)
+}
+
+func TestLoadBootstrapKeys_MissingFile(t *testing.T) {
+	t.Setenv("PERSON_API_KEY_BLUE_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	_, err := LoadBootstrapKeys()
+
+	assert.ErrorContains(t, err, "failed to read PERSON_API_KEY_BLUE_FILE")
+}
@@ -0,0 +1,246 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// env reads environment variables over a configuration and collects the
+// values that cannot be parsed
+type env struct {
+	problems []error
+}
+
+// loadEnv applies the environment variables that are set to config
+func loadEnv(config *Config) error {
+	e := &env{}
+
+	e.int("PORT", &config.Server.Port)
+	e.duration("SERVER_READ_TIMEOUT", &config.Server.ReadTimeout)
+	e.duration("SERVER_WRITE_TIMEOUT", &config.Server.WriteTimeout)
+	e.duration("SERVER_IDLE_TIMEOUT", &config.Server.IdleTimeout)
+	e.duration("SHUTDOWN_TIMEOUT", &config.Server.ShutdownTimeout)
+	e.duration("SHUTDOWN_READINESS_DELAY", &config.Server.ReadinessDelay)
+
+	e.secret("DATABASE_URL", &config.Database.URL)
+	e.int32("DB_MAX_CONNS", &config.Database.MaxConns)
+	e.int32("DB_MIN_CONNS", &config.Database.MinConns)
+	e.duration("DB_MAX_CONN_LIFETIME", &config.Database.MaxConnLifetime)
+	e.duration("DB_MAX_CONN_IDLE_TIME", &config.Database.MaxConnIdleTime)
+	e.duration("DB_HEALTH_CHECK_PERIOD", &config.Database.HealthCheckPeriod)
+	e.duration("DB_CONNECT_TIMEOUT", &config.Database.ConnectTimeout)
+	e.bool("TENANT_RLS", &config.Database.TenantRLS)
+	e.bool("MIGRATE_ON_START", &config.Database.MigrateOnStart)
+	e.duration("DB_MIGRATION_LOCK_TIMEOUT", &config.Database.MigrationLockTimeout)
+	e.int64("EXPECT_SCHEMA_VERSION", &config.Database.ExpectSchemaVersion)
+
+	e.encryptionKeys(&config.Encryption.Keys)
+	e.int64("ENCRYPTION_KEY_VERSION", &config.Encryption.KeyVersion)
+	e.int64("ENCRYPTION_UNVERSIONED_KEY_VERSION", &config.Encryption.UnversionedKeyVersion)
+
+	e.string("LOG_LEVEL", &config.Logging.Level)
+	e.string("LOG_FORMAT", &config.Logging.Format)
+	e.list("LOG_REDACT_KEYS", &config.Logging.RedactKeys)
+	e.string("GCP_PROJECT_ID", &config.Logging.GCPProjectID)
+
+	e.bool("ACCESS_LOG", &config.AccessLog.Enabled)
+	e.duration("ACCESS_LOG_SLOW_THRESHOLD", &config.AccessLog.SlowThreshold)
+	e.int("ACCESS_LOG_HEALTH_SAMPLE", &config.AccessLog.HealthSampleEvery)
+
+	e.bool("KV_ENCRYPT_VALUES", &config.KeyValue.EncryptValues)
+	e.duration("KV_SWEEP_INTERVAL", &config.KeyValue.SweepInterval)
+
+	e.string("JWT_JWKS", &config.JWT.JWKS)
+	e.string("JWT_ISSUER", &config.JWT.Issuer)
+	e.string("JWT_AUDIENCE", &config.JWT.Audience)
+	e.string("JWT_SCOPES_CLAIM", &config.JWT.ScopesClaim)
+	e.string("JWT_SCOPE_PREFIX", &config.JWT.ScopePrefix)
+	e.string("JWT_NAMESPACE_CLAIM", &config.JWT.NamespaceClaim)
+	e.string("JWT_TENANT_CLAIM", &config.JWT.TenantClaim)
+	e.duration("JWT_CLOCK_SKEW", &config.JWT.ClockSkew)
+
+	e.string("TLS_CERT_FILE", &config.TLS.CertFile)
+	e.string("TLS_KEY_FILE", &config.TLS.KeyFile)
+	e.string("TLS_CLIENT_CA_FILE", &config.TLS.ClientCAFile)
+	e.string("TLS_CLIENT_IDENTITY_FILE", &config.TLS.IdentityFile)
+	e.string("TLS_CLIENT_AUTH", &config.TLS.ClientAuth)
+
+	e.list("REQUEST_SIGNING_ROUTES", &config.Signing.Routes)
+	e.secret("REQUEST_SIGNING_KEYS", &config.Signing.Keys)
+	e.string("REQUEST_SIGNING_PRINCIPALS", &config.Signing.Principals)
+	e.duration("REQUEST_SIGNING_WINDOW", &config.Signing.Window)
+	e.string("REQUEST_SIGNING_NONCE_STORE", &config.Signing.NonceStore)
+
+	e.string("RATE_LIMIT_READ", &config.RateLimit.Read)
+	e.string("RATE_LIMIT_WRITE", &config.RateLimit.Write)
+	e.string("RATE_LIMIT_SEARCH", &config.RateLimit.Search)
+	e.string("RATE_LIMIT_BACKEND", &config.RateLimit.Backend)
+
+	e.string("ATTRIBUTE_POLICY_FILE", &config.Access.AttributePolicyFile)
+	e.string("REDACTION_PROFILES_FILE", &config.Access.RedactionProfilesFile)
+	e.list("PURPOSE_REGISTRY", &config.Access.Purposes)
+
+	return errors.Join(e.problems...)
+}
+
+// lookup returns the trimmed value of name and whether it is set and not empty
+func (e *env) lookup(name string) (string, bool) {
+	value := strings.TrimSpace(os.Getenv(name))
+	return value, value != ""
+}
+
+// invalid records that name holds a value that cannot be parsed
+func (e *env) invalid(name, value, expected string) {
+	e.problems = append(e.problems, fmt.Errorf("invalid %s %q: expected %s", name, value, expected))
+}
+
+func (e *env) string(name string, target *string) {
+	if value, ok := e.lookup(name); ok {
+		*target = value
+	}
+}
+
+// list reads a comma-separated list, dropping empty items
+func (e *env) list(name string, target *[]string) {
+	value, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*target = items
+}
+
+func (e *env) int(name string, target *int) {
+	value, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		e.invalid(name, value, "an integer")
+		return
+	}
+	*target = parsed
+}
+
+func (e *env) int32(name string, target *int32) {
+	value, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		e.invalid(name, value, "an integer")
+		return
+	}
+	*target = int32(parsed)
+}
+
+func (e *env) int64(name string, target *int64) {
+	value, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		e.invalid(name, value, "an integer")
+		return
+	}
+	*target = parsed
+}
+
+func (e *env) bool(name string, target *bool) {
+	value, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		e.invalid(name, value, "true or false")
+		return
+	}
+	*target = parsed
+}
+
+func (e *env) duration(name string, target *time.Duration) {
+	value, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		e.invalid(name, value, "a duration such as 30s or 5m")
+		return
+	}
+	*target = parsed
+}
+
+// secret reads name from the file named by name_FILE when that is set, so
+// mounted secrets stay out of the environment, and from name otherwise
+func (e *env) secret(name string, target *string) {
+	value, err := ReadSecret(name)
+	if err != nil {
+		e.problems = append(e.problems, err)
+		return
+	}
+	if value != "" {
+		*target = value
+	}
+}
+
+// encryptionKeyPattern matches the variables holding an encryption key and
+// captures the key version
+var encryptionKeyPattern = regexp.MustCompile(`^ENCRYPTION_KEY_([0-9]+)(_FILE)?$`)
+
+// encryptionKeys reads every ENCRYPTION_KEY_<VERSION> secret that is set. The
+// keys replace target, so the development key is only used when none is set.
+func (e *env) encryptionKeys(target *map[int64]string) {
+	keys := make(map[int64]string)
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+		match := encryptionKeyPattern.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			e.invalid(name, match[1], "a positive key version")
+			continue
+		}
+		if _, seen := keys[version]; seen {
+			continue
+		}
+		var key string
+		e.secret("ENCRYPTION_KEY_"+match[1], &key)
+		if key != "" {
+			keys[version] = key
+		}
+	}
+	if len(keys) > 0 {
+		*target = keys
+	}
+}
+
+// ReadSecret returns the value of the secret name, read from the file named
+// by name_FILE when that is set and from the variable name otherwise.
+// Surrounding whitespace, such as a trailing newline in the file, is removed.
+func ReadSecret(name string) (string, error) {
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s_FILE: %w", name, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return strings.TrimSpace(os.Getenv(name)), nil
+}
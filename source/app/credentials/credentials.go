@@ -0,0 +1,184 @@
+package credentials
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"person-service/auth"
+	errs "person-service/errors"
+	db "person-service/internal/db/generated"
+	"person-service/logging"
+	"person-service/purpose"
+	"person-service/redaction"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// keyPrefixLength is how many leading characters of a key are stored for identification.
+	// It covers "person-service-key-" plus the first 8 characters of the UUID.
+	keyPrefixLength = 27
+)
+
+// ErrInvalidCredential is returned when a key does not match an active credential
+var ErrInvalidCredential = errors.New("invalid API credential")
+
+// Store issues and authenticates database-backed API credentials
+type Store struct {
+	queries  *db.Queries
+	profiles *redaction.Profiles
+}
+
+// NewStore creates a new instance of Store with injected queries
+func NewStore(queries *db.Queries) *Store {
+	return &Store{
+		queries:  queries,
+		profiles: redaction.Default(),
+	}
+}
+
+// SetRedactionProfiles sets the redaction profiles credentials can be bound to.
+// The built-in full and masked profiles are used until it is called.
+func (s *Store) SetRedactionProfiles(profiles *redaction.Profiles) {
+	s.profiles = profiles
+}
+
+// GenerateKey returns a new random API key in the person-service-key-<UUID> format
+func GenerateKey() string {
+	return "person-service-key-" + uuid.New().String()
+}
+
+// HashKey returns the SHA-256 hash under which a key is stored.
+// Keys carry 122 random bits, so a fast unsalted hash is sufficient.
+func HashKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+// IssueError is returned by Issue when a credential request is invalid
+type IssueError struct {
+	Message   string
+	ErrorCode string
+}
+
+func (e *IssueError) Error() string {
+	return e.Message
+}
+
+// Issue validates req and stores a new credential. It returns the credential
+// and its plaintext key, which is not stored and cannot be shown again.
+func (s *Store) Issue(ctx context.Context, req CreateCredentialRequest) (db.ApiCredential, string, error) {
+	if strings.TrimSpace(req.Name) == "" || strings.TrimSpace(req.Owner) == "" {
+		return db.ApiCredential{}, "", &IssueError{"name and owner are required", errs.ErrCredMissingNameOrOwner}
+	}
+
+	if len(req.Scopes) == 0 {
+		return db.ApiCredential{}, "", &IssueError{"At least one scope is required", errs.ErrCredInvalidScope}
+	}
+	for _, scope := range req.Scopes {
+		if !auth.IsValidScope(scope) {
+			return db.ApiCredential{}, "", &IssueError{"Unknown scope \"" + scope + "\"", errs.ErrCredInvalidScope}
+		}
+	}
+
+	for _, name := range req.Purposes {
+		if !purpose.IsValidName(name) {
+			return db.ApiCredential{}, "", &IssueError{"Invalid purpose \"" + name + "\"", errs.ErrCredInvalidPurpose}
+		}
+	}
+	purposes := req.Purposes
+	if purposes == nil {
+		purposes = []string{}
+	}
+
+	if req.RedactionProfile != "" && !s.profiles.Has(req.RedactionProfile) {
+		return db.ApiCredential{}, "", &IssueError{"Unknown redaction profile \"" + req.RedactionProfile + "\"", errs.ErrCredUnknownRedactionProfile}
+	}
+
+	var expiresAt pgtype.Timestamptz
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			return db.ApiCredential{}, "", &IssueError{"expires_at must be in the future", errs.ErrCredInvalidExpiry}
+		}
+		expiresAt = pgtype.Timestamptz{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	namespace := req.Namespace
+	if namespace == "" {
+		namespace = auth.DefaultNamespace
+	}
+
+	tenant := req.Tenant
+	if tenant == "" {
+		tenant = auth.DefaultTenant
+	}
+
+	key := GenerateKey()
+	credential, err := s.queries.CreateAPICredential(ctx, db.CreateAPICredentialParams{
+		Name:             req.Name,
+		Owner:            req.Owner,
+		KeyHash:          HashKey(key),
+		KeyPrefix:        key[:keyPrefixLength],
+		Scopes:           req.Scopes,
+		Namespace:        namespace,
+		TenantID:         tenant,
+		RedactionProfile: pgtype.Text{String: req.RedactionProfile, Valid: req.RedactionProfile != ""},
+		Purposes:         purposes,
+		ExpiresAt:        expiresAt,
+	})
+	if err != nil {
+		return db.ApiCredential{}, "", fmt.Errorf("failed to create API credential: %w", err)
+	}
+	return credential, key, nil
+}
+
+// Authenticate resolves a presented API key to the principal of its credential
+// and records the use. It returns ErrInvalidCredential when the key is unknown,
+// revoked or expired.
+func (s *Store) Authenticate(ctx context.Context, key string) (auth.Principal, error) {
+	credential, err := s.queries.GetActiveAPICredentialByHash(ctx, HashKey(key))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return auth.Principal{}, ErrInvalidCredential
+		}
+		return auth.Principal{}, fmt.Errorf("failed to look up API credential: %w", err)
+	}
+
+	// Usage tracking must not block authentication
+	if err := s.queries.TouchAPICredential(ctx, credential.ID); err != nil {
+		logging.WarnContext(ctx, "Failed to record API credential use",
+			"credential_id", formatUUID(credential.ID),
+			"error", err)
+	}
+
+	return auth.Principal{
+		ID:               formatUUID(credential.ID),
+		Source:           auth.SourceCredential,
+		Name:             credential.Name,
+		Namespace:        credential.Namespace,
+		Tenant:           credential.TenantID,
+		Scopes:           credential.Scopes,
+		RedactionProfile: credential.RedactionProfile.String,
+		Purposes:         credential.Purposes,
+	}, nil
+}
+
+// formatUUID converts pgtype.UUID to a string representation
+func formatUUID(u pgtype.UUID) string {
+	b := u.Bytes
+	return fmt.Sprintf("%x-%x-%x-%x-%x",
+		b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// parseUUID parses a UUID string into pgtype.UUID
+func parseUUID(s string) (pgtype.UUID, error) {
+	var u pgtype.UUID
+	err := u.Scan(s)
+	return u, err
+}
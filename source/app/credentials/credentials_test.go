@@ -0,0 +1,176 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"person-service/auth"
+	db "person-service/internal/db/generated"
+	"person-service/internal/testdb"
+)
+
+var pool *pgxpool.Pool
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+	var err error
+	pool, err = testdb.GetPool(ctx)
+	if err != nil {
+		log.Fatalf("Failed to get pool: %v", err)
+	}
+	if err := testdb.RunMigrations(ctx, pool); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+	os.Exit(m.Run())
+}
+
+// createCredentialRequest calls CreateCredential with body and returns the recorder and decoded response
+func createCredentialRequest(t *testing.T, handler *CredentialsHandler, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/admin/api-keys", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	assert.NoError(t, handler.CreateCredential(e.NewContext(req, rec)))
+
+	var response map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &response)
+	return rec, response
+}
+
+// revokeCredentialRequest calls RevokeCredential for id
+func revokeCredentialRequest(t *testing.T, handler *CredentialsHandler, id string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/admin/api-keys/"+id, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+	assert.NoError(t, handler.RevokeCredential(c))
+	return rec
+}
+
+func TestCreateCredential_Success(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	handler := NewCredentialsHandler(db.New(pool))
+	rec, response := createCredentialRequest(t, handler,
+		`{"name":"billing","owner":"team-billing","scopes":["person:read","kv:write"],"namespace":"billing","tenant":"acme","redaction_profile":"masked","purposes":["customer_support"]}`)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	key, ok := response["api_key"].(string)
+	assert.True(t, ok)
+	assert.True(t, strings.HasPrefix(key, response["key_prefix"].(string)))
+	assert.Equal(t, "billing", response["namespace"])
+	assert.Equal(t, "acme", response["tenant"])
+	assert.Equal(t, "masked", response["redaction_profile"])
+	assert.NotContains(t, response, "key_hash")
+
+	// The issued key authenticates as the new credential
+	principal, err := NewStore(db.New(pool)).Authenticate(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, response["id"], principal.ID)
+	assert.Equal(t, "billing", principal.Namespace)
+	assert.Equal(t, "acme", principal.Tenant)
+	assert.Equal(t, "masked", principal.RedactionProfile)
+	assert.Equal(t, []string{"customer_support"}, principal.Purposes)
+	assert.True(t, principal.HasScope(auth.ScopeKVWrite))
+	assert.False(t, principal.HasScope(auth.ScopeAdmin))
+}
+
+func TestCreateCredential_Validation(t *testing.T) {
+	handler := NewCredentialsHandler(db.New(pool))
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name      string
+		body      string
+		errorCode string
+	}{
+		{"invalid body", `{"name":`, "CRED_001_INVALID_REQUEST_BODY"},
+		{"missing owner", `{"name":"n","scopes":["person:read"]}`, "CRED_002_MISSING_NAME_OR_OWNER"},
+		{"no scopes", `{"name":"n","owner":"o"}`, "CRED_003_INVALID_SCOPE"},
+		{"unknown scope", `{"name":"n","owner":"o","scopes":["person:delete"]}`, "CRED_003_INVALID_SCOPE"},
+		{"expired", `{"name":"n","owner":"o","scopes":["person:read"],"expires_at":"` + past + `"}`, "CRED_004_INVALID_EXPIRY"},
+		{"invalid purpose", `{"name":"n","owner":"o","scopes":["person:read"],"purposes":["Customer Support"]}`, "CRED_006_INVALID_PURPOSE"},
+		{"unknown redaction profile", `{"name":"n","owner":"o","scopes":["person:read"],"redaction_profile":"support"}`, "CRED_007_UNKNOWN_REDACTION_PROFILE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, _ := createCredentialRequest(t, handler, tt.body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.errorCode)
+		})
+	}
+}
+
+func TestListCredentials_OmitsSecrets(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	handler := NewCredentialsHandler(db.New(pool))
+	_, created := createCredentialRequest(t, handler, `{"name":"reader","owner":"ops","scopes":["person:read"]}`)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/admin/api-keys", nil)
+	rec := httptest.NewRecorder()
+	assert.NoError(t, handler.ListCredentials(e.NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), created["api_key"])
+
+	var response map[string][]map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Len(t, response["items"], 1)
+	assert.Equal(t, "reader", response["items"][0]["name"])
+	assert.Equal(t, created["key_prefix"], response["items"][0]["key_prefix"])
+}
+
+func TestRevokeCredential(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	handler := NewCredentialsHandler(db.New(pool))
+	store := NewStore(db.New(pool))
+	_, created := createCredentialRequest(t, handler, `{"name":"temp","owner":"ops","scopes":["kv:read"]}`)
+	id := created["id"].(string)
+	key := created["api_key"].(string)
+
+	rec := revokeCredentialRequest(t, handler, id)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Revoked keys no longer authenticate
+	_, err = store.Authenticate(ctx, key)
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+
+	// Revoking again reports not found
+	rec = revokeCredentialRequest(t, handler, id)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), "CRED_101_NOT_FOUND")
+
+	rec = revokeCredentialRequest(t, handler, "not-a-uuid")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "CRED_005_INVALID_ID")
+}
+
+func TestAuthenticate_UnknownKey(t *testing.T) {
+	_, err := NewStore(db.New(pool)).Authenticate(context.Background(), GenerateKey())
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+}
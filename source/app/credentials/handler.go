@@ -0,0 +1,182 @@
+package credentials
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	errs "person-service/errors"
+	db "person-service/internal/db/generated"
+	"person-service/logging"
+	"person-service/redaction"
+
+	"github.com/labstack/echo/v4"
+)
+
+// CreateCredentialRequest represents the request body for issuing an API key.
+// Namespace defaults to auth.DefaultNamespace, Tenant defaults to
+// auth.DefaultTenant, and RedactionProfile and ExpiresAt are optional.
+// RedactionProfile must name a loaded redaction profile and is applied to
+// every attribute value the key reads, and Purposes limits the purposes of use
+// it may declare; empty allows all.
+type CreateCredentialRequest struct {
+	Name             string     `json:"name"`
+	Owner            string     `json:"owner"`
+	Scopes           []string   `json:"scopes"`
+	Namespace        string     `json:"namespace,omitempty"`
+	Tenant           string     `json:"tenant,omitempty"`
+	RedactionProfile string     `json:"redaction_profile,omitempty"`
+	Purposes         []string   `json:"purposes,omitempty"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+}
+
+// CredentialsHandler handles the admin API for API credentials
+type CredentialsHandler struct {
+	queries *db.Queries
+	store   *Store
+}
+
+// NewCredentialsHandler creates a new instance of CredentialsHandler with injected queries
+func NewCredentialsHandler(queries *db.Queries) *CredentialsHandler {
+	return &CredentialsHandler{
+		queries: queries,
+		store:   NewStore(queries),
+	}
+}
+
+// SetRedactionProfiles sets the redaction profiles credentials can be bound to.
+// The built-in full and masked profiles are used until it is called.
+func (h *CredentialsHandler) SetRedactionProfiles(profiles *redaction.Profiles) {
+	h.store.SetRedactionProfiles(profiles)
+}
+
+// CreateCredential handles POST /admin/api-keys - issues a new API key.
+// The plaintext key is only returned in this response; only its hash is stored.
+func (h *CredentialsHandler) CreateCredential(c echo.Context) error {
+	var req CreateCredentialRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+			Message:   "Invalid request body",
+			ErrorCode: errs.ErrCredInvalidRequestBody,
+		})
+	}
+
+	// Use request context for trace propagation
+	ctx := c.Request().Context()
+
+	credential, key, err := h.store.Issue(ctx, req)
+	var issueErr *IssueError
+	if errors.As(err, &issueErr) {
+		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+			Message:   issueErr.Message,
+			ErrorCode: issueErr.ErrorCode,
+		})
+	}
+	if err != nil {
+		logging.ErrorContext(ctx, "Failed to create API credential", "error", err)
+		return c.JSON(http.StatusInternalServerError, errs.ErrorResponse{
+			Message:   "Failed to create API key",
+			ErrorCode: errs.ErrCredFailedCreate,
+		})
+	}
+
+	logging.InfoContext(ctx, "API credential issued",
+		"credential_id", formatUUID(credential.ID),
+		"name", credential.Name,
+		"owner", credential.Owner)
+
+	response := CredentialResponse(credential)
+	response["api_key"] = key
+	return c.JSON(http.StatusCreated, response)
+}
+
+// ListCredentials handles GET /admin/api-keys - lists issued API keys without secrets
+func (h *CredentialsHandler) ListCredentials(c echo.Context) error {
+	// Use request context for trace propagation
+	ctx := c.Request().Context()
+
+	credentials, err := h.queries.ListAPICredentials(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, errs.ErrorResponse{
+			Message:   "Failed to list API keys",
+			ErrorCode: errs.ErrCredFailedList,
+		})
+	}
+
+	items := make([]map[string]interface{}, 0, len(credentials))
+	for _, credential := range credentials {
+		items = append(items, CredentialResponse(credential))
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"items": items,
+	})
+}
+
+// RevokeCredential handles DELETE /admin/api-keys/:id - revokes an API key immediately
+func (h *CredentialsHandler) RevokeCredential(c echo.Context) error {
+	id, err := parseUUID(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+			Message:   "Invalid API key id",
+			ErrorCode: errs.ErrCredInvalidID,
+		})
+	}
+
+	// Use request context for trace propagation
+	ctx := c.Request().Context()
+
+	revoked, err := h.queries.RevokeAPICredential(ctx, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, errs.ErrorResponse{
+			Message:   "Failed to revoke API key",
+			ErrorCode: errs.ErrCredFailedRevoke,
+		})
+	}
+	if revoked == 0 {
+		return c.JSON(http.StatusNotFound, errs.ErrorResponse{
+			Message:   "API key not found or already revoked",
+			ErrorCode: errs.ErrCredNotFound,
+		})
+	}
+
+	logging.InfoContext(ctx, "API credential revoked", "credential_id", formatUUID(id))
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "API key revoked successfully",
+	})
+}
+
+// CredentialResponse creates a response map from an ApiCredential record.
+// The key hash is never included.
+func CredentialResponse(credential db.ApiCredential) map[string]interface{} {
+	response := map[string]interface{}{
+		"id":         formatUUID(credential.ID),
+		"name":       credential.Name,
+		"owner":      credential.Owner,
+		"key_prefix": credential.KeyPrefix,
+		"scopes":     credential.Scopes,
+		"namespace":  credential.Namespace,
+		"tenant":     credential.TenantID,
+		"purposes":   credential.Purposes,
+	}
+	if credential.RedactionProfile.Valid {
+		response["redaction_profile"] = credential.RedactionProfile.String
+	}
+
+	// Add optional timestamps if they are valid
+	if credential.ExpiresAt.Valid {
+		response["expires_at"] = credential.ExpiresAt.Time
+	}
+	if credential.LastUsedAt.Valid {
+		response["last_used_at"] = credential.LastUsedAt.Time
+	}
+	if credential.RevokedAt.Valid {
+		response["revoked_at"] = credential.RevokedAt.Time
+	}
+	if credential.CreatedAt.Valid {
+		response["created_at"] = credential.CreatedAt.Time
+	}
+
+	return response
+}
@@ -16,6 +16,9 @@ const (
 	ErrMissingRequiredFieldMeta = "PA_005_MISSING_META"
 	ErrInvalidAttributeIDFormat  = "PA_006_INVALID_ATTRIBUTE_ID_FORMAT"
 	ErrMissingRequiredFieldValue = "PA_007_MISSING_VALUE"
+	ErrInvalidAttributePolicy    = "PA_008_INVALID_ATTRIBUTE_POLICY"
+	ErrInvalidView               = "PA_009_INVALID_VIEW"
+	ErrInvalidRedactionProfiles  = "PA_010_INVALID_REDACTION_PROFILES"
 
 	// Resource not found errors (1100-1199)
 	ErrPersonNotFound    = "PA_101_PERSON_NOT_FOUND"
@@ -42,6 +45,11 @@ const (
 	ErrKVInvalidRequestBody = "KV_001_INVALID_REQUEST_BODY"
 	ErrKVMissingKeyOrValue  = "KV_002_MISSING_KEY_OR_VALUE"
 	ErrKVMissingKeyParam    = "KV_003_MISSING_KEY_PARAM"
+	ErrKVInvalidTTL         = "KV_004_INVALID_TTL"
+	ErrKVInvalidCondition   = "KV_005_INVALID_CONDITION"
+	ErrKVInvalidListParams  = "KV_006_INVALID_LIST_PARAMS"
+	ErrKVDeleteNotConfirmed = "KV_007_DELETE_NOT_CONFIRMED"
+	ErrKVInvalidWatchParams = "KV_008_INVALID_WATCH_PARAMS"
 
 	// Resource not found errors (2100-2199)
 	ErrKVKeyNotFound = "KV_101_KEY_NOT_FOUND"
@@ -50,6 +58,11 @@ const (
 	ErrKVFailedSetValue      = "KV_201_FAILED_SET_VALUE"
 	ErrKVFailedRetrieveValue = "KV_202_FAILED_RETRIEVE_VALUE"
 	ErrKVFailedDeleteValue   = "KV_203_FAILED_DELETE_VALUE"
+	ErrKVKeyExists           = "KV_204_KEY_EXISTS"
+	ErrKVVersionMismatch     = "KV_205_VERSION_MISMATCH"
+	ErrKVValueNotInteger     = "KV_206_VALUE_NOT_INTEGER"
+	ErrKVFailedIncrement     = "KV_207_FAILED_INCREMENT"
+	ErrKVFailedList          = "KV_208_FAILED_LIST"
 )
 
 // Error codes for API Key middleware
@@ -80,20 +93,119 @@ const (
 	ErrInvalidBearerFormat = "API_006_INVALID_BEARER_FORMAT"
 )
 
+// Error codes for credential lookup, JWT validation and scope enforcement
+const (
+	ErrCredentialLookupFailed = "API_007_CREDENTIAL_LOOKUP_FAILED"
+	ErrInsufficientScope      = "API_008_INSUFFICIENT_SCOPE"
+	ErrInvalidToken           = "API_009_INVALID_TOKEN"
+	ErrInvalidJWTConfig       = "API_010_INVALID_JWT_CONFIG"
+)
+
+// Error codes for TLS serving and client certificate authentication
+const (
+	ErrUnknownClientCertificate = "API_011_UNKNOWN_CLIENT_CERTIFICATE"
+	ErrInvalidTLSConfig         = "API_012_INVALID_TLS_CONFIG"
+)
+
+// Error codes for API credential admin endpoints
+const (
+	// Validation errors (6000-6099)
+	ErrCredInvalidRequestBody      = "CRED_001_INVALID_REQUEST_BODY"
+	ErrCredMissingNameOrOwner      = "CRED_002_MISSING_NAME_OR_OWNER"
+	ErrCredInvalidScope            = "CRED_003_INVALID_SCOPE"
+	ErrCredInvalidExpiry           = "CRED_004_INVALID_EXPIRY"
+	ErrCredInvalidID               = "CRED_005_INVALID_ID"
+	ErrCredInvalidPurpose          = "CRED_006_INVALID_PURPOSE"
+	ErrCredUnknownRedactionProfile = "CRED_007_UNKNOWN_REDACTION_PROFILE"
+
+	// Resource not found errors (6100-6199)
+	ErrCredNotFound = "CRED_101_NOT_FOUND"
+
+	// Database operation errors (6200-6299)
+	ErrCredFailedCreate = "CRED_201_FAILED_CREATE"
+	ErrCredFailedList   = "CRED_202_FAILED_LIST"
+	ErrCredFailedRevoke = "CRED_203_FAILED_REVOKE"
+)
+
+// Error codes for request signature middleware
+const (
+	// Validation errors (7000-7099)
+	ErrMissingSignature            = "SIG_001_MISSING_SIGNATURE"
+	ErrInvalidSignature            = "SIG_002_INVALID_SIGNATURE"
+	ErrSignatureExpired            = "SIG_003_TIMESTAMP_OUT_OF_WINDOW"
+	ErrSignatureNonceReused        = "SIG_004_NONCE_REUSED"
+	ErrInvalidSigningConfig        = "SIG_005_INVALID_SIGNING_CONFIG"
+	ErrSigningKeyPrincipalMismatch = "SIG_006_KEY_PRINCIPAL_MISMATCH"
+	ErrSignedBodyTooLarge          = "SIG_007_BODY_TOO_LARGE"
+
+	// Database operation errors (7200-7299)
+	ErrFailedRecordNonce = "SIG_201_FAILED_RECORD_NONCE"
+)
+
+// Error codes for rate limit middleware
+const (
+	// Validation errors (8000-8099)
+	ErrRateLimitExceeded      = "RL_001_RATE_LIMIT_EXCEEDED"
+	ErrInvalidRateLimitConfig = "RL_002_INVALID_RATE_LIMIT_CONFIG"
+
+	// Database operation errors (8200-8299)
+	ErrFailedCheckRateLimit = "RL_201_FAILED_CHECK_RATE_LIMIT"
+)
+
+// Error codes for purpose-of-use middleware
+const (
+	// Validation errors (9000-9099)
+	ErrMissingPurpose       = "PU_001_MISSING_PURPOSE"
+	ErrUnknownPurpose       = "PU_002_UNKNOWN_PURPOSE"
+	ErrInvalidPurposeConfig = "PU_003_INVALID_PURPOSE_CONFIG"
+	ErrPurposeBodyTooLarge  = "PU_004_BODY_TOO_LARGE"
+
+	// Authorization errors (9100-9199)
+	ErrPurposeNotAllowed = "PU_101_PURPOSE_NOT_ALLOWED"
+
+	// Database operation errors (9200-9299)
+	ErrFailedRecordPurpose = "PU_201_FAILED_RECORD_PURPOSE"
+)
+
+// Error codes for logging configuration and the log level admin endpoint
+const (
+	// Validation errors (10000-10099)
+	ErrInvalidLogLevel  = "LOG_001_INVALID_LOG_LEVEL"
+	ErrInvalidLogConfig = "LOG_002_INVALID_LOG_CONFIG"
+)
+
 // Error codes for Health Check
 const (
 	// Health check errors (4000-4099)
-	ErrHealthCheckFailed = "HC_001_HEALTH_CHECK_FAILED"
+	ErrHealthCheckFailed  = "HC_001_HEALTH_CHECK_FAILED"
+	ErrNotReady           = "HC_002_NOT_READY"
+	ErrFailedEnsureCanary = "HC_003_FAILED_ENSURE_CANARY"
 )
 
 // Error codes for Database Setup
 const (
 	// Database connection errors (5000-5099)
-	ErrDatabaseURLNotSet    = "DB_001_URL_NOT_SET"
-	ErrInvalidPort          = "DB_002_INVALID_PORT"
-	ErrFailedParseDBURL     = "DB_003_FAILED_PARSE_URL"
-	ErrFailedCreateConnPool = "DB_004_FAILED_CREATE_POOL"
-	ErrDatabasePingFailed   = "DB_005_PING_FAILED"
-	ErrFailedStartServer    = "DB_006_FAILED_START_SERVER"
-	ErrFailedShutdownServer = "DB_007_FAILED_SHUTDOWN_SERVER"
+	ErrDatabaseURLNotSet      = "DB_001_URL_NOT_SET"
+	ErrInvalidPort            = "DB_002_INVALID_PORT"
+	ErrFailedParseDBURL       = "DB_003_FAILED_PARSE_URL"
+	ErrFailedCreateConnPool   = "DB_004_FAILED_CREATE_POOL"
+	ErrDatabasePingFailed     = "DB_005_PING_FAILED"
+	ErrFailedStartServer      = "DB_006_FAILED_START_SERVER"
+	ErrFailedShutdownServer   = "DB_007_FAILED_SHUTDOWN_SERVER"
+	ErrInvalidTracingConfig   = "DB_008_INVALID_TRACING_CONFIG"
+	ErrFailedFlushTraces      = "DB_009_FAILED_FLUSH_TRACES"
+	ErrInvalidAccessLog       = "DB_010_INVALID_ACCESS_LOG_CONFIG"
+	ErrInvalidShutdownTimeout = "DB_011_INVALID_SHUTDOWN_TIMEOUT"
+	ErrInvalidConfig          = "DB_012_INVALID_CONFIG"
+	ErrMigrationFailed        = "DB_013_MIGRATION_FAILED"
+	ErrMigrationDirty         = "DB_014_MIGRATION_DIRTY"
+	ErrSchemaVersionMismatch  = "DB_015_SCHEMA_VERSION_MISMATCH"
+)
+
+// Error codes for operational commands such as migrate and rotate-keys
+const (
+	// Command errors (11000-11099)
+	ErrInvalidUsage       = "CLI_001_INVALID_USAGE"
+	ErrCommandFailed      = "CLI_002_COMMAND_FAILED"
+	ErrEncryptionMismatch = "CLI_003_ENCRYPTION_MISMATCH"
 )
@@ -0,0 +1,135 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"person-service/config"
+	dbpkg "person-service/internal/db"
+	db "person-service/internal/db/generated"
+	"person-service/metrics"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CanaryValue is the plaintext stored encrypted in the encryption canary row
+const CanaryValue = "person-service-encryption-canary"
+
+// MigrationState is the schema version reported on /health/details
+type MigrationState struct {
+	Version  uint   `json:"version"`
+	Dirty    bool   `json:"dirty"`
+	Expected uint   `json:"expected"`
+	Latest   uint   `json:"latest"`
+	Error    string `json:"error,omitempty"`
+}
+
+// MigrationCheck checks that the schema did not fail part way and is at the
+// version the service expects, or at least at the newest embedded migration
+// when no version is configured
+type MigrationCheck struct {
+	pool     *pgxpool.Pool
+	expected uint
+}
+
+// NewMigrationCheck creates a new instance of MigrationCheck with an injected
+// pool. expected is the schema version the service runs against, or 0 for
+// the newest migration embedded in the binary.
+func NewMigrationCheck(pool *pgxpool.Pool, expected uint) *MigrationCheck {
+	return &MigrationCheck{
+		pool:     pool,
+		expected: expected,
+	}
+}
+
+// Check fails when the schema is dirty, at another version than the configured
+// one, or, without one, older than the newest embedded migration. A newer
+// schema passes then so a rolling deploy does not fail the previous release.
+// The version read is recorded for /metrics, which does not query it itself.
+func (m *MigrationCheck) Check(ctx context.Context) error {
+	version, dirty, err := dbpkg.SchemaVersion(ctx, m.pool)
+	if err != nil {
+		return err
+	}
+	metrics.RecordSchemaVersion(version, dirty)
+
+	if m.expected > 0 {
+		return dbpkg.VerifySchemaVersion(version, dirty, m.expected)
+	}
+	latest, err := dbpkg.LatestVersion()
+	if err != nil {
+		return fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+	return dbpkg.VerifySchemaVersionAtLeast(version, dirty, latest)
+}
+
+// State returns the applied, expected and newest embedded schema versions.
+// Errors are reported in the state rather than returned.
+func (m *MigrationCheck) State(ctx context.Context) MigrationState {
+	var state MigrationState
+	var err error
+	if state.Latest, err = dbpkg.LatestVersion(); err != nil {
+		state.Error = fmt.Sprintf("failed to read embedded migrations: %v", err)
+		return state
+	}
+	state.Expected = state.Latest
+	if m.expected > 0 {
+		state.Expected = m.expected
+	}
+	state.Version, state.Dirty, err = dbpkg.CurrentVersion(ctx, m.pool)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		state.Error = fmt.Sprintf("failed to read migration version: %v", err)
+	}
+	return state
+}
+
+// EncryptionCanary checks that the configured encryption keys decrypt data
+// written with them, so a wrong or removed key fails readiness before
+// attribute reads fail
+type EncryptionCanary struct {
+	queries    *db.Queries
+	encryption config.Encryption
+}
+
+// NewEncryptionCanary creates a new instance of EncryptionCanary with injected
+// queries that checks the keys in encryption
+func NewEncryptionCanary(queries *db.Queries, encryption config.Encryption) *EncryptionCanary {
+	return &EncryptionCanary{
+		queries:    queries,
+		encryption: encryption,
+	}
+}
+
+// Ensure writes the canary row encrypted with the current key unless one
+// already exists. An existing row is kept so a changed key is detected.
+func (e *EncryptionCanary) Ensure(ctx context.Context) error {
+	return e.queries.EnsureEncryptionCanary(ctx, db.EnsureEncryptionCanaryParams{
+		Value:      CanaryValue,
+		EncKey:     e.encryption.Key,
+		KeyVersion: e.encryption.KeyVersion,
+	})
+}
+
+// Check decrypts the canary row with the key of its key version and compares
+// it with CanaryValue. rotate-keys re-encrypts the row with the current key.
+func (e *EncryptionCanary) Check(ctx context.Context) error {
+	version, err := e.queries.GetEncryptionCanaryKeyVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read canary: %w", err)
+	}
+	key, ok := e.encryption.KeyFor(version)
+	if !ok {
+		return fmt.Errorf("no encryption key is configured for canary key version %d", version)
+	}
+
+	value, err := e.queries.DecryptEncryptionCanary(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt canary: %w", err)
+	}
+	if value != CanaryValue {
+		return errors.New("canary decrypted to an unexpected value")
+	}
+	return nil
+}
@@ -1,27 +1,91 @@
 package health
 
 import (
+	"context"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	errs "person-service/errors"
 	db "person-service/internal/db/generated"
+	"person-service/logging"
 
 	"github.com/labstack/echo/v4"
 )
 
-// HealthCheckService handles health check operations
+// CheckTimeout bounds each readiness check so a hung dependency fails the
+// probe instead of blocking it
+const CheckTimeout = 2 * time.Second
+
+// Check status values
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+)
+
+// CheckFunc reports whether a dependency is usable; a nil error means it is
+type CheckFunc func(ctx context.Context) error
+
+// CheckResult is the outcome of one readiness check
+type CheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// namedCheck is a readiness check registered with AddCheck
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// HealthCheckHandler handles liveness, readiness and health check operations
 type HealthCheckHandler struct {
-	queries *db.Queries
+	queries      *db.Queries
+	checks       []namedCheck
+	migrations   *MigrationCheck
+	shuttingDown atomic.Bool
 }
 
-// NewHealthCheckHandler creates a new instance of HealthCheckService with injected queries
+// NewHealthCheckHandler creates a new instance of HealthCheckHandler with injected
+// queries. The database check is always registered; add others with AddCheck.
 func NewHealthCheckHandler(queries *db.Queries) *HealthCheckHandler {
-	return &HealthCheckHandler{
+	h := &HealthCheckHandler{
 		queries: queries,
 	}
+	h.AddCheck("database", queries.HealthCheck)
+	return h
+}
+
+// AddCheck registers a readiness check under name. It must be called before
+// the server starts.
+func (h *HealthCheckHandler) AddCheck(name string, check CheckFunc) {
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// AddMigrationCheck registers the migration check as the "migrations"
+// readiness check and reports its state on /health/details. It must be called
+// before the server starts.
+func (h *HealthCheckHandler) AddMigrationCheck(check *MigrationCheck) {
+	h.migrations = check
+	h.AddCheck("migrations", check.Check)
 }
 
-// Check performs a health check on the database and returns the result as an echo handler
+// SetShuttingDown marks the instance as draining so /readyz fails and load
+// balancers stop sending new requests while in-flight ones complete
+func (h *HealthCheckHandler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// ShuttingDown reports whether SetShuttingDown was called
+func (h *HealthCheckHandler) ShuttingDown() bool {
+	return h.shuttingDown.Load()
+}
+
+// Check performs a health check on the database and returns the result as an echo handler.
+// The error is logged but not returned, since the endpoint is public.
 func (h *HealthCheckHandler) Check(c echo.Context) error {
 	// Use request context for trace propagation
 	ctx := c.Request().Context()
@@ -29,8 +93,11 @@ func (h *HealthCheckHandler) Check(c echo.Context) error {
 	// Call HealthCheck directly
 	err := h.queries.HealthCheck(ctx)
 	if err != nil {
+		logging.ErrorContext(ctx, "Health check failed",
+			"error", err,
+			"error_code", errs.ErrHealthCheckFailed)
 		return c.JSON(http.StatusInternalServerError, errs.ErrorResponse{
-			Message:   err.Error(),
+			Message:   "Database unavailable",
 			ErrorCode: errs.ErrHealthCheckFailed,
 		})
 	}
@@ -40,3 +107,112 @@ func (h *HealthCheckHandler) Check(c echo.Context) error {
 		"status": "healthy",
 	})
 }
+
+// Livez handles GET /livez - reports that the process is running. It checks no
+// dependencies, so a database outage never gets the instance restarted.
+func (h *HealthCheckHandler) Livez(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "alive",
+	})
+}
+
+// Readyz handles GET /readyz - reports whether the instance can serve traffic:
+// the database is reachable, every registered check passes and the server is
+// not shutting down. Only pass or fail is returned per check; failure details
+// are logged and shown on /health/details.
+func (h *HealthCheckHandler) Readyz(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	if h.ShuttingDown() {
+		return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+			"status":     "shutting_down",
+			"error_code": errs.ErrNotReady,
+		})
+	}
+
+	results := h.runChecks(ctx)
+	statuses := make(map[string]string, len(results))
+	ready := true
+	for _, result := range results {
+		statuses[result.Name] = result.Status
+		if result.Status != StatusPass {
+			ready = false
+			logging.WarnContext(ctx, "Readiness check failed",
+				"check", result.Name,
+				"error", result.Error,
+				"error_code", errs.ErrNotReady)
+		}
+	}
+
+	if !ready {
+		return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+			"status":     "not_ready",
+			"checks":     statuses,
+			"error_code": errs.ErrNotReady,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "ready",
+		"checks": statuses,
+	})
+}
+
+// Details handles GET /health/details - reports each readiness check with its
+// latency and error, and the schema version when a migration check is added.
+// It exposes internal details, so it must be served behind authentication.
+func (h *HealthCheckHandler) Details(c echo.Context) error {
+	results := h.runChecks(c.Request().Context())
+
+	status := "ready"
+	for _, result := range results {
+		if result.Status != StatusPass {
+			status = "not_ready"
+		}
+	}
+	if h.ShuttingDown() {
+		status = "shutting_down"
+	}
+
+	response := map[string]interface{}{
+		"status":        status,
+		"shutting_down": h.ShuttingDown(),
+		"checks":        results,
+	}
+	if h.migrations != nil {
+		response["migrations"] = h.migrations.State(c.Request().Context())
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// runChecks runs the registered checks concurrently, each with CheckTimeout,
+// and returns their results in registration order
+func (h *HealthCheckHandler) runChecks(ctx context.Context) []CheckResult {
+	results := make([]CheckResult, len(h.checks))
+
+	var wg sync.WaitGroup
+	for i, named := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, CheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := named.check(checkCtx)
+			result := CheckResult{
+				Name:      named.name,
+				Status:    StatusPass,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = StatusFail
+				result.Error = err.Error()
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
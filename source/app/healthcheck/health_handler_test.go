@@ -2,18 +2,25 @@ package health
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 
+	"person-service/config"
+	dbpkg "person-service/internal/db"
 	db "person-service/internal/db/generated"
 	"person-service/internal/testdb"
+	"person-service/metrics"
 )
 
 var pool *pgxpool.Pool
@@ -93,6 +100,8 @@ func TestCheck_DatabaseError(t *testing.T) {
 	assert.Contains(t, rec.Body.String(), "HC_001_HEALTH_CHECK_FAILED")
 	// Verify it's NOT a healthy response
 	assert.NotContains(t, rec.Body.String(), `"status":"healthy"`, "Should not return healthy for failed DB")
+	// The endpoint is public, so the database error must not be returned
+	assert.NotContains(t, rec.Body.String(), "closed pool")
 }
 
 // TestCheck_HealthyVsUnhealthy explicitly tests that healthy DB gets 200 and unhealthy gets 500
@@ -139,6 +148,228 @@ func TestCheck_HealthyVsUnhealthy(t *testing.T) {
 	})
 }
 
+func TestLivez(t *testing.T) {
+	// Liveness does not depend on the database
+	closedPool, err := createClosedPool()
+	assert.NoError(t, err)
+	handler := NewHealthCheckHandler(db.New(closedPool))
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/livez", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err = handler.Livez(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"alive"`)
+}
+
+func TestReadyz(t *testing.T) {
+	serve := func(handler *HealthCheckHandler) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		assert.NoError(t, handler.Readyz(c))
+		return rec
+	}
+
+	t.Run("ready when all checks pass", func(t *testing.T) {
+		handler := NewHealthCheckHandler(db.New(pool))
+		handler.AddCheck("extra", func(ctx context.Context) error { return nil })
+
+		rec := serve(handler)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"status":"ready"`)
+		assert.Contains(t, rec.Body.String(), `"database":"pass"`)
+		assert.Contains(t, rec.Body.String(), `"extra":"pass"`)
+	})
+
+	t.Run("not ready when a check fails without leaking the error", func(t *testing.T) {
+		handler := NewHealthCheckHandler(db.New(pool))
+		handler.AddCheck("extra", func(ctx context.Context) error {
+			return errors.New("secret connection detail")
+		})
+
+		rec := serve(handler)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), "HC_002_NOT_READY")
+		assert.Contains(t, rec.Body.String(), `"extra":"fail"`)
+		assert.Contains(t, rec.Body.String(), `"database":"pass"`)
+		assert.NotContains(t, rec.Body.String(), "secret connection detail")
+	})
+
+	t.Run("not ready when the database is unreachable", func(t *testing.T) {
+		closedPool, err := createClosedPool()
+		assert.NoError(t, err)
+
+		rec := serve(NewHealthCheckHandler(db.New(closedPool)))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"database":"fail"`)
+	})
+
+	t.Run("not ready while shutting down", func(t *testing.T) {
+		handler := NewHealthCheckHandler(db.New(pool))
+		handler.SetShuttingDown()
+
+		rec := serve(handler)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"status":"shutting_down"`)
+	})
+}
+
+func TestDetails(t *testing.T) {
+	handler := NewHealthCheckHandler(db.New(pool))
+	handler.AddCheck("extra", func(ctx context.Context) error {
+		return errors.New("dependency unavailable")
+	})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/health/details", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := handler.Details(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var body struct {
+		Status string        `json:"status"`
+		Checks []CheckResult `json:"checks"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "not_ready", body.Status)
+	if assert.Len(t, body.Checks, 2) {
+		assert.Equal(t, "database", body.Checks[0].Name)
+		assert.Equal(t, StatusPass, body.Checks[0].Status)
+		assert.GreaterOrEqual(t, body.Checks[0].LatencyMs, 0.0)
+		assert.Equal(t, "extra", body.Checks[1].Name)
+		assert.Equal(t, StatusFail, body.Checks[1].Status)
+		assert.Equal(t, "dependency unavailable", body.Checks[1].Error)
+	}
+}
+
+func TestEncryptionCanary(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+	queries := db.New(pool)
+
+	// Fails before the canary row is written
+	canary := NewEncryptionCanary(queries, config.Default().Encryption)
+	assert.Error(t, canary.Check(ctx))
+
+	assert.NoError(t, canary.Ensure(ctx))
+	assert.NoError(t, canary.Check(ctx))
+
+	// A different key cannot decrypt the canary, and Ensure keeps the old row
+	otherKey := NewEncryptionCanary(queries, config.Encryption{
+		Key:        "some-other-key",
+		KeyVersion: 1,
+		Keys:       map[int64]string{1: "some-other-key"},
+	})
+	assert.NoError(t, otherKey.Ensure(ctx))
+	assert.Error(t, otherKey.Check(ctx))
+	assert.NoError(t, canary.Check(ctx))
+
+	// The key of the canary's version must still be configured after a new key is added
+	rotated := NewEncryptionCanary(queries, config.Encryption{
+		Key:        "new-key",
+		KeyVersion: 2,
+		Keys:       map[int64]string{1: config.DefaultEncryptionKey, 2: "new-key"},
+	})
+	assert.NoError(t, rotated.Check(ctx))
+	removed := NewEncryptionCanary(queries, config.Encryption{
+		Key:        "new-key",
+		KeyVersion: 2,
+		Keys:       map[int64]string{2: "new-key"},
+	})
+	assert.ErrorContains(t, removed.Check(ctx), "no encryption key is configured for canary key version 1")
+}
+
+// setSchemaVersion records version as the applied migration, as golang-migrate would
+func setSchemaVersion(t *testing.T, ctx context.Context, version uint, dirty bool) {
+	t.Helper()
+	_, err := pool.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version bigint PRIMARY KEY, dirty boolean NOT NULL)`)
+	assert.NoError(t, err)
+	_, err = pool.Exec(ctx, `TRUNCATE schema_migrations`)
+	assert.NoError(t, err)
+	_, err = pool.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`, int64(version), dirty)
+	assert.NoError(t, err)
+}
+
+func TestMigrationCheck(t *testing.T) {
+	ctx := context.Background()
+	latest, err := dbpkg.LatestVersion()
+	assert.NoError(t, err)
+
+	setSchemaVersion(t, ctx, latest, false)
+	assert.NoError(t, NewMigrationCheck(pool, 0).Check(ctx))
+	assert.Error(t, NewMigrationCheck(pool, latest+1).Check(ctx))
+
+	// A newer release migrating further keeps this one ready unless a version is configured
+	setSchemaVersion(t, ctx, latest+1, false)
+	assert.NoError(t, NewMigrationCheck(pool, 0).Check(ctx))
+	assert.Error(t, NewMigrationCheck(pool, latest).Check(ctx))
+
+	setSchemaVersion(t, ctx, latest, true)
+	assert.ErrorContains(t, NewMigrationCheck(pool, 0).Check(ctx), "dirty")
+
+	// The check records the version it read for /metrics
+	assert.NoError(t, testutil.GatherAndCompare(metrics.Registry, strings.NewReader(`
+# HELP person_service_db_migration_dirty 1 when the last schema migration failed part way, otherwise 0.
+# TYPE person_service_db_migration_dirty gauge
+person_service_db_migration_dirty 1
+`), "person_service_db_migration_dirty"))
+
+	// The expected version need not be the newest embedded migration
+	setSchemaVersion(t, ctx, latest-1, false)
+	assert.Error(t, NewMigrationCheck(pool, 0).Check(ctx))
+	check := NewMigrationCheck(pool, latest-1)
+	assert.NoError(t, check.Check(ctx))
+	assert.Equal(t, MigrationState{
+		Version:  latest - 1,
+		Expected: latest - 1,
+		Latest:   latest,
+	}, check.State(ctx))
+}
+
+func TestDetails_MigrationState(t *testing.T) {
+	ctx := context.Background()
+	latest, err := dbpkg.LatestVersion()
+	assert.NoError(t, err)
+	setSchemaVersion(t, ctx, latest, true)
+
+	handler := NewHealthCheckHandler(db.New(pool))
+	handler.AddMigrationCheck(NewMigrationCheck(pool, 0))
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/health/details", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	assert.NoError(t, handler.Details(c))
+
+	var body struct {
+		Status     string         `json:"status"`
+		Checks     []CheckResult  `json:"checks"`
+		Migrations MigrationState `json:"migrations"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "not_ready", body.Status)
+	assert.Equal(t, MigrationState{Version: latest, Dirty: true, Expected: latest, Latest: latest}, body.Migrations)
+	if assert.Len(t, body.Checks, 2) {
+		assert.Equal(t, "migrations", body.Checks[1].Name)
+		assert.Equal(t, StatusFail, body.Checks[1].Status)
+	}
+}
+
 // createClosedPool creates a pool and immediately closes it to simulate database errors
 func createClosedPool() (*pgxpool.Pool, error) {
 	ctx := context.Background()
@@ -149,6 +149,19 @@ Feature: Key Value integration tests
       And the "created_at" timestamp should be a valid ISO 8601 datetime
       And the "updated_at" timestamp should be a valid ISO 8601 datetime
 
+  Scenario: Get key without API key
+    Given a key-value pair exists with key "auth-key" and value "auth-value"
+    When I send a GET request to "/api/key-value/auth-key" without API key
+    Then the response status should be 401
+
+  Scenario: Keys are isolated per API key namespace
+    Given a key-value pair exists with key "blue-only-key" and value "blue-value"
+    When I send a GET request to "/api/key-value/blue-only-key" using green API key
+    Then the response status should be 404
+    When I send a GET request to "/api/key-value/blue-only-key"
+    Then the response status should be 200
+      And the response should contain field "value" with value "blue-value"
+
   # ============================================
   # DELETE /api/key-value/:key scenarios
   # ============================================
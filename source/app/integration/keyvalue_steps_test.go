@@ -56,7 +56,7 @@ func registerKeyValueSteps(sc *godog.ScenarioContext, tc *TestContext) {
 			"key":   key,
 			"value": value,
 		}
-		tc.Response = tc.Server.POST("/api/key-value", body, nil)
+		tc.Response = tc.Server.POST("/api/key-value", body, testutil.WithAPIKey())
 		return nil
 	})
 
@@ -118,12 +118,12 @@ func registerKeyValueSteps(sc *godog.ScenarioContext, tc *TestContext) {
 
 	// Request steps - specific to key-value API paths
 	sc.Step(`^I send a GET request to "/api/key-value/([^"]*)"$`, func(key string) error {
-		tc.Response = tc.Server.GET("/api/key-value/"+key, nil)
+		tc.Response = tc.Server.GET("/api/key-value/"+key, testutil.WithAPIKey())
 		return nil
 	})
 
 	sc.Step(`^I send a DELETE request to "/api/key-value/([^"]*)"$`, func(key string) error {
-		tc.Response = tc.Server.DELETE("/api/key-value/"+key, nil)
+		tc.Response = tc.Server.DELETE("/api/key-value/"+key, testutil.WithAPIKey())
 		return nil
 	})
 
@@ -132,12 +132,22 @@ func registerKeyValueSteps(sc *godog.ScenarioContext, tc *TestContext) {
 		if err := json.Unmarshal([]byte(body.Content), &jsonBody); err != nil {
 			return fmt.Errorf("invalid JSON in docstring: %w", err)
 		}
-		tc.Response = tc.Server.POST(path, jsonBody, nil)
+		tc.Response = tc.Server.POST(path, jsonBody, testutil.WithAPIKey())
+		return nil
+	})
+
+	sc.Step(`^I send a GET request to "/api/key-value/([^"]*)" without API key$`, func(key string) error {
+		tc.Response = tc.Server.GET("/api/key-value/"+key, nil)
+		return nil
+	})
+
+	sc.Step(`^I send a GET request to "/api/key-value/([^"]*)" using green API key$`, func(key string) error {
+		tc.Response = tc.Server.GET("/api/key-value/"+key, testutil.WithGreenAPIKey())
 		return nil
 	})
 
 	sc.Step(`^I send a POST request to "/api/key-value" with invalid JSON$`, func() error {
-		tc.Response = tc.Server.POSTRaw("/api/key-value", "{invalid json", nil)
+		tc.Response = tc.Server.POSTRaw("/api/key-value", "{invalid json", testutil.WithAPIKey())
 		return nil
 	})
 
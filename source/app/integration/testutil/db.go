@@ -26,7 +26,7 @@ func RunMigrations(ctx context.Context, pool *pgxpool.Pool) error {
 // Uses TRUNCATE with CASCADE to handle foreign key constraints.
 func TruncateTables(ctx context.Context, pool *pgxpool.Pool) error {
 	_, err := pool.Exec(ctx, `
-		TRUNCATE TABLE person_attributes, person_images, request_log, person, key_value RESTART IDENTITY CASCADE
+		TRUNCATE TABLE person_attributes, person_images, request_log, person, key_value, api_credential, request_nonce, rate_limit_bucket RESTART IDENTITY CASCADE
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to truncate tables: %w", err)
@@ -95,7 +95,7 @@ func InsertKeyValueDirect(ctx context.Context, pool *pgxpool.Pool, key, value st
 	_, err := pool.Exec(ctx, `
 		INSERT INTO key_value (key, value)
 		VALUES ($1, $2)
-		ON CONFLICT (key) DO UPDATE SET value = $2, updated_at = CURRENT_TIMESTAMP
+		ON CONFLICT (namespace, key) DO UPDATE SET value = $2, updated_at = CURRENT_TIMESTAMP
 	`, key, value)
 	if err != nil {
 		return fmt.Errorf("failed to insert key-value: %w", err)
@@ -10,6 +10,9 @@ import (
 
 	db "person-service/internal/db/generated"
 
+	"person-service/auth"
+	"person-service/config"
+	"person-service/credentials"
 	health "person-service/healthcheck"
 	key_value "person-service/key_value"
 	"person-service/middleware"
@@ -26,6 +29,8 @@ const (
 	TestAPIKeyBlue = "person-service-key-11111111-2222-3333-4444-555555555555"
 	// TestAPIKeyGreen is a valid API key for tests (green)
 	TestAPIKeyGreen = "person-service-key-aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee"
+	// TestGreenNamespace is the key-value namespace of the green API key; blue uses the default namespace
+	TestGreenNamespace = "green"
 )
 
 // TestServer wraps an Echo instance configured for testing
@@ -37,37 +42,50 @@ type TestServer struct {
 
 // NewTestServer creates a new test server with all handlers configured
 func NewTestServer(pool *pgxpool.Pool) *TestServer {
-	// Set up environment variables for the handlers
-	os.Setenv("ENCRYPTION_KEY_1", TestEncryptionKey)
+	// Set up environment variables for the bootstrap keys
 	os.Setenv("PERSON_API_KEY_BLUE", TestAPIKeyBlue)
 	os.Setenv("PERSON_API_KEY_GREEN", TestAPIKeyGreen)
+	os.Setenv("PERSON_API_KEY_GREEN_NAMESPACE", TestGreenNamespace)
 
 	queries := db.New(pool)
+	encryption := config.Encryption{Key: TestEncryptionKey, KeyVersion: 1}
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
 
 	// Setup handlers
 	healthHandler := health.NewHealthCheckHandler(queries)
-	keyValueHandler := key_value.NewKeyValueHandler(queries)
-	personAttributesHandler := person_attributes.NewPersonAttributesHandler(queries)
+	keyValueHandler := key_value.NewKeyValueHandler(queries, encryption, config.KeyValue{})
+	personAttributesHandler := person_attributes.NewPersonAttributesHandler(queries, encryption)
+	credentialsHandler := credentials.NewCredentialsHandler(queries)
+	authenticator := middleware.NewAuthenticator(credentials.NewStore(queries), nil, nil)
 
 	// Setup routes (same as main.go)
 	e.GET("/health", healthHandler.Check)
 
-	// Key-value API routes
-	e.POST("/api/key-value", keyValueHandler.SetValue)
-	e.GET("/api/key-value/:key", keyValueHandler.GetValue)
-	e.DELETE("/api/key-value/:key", keyValueHandler.DeleteValue)
-
-	// Person attributes API routes - protected with API key middleware
-	personAttributesGroup := e.Group("/persons", middleware.APIKeyMiddleware())
-	personAttributesGroup.POST("/:personId/attributes", personAttributesHandler.CreateAttribute)
-	personAttributesGroup.PUT("/:personId/attributes", personAttributesHandler.CreateAttribute)
-	personAttributesGroup.GET("/:personId/attributes", personAttributesHandler.GetAllAttributes)
-	personAttributesGroup.GET("/:personId/attributes/:attributeId", personAttributesHandler.GetAttribute)
-	personAttributesGroup.PUT("/:personId/attributes/:attributeId", personAttributesHandler.UpdateAttribute)
-	personAttributesGroup.DELETE("/:personId/attributes/:attributeId", personAttributesHandler.DeleteAttribute)
+	// API credential admin routes - require the admin scope
+	adminGroup := e.Group("/admin/api-keys", authenticator.Middleware(), middleware.RequireScope(auth.ScopeAdmin))
+	adminGroup.POST("", credentialsHandler.CreateCredential)
+	adminGroup.GET("", credentialsHandler.ListCredentials)
+	adminGroup.DELETE("/:id", credentialsHandler.RevokeCredential)
+
+	// Key-value API routes - protected with API key authentication, scoped to the key's namespace
+	keyValueGroup := e.Group("/api/key-value", authenticator.Middleware())
+	keyValueGroup.POST("", keyValueHandler.SetValue, middleware.RequireScope(auth.ScopeKVWrite))
+	keyValueGroup.GET("", keyValueHandler.ListValues, middleware.RequireScope(auth.ScopeKVRead))
+	keyValueGroup.DELETE("", keyValueHandler.DeleteByPrefix, middleware.RequireScope(auth.ScopeKVWrite))
+	keyValueGroup.GET("/:key", keyValueHandler.GetValue, middleware.RequireScope(auth.ScopeKVRead))
+	keyValueGroup.DELETE("/:key", keyValueHandler.DeleteValue, middleware.RequireScope(auth.ScopeKVWrite))
+	keyValueGroup.POST("/:key/increment", keyValueHandler.IncrementValue, middleware.RequireScope(auth.ScopeKVWrite))
+
+	// Person attributes API routes - protected with API key authentication
+	personAttributesGroup := e.Group("/persons", authenticator.Middleware())
+	personAttributesGroup.POST("/:personId/attributes", personAttributesHandler.CreateAttribute, middleware.RequireScope(auth.ScopeAttributesWrite))
+	personAttributesGroup.PUT("/:personId/attributes", personAttributesHandler.CreateAttribute, middleware.RequireScope(auth.ScopeAttributesWrite))
+	personAttributesGroup.GET("/:personId/attributes", personAttributesHandler.GetAllAttributes, middleware.RequireScope(auth.ScopeAttributesRead))
+	personAttributesGroup.GET("/:personId/attributes/:attributeId", personAttributesHandler.GetAttribute, middleware.RequireScope(auth.ScopeAttributesRead))
+	personAttributesGroup.PUT("/:personId/attributes/:attributeId", personAttributesHandler.UpdateAttribute, middleware.RequireScope(auth.ScopeAttributesWrite))
+	personAttributesGroup.DELETE("/:personId/attributes/:attributeId", personAttributesHandler.DeleteAttribute, middleware.RequireScope(auth.ScopeAttributesWrite))
 
 	return &TestServer{
 		Echo:    e,
@@ -18,22 +18,62 @@ $$ LANGUAGE plpgsql VOLATILE;
 
 -- create Key Value table
 CREATE TABLE IF NOT EXISTS key_value (
-    key text PRIMARY KEY,
-    value text NOT NULL,
+    key text NOT NULL,
+    value text NOT NULL, -- empty when encrypted_value is set
     created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
-    updated_at timestamptz DEFAULT CURRENT_TIMESTAMP
+    updated_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    expires_at timestamptz, -- optional TTL, NULL means never expires
+    version bigint NOT NULL DEFAULT 1, -- compare-and-swap version, restarts at 1 on (re)create
+    namespace text NOT NULL DEFAULT 'default', -- owning credential namespace
+    encrypted_value BYTEA, -- value encrypted using pgp_sym_encrypt when encryption at rest is enabled
+    key_version bigint, -- encryption key version of encrypted_value, NULL when not encrypted
+    PRIMARY KEY (namespace, key)
 );
 
+CREATE INDEX IF NOT EXISTS idx_key_value_expires_at ON key_value(expires_at) WHERE expires_at IS NOT NULL;
+
+-- Notify watchers of key-value changes on the key_value_changes channel.
+-- Keys too long for a NOTIFY payload are sent without the key, which wakes every watcher in the namespace.
+CREATE OR REPLACE FUNCTION notify_key_value_change() RETURNS trigger AS $$
+DECLARE
+    changed key_value;
+    payload text;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+    ELSE
+        changed := NEW;
+    END IF;
+
+    payload := json_build_object('namespace', changed.namespace, 'key', changed.key)::text;
+    IF octet_length(payload) >= 8000 THEN
+        payload := json_build_object('namespace', changed.namespace)::text;
+    END IF;
+
+    PERFORM pg_notify('key_value_changes', payload);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS key_value_notify ON key_value;
+CREATE TRIGGER key_value_notify
+    AFTER INSERT OR UPDATE OR DELETE ON key_value
+    FOR EACH ROW EXECUTE FUNCTION notify_key_value_change();
+
 -- Request log table for idempotency with encryption
 CREATE TABLE IF NOT EXISTS request_log (
     id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
-    trace_id text UNIQUE NOT NULL, -- for idempotency check
+    trace_id text NOT NULL, -- for idempotency check
     caller_info text NOT NULL,
     reason text NOT NULL,
     encrypted_request_body BYTEA, -- encrypted using pgp_sym_encrypt
     encrypted_response_body BYTEA, -- encrypted using pgp_sym_encrypt
     key_version bigint NOT NULL,
-    created_at timestamptz DEFAULT CURRENT_TIMESTAMP
+    created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    principal_id text, -- authenticated principal that made the request
+    purpose text, -- declared purpose of use of the request
+    tenant_id text NOT NULL DEFAULT 'default', -- tenant of the request
+    UNIQUE(tenant_id, trace_id) -- trace ids are unique per tenant
 );
 
 CREATE INDEX IF NOT EXISTS idx_request_log_trace_id ON request_log(trace_id);
@@ -41,10 +81,13 @@ CREATE INDEX IF NOT EXISTS idx_request_log_trace_id ON request_log(trace_id);
 -- Person table - stores person data
 CREATE TABLE IF NOT EXISTS person (
     id UUID PRIMARY KEY DEFAULT uuidv7(), -- internal service id
-    client_id text UNIQUE NOT NULL, -- id from client system
+    client_id text NOT NULL, -- id from client system, unique per tenant
     created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
     updated_at timestamptz DEFAULT CURRENT_TIMESTAMP,
-    deleted_at timestamptz -- soft delete support
+    deleted_at timestamptz, -- soft delete support
+    tenant_id text NOT NULL DEFAULT 'default', -- owning tenant
+    UNIQUE(tenant_id, client_id),
+    UNIQUE(tenant_id, id) -- target of the tenant-scoped foreign keys of derived tables
 );
 
 CREATE INDEX IF NOT EXISTS idx_person_client_id ON person(client_id);
@@ -59,7 +102,9 @@ CREATE TABLE IF NOT EXISTS person_attributes (
     version bigint NOT NULL DEFAULT 1, -- optimistic concurrency control
     created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
     updated_at timestamptz DEFAULT CURRENT_TIMESTAMP,
-    UNIQUE(person_id, attribute_key) -- prevent duplicate attributes for same person
+    tenant_id text NOT NULL DEFAULT 'default', -- tenant of the person
+    UNIQUE(person_id, attribute_key), -- prevent duplicate attributes for same person
+    FOREIGN KEY (tenant_id, person_id) REFERENCES person(tenant_id, id) ON DELETE CASCADE
 );
 
 CREATE INDEX IF NOT EXISTS idx_person_attributes_person_id ON person_attributes(person_id);
@@ -79,8 +124,83 @@ CREATE TABLE IF NOT EXISTS person_images (
     height bigint,
     created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
     updated_at timestamptz DEFAULT CURRENT_TIMESTAMP,
-    UNIQUE(person_id, attribute_key) -- prevent duplicate images for same person
+    tenant_id text NOT NULL DEFAULT 'default', -- tenant of the person
+    UNIQUE(person_id, attribute_key), -- prevent duplicate images for same person
+    FOREIGN KEY (tenant_id, person_id) REFERENCES person(tenant_id, id) ON DELETE CASCADE
 );
 
 CREATE INDEX IF NOT EXISTS idx_person_images_person_id ON person_images(person_id);
 CREATE INDEX IF NOT EXISTS idx_person_images_type ON person_images(image_type);
+
+-- Row-level security on tenant data. Queries always filter by tenant; when the
+-- service sets app.tenant_id for a statement, Postgres also hides and rejects
+-- rows of other tenants. Without the setting every row stays visible.
+CREATE OR REPLACE FUNCTION tenant_visible(row_tenant_id text) RETURNS boolean AS $$
+    SELECT COALESCE(NULLIF(current_setting('app.tenant_id', true), ''), row_tenant_id) = row_tenant_id;
+$$ LANGUAGE sql STABLE;
+
+ALTER TABLE person ENABLE ROW LEVEL SECURITY;
+ALTER TABLE person FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS person_tenant_isolation ON person;
+CREATE POLICY person_tenant_isolation ON person USING (tenant_visible(tenant_id));
+
+ALTER TABLE person_attributes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE person_attributes FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS person_attributes_tenant_isolation ON person_attributes;
+CREATE POLICY person_attributes_tenant_isolation ON person_attributes USING (tenant_visible(tenant_id));
+
+ALTER TABLE person_images ENABLE ROW LEVEL SECURITY;
+ALTER TABLE person_images FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS person_images_tenant_isolation ON person_images;
+CREATE POLICY person_images_tenant_isolation ON person_images USING (tenant_visible(tenant_id));
+
+ALTER TABLE request_log ENABLE ROW LEVEL SECURITY;
+ALTER TABLE request_log FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS request_log_tenant_isolation ON request_log;
+CREATE POLICY request_log_tenant_isolation ON request_log USING (tenant_visible(tenant_id));
+
+-- API credentials issued through the admin API; only a hash of each key is stored
+CREATE TABLE IF NOT EXISTS api_credential (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name text NOT NULL,
+    owner text NOT NULL,
+    key_hash BYTEA UNIQUE NOT NULL, -- sha256 of the API key
+    key_prefix text NOT NULL, -- leading characters of the key, for identification only
+    scopes text[] NOT NULL DEFAULT '{}',
+    namespace text NOT NULL DEFAULT 'default', -- key-value namespace of the credential
+    expires_at timestamptz, -- NULL means the key never expires
+    last_used_at timestamptz,
+    revoked_at timestamptz,
+    created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    tenant_id text NOT NULL DEFAULT 'default', -- tenant whose persons the credential can access
+    redaction_profile text, -- redaction profile always applied to the credential's reads; NULL lets requests choose
+    purposes text[] NOT NULL DEFAULT '{}' -- purposes of use the credential may declare; empty allows every registered purpose
+);
+
+-- Nonces of signed requests, kept until their signature timestamp leaves the allowed window
+CREATE TABLE IF NOT EXISTS request_nonce (
+    key_id text NOT NULL,
+    nonce text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    PRIMARY KEY (key_id, nonce)
+);
+
+CREATE INDEX IF NOT EXISTS idx_request_nonce_expires_at ON request_nonce(expires_at);
+
+-- Token buckets of the Postgres rate limit backend, shared by all instances
+CREATE TABLE IF NOT EXISTS rate_limit_bucket (
+    bucket_key text PRIMARY KEY,
+    tokens double precision NOT NULL,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_bucket_updated_at ON rate_limit_bucket(updated_at);
+
+-- Known value encrypted with the encryption key; readiness fails when the
+-- configured key can no longer decrypt it
+CREATE TABLE IF NOT EXISTS encryption_canary (
+    id integer PRIMARY KEY,
+    encrypted_value bytea NOT NULL,
+    key_version bigint NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
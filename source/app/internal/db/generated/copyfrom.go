@@ -29,6 +29,7 @@ func (r *iteratorForBulkCreatePersonAttributes) Next() bool {
 
 func (r iteratorForBulkCreatePersonAttributes) Values() ([]interface{}, error) {
 	return []interface{}{
+		r.rows[0].TenantID,
 		r.rows[0].PersonID,
 		r.rows[0].AttributeKey,
 		r.rows[0].EncryptedValue,
@@ -42,5 +43,5 @@ func (r iteratorForBulkCreatePersonAttributes) Err() error {
 
 // Bulk insert person attributes (use with COPY FROM)
 func (q *Queries) BulkCreatePersonAttributes(ctx context.Context, arg []BulkCreatePersonAttributesParams) (int64, error) {
-	return q.db.CopyFrom(ctx, []string{"person_attributes"}, []string{"person_id", "attribute_key", "encrypted_value", "key_version"}, &iteratorForBulkCreatePersonAttributes{rows: arg})
+	return q.db.CopyFrom(ctx, []string{"person_attributes"}, []string{"tenant_id", "person_id", "attribute_key", "encrypted_value", "key_version"}, &iteratorForBulkCreatePersonAttributes{rows: arg})
 }
@@ -8,11 +8,40 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ApiCredential struct {
+	ID               pgtype.UUID
+	Name             string
+	Owner            string
+	KeyHash          []byte
+	KeyPrefix        string
+	Scopes           []string
+	Namespace        string
+	ExpiresAt        pgtype.Timestamptz
+	LastUsedAt       pgtype.Timestamptz
+	RevokedAt        pgtype.Timestamptz
+	CreatedAt        pgtype.Timestamptz
+	TenantID         string
+	RedactionProfile pgtype.Text
+	Purposes         []string
+}
+
+type EncryptionCanary struct {
+	ID             int32
+	EncryptedValue []byte
+	KeyVersion     int64
+	CreatedAt      pgtype.Timestamptz
+}
+
 type KeyValue struct {
-	Key       string
-	Value     string
-	CreatedAt pgtype.Timestamptz
-	UpdatedAt pgtype.Timestamptz
+	Key            string
+	Value          string
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+	ExpiresAt      pgtype.Timestamptz
+	Version        int64
+	Namespace      string
+	EncryptedValue []byte
+	KeyVersion     pgtype.Int8
 }
 
 type Person struct {
@@ -21,6 +50,7 @@ type Person struct {
 	CreatedAt pgtype.Timestamptz
 	UpdatedAt pgtype.Timestamptz
 	DeletedAt pgtype.Timestamptz
+	TenantID  string
 }
 
 type PersonAttribute struct {
@@ -32,6 +62,7 @@ type PersonAttribute struct {
 	Version        int64
 	CreatedAt      pgtype.Timestamptz
 	UpdatedAt      pgtype.Timestamptz
+	TenantID       string
 }
 
 type PersonImage struct {
@@ -47,6 +78,13 @@ type PersonImage struct {
 	Height             pgtype.Int8
 	CreatedAt          pgtype.Timestamptz
 	UpdatedAt          pgtype.Timestamptz
+	TenantID           string
+}
+
+type RateLimitBucket struct {
+	BucketKey string
+	Tokens    float64
+	UpdatedAt pgtype.Timestamptz
 }
 
 type RequestLog struct {
@@ -58,4 +96,13 @@ type RequestLog struct {
 	EncryptedResponseBody []byte
 	KeyVersion            int64
 	CreatedAt             pgtype.Timestamptz
+	PrincipalID           pgtype.Text
+	Purpose               pgtype.Text
+	TenantID              string
+}
+
+type RequestNonce struct {
+	KeyID     string
+	Nonce     string
+	ExpiresAt pgtype.Timestamptz
 }
@@ -12,6 +12,7 @@ import (
 )
 
 type BulkCreatePersonAttributesParams struct {
+	TenantID       string
 	PersonID       pgtype.UUID
 	AttributeKey   string
 	EncryptedValue []byte
@@ -19,44 +20,138 @@ type BulkCreatePersonAttributesParams struct {
 }
 
 const checkTraceIdExists = `-- name: CheckTraceIdExists :one
-SELECT EXISTS(SELECT 1 FROM request_log WHERE trace_id = $1)
+SELECT EXISTS(SELECT 1 FROM request_log WHERE tenant_id = $1 AND trace_id = $2)
 `
 
+type CheckTraceIdExistsParams struct {
+	TenantID string
+	TraceID  string
+}
+
 // Check if a trace_id already exists (for idempotency)
-func (q *Queries) CheckTraceIdExists(ctx context.Context, traceID string) (bool, error) {
-	row := q.db.QueryRow(ctx, checkTraceIdExists, traceID)
+func (q *Queries) CheckTraceIdExists(ctx context.Context, arg CheckTraceIdExistsParams) (bool, error) {
+	row := q.db.QueryRow(ctx, checkTraceIdExists, arg.TenantID, arg.TraceID)
 	var exists bool
 	err := row.Scan(&exists)
 	return exists, err
 }
 
 const countPersonAttributes = `-- name: CountPersonAttributes :one
-SELECT COUNT(*) FROM person_attributes WHERE person_id = $1
+SELECT COUNT(*) FROM person_attributes WHERE tenant_id = $1 AND person_id = $2
 `
 
+type CountPersonAttributesParams struct {
+	TenantID string
+	PersonID pgtype.UUID
+}
+
 // Count attributes for a person
-func (q *Queries) CountPersonAttributes(ctx context.Context, personID pgtype.UUID) (int64, error) {
-	row := q.db.QueryRow(ctx, countPersonAttributes, personID)
+func (q *Queries) CountPersonAttributes(ctx context.Context, arg CountPersonAttributesParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countPersonAttributes, arg.TenantID, arg.PersonID)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
 const countPersonImages = `-- name: CountPersonImages :one
-SELECT COUNT(*) FROM person_images WHERE person_id = $1
+SELECT COUNT(*) FROM person_images WHERE tenant_id = $1 AND person_id = $2
 `
 
+type CountPersonImagesParams struct {
+	TenantID string
+	PersonID pgtype.UUID
+}
+
 // Count images for a person
-func (q *Queries) CountPersonImages(ctx context.Context, personID pgtype.UUID) (int64, error) {
-	row := q.db.QueryRow(ctx, countPersonImages, personID)
+func (q *Queries) CountPersonImages(ctx context.Context, arg CountPersonImagesParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countPersonImages, arg.TenantID, arg.PersonID)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
+const createAPICredential = `-- name: CreateAPICredential :one
+
+INSERT INTO api_credential (
+    name,
+    owner,
+    key_hash,
+    key_prefix,
+    scopes,
+    namespace,
+    tenant_id,
+    redaction_profile,
+    purposes,
+    expires_at
+) VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10
+)
+RETURNING id, name, owner, key_hash, key_prefix, scopes, namespace, expires_at, last_used_at, revoked_at, created_at, tenant_id, redaction_profile, purposes
+`
+
+type CreateAPICredentialParams struct {
+	Name             string
+	Owner            string
+	KeyHash          []byte
+	KeyPrefix        string
+	Scopes           []string
+	Namespace        string
+	TenantID         string
+	RedactionProfile pgtype.Text
+	Purposes         []string
+	ExpiresAt        pgtype.Timestamptz
+}
+
+// ============================================================================
+// API CREDENTIAL OPERATIONS
+// ============================================================================
+// Create an API credential from the hash of a newly generated key
+func (q *Queries) CreateAPICredential(ctx context.Context, arg CreateAPICredentialParams) (ApiCredential, error) {
+	row := q.db.QueryRow(ctx, createAPICredential,
+		arg.Name,
+		arg.Owner,
+		arg.KeyHash,
+		arg.KeyPrefix,
+		arg.Scopes,
+		arg.Namespace,
+		arg.TenantID,
+		arg.RedactionProfile,
+		arg.Purposes,
+		arg.ExpiresAt,
+	)
+	var i ApiCredential
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Owner,
+		&i.KeyHash,
+		&i.KeyPrefix,
+		&i.Scopes,
+		&i.Namespace,
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.TenantID,
+		&i.RedactionProfile,
+		&i.Purposes,
+	)
+	return i, err
+}
+
 const createOrUpdatePersonAttribute = `-- name: CreateOrUpdatePersonAttribute :one
 
 INSERT INTO person_attributes (
+    tenant_id,
     person_id,
     attribute_key,
     encrypted_value,
@@ -65,20 +160,22 @@ INSERT INTO person_attributes (
 ) VALUES (
     $1,
     $2,
-    pgp_sym_encrypt($3, $4),
-    $5,
+    $3,
+    pgp_sym_encrypt($4, $5),
+    $6,
     1
 )
 ON CONFLICT (person_id, attribute_key)
 DO UPDATE SET
-    encrypted_value = pgp_sym_encrypt($3, $4),
-    key_version = $5,
+    encrypted_value = pgp_sym_encrypt($4, $5),
+    key_version = $6,
     version = person_attributes.version + 1,
     updated_at = CURRENT_TIMESTAMP
 RETURNING id, person_id, attribute_key, key_version, version, created_at, updated_at
 `
 
 type CreateOrUpdatePersonAttributeParams struct {
+	TenantID       string
 	PersonID       pgtype.UUID
 	AttributeKey   string
 	AttributeValue string
@@ -102,6 +199,7 @@ type CreateOrUpdatePersonAttributeRow struct {
 // Create or update a person attribute with encryption
 func (q *Queries) CreateOrUpdatePersonAttribute(ctx context.Context, arg CreateOrUpdatePersonAttributeParams) (CreateOrUpdatePersonAttributeRow, error) {
 	row := q.db.QueryRow(ctx, createOrUpdatePersonAttribute,
+		arg.TenantID,
 		arg.PersonID,
 		arg.AttributeKey,
 		arg.AttributeValue,
@@ -124,6 +222,7 @@ func (q *Queries) CreateOrUpdatePersonAttribute(ctx context.Context, arg CreateO
 const createOrUpdatePersonImage = `-- name: CreateOrUpdatePersonImage :one
 
 INSERT INTO person_images (
+    tenant_id,
     person_id,
     attribute_key,
     image_type,
@@ -134,30 +233,32 @@ INSERT INTO person_images (
     width,
     height
 ) VALUES (
-    $1, 
+    $1,
     $2, 
     $3, 
-    pgp_sym_encrypt($4, $5), 
-    $6, 
+    $4, 
+    pgp_sym_encrypt($5, $6), 
     $7, 
     $8, 
     $9, 
-    $10
+    $10, 
+    $11
 )
 ON CONFLICT (person_id, attribute_key)
 DO UPDATE SET
-    image_type = $3,
-    encrypted_image_data = pgp_sym_encrypt($4, $5),
-    key_version = $6,
-    mime_type = $7,
-    file_size = $8,
-    width = $9,
-    height = $10,
+    image_type = $4,
+    encrypted_image_data = pgp_sym_encrypt($5, $6),
+    key_version = $7,
+    mime_type = $8,
+    file_size = $9,
+    width = $10,
+    height = $11,
     updated_at = CURRENT_TIMESTAMP
 RETURNING id, person_id, attribute_key, image_type, key_version, mime_type, file_size, width, height, created_at, updated_at
 `
 
 type CreateOrUpdatePersonImageParams struct {
+	TenantID     string
 	PersonID     pgtype.UUID
 	AttributeKey string
 	ImageType    string
@@ -190,6 +291,7 @@ type CreateOrUpdatePersonImageRow struct {
 // Create or update a person image with encryption
 func (q *Queries) CreateOrUpdatePersonImage(ctx context.Context, arg CreateOrUpdatePersonImageParams) (CreateOrUpdatePersonImageRow, error) {
 	row := q.db.QueryRow(ctx, createOrUpdatePersonImage,
+		arg.TenantID,
 		arg.PersonID,
 		arg.AttributeKey,
 		arg.ImageType,
@@ -220,17 +322,22 @@ func (q *Queries) CreateOrUpdatePersonImage(ctx context.Context, arg CreateOrUpd
 
 const createPerson = `-- name: CreatePerson :one
 
-INSERT INTO person (client_id)
-VALUES ($1)
-RETURNING id, client_id, created_at, updated_at, deleted_at
+INSERT INTO person (tenant_id, client_id)
+VALUES ($1, $2)
+RETURNING id, client_id, created_at, updated_at, deleted_at, tenant_id
 `
 
+type CreatePersonParams struct {
+	TenantID string
+	ClientID string
+}
+
 // ============================================================================
 // PERSON OPERATIONS
 // ============================================================================
 // Create a new person
-func (q *Queries) CreatePerson(ctx context.Context, clientID string) (Person, error) {
-	row := q.db.QueryRow(ctx, createPerson, clientID)
+func (q *Queries) CreatePerson(ctx context.Context, arg CreatePersonParams) (Person, error) {
+	row := q.db.QueryRow(ctx, createPerson, arg.TenantID, arg.ClientID)
 	var i Person
 	err := row.Scan(
 		&i.ID,
@@ -238,74 +345,266 @@ func (q *Queries) CreatePerson(ctx context.Context, clientID string) (Person, er
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.TenantID,
 	)
 	return i, err
 }
 
+const decryptEncryptionCanary = `-- name: DecryptEncryptionCanary :one
+SELECT pgp_sym_decrypt(encrypted_value, $1::text)::text AS value
+FROM encryption_canary
+WHERE id = 1
+`
+
+// Decrypt the canary value; fails when the key cannot decrypt it
+func (q *Queries) DecryptEncryptionCanary(ctx context.Context, encKey string) (string, error) {
+	row := q.db.QueryRow(ctx, decryptEncryptionCanary, encKey)
+	var value string
+	err := row.Scan(&value)
+	return value, err
+}
+
 const deleteAllPersonAttributes = `-- name: DeleteAllPersonAttributes :exec
 DELETE FROM person_attributes
-WHERE person_id = $1
+WHERE tenant_id = $1 AND person_id = $2
 `
 
+type DeleteAllPersonAttributesParams struct {
+	TenantID string
+	PersonID pgtype.UUID
+}
+
 // Delete all attributes for a person
-func (q *Queries) DeleteAllPersonAttributes(ctx context.Context, personID pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, deleteAllPersonAttributes, personID)
+func (q *Queries) DeleteAllPersonAttributes(ctx context.Context, arg DeleteAllPersonAttributesParams) error {
+	_, err := q.db.Exec(ctx, deleteAllPersonAttributes, arg.TenantID, arg.PersonID)
 	return err
 }
 
 const deleteAllPersonImages = `-- name: DeleteAllPersonImages :exec
 DELETE FROM person_images
-WHERE person_id = $1
+WHERE tenant_id = $1 AND person_id = $2
 `
 
+type DeleteAllPersonImagesParams struct {
+	TenantID string
+	PersonID pgtype.UUID
+}
+
 // Delete all images for a person
-func (q *Queries) DeleteAllPersonImages(ctx context.Context, personID pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, deleteAllPersonImages, personID)
+func (q *Queries) DeleteAllPersonImages(ctx context.Context, arg DeleteAllPersonImagesParams) error {
+	_, err := q.db.Exec(ctx, deleteAllPersonImages, arg.TenantID, arg.PersonID)
 	return err
 }
 
+const deleteExpiredKeyValues = `-- name: DeleteExpiredKeyValues :execrows
+DELETE FROM key_value
+WHERE (namespace, key) IN (
+    SELECT namespace, key FROM key_value
+    WHERE expires_at IS NOT NULL AND expires_at <= CURRENT_TIMESTAMP
+    LIMIT $1
+)
+`
+
+// Delete up to batch_size expired key-value entries across all namespaces
+func (q *Queries) DeleteExpiredKeyValues(ctx context.Context, batchSize int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredKeyValues, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteExpiredRequestNonces = `-- name: DeleteExpiredRequestNonces :execrows
+DELETE FROM request_nonce
+WHERE (key_id, nonce) IN (
+    SELECT key_id, nonce FROM request_nonce
+    WHERE expires_at <= CURRENT_TIMESTAMP
+    LIMIT $1
+)
+`
+
+// Delete up to batch_size expired request nonces
+func (q *Queries) DeleteExpiredRequestNonces(ctx context.Context, batchSize int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredRequestNonces, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteIdleRateLimitBuckets = `-- name: DeleteIdleRateLimitBuckets :execrows
+DELETE FROM rate_limit_bucket
+WHERE bucket_key IN (
+    SELECT idle.bucket_key FROM rate_limit_bucket idle
+    WHERE idle.updated_at < $1
+    LIMIT $2
+)
+`
+
+type DeleteIdleRateLimitBucketsParams struct {
+	IdleBefore pgtype.Timestamptz
+	BatchSize  int32
+}
+
+// Delete up to batch_size buckets unused since idle_before; they have refilled completely
+func (q *Queries) DeleteIdleRateLimitBuckets(ctx context.Context, arg DeleteIdleRateLimitBucketsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteIdleRateLimitBuckets, arg.IdleBefore, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteKeyValuesByPrefix = `-- name: DeleteKeyValuesByPrefix :execrows
+DELETE FROM key_value
+WHERE namespace = $1 AND starts_with(key, $2::text)
+`
+
+type DeleteKeyValuesByPrefixParams struct {
+	Namespace string
+	Prefix    string
+}
+
+// Delete every key-value entry in a namespace whose key starts with prefix
+func (q *Queries) DeleteKeyValuesByPrefix(ctx context.Context, arg DeleteKeyValuesByPrefixParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteKeyValuesByPrefix, arg.Namespace, arg.Prefix)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deletePersonAttribute = `-- name: DeletePersonAttribute :exec
 DELETE FROM person_attributes
-WHERE person_id = $1 AND attribute_key = $2
+WHERE tenant_id = $1 AND person_id = $2 AND attribute_key = $3
 `
 
 type DeletePersonAttributeParams struct {
+	TenantID     string
 	PersonID     pgtype.UUID
 	AttributeKey string
 }
 
 // Delete a specific attribute for a person
 func (q *Queries) DeletePersonAttribute(ctx context.Context, arg DeletePersonAttributeParams) error {
-	_, err := q.db.Exec(ctx, deletePersonAttribute, arg.PersonID, arg.AttributeKey)
+	_, err := q.db.Exec(ctx, deletePersonAttribute, arg.TenantID, arg.PersonID, arg.AttributeKey)
 	return err
 }
 
 const deletePersonImage = `-- name: DeletePersonImage :exec
 DELETE FROM person_images
-WHERE person_id = $1 AND attribute_key = $2
+WHERE tenant_id = $1 AND person_id = $2 AND attribute_key = $3
 `
 
 type DeletePersonImageParams struct {
+	TenantID     string
 	PersonID     pgtype.UUID
 	AttributeKey string
 }
 
 // Delete a specific image for a person
 func (q *Queries) DeletePersonImage(ctx context.Context, arg DeletePersonImageParams) error {
-	_, err := q.db.Exec(ctx, deletePersonImage, arg.PersonID, arg.AttributeKey)
+	_, err := q.db.Exec(ctx, deletePersonImage, arg.TenantID, arg.PersonID, arg.AttributeKey)
 	return err
 }
 
-const deleteValue = `-- name: DeleteValue :exec
-DELETE FROM key_value WHERE key = $1
+const deleteValue = `-- name: DeleteValue :execrows
+DELETE FROM key_value
+WHERE namespace = $1 AND key = $2
+    AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+`
+
+type DeleteValueParams struct {
+	Namespace string
+	Key       string
+}
+
+// Delete a live value by namespace and key; returns 0 when the key does not exist or has expired
+func (q *Queries) DeleteValue(ctx context.Context, arg DeleteValueParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteValue, arg.Namespace, arg.Key)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteValueIfVersion = `-- name: DeleteValueIfVersion :execrows
+DELETE FROM key_value
+WHERE namespace = $1
+    AND key = $2
+    AND version = $3
+    AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+`
+
+type DeleteValueIfVersionParams struct {
+	Namespace       string
+	Key             string
+	ExpectedVersion int64
+}
+
+// Delete a live value by namespace and key only if its version matches
+func (q *Queries) DeleteValueIfVersion(ctx context.Context, arg DeleteValueIfVersionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteValueIfVersion, arg.Namespace, arg.Key, arg.ExpectedVersion)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const ensureEncryptionCanary = `-- name: EnsureEncryptionCanary :exec
+
+INSERT INTO encryption_canary (id, encrypted_value, key_version)
+VALUES (1, pgp_sym_encrypt($1::text, $2::text), $3)
+ON CONFLICT (id) DO NOTHING
 `
 
-// Delete a value by key
-func (q *Queries) DeleteValue(ctx context.Context, key string) error {
-	_, err := q.db.Exec(ctx, deleteValue, key)
+type EnsureEncryptionCanaryParams struct {
+	Value      string
+	EncKey     string
+	KeyVersion int64
+}
+
+// ============================================================================
+// ENCRYPTION CANARY OPERATIONS
+// ============================================================================
+// Store the canary value encrypted with the current key unless it already exists
+func (q *Queries) EnsureEncryptionCanary(ctx context.Context, arg EnsureEncryptionCanaryParams) error {
+	_, err := q.db.Exec(ctx, ensureEncryptionCanary, arg.Value, arg.EncKey, arg.KeyVersion)
 	return err
 }
 
+const getActiveAPICredentialByHash = `-- name: GetActiveAPICredentialByHash :one
+SELECT id, name, owner, key_hash, key_prefix, scopes, namespace, expires_at, last_used_at, revoked_at, created_at, tenant_id, redaction_profile, purposes
+FROM api_credential
+WHERE key_hash = $1
+    AND revoked_at IS NULL
+    AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+LIMIT 1
+`
+
+// Get an unrevoked, unexpired API credential by key hash
+func (q *Queries) GetActiveAPICredentialByHash(ctx context.Context, keyHash []byte) (ApiCredential, error) {
+	row := q.db.QueryRow(ctx, getActiveAPICredentialByHash, keyHash)
+	var i ApiCredential
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Owner,
+		&i.KeyHash,
+		&i.KeyPrefix,
+		&i.Scopes,
+		&i.Namespace,
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.TenantID,
+		&i.RedactionProfile,
+		&i.Purposes,
+	)
+	return i, err
+}
+
 const getAllPersonAttributes = `-- name: GetAllPersonAttributes :many
 SELECT
     id,
@@ -317,12 +616,13 @@ SELECT
     created_at,
     updated_at
 FROM person_attributes
-WHERE person_id = $2
+WHERE tenant_id = $2 AND person_id = $3
 ORDER BY attribute_key
 `
 
 type GetAllPersonAttributesParams struct {
 	EncKey   string
+	TenantID string
 	PersonID pgtype.UUID
 }
 
@@ -339,7 +639,7 @@ type GetAllPersonAttributesRow struct {
 
 // Get all decrypted attributes for a person
 func (q *Queries) GetAllPersonAttributes(ctx context.Context, arg GetAllPersonAttributesParams) ([]GetAllPersonAttributesRow, error) {
-	rows, err := q.db.Query(ctx, getAllPersonAttributes, arg.EncKey, arg.PersonID)
+	rows, err := q.db.Query(ctx, getAllPersonAttributes, arg.EncKey, arg.TenantID, arg.PersonID)
 	if err != nil {
 		return nil, err
 	}
@@ -367,19 +667,59 @@ func (q *Queries) GetAllPersonAttributes(ctx context.Context, arg GetAllPersonAt
 	return items, nil
 }
 
+const getEncryptionCanaryKeyVersion = `-- name: GetEncryptionCanaryKeyVersion :one
+SELECT key_version FROM encryption_canary WHERE id = 1
+`
+
+// Get the version of the key the canary value is encrypted with
+func (q *Queries) GetEncryptionCanaryKeyVersion(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, getEncryptionCanaryKeyVersion)
+	var key_version int64
+	err := row.Scan(&key_version)
+	return key_version, err
+}
+
 const getKeyValue = `-- name: GetKeyValue :one
-SELECT key, value, created_at, updated_at FROM key_value WHERE key = $1 LIMIT 1
+SELECT
+    key,
+    CASE WHEN encrypted_value IS NULL THEN value
+        ELSE pgp_sym_decrypt(encrypted_value, $1::text) END::text AS value,
+    created_at,
+    updated_at,
+    expires_at,
+    version
+FROM key_value
+WHERE namespace = $2 AND key = $3
+    AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+LIMIT 1
 `
 
-// Retrieve the full key-value record by key
-func (q *Queries) GetKeyValue(ctx context.Context, key string) (KeyValue, error) {
-	row := q.db.QueryRow(ctx, getKeyValue, key)
-	var i KeyValue
+type GetKeyValueParams struct {
+	EncKey    string
+	Namespace string
+	Key       string
+}
+
+type GetKeyValueRow struct {
+	Key       string
+	Value     string
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+	ExpiresAt pgtype.Timestamptz
+	Version   int64
+}
+
+// Retrieve the full decrypted key-value record by namespace and key, ignoring expired entries
+func (q *Queries) GetKeyValue(ctx context.Context, arg GetKeyValueParams) (GetKeyValueRow, error) {
+	row := q.db.QueryRow(ctx, getKeyValue, arg.EncKey, arg.Namespace, arg.Key)
+	var i GetKeyValueRow
 	err := row.Scan(
 		&i.Key,
 		&i.Value,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.Version,
 	)
 	return i, err
 }
@@ -395,12 +735,13 @@ SELECT
     created_at,
     updated_at
 FROM person_attributes
-WHERE person_id = $2 AND attribute_key = ANY($3::citext[])
+WHERE tenant_id = $2 AND person_id = $3 AND attribute_key = ANY($4::citext[])
 ORDER BY attribute_key
 `
 
 type GetMultiplePersonAttributesParams struct {
 	EncKey        string
+	TenantID      string
 	PersonID      pgtype.UUID
 	AttributeKeys []string
 }
@@ -418,7 +759,12 @@ type GetMultiplePersonAttributesRow struct {
 
 // Get multiple specific attributes for a person (pass array of keys)
 func (q *Queries) GetMultiplePersonAttributes(ctx context.Context, arg GetMultiplePersonAttributesParams) ([]GetMultiplePersonAttributesRow, error) {
-	rows, err := q.db.Query(ctx, getMultiplePersonAttributes, arg.EncKey, arg.PersonID, arg.AttributeKeys)
+	rows, err := q.db.Query(ctx, getMultiplePersonAttributes,
+		arg.EncKey,
+		arg.TenantID,
+		arg.PersonID,
+		arg.AttributeKeys,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -457,12 +803,13 @@ SELECT
     created_at,
     updated_at
 FROM person_attributes
-WHERE person_id = $2 AND attribute_key = $3
+WHERE tenant_id = $2 AND person_id = $3 AND attribute_key = $4
 LIMIT 1
 `
 
 type GetPersonAttributeParams struct {
 	EncKey       string
+	TenantID     string
 	PersonID     pgtype.UUID
 	AttributeKey string
 }
@@ -480,7 +827,12 @@ type GetPersonAttributeRow struct {
 
 // Get a single decrypted attribute for a person
 func (q *Queries) GetPersonAttribute(ctx context.Context, arg GetPersonAttributeParams) (GetPersonAttributeRow, error) {
-	row := q.db.QueryRow(ctx, getPersonAttribute, arg.EncKey, arg.PersonID, arg.AttributeKey)
+	row := q.db.QueryRow(ctx, getPersonAttribute,
+		arg.EncKey,
+		arg.TenantID,
+		arg.PersonID,
+		arg.AttributeKey,
+	)
 	var i GetPersonAttributeRow
 	err := row.Scan(
 		&i.ID,
@@ -496,15 +848,20 @@ func (q *Queries) GetPersonAttribute(ctx context.Context, arg GetPersonAttribute
 }
 
 const getPersonByClientId = `-- name: GetPersonByClientId :one
-SELECT id, client_id, created_at, updated_at, deleted_at
+SELECT id, client_id, created_at, updated_at, deleted_at, tenant_id
 FROM person
-WHERE client_id = $1 AND deleted_at IS NULL
+WHERE tenant_id = $1 AND client_id = $2 AND deleted_at IS NULL
 LIMIT 1
 `
 
+type GetPersonByClientIdParams struct {
+	TenantID string
+	ClientID string
+}
+
 // Get person by client_id
-func (q *Queries) GetPersonByClientId(ctx context.Context, clientID string) (Person, error) {
-	row := q.db.QueryRow(ctx, getPersonByClientId, clientID)
+func (q *Queries) GetPersonByClientId(ctx context.Context, arg GetPersonByClientIdParams) (Person, error) {
+	row := q.db.QueryRow(ctx, getPersonByClientId, arg.TenantID, arg.ClientID)
 	var i Person
 	err := row.Scan(
 		&i.ID,
@@ -512,20 +869,26 @@ func (q *Queries) GetPersonByClientId(ctx context.Context, clientID string) (Per
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.TenantID,
 	)
 	return i, err
 }
 
 const getPersonById = `-- name: GetPersonById :one
-SELECT id, client_id, created_at, updated_at, deleted_at
+SELECT id, client_id, created_at, updated_at, deleted_at, tenant_id
 FROM person
-WHERE id = $1 AND deleted_at IS NULL
+WHERE tenant_id = $1 AND id = $2 AND deleted_at IS NULL
 LIMIT 1
 `
 
+type GetPersonByIdParams struct {
+	TenantID string
+	ID       pgtype.UUID
+}
+
 // Get person by internal UUID
-func (q *Queries) GetPersonById(ctx context.Context, id pgtype.UUID) (Person, error) {
-	row := q.db.QueryRow(ctx, getPersonById, id)
+func (q *Queries) GetPersonById(ctx context.Context, arg GetPersonByIdParams) (Person, error) {
+	row := q.db.QueryRow(ctx, getPersonById, arg.TenantID, arg.ID)
 	var i Person
 	err := row.Scan(
 		&i.ID,
@@ -533,6 +896,7 @@ func (q *Queries) GetPersonById(ctx context.Context, id pgtype.UUID) (Person, er
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.TenantID,
 	)
 	return i, err
 }
@@ -552,12 +916,13 @@ SELECT
     created_at,
     updated_at
 FROM person_images
-WHERE person_id = $2 AND attribute_key = $3
+WHERE tenant_id = $2 AND person_id = $3 AND attribute_key = $4
 LIMIT 1
 `
 
 type GetPersonImageParams struct {
 	EncKey       string
+	TenantID     string
 	PersonID     pgtype.UUID
 	AttributeKey string
 }
@@ -579,7 +944,12 @@ type GetPersonImageRow struct {
 
 // Get a specific decrypted image for a person
 func (q *Queries) GetPersonImage(ctx context.Context, arg GetPersonImageParams) (GetPersonImageRow, error) {
-	row := q.db.QueryRow(ctx, getPersonImage, arg.EncKey, arg.PersonID, arg.AttributeKey)
+	row := q.db.QueryRow(ctx, getPersonImage,
+		arg.EncKey,
+		arg.TenantID,
+		arg.PersonID,
+		arg.AttributeKey,
+	)
 	var i GetPersonImageRow
 	err := row.Scan(
 		&i.ID,
@@ -612,11 +982,12 @@ SELECT
     created_at,
     updated_at
 FROM person_images
-WHERE person_id = $1 AND attribute_key = $2
+WHERE tenant_id = $1 AND person_id = $2 AND attribute_key = $3
 LIMIT 1
 `
 
 type GetPersonImageMetadataParams struct {
+	TenantID     string
 	PersonID     pgtype.UUID
 	AttributeKey string
 }
@@ -637,7 +1008,7 @@ type GetPersonImageMetadataRow struct {
 
 // Get image metadata without decrypting the image data (for performance)
 func (q *Queries) GetPersonImageMetadata(ctx context.Context, arg GetPersonImageMetadataParams) (GetPersonImageMetadataRow, error) {
-	row := q.db.QueryRow(ctx, getPersonImageMetadata, arg.PersonID, arg.AttributeKey)
+	row := q.db.QueryRow(ctx, getPersonImageMetadata, arg.TenantID, arg.PersonID, arg.AttributeKey)
 	var i GetPersonImageMetadataRow
 	err := row.Scan(
 		&i.ID,
@@ -662,18 +1033,24 @@ SELECT
     p.client_id,
     p.created_at,
     p.updated_at,
-    p.deleted_at
+    p.deleted_at,
+    p.tenant_id
 FROM person p
-WHERE p.id = $1 AND p.deleted_at IS NULL
+WHERE p.tenant_id = $1 AND p.id = $2 AND p.deleted_at IS NULL
 LIMIT 1
 `
 
+type GetPersonWithAttributesParams struct {
+	TenantID string
+	ID       pgtype.UUID
+}
+
 // ============================================================================
 // COMBINED OPERATIONS
 // ============================================================================
 // Get person basic info (to be combined with attributes in application layer)
-func (q *Queries) GetPersonWithAttributes(ctx context.Context, id pgtype.UUID) (Person, error) {
-	row := q.db.QueryRow(ctx, getPersonWithAttributes, id)
+func (q *Queries) GetPersonWithAttributes(ctx context.Context, arg GetPersonWithAttributesParams) (Person, error) {
+	row := q.db.QueryRow(ctx, getPersonWithAttributes, arg.TenantID, arg.ID)
 	var i Person
 	err := row.Scan(
 		&i.ID,
@@ -681,10 +1058,32 @@ func (q *Queries) GetPersonWithAttributes(ctx context.Context, id pgtype.UUID) (
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.TenantID,
 	)
 	return i, err
 }
 
+const getRateLimitTokens = `-- name: GetRateLimitTokens :one
+SELECT LEAST($1::double precision,
+        tokens + EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - updated_at)::double precision * $2::double precision)::double precision AS tokens
+FROM rate_limit_bucket
+WHERE bucket_key = $3
+`
+
+type GetRateLimitTokensParams struct {
+	Capacity        float64
+	RefillPerSecond float64
+	BucketKey       string
+}
+
+// Return the tokens a bucket holds now, refilled for the time since its last update
+func (q *Queries) GetRateLimitTokens(ctx context.Context, arg GetRateLimitTokensParams) (float64, error) {
+	row := q.db.QueryRow(ctx, getRateLimitTokens, arg.Capacity, arg.RefillPerSecond, arg.BucketKey)
+	var tokens float64
+	err := row.Scan(&tokens)
+	return tokens, err
+}
+
 const getRequestLogByTraceId = `-- name: GetRequestLogByTraceId :one
 SELECT 
     id,
@@ -694,15 +1093,17 @@ SELECT
     pgp_sym_decrypt(encrypted_request_body, $1) AS request_body,
     pgp_sym_decrypt(encrypted_response_body, $1) AS response_body,
     key_version,
-    created_at
+    created_at,
+    purpose
 FROM request_log
-WHERE trace_id = $2
+WHERE tenant_id = $2 AND trace_id = $3
 LIMIT 1
 `
 
 type GetRequestLogByTraceIdParams struct {
-	EncKey  string
-	TraceID string
+	EncKey   string
+	TenantID string
+	TraceID  string
 }
 
 type GetRequestLogByTraceIdRow struct {
@@ -714,11 +1115,12 @@ type GetRequestLogByTraceIdRow struct {
 	ResponseBody string
 	KeyVersion   int64
 	CreatedAt    pgtype.Timestamptz
+	Purpose      pgtype.Text
 }
 
 // Retrieve request log by trace_id with decrypted data
 func (q *Queries) GetRequestLogByTraceId(ctx context.Context, arg GetRequestLogByTraceIdParams) (GetRequestLogByTraceIdRow, error) {
-	row := q.db.QueryRow(ctx, getRequestLogByTraceId, arg.EncKey, arg.TraceID)
+	row := q.db.QueryRow(ctx, getRequestLogByTraceId, arg.EncKey, arg.TenantID, arg.TraceID)
 	var i GetRequestLogByTraceIdRow
 	err := row.Scan(
 		&i.ID,
@@ -729,29 +1131,47 @@ func (q *Queries) GetRequestLogByTraceId(ctx context.Context, arg GetRequestLogB
 		&i.ResponseBody,
 		&i.KeyVersion,
 		&i.CreatedAt,
+		&i.Purpose,
 	)
 	return i, err
 }
 
 const getValue = `-- name: GetValue :one
-SELECT value FROM key_value WHERE key = $1 LIMIT 1
+SELECT
+    CASE WHEN encrypted_value IS NULL THEN value
+        ELSE pgp_sym_decrypt(encrypted_value, $1::text) END::text AS value
+FROM key_value
+WHERE namespace = $2 AND key = $3
+    AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+LIMIT 1
 `
 
-// Retrieve a value by key
-func (q *Queries) GetValue(ctx context.Context, key string) (string, error) {
-	row := q.db.QueryRow(ctx, getValue, key)
+type GetValueParams struct {
+	EncKey    string
+	Namespace string
+	Key       string
+}
+
+// Retrieve a decrypted value by namespace and key, ignoring expired entries
+func (q *Queries) GetValue(ctx context.Context, arg GetValueParams) (string, error) {
+	row := q.db.QueryRow(ctx, getValue, arg.EncKey, arg.Namespace, arg.Key)
 	var value string
 	err := row.Scan(&value)
 	return value, err
 }
 
 const hardDeletePerson = `-- name: HardDeletePerson :exec
-DELETE FROM person WHERE id = $1
+DELETE FROM person WHERE tenant_id = $1 AND id = $2
 `
 
+type HardDeletePersonParams struct {
+	TenantID string
+	ID       pgtype.UUID
+}
+
 // Hard delete a person (use with caution)
-func (q *Queries) HardDeletePerson(ctx context.Context, id pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, hardDeletePerson, id)
+func (q *Queries) HardDeletePerson(ctx context.Context, arg HardDeletePersonParams) error {
+	_, err := q.db.Exec(ctx, hardDeletePerson, arg.TenantID, arg.ID)
 	return err
 }
 
@@ -765,33 +1185,118 @@ func (q *Queries) HealthCheck(ctx context.Context) error {
 	return err
 }
 
+const incrementValue = `-- name: IncrementValue :one
+INSERT INTO key_value (namespace, key, value, encrypted_value, key_version) VALUES (
+    $1,
+    $2,
+    CASE WHEN $3::boolean THEN '' ELSE $4::bigint::text END,
+    CASE WHEN $3::boolean THEN pgp_sym_encrypt($4::bigint::text, $5::text) END,
+    CASE WHEN $3::boolean THEN $6::bigint END
+)
+ON CONFLICT (namespace, key) DO UPDATE SET
+    value = CASE WHEN $3::boolean THEN '' ELSE (
+        CASE WHEN key_value.expires_at <= CURRENT_TIMESTAMP THEN 0
+            WHEN key_value.encrypted_value IS NULL THEN key_value.value::bigint
+            ELSE pgp_sym_decrypt(key_value.encrypted_value, $5::text)::bigint END
+        + $4::bigint)::text END,
+    encrypted_value = CASE WHEN $3::boolean THEN pgp_sym_encrypt((
+        CASE WHEN key_value.expires_at <= CURRENT_TIMESTAMP THEN 0
+            WHEN key_value.encrypted_value IS NULL THEN key_value.value::bigint
+            ELSE pgp_sym_decrypt(key_value.encrypted_value, $5::text)::bigint END
+        + $4::bigint)::text, $5::text) END,
+    key_version = EXCLUDED.key_version,
+    expires_at = CASE WHEN key_value.expires_at <= CURRENT_TIMESTAMP THEN NULL ELSE key_value.expires_at END,
+    version = CASE WHEN key_value.expires_at <= CURRENT_TIMESTAMP THEN 1 ELSE key_value.version + 1 END,
+    created_at = CASE WHEN key_value.expires_at <= CURRENT_TIMESTAMP THEN CURRENT_TIMESTAMP ELSE key_value.created_at END,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING
+    key,
+    CASE WHEN encrypted_value IS NULL THEN value
+        ELSE pgp_sym_decrypt(encrypted_value, $5::text) END::text AS value,
+    created_at,
+    updated_at,
+    expires_at,
+    version
+`
+
+type IncrementValueParams struct {
+	Namespace  string
+	Key        string
+	Encrypt    bool
+	Delta      int64
+	EncKey     string
+	KeyVersion int64
+}
+
+type IncrementValueRow struct {
+	Key       string
+	Value     string
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+	ExpiresAt pgtype.Timestamptz
+	Version   int64
+}
+
+// Atomically add delta to an integer value, creating the key (or replacing an expired one) with delta.
+// Encrypted values are decrypted, incremented and re-encrypted according to encrypt.
+// Fails with invalid_text_representation when the stored value is not an integer.
+func (q *Queries) IncrementValue(ctx context.Context, arg IncrementValueParams) (IncrementValueRow, error) {
+	row := q.db.QueryRow(ctx, incrementValue,
+		arg.Namespace,
+		arg.Key,
+		arg.Encrypt,
+		arg.Delta,
+		arg.EncKey,
+		arg.KeyVersion,
+	)
+	var i IncrementValueRow
+	err := row.Scan(
+		&i.Key,
+		&i.Value,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.Version,
+	)
+	return i, err
+}
+
 const insertRequestLog = `-- name: InsertRequestLog :one
 
 INSERT INTO request_log (
+    tenant_id,
     trace_id, 
     caller_info,
+    principal_id,
     reason, 
     encrypted_request_body, 
     encrypted_response_body, 
-    key_version
+    key_version,
+    purpose
 ) VALUES (
-    $1, 
-    $2,
-    $3, 
-    pgp_sym_encrypt($4, $5), 
-    pgp_sym_encrypt($6, $5), 
-    $7
+    $1,
+    $2, 
+    $3,
+    $4,
+    $5, 
+    pgp_sym_encrypt($6, $7), 
+    pgp_sym_encrypt($8, $7), 
+    $9,
+    $10
 ) RETURNING id, trace_id, created_at
 `
 
 type InsertRequestLogParams struct {
+	TenantID              string
 	TraceID               string
 	CallerInfo            string
+	PrincipalID           pgtype.Text
 	Reason                string
 	EncryptedRequestBody  string
 	EncKey                string
 	EncryptedResponseBody string
 	KeyVersion            int64
+	Purpose               pgtype.Text
 }
 
 type InsertRequestLogRow struct {
@@ -806,28 +1311,74 @@ type InsertRequestLogRow struct {
 // Insert a new request log entry with encrypted data
 func (q *Queries) InsertRequestLog(ctx context.Context, arg InsertRequestLogParams) (InsertRequestLogRow, error) {
 	row := q.db.QueryRow(ctx, insertRequestLog,
+		arg.TenantID,
 		arg.TraceID,
 		arg.CallerInfo,
+		arg.PrincipalID,
 		arg.Reason,
 		arg.EncryptedRequestBody,
 		arg.EncKey,
 		arg.EncryptedResponseBody,
 		arg.KeyVersion,
+		arg.Purpose,
 	)
 	var i InsertRequestLogRow
 	err := row.Scan(&i.ID, &i.TraceID, &i.CreatedAt)
 	return i, err
 }
 
+const listAPICredentials = `-- name: ListAPICredentials :many
+SELECT id, name, owner, key_hash, key_prefix, scopes, namespace, expires_at, last_used_at, revoked_at, created_at, tenant_id, redaction_profile, purposes
+FROM api_credential
+ORDER BY created_at DESC
+`
+
+// List all API credentials, newest first
+func (q *Queries) ListAPICredentials(ctx context.Context) ([]ApiCredential, error) {
+	rows, err := q.db.Query(ctx, listAPICredentials)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ApiCredential{}
+	for rows.Next() {
+		var i ApiCredential
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Owner,
+			&i.KeyHash,
+			&i.KeyPrefix,
+			&i.Scopes,
+			&i.Namespace,
+			&i.ExpiresAt,
+			&i.LastUsedAt,
+			&i.RevokedAt,
+			&i.CreatedAt,
+			&i.TenantID,
+			&i.RedactionProfile,
+			&i.Purposes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listAttributeKeys = `-- name: ListAttributeKeys :many
 SELECT DISTINCT attribute_key
 FROM person_attributes
+WHERE tenant_id = $1
 ORDER BY attribute_key
 `
 
-// List all unique attribute keys used across all persons
-func (q *Queries) ListAttributeKeys(ctx context.Context) ([]string, error) {
-	rows, err := q.db.Query(ctx, listAttributeKeys)
+// List all unique attribute keys used across the persons of a tenant
+func (q *Queries) ListAttributeKeys(ctx context.Context, tenantID string) ([]string, error) {
+	rows, err := q.db.Query(ctx, listAttributeKeys, tenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -846,6 +1397,76 @@ func (q *Queries) ListAttributeKeys(ctx context.Context) ([]string, error) {
 	return items, nil
 }
 
+const listKeyValues = `-- name: ListKeyValues :many
+SELECT
+    key,
+    CASE WHEN encrypted_value IS NULL THEN value
+        ELSE pgp_sym_decrypt(encrypted_value, $1::text) END::text AS value,
+    created_at,
+    updated_at,
+    expires_at,
+    version
+FROM key_value
+WHERE namespace = $2
+    AND starts_with(key, $3::text)
+    AND key > $4::text
+    AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+ORDER BY key
+LIMIT $5
+`
+
+type ListKeyValuesParams struct {
+	EncKey     string
+	Namespace  string
+	Prefix     string
+	AfterKey   string
+	LimitCount int32
+}
+
+type ListKeyValuesRow struct {
+	Key       string
+	Value     string
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+	ExpiresAt pgtype.Timestamptz
+	Version   int64
+}
+
+// List live decrypted key-value entries in a namespace whose key starts with prefix,
+// in key order after after_key (keyset pagination)
+func (q *Queries) ListKeyValues(ctx context.Context, arg ListKeyValuesParams) ([]ListKeyValuesRow, error) {
+	rows, err := q.db.Query(ctx, listKeyValues,
+		arg.EncKey,
+		arg.Namespace,
+		arg.Prefix,
+		arg.AfterKey,
+		arg.LimitCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListKeyValuesRow{}
+	for rows.Next() {
+		var i ListKeyValuesRow
+		if err := rows.Scan(
+			&i.Key,
+			&i.Value,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listPersonImages = `-- name: ListPersonImages :many
 SELECT 
     id,
@@ -860,10 +1481,15 @@ SELECT
     created_at,
     updated_at
 FROM person_images
-WHERE person_id = $1
+WHERE tenant_id = $1 AND person_id = $2
 ORDER BY created_at DESC
 `
 
+type ListPersonImagesParams struct {
+	TenantID string
+	PersonID pgtype.UUID
+}
+
 type ListPersonImagesRow struct {
 	ID           int64
 	PersonID     pgtype.UUID
@@ -879,8 +1505,8 @@ type ListPersonImagesRow struct {
 }
 
 // List all image metadata for a person (without decrypting)
-func (q *Queries) ListPersonImages(ctx context.Context, personID pgtype.UUID) ([]ListPersonImagesRow, error) {
-	rows, err := q.db.Query(ctx, listPersonImages, personID)
+func (q *Queries) ListPersonImages(ctx context.Context, arg ListPersonImagesParams) ([]ListPersonImagesRow, error) {
+	rows, err := q.db.Query(ctx, listPersonImages, arg.TenantID, arg.PersonID)
 	if err != nil {
 		return nil, err
 	}
@@ -925,11 +1551,12 @@ SELECT
     created_at,
     updated_at
 FROM person_images
-WHERE person_id = $1 AND image_type = $2
+WHERE tenant_id = $1 AND person_id = $2 AND image_type = $3
 ORDER BY created_at DESC
 `
 
 type ListPersonImagesByTypeParams struct {
+	TenantID  string
 	PersonID  pgtype.UUID
 	ImageType string
 }
@@ -950,7 +1577,7 @@ type ListPersonImagesByTypeRow struct {
 
 // List images of a specific type for a person (without decrypting)
 func (q *Queries) ListPersonImagesByType(ctx context.Context, arg ListPersonImagesByTypeParams) ([]ListPersonImagesByTypeRow, error) {
-	rows, err := q.db.Query(ctx, listPersonImagesByType, arg.PersonID, arg.ImageType)
+	rows, err := q.db.Query(ctx, listPersonImagesByType, arg.TenantID, arg.PersonID, arg.ImageType)
 	if err != nil {
 		return nil, err
 	}
@@ -982,21 +1609,22 @@ func (q *Queries) ListPersonImagesByType(ctx context.Context, arg ListPersonImag
 }
 
 const listPersons = `-- name: ListPersons :many
-SELECT id, client_id, created_at, updated_at, deleted_at
+SELECT id, client_id, created_at, updated_at, deleted_at, tenant_id
 FROM person
-WHERE deleted_at IS NULL
+WHERE tenant_id = $1 AND deleted_at IS NULL
 ORDER BY created_at DESC
-LIMIT $2 OFFSET $1
+LIMIT $3 OFFSET $2
 `
 
 type ListPersonsParams struct {
+	TenantID    string
 	OffsetCount int32
 	LimitCount  int32
 }
 
-// List all active persons with pagination
+// List all active persons of a tenant with pagination
 func (q *Queries) ListPersons(ctx context.Context, arg ListPersonsParams) ([]Person, error) {
-	rows, err := q.db.Query(ctx, listPersons, arg.OffsetCount, arg.LimitCount)
+	rows, err := q.db.Query(ctx, listPersons, arg.TenantID, arg.OffsetCount, arg.LimitCount)
 	if err != nil {
 		return nil, err
 	}
@@ -1010,6 +1638,7 @@ func (q *Queries) ListPersons(ctx context.Context, arg ListPersonsParams) ([]Per
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.TenantID,
 		); err != nil {
 			return nil, err
 		}
@@ -1024,15 +1653,35 @@ func (q *Queries) ListPersons(ctx context.Context, arg ListPersonsParams) ([]Per
 const restorePerson = `-- name: RestorePerson :exec
 UPDATE person
 SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
-WHERE id = $1
+WHERE tenant_id = $1 AND id = $2
 `
 
+type RestorePersonParams struct {
+	TenantID string
+	ID       pgtype.UUID
+}
+
 // Restore a soft-deleted person
-func (q *Queries) RestorePerson(ctx context.Context, id pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, restorePerson, id)
+func (q *Queries) RestorePerson(ctx context.Context, arg RestorePersonParams) error {
+	_, err := q.db.Exec(ctx, restorePerson, arg.TenantID, arg.ID)
 	return err
 }
 
+const revokeAPICredential = `-- name: RevokeAPICredential :execrows
+UPDATE api_credential
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND revoked_at IS NULL
+`
+
+// Revoke an API credential; returns 0 when it does not exist or is already revoked
+func (q *Queries) RevokeAPICredential(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeAPICredential, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const searchPersonsByAttribute = `-- name: SearchPersonsByAttribute :many
 SELECT DISTINCT
     p.id,
@@ -1041,12 +1690,15 @@ SELECT DISTINCT
     p.updated_at
 FROM person p
 JOIN person_attributes pa ON p.id = pa.person_id
-WHERE pa.attribute_key = $1
-    AND pgp_sym_decrypt(pa.encrypted_value, $2) = $3
+WHERE p.tenant_id = $1
+    AND pa.tenant_id = p.tenant_id
+    AND pa.attribute_key = $2
+    AND pgp_sym_decrypt(pa.encrypted_value, $3) = $4
     AND p.deleted_at IS NULL
 `
 
 type SearchPersonsByAttributeParams struct {
+	TenantID       string
 	AttributeKey   string
 	EncKey         string
 	AttributeValue []byte
@@ -1061,7 +1713,12 @@ type SearchPersonsByAttributeRow struct {
 
 // Search persons by a specific decrypted attribute value (note: performance intensive)
 func (q *Queries) SearchPersonsByAttribute(ctx context.Context, arg SearchPersonsByAttributeParams) ([]SearchPersonsByAttributeRow, error) {
-	rows, err := q.db.Query(ctx, searchPersonsByAttribute, arg.AttributeKey, arg.EncKey, arg.AttributeValue)
+	rows, err := q.db.Query(ctx, searchPersonsByAttribute,
+		arg.TenantID,
+		arg.AttributeKey,
+		arg.EncKey,
+		arg.AttributeValue,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -1085,31 +1742,251 @@ func (q *Queries) SearchPersonsByAttribute(ctx context.Context, arg SearchPerson
 	return items, nil
 }
 
-const setValue = `-- name: SetValue :exec
-INSERT INTO key_value (key, value) VALUES ($1, $2)
-ON CONFLICT (key) DO UPDATE SET value = $2
+const setValue = `-- name: SetValue :one
+INSERT INTO key_value (namespace, key, value, encrypted_value, key_version, expires_at) VALUES (
+    $1,
+    $2,
+    CASE WHEN $3::boolean THEN '' ELSE $4::text END,
+    CASE WHEN $3::boolean THEN pgp_sym_encrypt($4::text, $5::text) END,
+    CASE WHEN $3::boolean THEN $6::bigint END,
+    $7
+)
+ON CONFLICT (namespace, key) DO UPDATE SET
+    value = EXCLUDED.value,
+    encrypted_value = EXCLUDED.encrypted_value,
+    key_version = EXCLUDED.key_version,
+    expires_at = EXCLUDED.expires_at,
+    version = CASE WHEN key_value.expires_at <= CURRENT_TIMESTAMP THEN 1 ELSE key_value.version + 1 END,
+    created_at = CASE WHEN key_value.expires_at <= CURRENT_TIMESTAMP THEN CURRENT_TIMESTAMP ELSE key_value.created_at END,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING key, $4::text AS value, created_at, updated_at, expires_at, version
 `
 
 type SetValueParams struct {
-	Key   string
-	Value string
+	Namespace  string
+	Key        string
+	Encrypt    bool
+	Value      string
+	EncKey     string
+	KeyVersion int64
+	ExpiresAt  pgtype.Timestamptz
+}
+
+type SetValueRow struct {
+	Key       string
+	Value     string
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+	ExpiresAt pgtype.Timestamptz
+	Version   int64
+}
+
+// Set a value by namespace and key with an optional expiry (NULL means never expires).
+// When encrypt is true the value is stored with pgp_sym_encrypt and the plaintext column is left empty.
+// An expired entry is replaced as if absent, so a returned version of 1 means the key was created.
+func (q *Queries) SetValue(ctx context.Context, arg SetValueParams) (SetValueRow, error) {
+	row := q.db.QueryRow(ctx, setValue,
+		arg.Namespace,
+		arg.Key,
+		arg.Encrypt,
+		arg.Value,
+		arg.EncKey,
+		arg.KeyVersion,
+		arg.ExpiresAt,
+	)
+	var i SetValueRow
+	err := row.Scan(
+		&i.Key,
+		&i.Value,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.Version,
+	)
+	return i, err
 }
 
-// Set a value by key
-func (q *Queries) SetValue(ctx context.Context, arg SetValueParams) error {
-	_, err := q.db.Exec(ctx, setValue, arg.Key, arg.Value)
-	return err
+const setValueIfAbsent = `-- name: SetValueIfAbsent :one
+INSERT INTO key_value (namespace, key, value, encrypted_value, key_version, expires_at) VALUES (
+    $1,
+    $2,
+    CASE WHEN $3::boolean THEN '' ELSE $4::text END,
+    CASE WHEN $3::boolean THEN pgp_sym_encrypt($4::text, $5::text) END,
+    CASE WHEN $3::boolean THEN $6::bigint END,
+    $7
+)
+ON CONFLICT (namespace, key) DO UPDATE SET
+    value = EXCLUDED.value,
+    encrypted_value = EXCLUDED.encrypted_value,
+    key_version = EXCLUDED.key_version,
+    expires_at = EXCLUDED.expires_at,
+    version = 1,
+    created_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE key_value.expires_at <= CURRENT_TIMESTAMP
+RETURNING key, $4::text AS value, created_at, updated_at, expires_at, version
+`
+
+type SetValueIfAbsentParams struct {
+	Namespace  string
+	Key        string
+	Encrypt    bool
+	Value      string
+	EncKey     string
+	KeyVersion int64
+	ExpiresAt  pgtype.Timestamptz
+}
+
+type SetValueIfAbsentRow struct {
+	Key       string
+	Value     string
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+	ExpiresAt pgtype.Timestamptz
+	Version   int64
+}
+
+// Create a key only if it does not exist (or has expired) in the namespace; returns no rows otherwise
+func (q *Queries) SetValueIfAbsent(ctx context.Context, arg SetValueIfAbsentParams) (SetValueIfAbsentRow, error) {
+	row := q.db.QueryRow(ctx, setValueIfAbsent,
+		arg.Namespace,
+		arg.Key,
+		arg.Encrypt,
+		arg.Value,
+		arg.EncKey,
+		arg.KeyVersion,
+		arg.ExpiresAt,
+	)
+	var i SetValueIfAbsentRow
+	err := row.Scan(
+		&i.Key,
+		&i.Value,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.Version,
+	)
+	return i, err
+}
+
+const setValueIfVersion = `-- name: SetValueIfVersion :one
+UPDATE key_value
+SET
+    value = CASE WHEN $1::boolean THEN '' ELSE $2::text END,
+    encrypted_value = CASE WHEN $1::boolean THEN pgp_sym_encrypt($2::text, $3::text) END,
+    key_version = CASE WHEN $1::boolean THEN $4::bigint END,
+    expires_at = $5,
+    version = version + 1,
+    updated_at = CURRENT_TIMESTAMP
+WHERE namespace = $6
+    AND key = $7
+    AND version = $8
+    AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+RETURNING key, $2::text AS value, created_at, updated_at, expires_at, version
+`
+
+type SetValueIfVersionParams struct {
+	Encrypt         bool
+	Value           string
+	EncKey          string
+	KeyVersion      int64
+	ExpiresAt       pgtype.Timestamptz
+	Namespace       string
+	Key             string
+	ExpectedVersion int64
+}
+
+type SetValueIfVersionRow struct {
+	Key       string
+	Value     string
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+	ExpiresAt pgtype.Timestamptz
+	Version   int64
+}
+
+// Update a live key only if its version matches; returns no rows otherwise
+func (q *Queries) SetValueIfVersion(ctx context.Context, arg SetValueIfVersionParams) (SetValueIfVersionRow, error) {
+	row := q.db.QueryRow(ctx, setValueIfVersion,
+		arg.Encrypt,
+		arg.Value,
+		arg.EncKey,
+		arg.KeyVersion,
+		arg.ExpiresAt,
+		arg.Namespace,
+		arg.Key,
+		arg.ExpectedVersion,
+	)
+	var i SetValueIfVersionRow
+	err := row.Scan(
+		&i.Key,
+		&i.Value,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.Version,
+	)
+	return i, err
 }
 
 const softDeletePerson = `-- name: SoftDeletePerson :exec
 UPDATE person
 SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
-WHERE id = $1 AND deleted_at IS NULL
+WHERE tenant_id = $1 AND id = $2 AND deleted_at IS NULL
 `
 
+type SoftDeletePersonParams struct {
+	TenantID string
+	ID       pgtype.UUID
+}
+
 // Soft delete a person
-func (q *Queries) SoftDeletePerson(ctx context.Context, id pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, softDeletePerson, id)
+func (q *Queries) SoftDeletePerson(ctx context.Context, arg SoftDeletePersonParams) error {
+	_, err := q.db.Exec(ctx, softDeletePerson, arg.TenantID, arg.ID)
+	return err
+}
+
+const takeRateLimitToken = `-- name: TakeRateLimitToken :one
+
+INSERT INTO rate_limit_bucket (bucket_key, tokens, updated_at)
+VALUES ($1, $2::double precision - 1, CURRENT_TIMESTAMP)
+ON CONFLICT (bucket_key) DO UPDATE
+SET tokens = LEAST($2::double precision,
+        rate_limit_bucket.tokens + EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - rate_limit_bucket.updated_at)::double precision * $3::double precision) - 1,
+    updated_at = CURRENT_TIMESTAMP
+WHERE LEAST($2::double precision,
+        rate_limit_bucket.tokens + EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - rate_limit_bucket.updated_at)::double precision * $3::double precision) >= 1
+RETURNING tokens
+`
+
+type TakeRateLimitTokenParams struct {
+	BucketKey       string
+	Capacity        float64
+	RefillPerSecond float64
+}
+
+// ============================================================================
+// RATE LIMIT OPERATIONS
+// ============================================================================
+// Refill a token bucket for the time since its last update and take one token.
+// Returns no row when the bucket holds less than one token.
+func (q *Queries) TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (float64, error) {
+	row := q.db.QueryRow(ctx, takeRateLimitToken, arg.BucketKey, arg.Capacity, arg.RefillPerSecond)
+	var tokens float64
+	err := row.Scan(&tokens)
+	return tokens, err
+}
+
+const touchAPICredential = `-- name: TouchAPICredential :exec
+UPDATE api_credential
+SET last_used_at = CURRENT_TIMESTAMP
+WHERE id = $1
+    AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - interval '1 minute')
+`
+
+// Record that a credential was used, at most once per minute to limit writes
+func (q *Queries) TouchAPICredential(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, touchAPICredential, id)
 	return err
 }
 
@@ -1120,9 +1997,10 @@ SET
     key_version = $3,
     version = version + 1,
     updated_at = CURRENT_TIMESTAMP
-WHERE person_id = $4
-    AND attribute_key = $5
-    AND version = $6
+WHERE tenant_id = $4
+    AND person_id = $5
+    AND attribute_key = $6
+    AND version = $7
 RETURNING id, person_id, attribute_key, key_version, version, created_at, updated_at
 `
 
@@ -1130,6 +2008,7 @@ type UpdatePersonAttributeWithVersionParams struct {
 	AttributeValue  string
 	EncKey          string
 	KeyVersion      int64
+	TenantID        string
 	PersonID        pgtype.UUID
 	AttributeKey    string
 	ExpectedVersion int64
@@ -1151,6 +2030,7 @@ func (q *Queries) UpdatePersonAttributeWithVersion(ctx context.Context, arg Upda
 		arg.AttributeValue,
 		arg.EncKey,
 		arg.KeyVersion,
+		arg.TenantID,
 		arg.PersonID,
 		arg.AttributeKey,
 		arg.ExpectedVersion,
@@ -1171,16 +2051,44 @@ func (q *Queries) UpdatePersonAttributeWithVersion(ctx context.Context, arg Upda
 const updatePersonClientId = `-- name: UpdatePersonClientId :exec
 UPDATE person
 SET client_id = $1, updated_at = CURRENT_TIMESTAMP
-WHERE id = $2 AND deleted_at IS NULL
+WHERE tenant_id = $2 AND id = $3 AND deleted_at IS NULL
 `
 
 type UpdatePersonClientIdParams struct {
 	NewClientID string
+	TenantID    string
 	ID          pgtype.UUID
 }
 
 // Update person's client_id
 func (q *Queries) UpdatePersonClientId(ctx context.Context, arg UpdatePersonClientIdParams) error {
-	_, err := q.db.Exec(ctx, updatePersonClientId, arg.NewClientID, arg.ID)
+	_, err := q.db.Exec(ctx, updatePersonClientId, arg.NewClientID, arg.TenantID, arg.ID)
 	return err
 }
+
+const useRequestNonce = `-- name: UseRequestNonce :execrows
+
+INSERT INTO request_nonce (key_id, nonce, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (key_id, nonce) DO UPDATE
+SET expires_at = EXCLUDED.expires_at
+WHERE request_nonce.expires_at <= CURRENT_TIMESTAMP
+`
+
+type UseRequestNonceParams struct {
+	KeyID     string
+	Nonce     string
+	ExpiresAt pgtype.Timestamptz
+}
+
+// ============================================================================
+// REQUEST NONCE OPERATIONS
+// ============================================================================
+// Record a signed request nonce; returns 0 when it was already used and has not expired
+func (q *Queries) UseRequestNonce(ctx context.Context, arg UseRequestNonceParams) (int64, error) {
+	result, err := q.db.Exec(ctx, useRequestNonce, arg.KeyID, arg.Nonce, arg.ExpiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
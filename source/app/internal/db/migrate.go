@@ -4,60 +4,349 @@ import (
 	"context"
 	"embed"
 	"errors"
+	"fmt"
+	"io/fs"
 	"person-service/logging"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 //go:embed migrations/*.sql
 var migrationsFS embed.FS
 
-// RunMigrations applies all pending database migrations.
-// It uses the embedded migration files and the provided connection pool.
-func RunMigrations(ctx context.Context, pool *pgxpool.Pool, databaseURL string) error {
-	logger := logging.LoggerFromContext(ctx)
-	logger.Info("Starting database migrations")
+// migrationLockID is the key of the session advisory lock held while
+// migrating, so only one instance or migration job changes the schema at a
+// time. golang-migrate takes its own lock per run; this one also covers
+// reading the version before it.
+const migrationLockID int64 = 7_301_944_160_521
+
+// DirtyError reports that a migration failed part way. golang-migrate refuses
+// to run any other migration until the schema has been repaired by hand and
+// the version recorded with "migrate force".
+type DirtyError struct {
+	Version uint
+}
+
+func (e *DirtyError) Error() string {
+	return fmt.Sprintf("migration %d failed part way and left the schema dirty; repair it by hand, then record the version with \"migrate force VERSION\"", e.Version)
+}
+
+// VersionMismatchError reports that the schema is not at the version the
+// service expects
+type VersionMismatchError struct {
+	Version  uint
+	Expected uint
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("schema version is %d, expected %d", e.Version, e.Expected)
+}
+
+// MigrationStatus describes the schema version of a database
+type MigrationStatus struct {
+	// Version is the last applied migration, 0 when none has been applied
+	Version uint `json:"version"`
+	// Dirty reports that the last migration failed part way and must be
+	// fixed by hand and then forced
+	Dirty bool `json:"dirty"`
+	// Latest is the newest migration embedded in the binary
+	Latest uint `json:"latest"`
+	// Pending lists the embedded migrations newer than Version
+	Pending []uint `json:"pending"`
+}
+
+// Migrator applies and reverts the embedded migrations
+type Migrator struct {
+	m *migrate.Migrate
+}
 
+// NewMigrator creates a Migrator for the database at databaseURL. It opens its
+// own connection, so the URL may use a different role than the service.
+func NewMigrator(databaseURL string) (*Migrator, error) {
 	// Create source driver from embedded filesystem
 	source, err := iofs.New(migrationsFS, "migrations")
 	if err != nil {
-		logger.Error("Failed to create migration source", "error", err)
+		return nil, fmt.Errorf("failed to create migration source: %w", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", source, MigrationURL(databaseURL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migrate instance: %w", err)
+	}
+	return &Migrator{m: m}, nil
+}
+
+// MigrationURL converts a postgres:// or postgresql:// URL to the pgx5://
+// scheme golang-migrate expects
+func MigrationURL(databaseURL string) string {
+	for _, scheme := range []string{"postgres://", "postgresql://"} {
+		if strings.HasPrefix(databaseURL, scheme) {
+			return "pgx5://" + strings.TrimPrefix(databaseURL, scheme)
+		}
+	}
+	return databaseURL
+}
+
+// Close releases the database connection
+func (m *Migrator) Close() error {
+	sourceErr, databaseErr := m.m.Close()
+	return errors.Join(sourceErr, databaseErr)
+}
+
+// Up applies every pending migration. It succeeds when there is none.
+func (m *Migrator) Up() error {
+	return migrationError(m.m.Up())
+}
+
+// Down reverts the last steps migrations, or all of them when steps is 0
+func (m *Migrator) Down(steps int) error {
+	var err error
+	if steps == 0 {
+		err = m.m.Down()
+	} else {
+		err = m.m.Steps(-steps)
+	}
+	return migrationError(err)
+}
+
+// migrationError drops migrate.ErrNoChange and converts migrate.ErrDirty to a
+// DirtyError
+func migrationError(err error) error {
+	var dirty migrate.ErrDirty
+	switch {
+	case err == nil, errors.Is(err, migrate.ErrNoChange):
+		return nil
+	case errors.As(err, &dirty):
+		return &DirtyError{Version: uint(dirty.Version)}
+	default:
 		return err
 	}
+}
+
+// Force records version as applied and clears the dirty flag without running
+// any migration. Use it after fixing a failed migration by hand; -1 records
+// that no migration is applied.
+func (m *Migrator) Force(version int) error {
+	return m.m.Force(version)
+}
+
+// Status returns the applied version and the pending migrations
+func (m *Migrator) Status() (MigrationStatus, error) {
+	var status MigrationStatus
+	version, dirty, err := m.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return status, err
+	}
+	status.Version = version
+	status.Dirty = dirty
+
+	versions, err := embeddedVersions()
+	if err != nil {
+		return status, err
+	}
+	status.Latest = versions[len(versions)-1]
+	for _, embedded := range versions {
+		if embedded > status.Version {
+			status.Pending = append(status.Pending, embedded)
+		}
+	}
+	return status, nil
+}
+
+// WithMigrationLock runs fn while holding the migration advisory lock on a
+// connection of its own to databaseURL. When another instance holds the lock
+// it waits until that one is done or ctx ends.
+func WithMigrationLock(ctx context.Context, databaseURL string, fn func() error) error {
+	logger := logging.LoggerFromContext(ctx)
 
-	// Create migrate instance
-	// Note: We use the database URL directly as golang-migrate manages its own connection
-	m, err := migrate.NewWithSourceInstance("iofs", source, databaseURL)
+	conn, err := pgx.Connect(ctx, databaseURL)
 	if err != nil {
-		logger.Error("Failed to create migrate instance", "error", err)
+		return fmt.Errorf("failed to connect for the migration lock: %w", err)
+	}
+	// Closing the connection also releases the lock if the unlock fails
+	defer conn.Close(context.Background())
+
+	var acquired bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", migrationLockID).Scan(&acquired); err != nil {
+		return fmt.Errorf("failed to acquire the migration lock: %w", err)
+	}
+	if !acquired {
+		logger.Info("Waiting for another instance to finish migrating")
+		if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+			return fmt.Errorf("failed to acquire the migration lock: %w", err)
+		}
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			logger.Warn("Failed to release the migration lock", "error", err)
+		}
+	}()
+
+	return fn()
+}
+
+// RunMigrations applies all pending database migrations.
+// It uses the embedded migration files and opens its own connection to databaseURL.
+// Concurrent callers are serialized with the migration lock, so the ones that
+// wait find the schema up to date. A dirty schema returns a DirtyError
+// without running anything.
+func RunMigrations(ctx context.Context, databaseURL string) error {
+	return WithMigrationLock(ctx, databaseURL, func() error {
+		return runMigrations(ctx, databaseURL)
+	})
+}
+
+// runMigrations applies the pending migrations while the migration lock is held
+func runMigrations(ctx context.Context, databaseURL string) error {
+	logger := logging.LoggerFromContext(ctx)
+	logger.Info("Starting database migrations")
+
+	m, err := NewMigrator(databaseURL)
+	if err != nil {
+		logger.Error("Failed to create migrator", "error", err)
 		return err
 	}
 	defer m.Close()
 
-	// Run migrations
-	err = m.Up()
+	before, err := m.Status()
 	if err != nil {
-		if errors.Is(err, migrate.ErrNoChange) {
-			logger.Info("Database schema is up to date, no migrations needed")
-			return nil
-		}
+		logger.Error("Failed to read migration version", "error", err)
+		return err
+	}
+	if before.Dirty {
+		return &DirtyError{Version: before.Version}
+	}
+	if before.Version > before.Latest {
+		logger.Warn("Database schema is newer than the embedded migrations",
+			"version", before.Version,
+			"latest", before.Latest)
+		return nil
+	}
+	if len(before.Pending) == 0 {
+		logger.Info("Database schema is up to date, no migrations needed", "version", before.Version)
+		return nil
+	}
+
+	// Run migrations
+	logger.Info("Applying migrations", "from", before.Version, "pending", before.Pending)
+	if err := m.Up(); err != nil {
 		logger.Error("Migration failed", "error", err)
 		return err
 	}
 
 	// Get current version after migration
-	version, dirty, err := m.Version()
-	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+	after, err := m.Status()
+	if err != nil {
 		logger.Warn("Could not get migration version", "error", err)
 	} else {
 		logger.Info("Database migrations completed successfully",
-			"version", version,
-			"dirty", dirty,
+			"version", after.Version,
+			"dirty", after.Dirty,
 		)
 	}
 
 	return nil
 }
+
+// LatestVersion returns the version of the newest embedded migration, which
+// is the version the schema has once RunMigrations succeeded.
+func LatestVersion() (uint, error) {
+	versions, err := embeddedVersions()
+	if err != nil {
+		return 0, err
+	}
+	return versions[len(versions)-1], nil
+}
+
+// embeddedVersions returns the versions of the embedded migrations in order
+func embeddedVersions() ([]uint, error) {
+	source, err := iofs.New(migrationsFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	version, err := source.First()
+	if err != nil {
+		return nil, err
+	}
+	versions := []uint{version}
+	for {
+		next, err := source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return versions, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, next)
+		version = next
+	}
+}
+
+// CurrentVersion returns the version of the last applied migration and
+// whether it failed part way, as recorded by golang-migrate.
+func CurrentVersion(ctx context.Context, pool *pgxpool.Pool) (uint, bool, error) {
+	var version int64
+	var dirty bool
+	err := pool.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err != nil {
+		return 0, false, err
+	}
+	return uint(version), dirty, nil
+}
+
+// CheckSchemaVersion returns a DirtyError when the last migration failed part
+// way and a VersionMismatchError unless the schema is at version expected
+func CheckSchemaVersion(ctx context.Context, pool *pgxpool.Pool, expected uint) error {
+	version, dirty, err := SchemaVersion(ctx, pool)
+	if err != nil {
+		return err
+	}
+	return VerifySchemaVersion(version, dirty, expected)
+}
+
+// SchemaVersion returns the version of the last applied migration and whether
+// it failed part way, or 0 when no migration is applied
+func SchemaVersion(ctx context.Context, pool *pgxpool.Pool) (uint, bool, error) {
+	version, dirty, err := CurrentVersion(ctx, pool)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// golang-migrate empties the table when every migration is reverted
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read migration version: %w", err)
+	}
+	return version, dirty, nil
+}
+
+// VerifySchemaVersion returns a DirtyError when dirty and a
+// VersionMismatchError unless version is expected
+func VerifySchemaVersion(version uint, dirty bool, expected uint) error {
+	if dirty {
+		return &DirtyError{Version: version}
+	}
+	if version != expected {
+		return &VersionMismatchError{Version: version, Expected: expected}
+	}
+	return nil
+}
+
+// VerifySchemaVersionAtLeast returns a DirtyError when dirty and a
+// VersionMismatchError when version is older than minimum. A newer schema
+// passes, so instances of the previous release keep serving while a new
+// release migrates during a rolling deploy.
+func VerifySchemaVersionAtLeast(version uint, dirty bool, minimum uint) error {
+	if dirty {
+		return &DirtyError{Version: version}
+	}
+	if version < minimum {
+		return &VersionMismatchError{Version: version, Expected: minimum}
+	}
+	return nil
+}
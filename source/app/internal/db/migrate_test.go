@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"person-service/internal/testdb"
+)
+
+// migrateDatabase is created empty so the embedded migrations run from scratch
+const migrateDatabase = "migrate_test"
+
+var (
+	databaseURL string
+	pool        *pgxpool.Pool
+)
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+	adminPool, err := testdb.GetPool(ctx)
+	if err != nil {
+		log.Fatalf("Failed to get pool: %v", err)
+	}
+	if _, err := adminPool.Exec(ctx, "DROP DATABASE IF EXISTS "+migrateDatabase); err != nil {
+		log.Fatalf("Failed to drop database: %v", err)
+	}
+	if _, err := adminPool.Exec(ctx, "CREATE DATABASE "+migrateDatabase); err != nil {
+		log.Fatalf("Failed to create database: %v", err)
+	}
+
+	connStr, err := testdb.GetConnectionString(ctx)
+	if err != nil {
+		log.Fatalf("Failed to get connection string: %v", err)
+	}
+	databaseURL = strings.Replace(connStr, "/testdb?", "/"+migrateDatabase+"?", 1)
+	pool, err = pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		log.Fatalf("Failed to create pool: %v", err)
+	}
+
+	code := m.Run()
+	pool.Close()
+	os.Exit(code)
+}
+
+// status returns the migration status of the test database
+func status(t *testing.T) MigrationStatus {
+	t.Helper()
+	migrator, err := NewMigrator(databaseURL)
+	require.NoError(t, err)
+	defer migrator.Close()
+
+	status, err := migrator.Status()
+	require.NoError(t, err)
+	return status
+}
+
+func TestMigrationURL(t *testing.T) {
+	assert.Equal(t, "pgx5://user@host/db", MigrationURL("postgres://user@host/db"))
+	assert.Equal(t, "pgx5://user@host/db", MigrationURL("postgresql://user@host/db"))
+	assert.Equal(t, "pgx5://user@host/db", MigrationURL("pgx5://user@host/db"))
+}
+
+func TestRunMigrations_Concurrent(t *testing.T) {
+	ctx := context.Background()
+
+	// Every replica starting at once must succeed; only one applies them
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = RunMigrations(ctx, databaseURL)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+
+	latest, err := LatestVersion()
+	require.NoError(t, err)
+	current := status(t)
+	assert.Equal(t, latest, current.Version)
+	assert.False(t, current.Dirty)
+	assert.Empty(t, current.Pending)
+	assert.NoError(t, CheckSchemaVersion(ctx, pool, latest))
+}
+
+func TestMigrator_DownAndUp(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, RunMigrations(ctx, databaseURL))
+	latest, err := LatestVersion()
+	require.NoError(t, err)
+
+	migrator, err := NewMigrator(databaseURL)
+	require.NoError(t, err)
+	defer migrator.Close()
+
+	require.NoError(t, migrator.Down(1))
+	assert.Equal(t, latest-1, status(t).Version)
+
+	// Reverting every migration runs 000001_initial_schema.down.sql too
+	require.NoError(t, migrator.Down(0))
+	current := status(t)
+	assert.Equal(t, uint(0), current.Version)
+	assert.Len(t, current.Pending, int(latest))
+	assert.NoError(t, CheckSchemaVersion(ctx, pool, 0))
+
+	require.NoError(t, migrator.Up())
+	assert.Equal(t, latest, status(t).Version)
+}
+
+func TestRunMigrations_RefusesDirtySchema(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, RunMigrations(ctx, databaseURL))
+	latest, err := LatestVersion()
+	require.NoError(t, err)
+
+	_, err = pool.Exec(ctx, "UPDATE schema_migrations SET dirty = true")
+	require.NoError(t, err)
+
+	var dirtyErr *DirtyError
+	err = RunMigrations(ctx, databaseURL)
+	if assert.True(t, errors.As(err, &dirtyErr)) {
+		assert.Equal(t, latest, dirtyErr.Version)
+	}
+	assert.True(t, errors.As(CheckSchemaVersion(ctx, pool, latest), &dirtyErr))
+
+	// Forcing the version clears the dirty flag
+	migrator, err := NewMigrator(databaseURL)
+	require.NoError(t, err)
+	defer migrator.Close()
+	require.NoError(t, migrator.Force(int(latest)))
+	assert.NoError(t, RunMigrations(ctx, databaseURL))
+}
+
+func TestVerifySchemaVersionAtLeast(t *testing.T) {
+	assert.NoError(t, VerifySchemaVersionAtLeast(14, false, 14))
+	assert.NoError(t, VerifySchemaVersionAtLeast(15, false, 14))
+
+	var mismatch *VersionMismatchError
+	err := VerifySchemaVersionAtLeast(13, false, 14)
+	if assert.True(t, errors.As(err, &mismatch)) {
+		assert.Equal(t, uint(13), mismatch.Version)
+		assert.Equal(t, uint(14), mismatch.Expected)
+	}
+
+	var dirtyErr *DirtyError
+	assert.True(t, errors.As(VerifySchemaVersionAtLeast(15, true, 14), &dirtyErr))
+}
+
+func TestCheckSchemaVersion_Mismatch(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, RunMigrations(ctx, databaseURL))
+	latest, err := LatestVersion()
+	require.NoError(t, err)
+
+	var mismatchErr *VersionMismatchError
+	err = CheckSchemaVersion(ctx, pool, latest+1)
+	if assert.True(t, errors.As(err, &mismatchErr)) {
+		assert.Equal(t, latest, mismatchErr.Version)
+		assert.Equal(t, latest+1, mismatchErr.Expected)
+	}
+}
+
+func TestWithMigrationLock_Exclusive(t *testing.T) {
+	ctx := context.Background()
+
+	held := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- WithMigrationLock(ctx, databaseURL, func() error {
+			close(held)
+			<-release
+			return nil
+		})
+	}()
+	<-held
+
+	// A second holder waits until ctx ends
+	waitCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	err := WithMigrationLock(waitCtx, databaseURL, func() error {
+		t.Error("lock acquired while held")
+		return nil
+	})
+	assert.Error(t, err)
+
+	close(release)
+	assert.NoError(t, <-done)
+	assert.NoError(t, WithMigrationLock(ctx, databaseURL, func() error { return nil }))
+}
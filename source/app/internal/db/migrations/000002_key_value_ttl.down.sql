@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_key_value_expires_at;
+
+ALTER TABLE key_value DROP COLUMN IF EXISTS expires_at;
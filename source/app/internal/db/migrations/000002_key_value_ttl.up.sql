@@ -0,0 +1,8 @@
+set lock_timeout = '1s';
+set statement_timeout = '5s';
+
+-- Optional expiry for key-value entries; NULL means the entry never expires
+ALTER TABLE key_value ADD COLUMN IF NOT EXISTS expires_at timestamptz;
+
+-- Partial index used by the expiry sweeper
+CREATE INDEX IF NOT EXISTS idx_key_value_expires_at ON key_value(expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,5 @@
+set lock_timeout = '1s';
+set statement_timeout = '5s';
+
+-- Monotonic per-key version for compare-and-swap; restarts at 1 when a key is (re)created
+ALTER TABLE key_value ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
@@ -0,0 +1,9 @@
+-- Encrypted values cannot be restored without the key; drop them rather than keep empty values
+DELETE FROM key_value WHERE encrypted_value IS NOT NULL;
+ALTER TABLE key_value DROP COLUMN IF EXISTS encrypted_value;
+
+-- Only the default namespace survives the downgrade, since keys are unique again
+DELETE FROM key_value WHERE namespace <> 'default';
+ALTER TABLE key_value DROP CONSTRAINT IF EXISTS key_value_pkey;
+ALTER TABLE key_value ADD CONSTRAINT key_value_pkey PRIMARY KEY (key);
+ALTER TABLE key_value DROP COLUMN IF EXISTS namespace;
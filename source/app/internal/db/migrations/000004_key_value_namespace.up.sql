@@ -0,0 +1,10 @@
+set lock_timeout = '1s';
+set statement_timeout = '5s';
+
+-- Keys are scoped per credential namespace; existing keys move to the default namespace
+ALTER TABLE key_value ADD COLUMN IF NOT EXISTS namespace text NOT NULL DEFAULT 'default';
+ALTER TABLE key_value DROP CONSTRAINT IF EXISTS key_value_pkey;
+ALTER TABLE key_value ADD CONSTRAINT key_value_pkey PRIMARY KEY (namespace, key);
+
+-- Optional encryption at rest: when set, value is empty and the plaintext lives here
+ALTER TABLE key_value ADD COLUMN IF NOT EXISTS encrypted_value bytea;
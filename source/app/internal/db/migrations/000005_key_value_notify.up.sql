@@ -0,0 +1,30 @@
+set lock_timeout = '1s';
+set statement_timeout = '5s';
+
+-- Publish every key-value change on the key_value_changes channel for watchers.
+-- Keys too long for a NOTIFY payload are sent without the key, which wakes every watcher in the namespace.
+CREATE OR REPLACE FUNCTION notify_key_value_change() RETURNS trigger AS $$
+DECLARE
+    changed key_value;
+    payload text;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+    ELSE
+        changed := NEW;
+    END IF;
+
+    payload := json_build_object('namespace', changed.namespace, 'key', changed.key)::text;
+    IF octet_length(payload) >= 8000 THEN
+        payload := json_build_object('namespace', changed.namespace)::text;
+    END IF;
+
+    PERFORM pg_notify('key_value_changes', payload);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS key_value_notify ON key_value;
+CREATE TRIGGER key_value_notify
+    AFTER INSERT OR UPDATE OR DELETE ON key_value
+    FOR EACH ROW EXECUTE FUNCTION notify_key_value_change();
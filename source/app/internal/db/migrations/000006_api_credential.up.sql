@@ -0,0 +1,17 @@
+set lock_timeout = '1s';
+set statement_timeout = '5s';
+
+-- API credentials issued through the admin API; only a hash of each key is stored
+CREATE TABLE IF NOT EXISTS api_credential (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name text NOT NULL,
+    owner text NOT NULL,
+    key_hash BYTEA UNIQUE NOT NULL, -- sha256 of the API key
+    key_prefix text NOT NULL, -- leading characters of the key, for identification only
+    scopes text[] NOT NULL DEFAULT '{}',
+    namespace text NOT NULL DEFAULT 'default', -- key-value namespace of the credential
+    expires_at timestamptz, -- NULL means the key never expires
+    last_used_at timestamptz,
+    revoked_at timestamptz,
+    created_at timestamptz DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1 @@
+ALTER TABLE request_log DROP COLUMN IF EXISTS principal_id;
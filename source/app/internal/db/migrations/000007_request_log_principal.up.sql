@@ -0,0 +1,5 @@
+set lock_timeout = '1s';
+set statement_timeout = '5s';
+
+-- Authenticated principal that made the audited request; NULL for older entries
+ALTER TABLE request_log ADD COLUMN IF NOT EXISTS principal_id text;
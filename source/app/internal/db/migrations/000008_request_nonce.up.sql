@@ -0,0 +1,12 @@
+set lock_timeout = '1s';
+set statement_timeout = '5s';
+
+-- Nonces of signed requests, kept until their signature timestamp leaves the allowed window
+CREATE TABLE IF NOT EXISTS request_nonce (
+    key_id text NOT NULL,
+    nonce text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    PRIMARY KEY (key_id, nonce)
+);
+
+CREATE INDEX IF NOT EXISTS idx_request_nonce_expires_at ON request_nonce(expires_at);
@@ -0,0 +1,11 @@
+set lock_timeout = '1s';
+set statement_timeout = '5s';
+
+-- Token buckets of the Postgres rate limit backend, shared by all instances
+CREATE TABLE IF NOT EXISTS rate_limit_bucket (
+    bucket_key text PRIMARY KEY,
+    tokens double precision NOT NULL,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_bucket_updated_at ON rate_limit_bucket(updated_at);
@@ -0,0 +1,18 @@
+DROP POLICY IF EXISTS person_images_tenant_isolation ON person_images;
+DROP POLICY IF EXISTS person_attributes_tenant_isolation ON person_attributes;
+DROP POLICY IF EXISTS person_tenant_isolation ON person;
+ALTER TABLE person_images NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE person_images DISABLE ROW LEVEL SECURITY;
+ALTER TABLE person_attributes NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE person_attributes DISABLE ROW LEVEL SECURITY;
+ALTER TABLE person NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE person DISABLE ROW LEVEL SECURITY;
+DROP FUNCTION IF EXISTS tenant_visible(text);
+
+ALTER TABLE api_credential DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE person_images DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE person_attributes DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE person DROP CONSTRAINT IF EXISTS person_tenant_id_id_key;
+ALTER TABLE person DROP CONSTRAINT IF EXISTS person_tenant_id_client_id_key;
+ALTER TABLE person DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE person ADD CONSTRAINT person_client_id_key UNIQUE (client_id);
@@ -0,0 +1,38 @@
+set lock_timeout = '1s';
+set statement_timeout = '5s';
+
+-- Persons belong to a tenant and client ids are unique per tenant
+ALTER TABLE person ADD COLUMN IF NOT EXISTS tenant_id text NOT NULL DEFAULT 'default';
+ALTER TABLE person DROP CONSTRAINT IF EXISTS person_client_id_key;
+ALTER TABLE person ADD CONSTRAINT person_tenant_id_client_id_key UNIQUE (tenant_id, client_id);
+ALTER TABLE person ADD CONSTRAINT person_tenant_id_id_key UNIQUE (tenant_id, id);
+
+-- Derived rows carry the tenant of their person, enforced by a tenant-scoped foreign key
+ALTER TABLE person_attributes ADD COLUMN IF NOT EXISTS tenant_id text NOT NULL DEFAULT 'default';
+ALTER TABLE person_attributes ADD CONSTRAINT person_attributes_tenant_id_person_id_fkey
+    FOREIGN KEY (tenant_id, person_id) REFERENCES person(tenant_id, id) ON DELETE CASCADE;
+
+ALTER TABLE person_images ADD COLUMN IF NOT EXISTS tenant_id text NOT NULL DEFAULT 'default';
+ALTER TABLE person_images ADD CONSTRAINT person_images_tenant_id_person_id_fkey
+    FOREIGN KEY (tenant_id, person_id) REFERENCES person(tenant_id, id) ON DELETE CASCADE;
+
+ALTER TABLE api_credential ADD COLUMN IF NOT EXISTS tenant_id text NOT NULL DEFAULT 'default';
+
+-- Row-level security on tenant data. Queries always filter by tenant; when the
+-- service sets app.tenant_id for a statement, Postgres also hides and rejects
+-- rows of other tenants. Without the setting every row stays visible.
+CREATE OR REPLACE FUNCTION tenant_visible(row_tenant_id text) RETURNS boolean AS $$
+    SELECT COALESCE(NULLIF(current_setting('app.tenant_id', true), ''), row_tenant_id) = row_tenant_id;
+$$ LANGUAGE sql STABLE;
+
+ALTER TABLE person ENABLE ROW LEVEL SECURITY;
+ALTER TABLE person FORCE ROW LEVEL SECURITY;
+CREATE POLICY person_tenant_isolation ON person USING (tenant_visible(tenant_id));
+
+ALTER TABLE person_attributes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE person_attributes FORCE ROW LEVEL SECURITY;
+CREATE POLICY person_attributes_tenant_isolation ON person_attributes USING (tenant_visible(tenant_id));
+
+ALTER TABLE person_images ENABLE ROW LEVEL SECURITY;
+ALTER TABLE person_images FORCE ROW LEVEL SECURITY;
+CREATE POLICY person_images_tenant_isolation ON person_images USING (tenant_visible(tenant_id));
@@ -0,0 +1 @@
+ALTER TABLE api_credential DROP COLUMN IF EXISTS redaction_profile;
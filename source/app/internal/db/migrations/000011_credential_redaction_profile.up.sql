@@ -0,0 +1,5 @@
+set lock_timeout = '1s';
+set statement_timeout = '5s';
+
+-- Redaction profile always applied to the credential's reads; NULL lets requests choose
+ALTER TABLE api_credential ADD COLUMN IF NOT EXISTS redaction_profile text;
@@ -0,0 +1,2 @@
+ALTER TABLE api_credential DROP COLUMN IF EXISTS purposes;
+ALTER TABLE request_log DROP COLUMN IF EXISTS purpose;
@@ -0,0 +1,8 @@
+set lock_timeout = '1s';
+set statement_timeout = '5s';
+
+-- Declared purpose of use of audited requests; NULL for older entries
+ALTER TABLE request_log ADD COLUMN IF NOT EXISTS purpose text;
+
+-- Purposes of use each credential may declare; empty allows every registered purpose
+ALTER TABLE api_credential ADD COLUMN IF NOT EXISTS purposes text[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,11 @@
+set lock_timeout = '1s';
+set statement_timeout = '5s';
+
+-- Known value encrypted with the encryption key; readiness fails when the
+-- configured key can no longer decrypt it
+CREATE TABLE IF NOT EXISTS encryption_canary (
+    id integer PRIMARY KEY,
+    encrypted_value bytea NOT NULL,
+    key_version bigint NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1 @@
+ALTER TABLE key_value DROP COLUMN IF EXISTS key_version;
@@ -0,0 +1,7 @@
+set lock_timeout = '1s';
+set statement_timeout = '5s';
+
+-- Version of the encryption key of encrypted_value; NULL when the value is stored in plain text.
+-- Values encrypted before key versions were recorded keep NULL; maintenance commands treat
+-- them as encryption.unversioned_key_version, since the key they used is not known here.
+ALTER TABLE key_value ADD COLUMN IF NOT EXISTS key_version bigint;
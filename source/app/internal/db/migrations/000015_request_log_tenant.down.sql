@@ -0,0 +1,7 @@
+DROP POLICY IF EXISTS request_log_tenant_isolation ON request_log;
+ALTER TABLE request_log NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE request_log DISABLE ROW LEVEL SECURITY;
+
+ALTER TABLE request_log DROP CONSTRAINT IF EXISTS request_log_tenant_id_trace_id_key;
+ALTER TABLE request_log DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE request_log ADD CONSTRAINT request_log_trace_id_key UNIQUE (trace_id);
@@ -0,0 +1,13 @@
+set lock_timeout = '1s';
+set statement_timeout = '5s';
+
+-- Audit entries belong to the tenant of the request that wrote them; entries
+-- written before tenancy of the request log belong to the default tenant.
+-- Trace ids are only unique per tenant.
+ALTER TABLE request_log ADD COLUMN IF NOT EXISTS tenant_id text NOT NULL DEFAULT 'default';
+ALTER TABLE request_log DROP CONSTRAINT IF EXISTS request_log_trace_id_key;
+ALTER TABLE request_log ADD CONSTRAINT request_log_tenant_id_trace_id_key UNIQUE (tenant_id, trace_id);
+
+ALTER TABLE request_log ENABLE ROW LEVEL SECURITY;
+ALTER TABLE request_log FORCE ROW LEVEL SECURITY;
+CREATE POLICY request_log_tenant_isolation ON request_log USING (tenant_visible(tenant_id));
@@ -3,21 +3,171 @@
 SELECT 1;
 
 -- name: GetValue :one
--- Retrieve a value by key
-SELECT value FROM key_value WHERE key = sqlc.arg(key) LIMIT 1;
+-- Retrieve a decrypted value by namespace and key, ignoring expired entries
+SELECT
+    CASE WHEN encrypted_value IS NULL THEN value
+        ELSE pgp_sym_decrypt(encrypted_value, sqlc.arg(enc_key)::text) END::text AS value
+FROM key_value
+WHERE namespace = sqlc.arg(namespace) AND key = sqlc.arg(key)
+    AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+LIMIT 1;
 
 -- name: GetKeyValue :one
--- Retrieve the full key-value record by key
-SELECT key, value, created_at, updated_at FROM key_value WHERE key = sqlc.arg(key) LIMIT 1;
+-- Retrieve the full decrypted key-value record by namespace and key, ignoring expired entries
+SELECT
+    key,
+    CASE WHEN encrypted_value IS NULL THEN value
+        ELSE pgp_sym_decrypt(encrypted_value, sqlc.arg(enc_key)::text) END::text AS value,
+    created_at,
+    updated_at,
+    expires_at,
+    version
+FROM key_value
+WHERE namespace = sqlc.arg(namespace) AND key = sqlc.arg(key)
+    AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+LIMIT 1;
 
--- name: SetValue :exec
--- Set a value by key
-INSERT INTO key_value (key, value) VALUES (sqlc.arg(key), sqlc.arg(value))
-ON CONFLICT (key) DO UPDATE SET value = sqlc.arg(value);
+-- name: SetValue :one
+-- Set a value by namespace and key with an optional expiry (NULL means never expires).
+-- When encrypt is true the value is stored with pgp_sym_encrypt and the plaintext column is left empty.
+-- An expired entry is replaced as if absent, so a returned version of 1 means the key was created.
+INSERT INTO key_value (namespace, key, value, encrypted_value, key_version, expires_at) VALUES (
+    sqlc.arg(namespace),
+    sqlc.arg(key),
+    CASE WHEN sqlc.arg(encrypt)::boolean THEN '' ELSE sqlc.arg(value)::text END,
+    CASE WHEN sqlc.arg(encrypt)::boolean THEN pgp_sym_encrypt(sqlc.arg(value)::text, sqlc.arg(enc_key)::text) END,
+    CASE WHEN sqlc.arg(encrypt)::boolean THEN sqlc.arg(key_version)::bigint END,
+    sqlc.arg(expires_at)
+)
+ON CONFLICT (namespace, key) DO UPDATE SET
+    value = EXCLUDED.value,
+    encrypted_value = EXCLUDED.encrypted_value,
+    key_version = EXCLUDED.key_version,
+    expires_at = EXCLUDED.expires_at,
+    version = CASE WHEN key_value.expires_at <= CURRENT_TIMESTAMP THEN 1 ELSE key_value.version + 1 END,
+    created_at = CASE WHEN key_value.expires_at <= CURRENT_TIMESTAMP THEN CURRENT_TIMESTAMP ELSE key_value.created_at END,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING key, sqlc.arg(value)::text AS value, created_at, updated_at, expires_at, version;
+
+-- name: SetValueIfAbsent :one
+-- Create a key only if it does not exist (or has expired) in the namespace; returns no rows otherwise
+INSERT INTO key_value (namespace, key, value, encrypted_value, key_version, expires_at) VALUES (
+    sqlc.arg(namespace),
+    sqlc.arg(key),
+    CASE WHEN sqlc.arg(encrypt)::boolean THEN '' ELSE sqlc.arg(value)::text END,
+    CASE WHEN sqlc.arg(encrypt)::boolean THEN pgp_sym_encrypt(sqlc.arg(value)::text, sqlc.arg(enc_key)::text) END,
+    CASE WHEN sqlc.arg(encrypt)::boolean THEN sqlc.arg(key_version)::bigint END,
+    sqlc.arg(expires_at)
+)
+ON CONFLICT (namespace, key) DO UPDATE SET
+    value = EXCLUDED.value,
+    encrypted_value = EXCLUDED.encrypted_value,
+    key_version = EXCLUDED.key_version,
+    expires_at = EXCLUDED.expires_at,
+    version = 1,
+    created_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE key_value.expires_at <= CURRENT_TIMESTAMP
+RETURNING key, sqlc.arg(value)::text AS value, created_at, updated_at, expires_at, version;
 
--- name: DeleteValue :exec
--- Delete a value by key
-DELETE FROM key_value WHERE key = sqlc.arg(key);
+-- name: SetValueIfVersion :one
+-- Update a live key only if its version matches; returns no rows otherwise
+UPDATE key_value
+SET
+    value = CASE WHEN sqlc.arg(encrypt)::boolean THEN '' ELSE sqlc.arg(value)::text END,
+    encrypted_value = CASE WHEN sqlc.arg(encrypt)::boolean THEN pgp_sym_encrypt(sqlc.arg(value)::text, sqlc.arg(enc_key)::text) END,
+    key_version = CASE WHEN sqlc.arg(encrypt)::boolean THEN sqlc.arg(key_version)::bigint END,
+    expires_at = sqlc.arg(expires_at),
+    version = version + 1,
+    updated_at = CURRENT_TIMESTAMP
+WHERE namespace = sqlc.arg(namespace)
+    AND key = sqlc.arg(key)
+    AND version = sqlc.arg(expected_version)
+    AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+RETURNING key, sqlc.arg(value)::text AS value, created_at, updated_at, expires_at, version;
+
+-- name: IncrementValue :one
+-- Atomically add delta to an integer value, creating the key (or replacing an expired one) with delta.
+-- Encrypted values are decrypted, incremented and re-encrypted according to encrypt.
+-- Fails with invalid_text_representation when the stored value is not an integer.
+INSERT INTO key_value (namespace, key, value, encrypted_value, key_version) VALUES (
+    sqlc.arg(namespace),
+    sqlc.arg(key),
+    CASE WHEN sqlc.arg(encrypt)::boolean THEN '' ELSE sqlc.arg(delta)::bigint::text END,
+    CASE WHEN sqlc.arg(encrypt)::boolean THEN pgp_sym_encrypt(sqlc.arg(delta)::bigint::text, sqlc.arg(enc_key)::text) END,
+    CASE WHEN sqlc.arg(encrypt)::boolean THEN sqlc.arg(key_version)::bigint END
+)
+ON CONFLICT (namespace, key) DO UPDATE SET
+    value = CASE WHEN sqlc.arg(encrypt)::boolean THEN '' ELSE (
+        CASE WHEN key_value.expires_at <= CURRENT_TIMESTAMP THEN 0
+            WHEN key_value.encrypted_value IS NULL THEN key_value.value::bigint
+            ELSE pgp_sym_decrypt(key_value.encrypted_value, sqlc.arg(enc_key)::text)::bigint END
+        + sqlc.arg(delta)::bigint)::text END,
+    encrypted_value = CASE WHEN sqlc.arg(encrypt)::boolean THEN pgp_sym_encrypt((
+        CASE WHEN key_value.expires_at <= CURRENT_TIMESTAMP THEN 0
+            WHEN key_value.encrypted_value IS NULL THEN key_value.value::bigint
+            ELSE pgp_sym_decrypt(key_value.encrypted_value, sqlc.arg(enc_key)::text)::bigint END
+        + sqlc.arg(delta)::bigint)::text, sqlc.arg(enc_key)::text) END,
+    key_version = EXCLUDED.key_version,
+    expires_at = CASE WHEN key_value.expires_at <= CURRENT_TIMESTAMP THEN NULL ELSE key_value.expires_at END,
+    version = CASE WHEN key_value.expires_at <= CURRENT_TIMESTAMP THEN 1 ELSE key_value.version + 1 END,
+    created_at = CASE WHEN key_value.expires_at <= CURRENT_TIMESTAMP THEN CURRENT_TIMESTAMP ELSE key_value.created_at END,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING
+    key,
+    CASE WHEN encrypted_value IS NULL THEN value
+        ELSE pgp_sym_decrypt(encrypted_value, sqlc.arg(enc_key)::text) END::text AS value,
+    created_at,
+    updated_at,
+    expires_at,
+    version;
+
+-- name: DeleteValue :execrows
+-- Delete a live value by namespace and key; returns 0 when the key does not exist or has expired
+DELETE FROM key_value
+WHERE namespace = sqlc.arg(namespace) AND key = sqlc.arg(key)
+    AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP);
+
+-- name: DeleteValueIfVersion :execrows
+-- Delete a live value by namespace and key only if its version matches
+DELETE FROM key_value
+WHERE namespace = sqlc.arg(namespace)
+    AND key = sqlc.arg(key)
+    AND version = sqlc.arg(expected_version)
+    AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP);
+
+-- name: ListKeyValues :many
+-- List live decrypted key-value entries in a namespace whose key starts with prefix,
+-- in key order after after_key (keyset pagination)
+SELECT
+    key,
+    CASE WHEN encrypted_value IS NULL THEN value
+        ELSE pgp_sym_decrypt(encrypted_value, sqlc.arg(enc_key)::text) END::text AS value,
+    created_at,
+    updated_at,
+    expires_at,
+    version
+FROM key_value
+WHERE namespace = sqlc.arg(namespace)
+    AND starts_with(key, sqlc.arg(prefix)::text)
+    AND key > sqlc.arg(after_key)::text
+    AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+ORDER BY key
+LIMIT sqlc.arg(limit_count);
+
+-- name: DeleteKeyValuesByPrefix :execrows
+-- Delete every key-value entry in a namespace whose key starts with prefix
+DELETE FROM key_value
+WHERE namespace = sqlc.arg(namespace) AND starts_with(key, sqlc.arg(prefix)::text);
+
+-- name: DeleteExpiredKeyValues :execrows
+-- Delete up to batch_size expired key-value entries across all namespaces
+DELETE FROM key_value
+WHERE (namespace, key) IN (
+    SELECT namespace, key FROM key_value
+    WHERE expires_at IS NOT NULL AND expires_at <= CURRENT_TIMESTAMP
+    LIMIT sqlc.arg(batch_size)
+);
 
 -- ============================================================================
 -- REQUEST LOG OPERATIONS
@@ -26,19 +176,25 @@ DELETE FROM key_value WHERE key = sqlc.arg(key);
 -- name: InsertRequestLog :one
 -- Insert a new request log entry with encrypted data
 INSERT INTO request_log (
+    tenant_id,
     trace_id, 
     caller_info,
+    principal_id,
     reason, 
     encrypted_request_body, 
     encrypted_response_body, 
-    key_version
+    key_version,
+    purpose
 ) VALUES (
+    sqlc.arg(tenant_id),
     sqlc.arg(trace_id), 
     sqlc.arg(caller_info),
+    sqlc.narg(principal_id),
     sqlc.arg(reason), 
     pgp_sym_encrypt(sqlc.arg(encrypted_request_body), sqlc.arg(enc_key)), 
     pgp_sym_encrypt(sqlc.arg(encrypted_response_body), sqlc.arg(enc_key)), 
-    sqlc.arg(key_version)
+    sqlc.arg(key_version),
+    sqlc.narg(purpose)
 ) RETURNING id, trace_id, created_at;
 
 -- name: GetRequestLogByTraceId :one
@@ -51,14 +207,15 @@ SELECT
     pgp_sym_decrypt(encrypted_request_body, sqlc.arg(enc_key)) AS request_body,
     pgp_sym_decrypt(encrypted_response_body, sqlc.arg(enc_key)) AS response_body,
     key_version,
-    created_at
+    created_at,
+    purpose
 FROM request_log
-WHERE trace_id = sqlc.arg(trace_id)
+WHERE tenant_id = sqlc.arg(tenant_id) AND trace_id = sqlc.arg(trace_id)
 LIMIT 1;
 
 -- name: CheckTraceIdExists :one
 -- Check if a trace_id already exists (for idempotency)
-SELECT EXISTS(SELECT 1 FROM request_log WHERE trace_id = sqlc.arg(trace_id));
+SELECT EXISTS(SELECT 1 FROM request_log WHERE tenant_id = sqlc.arg(tenant_id) AND trace_id = sqlc.arg(trace_id));
 
 -- ============================================================================
 -- PERSON OPERATIONS
@@ -66,51 +223,51 @@ SELECT EXISTS(SELECT 1 FROM request_log WHERE trace_id = sqlc.arg(trace_id));
 
 -- name: CreatePerson :one
 -- Create a new person
-INSERT INTO person (client_id)
-VALUES (sqlc.arg(client_id))
-RETURNING id, client_id, created_at, updated_at, deleted_at;
+INSERT INTO person (tenant_id, client_id)
+VALUES (sqlc.arg(tenant_id), sqlc.arg(client_id))
+RETURNING id, client_id, created_at, updated_at, deleted_at, tenant_id;
 
 -- name: GetPersonById :one
 -- Get person by internal UUID
-SELECT id, client_id, created_at, updated_at, deleted_at
+SELECT id, client_id, created_at, updated_at, deleted_at, tenant_id
 FROM person
-WHERE id = sqlc.arg(id) AND deleted_at IS NULL
+WHERE tenant_id = sqlc.arg(tenant_id) AND id = sqlc.arg(id) AND deleted_at IS NULL
 LIMIT 1;
 
 -- name: GetPersonByClientId :one
 -- Get person by client_id
-SELECT id, client_id, created_at, updated_at, deleted_at
+SELECT id, client_id, created_at, updated_at, deleted_at, tenant_id
 FROM person
-WHERE client_id = sqlc.arg(client_id) AND deleted_at IS NULL
+WHERE tenant_id = sqlc.arg(tenant_id) AND client_id = sqlc.arg(client_id) AND deleted_at IS NULL
 LIMIT 1;
 
 -- name: UpdatePersonClientId :exec
 -- Update person's client_id
 UPDATE person
 SET client_id = sqlc.arg(new_client_id), updated_at = CURRENT_TIMESTAMP
-WHERE id = sqlc.arg(id) AND deleted_at IS NULL;
+WHERE tenant_id = sqlc.arg(tenant_id) AND id = sqlc.arg(id) AND deleted_at IS NULL;
 
 -- name: SoftDeletePerson :exec
 -- Soft delete a person
 UPDATE person
 SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
-WHERE id = sqlc.arg(id) AND deleted_at IS NULL;
+WHERE tenant_id = sqlc.arg(tenant_id) AND id = sqlc.arg(id) AND deleted_at IS NULL;
 
 -- name: HardDeletePerson :exec
 -- Hard delete a person (use with caution)
-DELETE FROM person WHERE id = sqlc.arg(id);
+DELETE FROM person WHERE tenant_id = sqlc.arg(tenant_id) AND id = sqlc.arg(id);
 
 -- name: RestorePerson :exec
 -- Restore a soft-deleted person
 UPDATE person
 SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
-WHERE id = sqlc.arg(id);
+WHERE tenant_id = sqlc.arg(tenant_id) AND id = sqlc.arg(id);
 
 -- name: ListPersons :many
--- List all active persons with pagination
-SELECT id, client_id, created_at, updated_at, deleted_at
+-- List all active persons of a tenant with pagination
+SELECT id, client_id, created_at, updated_at, deleted_at, tenant_id
 FROM person
-WHERE deleted_at IS NULL
+WHERE tenant_id = sqlc.arg(tenant_id) AND deleted_at IS NULL
 ORDER BY created_at DESC
 LIMIT sqlc.arg(limit_count) OFFSET sqlc.arg(offset_count);
 
@@ -121,12 +278,14 @@ LIMIT sqlc.arg(limit_count) OFFSET sqlc.arg(offset_count);
 -- name: CreateOrUpdatePersonAttribute :one
 -- Create or update a person attribute with encryption
 INSERT INTO person_attributes (
+    tenant_id,
     person_id,
     attribute_key,
     encrypted_value,
     key_version,
     version
 ) VALUES (
+    sqlc.arg(tenant_id),
     sqlc.arg(person_id),
     sqlc.arg(attribute_key),
     pgp_sym_encrypt(sqlc.arg(attribute_value), sqlc.arg(enc_key)),
@@ -149,7 +308,8 @@ SET
     key_version = sqlc.arg(key_version),
     version = version + 1,
     updated_at = CURRENT_TIMESTAMP
-WHERE person_id = sqlc.arg(person_id)
+WHERE tenant_id = sqlc.arg(tenant_id)
+    AND person_id = sqlc.arg(person_id)
     AND attribute_key = sqlc.arg(attribute_key)
     AND version = sqlc.arg(expected_version)
 RETURNING id, person_id, attribute_key, key_version, version, created_at, updated_at;
@@ -166,7 +326,7 @@ SELECT
     created_at,
     updated_at
 FROM person_attributes
-WHERE person_id = sqlc.arg(person_id) AND attribute_key = sqlc.arg(attribute_key)
+WHERE tenant_id = sqlc.arg(tenant_id) AND person_id = sqlc.arg(person_id) AND attribute_key = sqlc.arg(attribute_key)
 LIMIT 1;
 
 -- name: GetAllPersonAttributes :many
@@ -181,7 +341,7 @@ SELECT
     created_at,
     updated_at
 FROM person_attributes
-WHERE person_id = sqlc.arg(person_id)
+WHERE tenant_id = sqlc.arg(tenant_id) AND person_id = sqlc.arg(person_id)
 ORDER BY attribute_key;
 
 -- name: GetMultiplePersonAttributes :many
@@ -196,28 +356,29 @@ SELECT
     created_at,
     updated_at
 FROM person_attributes
-WHERE person_id = sqlc.arg(person_id) AND attribute_key = ANY(sqlc.arg(attribute_keys)::citext[])
+WHERE tenant_id = sqlc.arg(tenant_id) AND person_id = sqlc.arg(person_id) AND attribute_key = ANY(sqlc.arg(attribute_keys)::citext[])
 ORDER BY attribute_key;
 
 -- name: DeletePersonAttribute :exec
 -- Delete a specific attribute for a person
 DELETE FROM person_attributes
-WHERE person_id = sqlc.arg(person_id) AND attribute_key = sqlc.arg(attribute_key);
+WHERE tenant_id = sqlc.arg(tenant_id) AND person_id = sqlc.arg(person_id) AND attribute_key = sqlc.arg(attribute_key);
 
 -- name: DeleteAllPersonAttributes :exec
 -- Delete all attributes for a person
 DELETE FROM person_attributes
-WHERE person_id = sqlc.arg(person_id);
+WHERE tenant_id = sqlc.arg(tenant_id) AND person_id = sqlc.arg(person_id);
 
 -- name: ListAttributeKeys :many
--- List all unique attribute keys used across all persons
+-- List all unique attribute keys used across the persons of a tenant
 SELECT DISTINCT attribute_key
 FROM person_attributes
+WHERE tenant_id = sqlc.arg(tenant_id)
 ORDER BY attribute_key;
 
 -- name: CountPersonAttributes :one
 -- Count attributes for a person
-SELECT COUNT(*) FROM person_attributes WHERE person_id = sqlc.arg(person_id);
+SELECT COUNT(*) FROM person_attributes WHERE tenant_id = sqlc.arg(tenant_id) AND person_id = sqlc.arg(person_id);
 
 -- ============================================================================
 -- PERSON IMAGES OPERATIONS
@@ -226,6 +387,7 @@ SELECT COUNT(*) FROM person_attributes WHERE person_id = sqlc.arg(person_id);
 -- name: CreateOrUpdatePersonImage :one
 -- Create or update a person image with encryption
 INSERT INTO person_images (
+    tenant_id,
     person_id,
     attribute_key,
     image_type,
@@ -236,6 +398,7 @@ INSERT INTO person_images (
     width,
     height
 ) VALUES (
+    sqlc.arg(tenant_id),
     sqlc.arg(person_id), 
     sqlc.arg(attribute_key), 
     sqlc.arg(image_type), 
@@ -274,7 +437,7 @@ SELECT
     created_at,
     updated_at
 FROM person_images
-WHERE person_id = sqlc.arg(person_id) AND attribute_key = sqlc.arg(attribute_key)
+WHERE tenant_id = sqlc.arg(tenant_id) AND person_id = sqlc.arg(person_id) AND attribute_key = sqlc.arg(attribute_key)
 LIMIT 1;
 
 -- name: GetPersonImageMetadata :one
@@ -292,7 +455,7 @@ SELECT
     created_at,
     updated_at
 FROM person_images
-WHERE person_id = sqlc.arg(person_id) AND attribute_key = sqlc.arg(attribute_key)
+WHERE tenant_id = sqlc.arg(tenant_id) AND person_id = sqlc.arg(person_id) AND attribute_key = sqlc.arg(attribute_key)
 LIMIT 1;
 
 -- name: ListPersonImages :many
@@ -310,7 +473,7 @@ SELECT
     created_at,
     updated_at
 FROM person_images
-WHERE person_id = sqlc.arg(person_id)
+WHERE tenant_id = sqlc.arg(tenant_id) AND person_id = sqlc.arg(person_id)
 ORDER BY created_at DESC;
 
 -- name: ListPersonImagesByType :many
@@ -328,22 +491,22 @@ SELECT
     created_at,
     updated_at
 FROM person_images
-WHERE person_id = sqlc.arg(person_id) AND image_type = sqlc.arg(image_type)
+WHERE tenant_id = sqlc.arg(tenant_id) AND person_id = sqlc.arg(person_id) AND image_type = sqlc.arg(image_type)
 ORDER BY created_at DESC;
 
 -- name: DeletePersonImage :exec
 -- Delete a specific image for a person
 DELETE FROM person_images
-WHERE person_id = sqlc.arg(person_id) AND attribute_key = sqlc.arg(attribute_key);
+WHERE tenant_id = sqlc.arg(tenant_id) AND person_id = sqlc.arg(person_id) AND attribute_key = sqlc.arg(attribute_key);
 
 -- name: DeleteAllPersonImages :exec
 -- Delete all images for a person
 DELETE FROM person_images
-WHERE person_id = sqlc.arg(person_id);
+WHERE tenant_id = sqlc.arg(tenant_id) AND person_id = sqlc.arg(person_id);
 
 -- name: CountPersonImages :one
 -- Count images for a person
-SELECT COUNT(*) FROM person_images WHERE person_id = sqlc.arg(person_id);
+SELECT COUNT(*) FROM person_images WHERE tenant_id = sqlc.arg(tenant_id) AND person_id = sqlc.arg(person_id);
 
 -- ============================================================================
 -- COMBINED OPERATIONS
@@ -356,9 +519,10 @@ SELECT
     p.client_id,
     p.created_at,
     p.updated_at,
-    p.deleted_at
+    p.deleted_at,
+    p.tenant_id
 FROM person p
-WHERE p.id = sqlc.arg(id) AND p.deleted_at IS NULL
+WHERE p.tenant_id = sqlc.arg(tenant_id) AND p.id = sqlc.arg(id) AND p.deleted_at IS NULL
 LIMIT 1;
 
 -- name: SearchPersonsByAttribute :many
@@ -370,21 +534,158 @@ SELECT DISTINCT
     p.updated_at
 FROM person p
 JOIN person_attributes pa ON p.id = pa.person_id
-WHERE pa.attribute_key = sqlc.arg(attribute_key)
+WHERE p.tenant_id = sqlc.arg(tenant_id)
+    AND pa.tenant_id = p.tenant_id
+    AND pa.attribute_key = sqlc.arg(attribute_key)
     AND pgp_sym_decrypt(pa.encrypted_value, sqlc.arg(enc_key)) = sqlc.arg(attribute_value)
     AND p.deleted_at IS NULL;
 
 -- name: BulkCreatePersonAttributes :copyfrom
 -- Bulk insert person attributes (use with COPY FROM)
 INSERT INTO person_attributes (
+    tenant_id,
     person_id,
     attribute_key,
     encrypted_value,
     key_version
 ) VALUES (
+    sqlc.arg(tenant_id),
     sqlc.arg(person_id), 
     sqlc.arg(attribute_key), 
     sqlc.arg(encrypted_value), 
     sqlc.arg(key_version)
 );
 
+
+-- ============================================================================
+-- API CREDENTIAL OPERATIONS
+-- ============================================================================
+
+-- name: CreateAPICredential :one
+-- Create an API credential from the hash of a newly generated key
+INSERT INTO api_credential (
+    name,
+    owner,
+    key_hash,
+    key_prefix,
+    scopes,
+    namespace,
+    tenant_id,
+    redaction_profile,
+    purposes,
+    expires_at
+) VALUES (
+    sqlc.arg(name),
+    sqlc.arg(owner),
+    sqlc.arg(key_hash),
+    sqlc.arg(key_prefix),
+    sqlc.arg(scopes),
+    sqlc.arg(namespace),
+    sqlc.arg(tenant_id),
+    sqlc.narg(redaction_profile),
+    sqlc.arg(purposes),
+    sqlc.arg(expires_at)
+)
+RETURNING id, name, owner, key_hash, key_prefix, scopes, namespace, expires_at, last_used_at, revoked_at, created_at, tenant_id, redaction_profile, purposes;
+
+-- name: GetActiveAPICredentialByHash :one
+-- Get an unrevoked, unexpired API credential by key hash
+SELECT id, name, owner, key_hash, key_prefix, scopes, namespace, expires_at, last_used_at, revoked_at, created_at, tenant_id, redaction_profile, purposes
+FROM api_credential
+WHERE key_hash = sqlc.arg(key_hash)
+    AND revoked_at IS NULL
+    AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+LIMIT 1;
+
+-- name: TouchAPICredential :exec
+-- Record that a credential was used, at most once per minute to limit writes
+UPDATE api_credential
+SET last_used_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id)
+    AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - interval '1 minute');
+
+-- name: ListAPICredentials :many
+-- List all API credentials, newest first
+SELECT id, name, owner, key_hash, key_prefix, scopes, namespace, expires_at, last_used_at, revoked_at, created_at, tenant_id, redaction_profile, purposes
+FROM api_credential
+ORDER BY created_at DESC;
+
+-- name: RevokeAPICredential :execrows
+-- Revoke an API credential; returns 0 when it does not exist or is already revoked
+UPDATE api_credential
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND revoked_at IS NULL;
+
+-- ============================================================================
+-- REQUEST NONCE OPERATIONS
+-- ============================================================================
+
+-- name: UseRequestNonce :execrows
+-- Record a signed request nonce; returns 0 when it was already used and has not expired
+INSERT INTO request_nonce (key_id, nonce, expires_at)
+VALUES (sqlc.arg(key_id), sqlc.arg(nonce), sqlc.arg(expires_at))
+ON CONFLICT (key_id, nonce) DO UPDATE
+SET expires_at = EXCLUDED.expires_at
+WHERE request_nonce.expires_at <= CURRENT_TIMESTAMP;
+
+-- name: DeleteExpiredRequestNonces :execrows
+-- Delete up to batch_size expired request nonces
+DELETE FROM request_nonce
+WHERE (key_id, nonce) IN (
+    SELECT key_id, nonce FROM request_nonce
+    WHERE expires_at <= CURRENT_TIMESTAMP
+    LIMIT sqlc.arg(batch_size)
+);
+
+-- ============================================================================
+-- RATE LIMIT OPERATIONS
+-- ============================================================================
+
+-- name: TakeRateLimitToken :one
+-- Refill a token bucket for the time since its last update and take one token.
+-- Returns no row when the bucket holds less than one token.
+INSERT INTO rate_limit_bucket (bucket_key, tokens, updated_at)
+VALUES (sqlc.arg(bucket_key), sqlc.arg(capacity)::double precision - 1, CURRENT_TIMESTAMP)
+ON CONFLICT (bucket_key) DO UPDATE
+SET tokens = LEAST(sqlc.arg(capacity)::double precision,
+        rate_limit_bucket.tokens + EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - rate_limit_bucket.updated_at)::double precision * sqlc.arg(refill_per_second)::double precision) - 1,
+    updated_at = CURRENT_TIMESTAMP
+WHERE LEAST(sqlc.arg(capacity)::double precision,
+        rate_limit_bucket.tokens + EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - rate_limit_bucket.updated_at)::double precision * sqlc.arg(refill_per_second)::double precision) >= 1
+RETURNING tokens;
+
+-- name: GetRateLimitTokens :one
+-- Return the tokens a bucket holds now, refilled for the time since its last update
+SELECT LEAST(sqlc.arg(capacity)::double precision,
+        tokens + EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - updated_at)::double precision * sqlc.arg(refill_per_second)::double precision)::double precision AS tokens
+FROM rate_limit_bucket
+WHERE bucket_key = sqlc.arg(bucket_key);
+
+-- name: DeleteIdleRateLimitBuckets :execrows
+-- Delete up to batch_size buckets unused since idle_before; they have refilled completely
+DELETE FROM rate_limit_bucket
+WHERE bucket_key IN (
+    SELECT idle.bucket_key FROM rate_limit_bucket idle
+    WHERE idle.updated_at < sqlc.arg(idle_before)
+    LIMIT sqlc.arg(batch_size)
+);
+
+-- ============================================================================
+-- ENCRYPTION CANARY OPERATIONS
+-- ============================================================================
+
+-- name: EnsureEncryptionCanary :exec
+-- Store the canary value encrypted with the current key unless it already exists
+INSERT INTO encryption_canary (id, encrypted_value, key_version)
+VALUES (1, pgp_sym_encrypt(sqlc.arg(value)::text, sqlc.arg(enc_key)::text), sqlc.arg(key_version))
+ON CONFLICT (id) DO NOTHING;
+
+-- name: GetEncryptionCanaryKeyVersion :one
+-- Get the version of the key the canary value is encrypted with
+SELECT key_version FROM encryption_canary WHERE id = 1;
+
+-- name: DecryptEncryptionCanary :one
+-- Decrypt the canary value; fails when the key cannot decrypt it
+SELECT pgp_sym_decrypt(encrypted_value, sqlc.arg(enc_key)::text)::text AS value
+FROM encryption_canary
+WHERE id = 1;
@@ -112,7 +112,7 @@ func RunMigrations(ctx context.Context, pool *pgxpool.Pool) error {
 // Uses TRUNCATE with CASCADE to handle foreign key constraints.
 func TruncateTables(ctx context.Context, pool *pgxpool.Pool) error {
 	_, err := pool.Exec(ctx, `
-		TRUNCATE TABLE person_attributes, person_images, request_log, person, key_value RESTART IDENTITY CASCADE
+		TRUNCATE TABLE person_attributes, person_images, request_log, person, key_value, api_credential, request_nonce, rate_limit_bucket, encryption_canary RESTART IDENTITY CASCADE
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to truncate tables: %w", err)
@@ -139,7 +139,7 @@ func InsertKeyValueDirect(ctx context.Context, pool *pgxpool.Pool, key, value st
 	_, err := pool.Exec(ctx, `
 		INSERT INTO key_value (key, value)
 		VALUES ($1, $2)
-		ON CONFLICT (key) DO UPDATE SET value = $2, updated_at = CURRENT_TIMESTAMP
+		ON CONFLICT (namespace, key) DO UPDATE SET value = $2, updated_at = CURRENT_TIMESTAMP
 	`, key, value)
 	if err != nil {
 		return fmt.Errorf("failed to insert key-value: %w", err)
@@ -147,16 +147,43 @@ func InsertKeyValueDirect(ctx context.Context, pool *pgxpool.Pool, key, value st
 	return nil
 }
 
-// GetKeyValueDirect retrieves a value by key directly from the database
+// InsertKeyValueWithExpiryDirect inserts a key-value pair with an explicit expiry directly into the database
+func InsertKeyValueWithExpiryDirect(ctx context.Context, pool *pgxpool.Pool, key, value string, expiresAt time.Time) error {
+	_, err := pool.Exec(ctx, `
+		INSERT INTO key_value (key, value, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (namespace, key) DO UPDATE SET value = $2, expires_at = $3, updated_at = CURRENT_TIMESTAMP
+	`, key, value, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert key-value with expiry: %w", err)
+	}
+	return nil
+}
+
+// GetKeyValueDirect retrieves a value by key in the default namespace directly from the database
 func GetKeyValueDirect(ctx context.Context, pool *pgxpool.Pool, key string) (string, error) {
 	var value string
-	err := pool.QueryRow(ctx, `SELECT value FROM key_value WHERE key = $1`, key).Scan(&value)
+	err := pool.QueryRow(ctx, `SELECT value FROM key_value WHERE namespace = 'default' AND key = $1`, key).Scan(&value)
 	if err != nil {
 		return "", fmt.Errorf("failed to get key-value: %w", err)
 	}
 	return value, nil
 }
 
+// GetStoredKeyValueDirect retrieves the stored plaintext and encrypted columns of a key
+// in a namespace directly from the database, without decrypting
+func GetStoredKeyValueDirect(ctx context.Context, pool *pgxpool.Pool, namespace, key string) (string, []byte, error) {
+	var value string
+	var encryptedValue []byte
+	err := pool.QueryRow(ctx, `
+		SELECT value, encrypted_value FROM key_value WHERE namespace = $1 AND key = $2
+	`, namespace, key).Scan(&value, &encryptedValue)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get stored key-value: %w", err)
+	}
+	return value, encryptedValue, nil
+}
+
 // CleanupContainer terminates the PostgreSQL container.
 // Should be called in AfterSuite.
 func CleanupContainer(ctx context.Context) error {
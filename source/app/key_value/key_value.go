@@ -1,33 +1,106 @@
 package key_value
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"person-service/auth"
+	"person-service/config"
 	errs "person-service/errors"
 	db "person-service/internal/db/generated"
+	"person-service/logging"
+	"person-service/metrics"
+	"strconv"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labstack/echo/v4"
 )
 
-// SetValueRequest represents the request body for setting a key-value pair
+// SetValueRequest represents the request body for setting a key-value pair.
+// TTLSeconds and ExpiresAt are optional and mutually exclusive; when neither
+// is set the entry never expires. IfVersion and IfAbsent are optional and
+// mutually exclusive write conditions.
 type SetValueRequest struct {
-	Key   string `json:"key" validate:"required"`
-	Value string `json:"value" validate:"required"`
+	Key        string     `json:"key" validate:"required"`
+	Value      string     `json:"value" validate:"required"`
+	TTLSeconds *int64     `json:"ttl_seconds,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	IfVersion  *int64     `json:"if_version,omitempty"`
+	IfAbsent   bool       `json:"if_absent,omitempty"`
 }
 
-// KeyValueHandler handles KeyValue
+// IncrementRequest represents the request body for atomically incrementing a counter.
+// Delta defaults to 1 when omitted and may be negative.
+type IncrementRequest struct {
+	Delta *int64 `json:"delta"`
+}
+
+const (
+	// DefaultListLimit is the page size used when ?limit is not provided
+	DefaultListLimit = 100
+
+	// MaxListLimit is the largest page size a client may request
+	MaxListLimit = 1000
+
+	// MaxTTLSeconds is the largest ttl_seconds a client may set (10 years); it
+	// keeps the expiry far from the range where the duration overflows
+	MaxTTLSeconds = 10 * 365 * 24 * 60 * 60
+
+	// MaxWatchWait is the longest a client may long-poll with ?wait
+	MaxWatchWait = 60 * time.Second
+
+	// watchWriteGrace is added to the wait when extending the response write deadline
+	watchWriteGrace = 15 * time.Second
+)
+
+// keyValueRecord is the decrypted view of a key-value entry shared by the
+// generated query rows, which all have the same shape
+type keyValueRecord struct {
+	Key       string
+	Value     string
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+	ExpiresAt pgtype.Timestamptz
+	Version   int64
+}
+
+// KeyValueHandler handles KeyValue.
+// Entries are scoped to the namespace of the authenticated principal, and
+// values are encrypted at rest when KV_ENCRYPT_VALUES is enabled.
 type KeyValueHandler struct {
-	queries *db.Queries
+	queries       *db.Queries
+	encryptionKey string
+	keyVersion    int64
+	encryptValues bool
+	watchHub      *WatchHub
 }
 
-// KeyValueHandler creates a new instance of KeyValueHandler with injected queries
-func NewKeyValueHandler(queries *db.Queries) *KeyValueHandler {
+// KeyValueHandler creates a new instance of KeyValueHandler with injected queries.
+// Values are encrypted with the key in encryption when settings.EncryptValues
+// is set; previously encrypted values stay readable after it is turned off.
+func NewKeyValueHandler(queries *db.Queries, encryption config.Encryption, settings config.KeyValue) *KeyValueHandler {
 	return &KeyValueHandler{
-		queries: queries,
+		queries:       queries,
+		encryptionKey: encryption.Key,
+		keyVersion:    encryption.KeyVersion,
+		encryptValues: settings.EncryptValues,
+		watchHub:      NewWatchHub(),
 	}
 }
 
+// WatchHub returns the hub that wakes long-polling requests.
+// Its Listen loop must be running for watches to see changes before their wait elapses.
+func (h *KeyValueHandler) WatchHub() *WatchHub {
+	return h.watchHub
+}
+
 // SetValue handles POST /api/key_value - sets or updates a key-value pair
 func (h *KeyValueHandler) SetValue(c echo.Context) error {
 	// Parse request body
@@ -47,18 +120,81 @@ func (h *KeyValueHandler) SetValue(c echo.Context) error {
 		})
 	}
 
+	// Resolve the optional expiry
+	expiresAt, ok := resolveExpiry(req, time.Now())
+	if !ok {
+		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+			Message:   fmt.Sprintf("ttl_seconds must be between 1 and %d, expires_at must be in the future, and only one of them may be set", MaxTTLSeconds),
+			ErrorCode: errs.ErrKVInvalidTTL,
+		})
+	}
+
+	// Validate write conditions
+	if (req.IfAbsent && req.IfVersion != nil) || (req.IfVersion != nil && *req.IfVersion < 1) {
+		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+			Message:   "if_version must be a positive integer and cannot be combined with if_absent",
+			ErrorCode: errs.ErrKVInvalidCondition,
+		})
+	}
+
 	// Use request context for trace propagation
 	ctx := c.Request().Context()
+	namespace := auth.NamespaceFromContext(ctx)
 
-	// Check if key already exists to determine response status code
-	_, err := h.queries.GetKeyValue(ctx, req.Key)
-	isNewKey := errors.Is(err, pgx.ErrNoRows)
-
-	// Set value in database
-	err = h.queries.SetValue(ctx, db.SetValueParams{
-		Key:   req.Key,
-		Value: req.Value,
-	})
+	// Each branch is a single statement, so the decision and the write are atomic
+	var record keyValueRecord
+	var err error
+	switch {
+	case req.IfAbsent:
+		var row db.SetValueIfAbsentRow
+		row, err = h.queries.SetValueIfAbsent(ctx, db.SetValueIfAbsentParams{
+			Namespace:  namespace,
+			Key:        req.Key,
+			Encrypt:    h.encryptValues,
+			Value:      req.Value,
+			EncKey:     h.encryptionKey,
+			KeyVersion: h.keyVersion,
+			ExpiresAt:  expiresAt,
+		})
+		record = keyValueRecord(row)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.JSON(http.StatusConflict, errs.ErrorResponse{
+				Message:   "Key already exists",
+				ErrorCode: errs.ErrKVKeyExists,
+			})
+		}
+	case req.IfVersion != nil:
+		var row db.SetValueIfVersionRow
+		row, err = h.queries.SetValueIfVersion(ctx, db.SetValueIfVersionParams{
+			Encrypt:         h.encryptValues,
+			Value:           req.Value,
+			EncKey:          h.encryptionKey,
+			KeyVersion:      h.keyVersion,
+			ExpiresAt:       expiresAt,
+			Namespace:       namespace,
+			Key:             req.Key,
+			ExpectedVersion: *req.IfVersion,
+		})
+		record = keyValueRecord(row)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.JSON(http.StatusPreconditionFailed, errs.ErrorResponse{
+				Message:   "Version mismatch: key is missing or has been modified by another request",
+				ErrorCode: errs.ErrKVVersionMismatch,
+			})
+		}
+	default:
+		var row db.SetValueRow
+		row, err = h.queries.SetValue(ctx, db.SetValueParams{
+			Namespace:  namespace,
+			Key:        req.Key,
+			Encrypt:    h.encryptValues,
+			Value:      req.Value,
+			EncKey:     h.encryptionKey,
+			KeyVersion: h.keyVersion,
+			ExpiresAt:  expiresAt,
+		})
+		record = keyValueRecord(row)
+	}
 
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, errs.ErrorResponse{
@@ -66,39 +202,115 @@ func (h *KeyValueHandler) SetValue(c echo.Context) error {
 			ErrorCode: errs.ErrKVFailedSetValue,
 		})
 	}
-
-	// Retrieve the full record with timestamps
-	record, err := h.queries.GetKeyValue(ctx, req.Key)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, errs.ErrorResponse{
-			Message:   "Failed to retrieve value",
-			ErrorCode: errs.ErrKVFailedRetrieveValue,
-		})
+	if h.encryptValues {
+		metrics.RecordEncryption(metrics.ResourceKeyValue, 1)
 	}
 
 	// Return success with the full key-value record
-	response := map[string]interface{}{
-		"key":   record.Key,
-		"value": record.Value,
+	response := buildKeyValueResponse(record, time.Now())
+
+	// Return 201 Created for new keys (version restarts at 1), 200 OK for updates
+	if record.Version == 1 {
+		return c.JSON(http.StatusCreated, response)
 	}
+	return c.JSON(http.StatusOK, response)
+}
 
-	// Add timestamps if they are valid
-	if record.CreatedAt.Valid {
-		response["created_at"] = record.CreatedAt.Time
+// GetValue handles GET /api/key_value/:key - retrieves a value by key.
+// With ?wait=<duration> (at most 60s) the request long-polls until the key's version
+// differs from ?since_version (0 means absent, default is the version at request time)
+// and returns 304 Not Modified if nothing changed before the wait elapsed or the
+// server started shutting down.
+func (h *KeyValueHandler) GetValue(c echo.Context) error {
+	key := c.Param("key")
+	if key == "" {
+		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+			Message:   "Key parameter is required",
+			ErrorCode: errs.ErrKVMissingKeyParam,
+		})
 	}
-	if record.UpdatedAt.Valid {
-		response["updated_at"] = record.UpdatedAt.Time
+
+	wait, sinceVersion, ok := parseWatchParams(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+			Message:   "wait must be a duration between 1s and 60s and since_version a non-negative integer",
+			ErrorCode: errs.ErrKVInvalidWatchParams,
+		})
 	}
 
-	// Return 201 Created for new keys, 200 OK for updates
-	if isNewKey {
-		return c.JSON(http.StatusCreated, response)
+	// Use request context for trace propagation
+	ctx := c.Request().Context()
+	namespace := auth.NamespaceFromContext(ctx)
+
+	// Subscribe before the first read so a change in between is not missed
+	var updates <-chan struct{}
+	var deadline <-chan time.Time
+	if wait > 0 {
+		var unsubscribe func()
+		updates, unsubscribe = h.watchHub.Subscribe(namespace, key, false)
+		defer unsubscribe()
+
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		deadline = timer.C
+		extendWriteDeadline(c, wait)
+	}
+
+	for {
+		record, err := h.queries.GetKeyValue(ctx, db.GetKeyValueParams{
+			EncKey:    h.encryptionKey,
+			Namespace: namespace,
+			Key:       key,
+		})
+		found := err == nil
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return c.JSON(http.StatusInternalServerError, errs.ErrorResponse{
+				Message:   "Failed to retrieve value",
+				ErrorCode: errs.ErrKVFailedRetrieveValue,
+			})
+		}
+
+		currentVersion := int64(0)
+		if found {
+			currentVersion = record.Version
+		}
+		if sinceVersion == nil && wait > 0 {
+			// Without since_version, wait for the next change after this read
+			sinceVersion = &currentVersion
+		}
+
+		if sinceVersion == nil || currentVersion != *sinceVersion {
+			if !found {
+				return c.JSON(http.StatusNotFound, errs.ErrorResponse{
+					Message:   "Key not found",
+					ErrorCode: errs.ErrKVKeyNotFound,
+				})
+			}
+			// Return the full key-value record
+			return c.JSON(http.StatusOK, buildKeyValueResponse(keyValueRecord(record), time.Now()))
+		}
+
+		if wait == 0 {
+			return c.NoContent(http.StatusNotModified)
+		}
+
+		// Expiry is not a database change, so also wake when the key expires
+		select {
+		case <-updates:
+		case <-expiryAlarm(record.ExpiresAt):
+		case <-deadline:
+			return c.NoContent(http.StatusNotModified)
+		case <-h.watchHub.Done():
+			// The server is shutting down; the client polls another instance
+			return c.NoContent(http.StatusNotModified)
+		case <-ctx.Done():
+			return nil
+		}
 	}
-	return c.JSON(http.StatusOK, response)
 }
 
-// GetValue handles GET /api/key_value/:key - retrieves a value by key
-func (h *KeyValueHandler) GetValue(c echo.Context) error {
+// DeleteValue handles DELETE /api/key_value/:key - deletes a key-value pair
+func (h *KeyValueHandler) DeleteValue(c echo.Context) error {
 	key := c.Param("key")
 	if key == "" {
 		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
@@ -107,42 +319,242 @@ func (h *KeyValueHandler) GetValue(c echo.Context) error {
 		})
 	}
 
+	// Parse the optional version condition
+	var ifVersion *int64
+	if raw := c.QueryParam("if_version"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < 1 {
+			return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+				Message:   "if_version must be a positive integer",
+				ErrorCode: errs.ErrKVInvalidCondition,
+			})
+		}
+		ifVersion = &parsed
+	}
+
 	// Use request context for trace propagation
 	ctx := c.Request().Context()
-	record, err := h.queries.GetKeyValue(ctx, key)
+	namespace := auth.NamespaceFromContext(ctx)
+
+	// Delete value from database in a single statement
+	var deleted int64
+	var err error
+	if ifVersion != nil {
+		deleted, err = h.queries.DeleteValueIfVersion(ctx, db.DeleteValueIfVersionParams{
+			Namespace:       namespace,
+			Key:             key,
+			ExpectedVersion: *ifVersion,
+		})
+	} else {
+		deleted, err = h.queries.DeleteValue(ctx, db.DeleteValueParams{
+			Namespace: namespace,
+			Key:       key,
+		})
+	}
 
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return c.JSON(http.StatusNotFound, errs.ErrorResponse{
-				Message:   "Key not found",
-				ErrorCode: errs.ErrKVKeyNotFound,
+		return c.JSON(http.StatusInternalServerError, errs.ErrorResponse{
+			Message:   "Failed to delete value",
+			ErrorCode: errs.ErrKVFailedDeleteValue,
+		})
+	}
+
+	if deleted == 0 {
+		// Distinguish a missing key from a failed version condition
+		if ifVersion != nil {
+			_, err := h.queries.GetKeyValue(ctx, db.GetKeyValueParams{
+				EncKey:    h.encryptionKey,
+				Namespace: namespace,
+				Key:       key,
 			})
+			if err == nil {
+				return c.JSON(http.StatusPreconditionFailed, errs.ErrorResponse{
+					Message:   "Version mismatch: key has been modified by another request",
+					ErrorCode: errs.ErrKVVersionMismatch,
+				})
+			}
 		}
-		return c.JSON(http.StatusInternalServerError, errs.ErrorResponse{
-			Message:   "Failed to retrieve value",
-			ErrorCode: errs.ErrKVFailedRetrieveValue,
+		return c.JSON(http.StatusNotFound, errs.ErrorResponse{
+			Message:   "Key not found",
+			ErrorCode: errs.ErrKVKeyNotFound,
 		})
 	}
 
-	// Return the full key-value record
-	response := map[string]interface{}{
-		"key":   record.Key,
-		"value": record.Value,
+	// Return success message
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Key deleted successfully",
+	})
+}
+
+// ListValues handles GET /api/key-value - lists keys by prefix with keyset pagination.
+// Query parameters: prefix, limit (1-1000, default 100), cursor (opaque, from next_cursor)
+// and keys_only=true to omit values from the response. Every page carries an etag; with
+// ?wait=<duration> (at most 60s) the request long-polls until the page differs from
+// ?since_etag (default is the page at request time) and returns 304 Not Modified if
+// nothing under the prefix changed before the wait elapsed or the server started
+// shutting down.
+func (h *KeyValueHandler) ListValues(c echo.Context) error {
+	prefix := c.QueryParam("prefix")
+
+	limit := DefaultListLimit
+	if raw := c.QueryParam("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > MaxListLimit {
+			return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+				Message:   "limit must be an integer between 1 and 1000",
+				ErrorCode: errs.ErrKVInvalidListParams,
+			})
+		}
+		limit = parsed
 	}
 
-	// Add timestamps if they are valid
-	if record.CreatedAt.Valid {
-		response["created_at"] = record.CreatedAt.Time
+	afterKey, err := decodeCursor(c.QueryParam("cursor"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+			Message:   "Invalid cursor",
+			ErrorCode: errs.ErrKVInvalidListParams,
+		})
 	}
-	if record.UpdatedAt.Valid {
-		response["updated_at"] = record.UpdatedAt.Time
+
+	keysOnly := false
+	if raw := c.QueryParam("keys_only"); raw != "" {
+		keysOnly, err = strconv.ParseBool(raw)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+				Message:   "keys_only must be a boolean",
+				ErrorCode: errs.ErrKVInvalidListParams,
+			})
+		}
 	}
 
-	return c.JSON(http.StatusOK, response)
+	wait, _, ok := parseWatchParams(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+			Message:   "wait must be a duration between 1s and 60s",
+			ErrorCode: errs.ErrKVInvalidWatchParams,
+		})
+	}
+	sinceETag := c.QueryParam("since_etag")
+
+	// Use request context for trace propagation
+	ctx := c.Request().Context()
+	namespace := auth.NamespaceFromContext(ctx)
+
+	// Subscribe before the first read so a change in between is not missed
+	var updates <-chan struct{}
+	var deadline <-chan time.Time
+	if wait > 0 {
+		var unsubscribe func()
+		updates, unsubscribe = h.watchHub.Subscribe(namespace, prefix, true)
+		defer unsubscribe()
+
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		deadline = timer.C
+		extendWriteDeadline(c, wait)
+	}
+
+	for {
+		// Fetch one extra row to know whether another page exists
+		records, err := h.queries.ListKeyValues(ctx, db.ListKeyValuesParams{
+			EncKey:     h.encryptionKey,
+			Namespace:  namespace,
+			Prefix:     prefix,
+			AfterKey:   afterKey,
+			LimitCount: int32(limit + 1),
+		})
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, errs.ErrorResponse{
+				Message:   "Failed to list values",
+				ErrorCode: errs.ErrKVFailedList,
+			})
+		}
+
+		hasMore := len(records) > limit
+		if hasMore {
+			records = records[:limit]
+		}
+
+		etag := pageETag(records)
+		if sinceETag == "" && wait > 0 {
+			// Without since_etag, wait for the next change after this read
+			sinceETag = etag
+		}
+
+		if sinceETag == "" || etag != sinceETag {
+			now := time.Now()
+			items := make([]map[string]interface{}, 0, len(records))
+			for _, record := range records {
+				item := buildKeyValueResponse(keyValueRecord(record), now)
+				if keysOnly {
+					delete(item, "value")
+				}
+				items = append(items, item)
+			}
+
+			response := map[string]interface{}{
+				"items": items,
+				"etag":  etag,
+			}
+			if hasMore {
+				response["next_cursor"] = encodeCursor(records[len(records)-1].Key)
+			}
+
+			return c.JSON(http.StatusOK, response)
+		}
+
+		if wait == 0 {
+			return c.NoContent(http.StatusNotModified)
+		}
+
+		// Expiry is not a database change, so also wake when the first entry expires
+		select {
+		case <-updates:
+		case <-expiryAlarm(earliestExpiry(records)):
+		case <-deadline:
+			return c.NoContent(http.StatusNotModified)
+		case <-h.watchHub.Done():
+			// The server is shutting down; the client polls another instance
+			return c.NoContent(http.StatusNotModified)
+		case <-ctx.Done():
+			return nil
+		}
+	}
 }
 
-// DeleteValue handles DELETE /api/key_value/:key - deletes a key-value pair
-func (h *KeyValueHandler) DeleteValue(c echo.Context) error {
+// DeleteByPrefix handles DELETE /api/key-value?prefix=...&confirm=... - deletes every key under a prefix.
+// The confirm parameter must repeat the prefix exactly, and the prefix must not be empty.
+func (h *KeyValueHandler) DeleteByPrefix(c echo.Context) error {
+	prefix := c.QueryParam("prefix")
+	if prefix == "" || c.QueryParam("confirm") != prefix {
+		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+			Message:   "A non-empty prefix is required and confirm must repeat it exactly",
+			ErrorCode: errs.ErrKVDeleteNotConfirmed,
+		})
+	}
+
+	// Use request context for trace propagation
+	ctx := c.Request().Context()
+
+	deleted, err := h.queries.DeleteKeyValuesByPrefix(ctx, db.DeleteKeyValuesByPrefixParams{
+		Namespace: auth.NamespaceFromContext(ctx),
+		Prefix:    prefix,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, errs.ErrorResponse{
+			Message:   "Failed to delete values",
+			ErrorCode: errs.ErrKVFailedDeleteValue,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Keys deleted successfully",
+		"deleted": deleted,
+	})
+}
+
+// IncrementValue handles POST /api/key-value/:key/increment - atomically adds delta to an integer value
+func (h *KeyValueHandler) IncrementValue(c echo.Context) error {
 	key := c.Param("key")
 	if key == "" {
 		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
@@ -151,36 +563,196 @@ func (h *KeyValueHandler) DeleteValue(c echo.Context) error {
 		})
 	}
 
+	// Parse request body; an empty body increments by one
+	var req IncrementRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+			Message:   "Invalid request body",
+			ErrorCode: errs.ErrKVInvalidRequestBody,
+		})
+	}
+	delta := int64(1)
+	if req.Delta != nil {
+		delta = *req.Delta
+	}
+
 	// Use request context for trace propagation
 	ctx := c.Request().Context()
 
-	// Check if key exists before deleting
-	_, err := h.queries.GetKeyValue(ctx, key)
+	record, err := h.queries.IncrementValue(ctx, db.IncrementValueParams{
+		Namespace:  auth.NamespaceFromContext(ctx),
+		Key:        key,
+		Encrypt:    h.encryptValues,
+		Delta:      delta,
+		EncKey:     h.encryptionKey,
+		KeyVersion: h.keyVersion,
+	})
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return c.JSON(http.StatusNotFound, errs.ErrorResponse{
-				Message:   "Key not found",
-				ErrorCode: errs.ErrKVKeyNotFound,
+		if isNotIntegerError(err) {
+			return c.JSON(http.StatusConflict, errs.ErrorResponse{
+				Message:   "Stored value is not an integer or the result is out of range",
+				ErrorCode: errs.ErrKVValueNotInteger,
 			})
 		}
 		return c.JSON(http.StatusInternalServerError, errs.ErrorResponse{
-			Message:   "Failed to retrieve value",
-			ErrorCode: errs.ErrKVFailedRetrieveValue,
+			Message:   "Failed to increment value",
+			ErrorCode: errs.ErrKVFailedIncrement,
 		})
 	}
+	if h.encryptValues {
+		metrics.RecordEncryption(metrics.ResourceKeyValue, 1)
+	}
 
-	// Delete value from database
-	err = h.queries.DeleteValue(ctx, key)
+	response := buildKeyValueResponse(keyValueRecord(record), time.Now())
+	if record.Version == 1 {
+		return c.JSON(http.StatusCreated, response)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// isNotIntegerError checks if the error is a PostgreSQL invalid integer cast (22P02)
+// or integer overflow (22003) raised by IncrementValue
+func isNotIntegerError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "22P02" || pgErr.Code == "22003"
+}
+
+// resolveExpiry converts the optional ttl_seconds / expires_at fields of a
+// SetValueRequest into the expires_at column value. It returns false when the
+// combination is invalid.
+func resolveExpiry(req SetValueRequest, now time.Time) (pgtype.Timestamptz, bool) {
+	switch {
+	case req.TTLSeconds != nil && req.ExpiresAt != nil:
+		return pgtype.Timestamptz{}, false
+	case req.TTLSeconds != nil:
+		if *req.TTLSeconds <= 0 || *req.TTLSeconds > MaxTTLSeconds {
+			return pgtype.Timestamptz{}, false
+		}
+		return pgtype.Timestamptz{Time: now.Add(time.Duration(*req.TTLSeconds) * time.Second), Valid: true}, true
+	case req.ExpiresAt != nil:
+		if !req.ExpiresAt.After(now) {
+			return pgtype.Timestamptz{}, false
+		}
+		return pgtype.Timestamptz{Time: *req.ExpiresAt, Valid: true}, true
+	default:
+		return pgtype.Timestamptz{}, true
+	}
+}
+
+// buildKeyValueResponse creates a response map from a decrypted key-value record.
+// Entries with an expiry also report the remaining TTL in whole seconds.
+func buildKeyValueResponse(record keyValueRecord, now time.Time) map[string]interface{} {
+	response := map[string]interface{}{
+		"key":     record.Key,
+		"value":   record.Value,
+		"version": record.Version,
+	}
+
+	// Add timestamps if they are valid
+	if record.CreatedAt.Valid {
+		response["created_at"] = record.CreatedAt.Time
+	}
+	if record.UpdatedAt.Valid {
+		response["updated_at"] = record.UpdatedAt.Time
+	}
+	if record.ExpiresAt.Valid {
+		response["expires_at"] = record.ExpiresAt.Time
+		response["ttl_seconds"] = remainingTTLSeconds(record.ExpiresAt.Time, now)
+	}
+
+	return response
+}
+
+// remainingTTLSeconds returns the whole seconds left until expiresAt, rounded up
+func remainingTTLSeconds(expiresAt, now time.Time) int64 {
+	remaining := expiresAt.Sub(now).Seconds()
+	if remaining <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(remaining))
+}
+
+// encodeCursor turns the last key of a page into an opaque pagination cursor
+func encodeCursor(lastKey string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(lastKey))
+}
 
+// decodeCursor reverses encodeCursor; an empty cursor starts from the first key
+func decodeCursor(cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, errs.ErrorResponse{
-			Message:   "Failed to delete value",
-			ErrorCode: errs.ErrKVFailedDeleteValue,
-		})
+		return "", err
 	}
+	return string(decoded), nil
+}
 
-	// Return success message
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "Key deleted successfully",
-	})
+// parseWatchParams reads the optional long-poll parameters wait and since_version.
+// A zero wait means the request does not long-poll. It returns false when a
+// parameter is malformed or out of range.
+func parseWatchParams(c echo.Context) (time.Duration, *int64, bool) {
+	var wait time.Duration
+	if raw := c.QueryParam("wait"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed < time.Second || parsed > MaxWatchWait {
+			return 0, nil, false
+		}
+		wait = parsed
+	}
+
+	var sinceVersion *int64
+	if raw := c.QueryParam("since_version"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < 0 {
+			return 0, nil, false
+		}
+		sinceVersion = &parsed
+	}
+
+	return wait, sinceVersion, true
+}
+
+// extendWriteDeadline lets a long-polling response outlive the server's WriteTimeout
+func extendWriteDeadline(c echo.Context, wait time.Duration) {
+	controller := http.NewResponseController(c.Response())
+	if err := controller.SetWriteDeadline(time.Now().Add(wait + watchWriteGrace)); err != nil {
+		logging.Debug("Could not extend write deadline for watch request", "error", err)
+	}
+}
+
+// expiryAlarm returns a channel that fires when expiresAt passes, or nil (never fires) if unset
+func expiryAlarm(expiresAt pgtype.Timestamptz) <-chan time.Time {
+	if !expiresAt.Valid {
+		return nil
+	}
+	return time.After(time.Until(expiresAt.Time))
+}
+
+// earliestExpiry returns the soonest expiry among the listed records
+func earliestExpiry(records []db.ListKeyValuesRow) pgtype.Timestamptz {
+	var earliest pgtype.Timestamptz
+	for _, record := range records {
+		if record.ExpiresAt.Valid && (!earliest.Valid || record.ExpiresAt.Time.Before(earliest.Time)) {
+			earliest = record.ExpiresAt
+		}
+	}
+	return earliest
+}
+
+// pageETag fingerprints a listing page by its keys and versions, so a watcher
+// can tell whether anything on the page was created, updated or deleted
+func pageETag(records []db.ListKeyValuesRow) string {
+	hash := sha256.New()
+	for _, record := range records {
+		hash.Write([]byte(record.Key))
+		hash.Write([]byte{0})
+		hash.Write([]byte(strconv.FormatInt(record.Version, 10)))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:16])
 }
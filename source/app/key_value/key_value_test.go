@@ -11,11 +11,14 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 
+	"person-service/auth"
+	"person-service/config"
 	db "person-service/internal/db/generated"
 	"person-service/internal/testdb"
 )
@@ -37,7 +40,7 @@ func TestMain(m *testing.M) {
 
 func TestNewKeyValueHandler(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 	assert.NotNil(t, handler)
 	assert.Equal(t, queries, handler.queries)
 }
@@ -48,7 +51,7 @@ func TestSetValue_Success(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	jsonBody := `{"key":"test-key","value":"test-value"}`
@@ -67,7 +70,7 @@ func TestSetValue_Success(t *testing.T) {
 
 func TestSetValue_InvalidJSON(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	jsonBody := `{invalid-json}`
@@ -85,7 +88,7 @@ func TestSetValue_InvalidJSON(t *testing.T) {
 
 func TestSetValue_EmptyKey(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	jsonBody := `{"key":"","value":"test-value"}`
@@ -103,7 +106,7 @@ func TestSetValue_EmptyKey(t *testing.T) {
 
 func TestSetValue_EmptyValue(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	jsonBody := `{"key":"test-key","value":""}`
@@ -124,7 +127,7 @@ func TestSetValue_DatabaseErrorOnSet(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(closedPool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	jsonBody := `{"key":"test-key","value":"test-value"}`
@@ -150,7 +153,7 @@ func TestGetValue_Success(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/key-value/test-key", nil)
@@ -169,7 +172,7 @@ func TestGetValue_Success(t *testing.T) {
 
 func TestGetValue_EmptyKey(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/key-value/", nil)
@@ -191,7 +194,7 @@ func TestGetValue_KeyNotFound(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/key-value/nonexistent", nil)
@@ -212,7 +215,7 @@ func TestGetValue_DatabaseError(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(closedPool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/key-value/test-key", nil)
@@ -238,7 +241,7 @@ func TestDeleteValue_Success(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodDelete, "/api/key-value/test-key", nil)
@@ -256,7 +259,7 @@ func TestDeleteValue_Success(t *testing.T) {
 
 func TestDeleteValue_EmptyKey(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodDelete, "/api/key-value/", nil)
@@ -277,7 +280,7 @@ func TestDeleteValue_DatabaseError(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(closedPool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodDelete, "/api/key-value/test-key", nil)
@@ -290,8 +293,8 @@ func TestDeleteValue_DatabaseError(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
-	// Now calls GetKeyValue first to check existence, so error message is "Failed to retrieve value"
-	assert.Contains(t, rec.Body.String(), "Failed to retrieve value")
+	// Delete is a single conditional statement, so the failure surfaces on the delete itself
+	assert.Contains(t, rec.Body.String(), "Failed to delete value")
 }
 
 // TestSetValue_RetrieveErrorAfterSet tests the error path when GetKeyValue fails after SetValue succeeds
@@ -302,7 +305,7 @@ func TestSetValue_RetrieveErrorAfterSet(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	// First, set a value successfully
@@ -329,7 +332,7 @@ func TestSetValue_UpdateExisting(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	// First set
@@ -369,7 +372,7 @@ func TestGetValue_WithTimestamps(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/key-value/timestamp-key", nil)
@@ -420,7 +423,7 @@ func TestConcurrentSetValue_DifferentKeys(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	numGoroutines := 10
 	var wg sync.WaitGroup
@@ -462,7 +465,7 @@ func TestConcurrentSetValue_SameKey(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	numGoroutines := 5
 	var wg sync.WaitGroup
@@ -517,7 +520,7 @@ func TestConcurrentReadWrite(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	numReaders := 5
 	numWriters := 3
@@ -574,7 +577,7 @@ func TestSetValue_MaxKeyLength(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	// Create a key at exactly 255 characters (max VARCHAR length)
 	maxKey := strings.Repeat("k", 255)
@@ -609,7 +612,7 @@ func TestSetValue_KeyTooLong(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	// Create a key exceeding 255 characters
 	tooLongKey := strings.Repeat("k", 256)
@@ -634,7 +637,7 @@ func TestSetValue_MaxValueLength(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	// Create a value at exactly 255 characters
 	maxValue := strings.Repeat("v", 255)
@@ -658,7 +661,7 @@ func TestSetValue_ValueTooLong(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	// Create a value exceeding 255 characters
 	tooLongValue := strings.Repeat("v", 256)
@@ -683,7 +686,7 @@ func TestSetValue_SpecialCharacters(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	testCases := []struct {
 		name  string
@@ -732,7 +735,7 @@ func TestSetValue_UnicodeCharacters(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	testCases := []struct {
 		name  string
@@ -772,7 +775,7 @@ func TestSetValue_ResponseSchema(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	jsonBody := `{"key":"schema-key","value":"schema-value"}`
@@ -806,7 +809,7 @@ func TestGetValue_ResponseSchema(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/key-value/schema-get-key", nil)
@@ -835,7 +838,7 @@ func TestErrorResponse_KeyValue_Schema(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	testCases := []struct {
 		name           string
@@ -909,7 +912,7 @@ func TestContentType_KeyValue(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	jsonBody := `{"key":"ct-key","value":"ct-value"}`
@@ -936,7 +939,7 @@ func TestSetValue_SQLInjectionAttempt(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	sqlInjectionAttempts := []string{
 		"'; DROP TABLE key_value; --",
@@ -976,7 +979,7 @@ func TestGetValue_SQLInjectionAttempt(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	sqlInjectionAttempts := []string{
 		"' OR '1'='1",
@@ -1012,7 +1015,7 @@ func TestDeleteValue_SQLInjectionAttempt(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	sqlInjectionAttempts := []string{
 		"'; DROP TABLE key_value; --",
@@ -1047,7 +1050,7 @@ func TestDeleteValue_NotFound(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewKeyValueHandler(queries)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodDelete, "/api/key-value/nonexistent-key", nil)
@@ -1061,3 +1064,692 @@ func TestDeleteValue_NotFound(t *testing.T) {
 	// Delete of nonexistent key should succeed (idempotent) or return 404
 	assert.True(t, rec.Code == http.StatusOK || rec.Code == http.StatusNotFound)
 }
+
+// ============================================================================
+// TTL AND EXPIRY TESTS
+// ============================================================================
+
+// TestSetValue_WithTTLSeconds tests that ttl_seconds sets an expiry and reports the remaining TTL
+func TestSetValue_WithTTLSeconds(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	queries := db.New(pool)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
+
+	e := echo.New()
+	jsonBody := `{"key":"ttl-key","value":"ttl-value","ttl_seconds":60}`
+	req := httptest.NewRequest(http.MethodPost, "/api/key-value", strings.NewReader(jsonBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err = handler.SetValue(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	var response map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Contains(t, response, "expires_at")
+	ttl, ok := response["ttl_seconds"].(float64)
+	assert.True(t, ok, "Response should contain numeric 'ttl_seconds'")
+	assert.InDelta(t, 60, ttl, 2)
+}
+
+// TestSetValue_WithExpiresAt tests that an absolute expires_at is stored and returned
+func TestSetValue_WithExpiresAt(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	queries := db.New(pool)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	e := echo.New()
+	jsonBody := fmt.Sprintf(`{"key":"abs-key","value":"abs-value","expires_at":"%s"}`, expiresAt.Format(time.RFC3339))
+	req := httptest.NewRequest(http.MethodPost, "/api/key-value", strings.NewReader(jsonBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err = handler.SetValue(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	record, err := queries.GetKeyValue(ctx, db.GetKeyValueParams{
+		EncKey:    handler.encryptionKey,
+		Namespace: auth.DefaultNamespace,
+		Key:       "abs-key",
+	})
+	assert.NoError(t, err)
+	assert.True(t, record.ExpiresAt.Valid)
+	assert.True(t, expiresAt.Equal(record.ExpiresAt.Time))
+}
+
+// TestSetValue_InvalidTTL tests rejection of invalid expiry combinations
+func TestSetValue_InvalidTTL(t *testing.T) {
+	queries := db.New(pool)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
+
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	testCases := []struct {
+		name string
+		body string
+	}{
+		{name: "Zero TTL", body: `{"key":"k","value":"v","ttl_seconds":0}`},
+		{name: "Negative TTL", body: `{"key":"k","value":"v","ttl_seconds":-5}`},
+		{name: "TTL above maximum", body: fmt.Sprintf(`{"key":"k","value":"v","ttl_seconds":%d}`, MaxTTLSeconds+1)},
+		{name: "TTL overflowing a duration", body: `{"key":"k","value":"v","ttl_seconds":9223372036854775807}`},
+		{name: "Expiry in the past", body: fmt.Sprintf(`{"key":"k","value":"v","expires_at":"%s"}`, past)},
+		{name: "Both TTL and expiry", body: fmt.Sprintf(`{"key":"k","value":"v","ttl_seconds":10,"expires_at":"%s"}`, future)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/key-value", strings.NewReader(tc.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := handler.SetValue(c)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), "KV_004_INVALID_TTL")
+		})
+	}
+}
+
+// TestGetValue_ExpiredKey tests that expired keys are reported as not found
+func TestGetValue_ExpiredKey(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	err = testdb.InsertKeyValueWithExpiryDirect(ctx, pool, "expired-key", "expired-value", time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+
+	queries := db.New(pool)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/key-value/expired-key", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("key")
+	c.SetParamValues("expired-key")
+
+	err = handler.GetValue(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), "KV_101_KEY_NOT_FOUND")
+}
+
+// TestSetValue_OverwriteExpiredKey tests that overwriting an expired key is treated as a create
+func TestSetValue_OverwriteExpiredKey(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	err = testdb.InsertKeyValueWithExpiryDirect(ctx, pool, "reuse-key", "old-value", time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+
+	queries := db.New(pool)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
+
+	e := echo.New()
+	jsonBody := `{"key":"reuse-key","value":"new-value"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/key-value", strings.NewReader(jsonBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err = handler.SetValue(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "expires_at", "Overwrite without TTL should clear the expiry")
+}
+
+// TestDeleteValue_ExpiredKey tests that deleting an expired key returns 404
+func TestDeleteValue_ExpiredKey(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	err = testdb.InsertKeyValueWithExpiryDirect(ctx, pool, "expired-del", "value", time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+
+	queries := db.New(pool)
+	handler := NewKeyValueHandler(queries, config.Default().Encryption, config.KeyValue{})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/api/key-value/expired-del", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("key")
+	c.SetParamValues("expired-del")
+
+	err = handler.DeleteValue(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// TestRemainingTTLSeconds tests rounding of the remaining TTL
+func TestRemainingTTLSeconds(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, int64(10), remainingTTLSeconds(now.Add(10*time.Second), now))
+	assert.Equal(t, int64(1), remainingTTLSeconds(now.Add(100*time.Millisecond), now))
+	assert.Equal(t, int64(0), remainingTTLSeconds(now.Add(-time.Second), now))
+}
+
+// ============================================================================
+// CONDITIONAL WRITE AND INCREMENT TESTS
+// ============================================================================
+
+// setValueRequest posts a JSON body to SetValue and returns the recorder
+func setValueRequest(t *testing.T, handler *KeyValueHandler, jsonBody string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/key-value", strings.NewReader(jsonBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	assert.NoError(t, handler.SetValue(c))
+	return rec
+}
+
+// incrementRequest posts a JSON body to IncrementValue for key and returns the recorder
+func incrementRequest(t *testing.T, handler *KeyValueHandler, key, jsonBody string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/key-value/"+key+"/increment", strings.NewReader(jsonBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("key")
+	c.SetParamValues(key)
+	assert.NoError(t, handler.IncrementValue(c))
+	return rec
+}
+
+// TestSetValue_ReturnsVersion tests that the version starts at 1 and increases on update
+func TestSetValue_ReturnsVersion(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+
+	rec := setValueRequest(t, handler, `{"key":"ver-key","value":"a"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"version":1`)
+
+	rec = setValueRequest(t, handler, `{"key":"ver-key","value":"b"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"version":2`)
+}
+
+// TestSetValue_IfAbsent tests create-only writes
+func TestSetValue_IfAbsent(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+
+	rec := setValueRequest(t, handler, `{"key":"lock","value":"owner-a","if_absent":true}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = setValueRequest(t, handler, `{"key":"lock","value":"owner-b","if_absent":true}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "KV_204_KEY_EXISTS")
+
+	stored, err := testdb.GetKeyValueDirect(ctx, pool, "lock")
+	assert.NoError(t, err)
+	assert.Equal(t, "owner-a", stored)
+}
+
+// TestSetValue_IfAbsent_ExpiredKey tests that an expired key counts as absent
+func TestSetValue_IfAbsent_ExpiredKey(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	err = testdb.InsertKeyValueWithExpiryDirect(ctx, pool, "lease", "old-owner", time.Now().Add(-time.Second))
+	assert.NoError(t, err)
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+
+	rec := setValueRequest(t, handler, `{"key":"lease","value":"new-owner","if_absent":true}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"version":1`)
+}
+
+// TestSetValue_IfVersion tests compare-and-swap updates
+func TestSetValue_IfVersion(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+
+	rec := setValueRequest(t, handler, `{"key":"cas","value":"v1"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = setValueRequest(t, handler, `{"key":"cas","value":"v2","if_version":1}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"version":2`)
+
+	// Stale version is rejected
+	rec = setValueRequest(t, handler, `{"key":"cas","value":"v3","if_version":1}`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	assert.Contains(t, rec.Body.String(), "KV_205_VERSION_MISMATCH")
+
+	// Missing key is rejected too
+	rec = setValueRequest(t, handler, `{"key":"missing","value":"v","if_version":1}`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	stored, err := testdb.GetKeyValueDirect(ctx, pool, "cas")
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", stored)
+}
+
+// TestSetValue_InvalidCondition tests rejection of invalid write conditions
+func TestSetValue_InvalidCondition(t *testing.T) {
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+
+	rec := setValueRequest(t, handler, `{"key":"k","value":"v","if_version":1,"if_absent":true}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "KV_005_INVALID_CONDITION")
+
+	rec = setValueRequest(t, handler, `{"key":"k","value":"v","if_version":0}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "KV_005_INVALID_CONDITION")
+}
+
+// TestSetValue_ConcurrentIfAbsent tests that exactly one concurrent create-only write wins
+func TestSetValue_ConcurrentIfAbsent(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+
+	const writers = 10
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	codes := map[int]int{}
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rec := setValueRequest(t, handler, fmt.Sprintf(`{"key":"race","value":"writer-%d","if_absent":true}`, i))
+			mu.Lock()
+			codes[rec.Code]++
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, codes[http.StatusCreated])
+	assert.Equal(t, writers-1, codes[http.StatusConflict])
+}
+
+// TestDeleteValue_IfVersion tests conditional deletes
+func TestDeleteValue_IfVersion(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+	setValueRequest(t, handler, `{"key":"del-cas","value":"v1"}`)
+	setValueRequest(t, handler, `{"key":"del-cas","value":"v2"}`)
+
+	deleteWithVersion := func(key, version string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodDelete, "/api/key-value/"+key+"?if_version="+version, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("key")
+		c.SetParamValues(key)
+		assert.NoError(t, handler.DeleteValue(c))
+		return rec
+	}
+
+	rec := deleteWithVersion("del-cas", "abc")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "KV_005_INVALID_CONDITION")
+
+	rec = deleteWithVersion("del-cas", "1")
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	rec = deleteWithVersion("del-cas", "2")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = deleteWithVersion("del-cas", "2")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// TestIncrementValue_CreatesAndIncrements tests counter creation and increments
+func TestIncrementValue_CreatesAndIncrements(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+
+	rec := incrementRequest(t, handler, "counter", ``)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"value":"1"`)
+
+	rec = incrementRequest(t, handler, "counter", `{"delta":5}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"value":"6"`)
+
+	rec = incrementRequest(t, handler, "counter", `{"delta":-10}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"value":"-4"`)
+	assert.Contains(t, rec.Body.String(), `"version":3`)
+}
+
+// TestIncrementValue_NotInteger tests incrementing a non-integer value
+func TestIncrementValue_NotInteger(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	err = testdb.InsertKeyValueDirect(ctx, pool, "text", "hello")
+	assert.NoError(t, err)
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+
+	rec := incrementRequest(t, handler, "text", `{"delta":1}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "KV_206_VALUE_NOT_INTEGER")
+}
+
+// TestIncrementValue_Concurrent tests that concurrent increments are not lost
+func TestIncrementValue_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+
+	const increments = 20
+	var wg sync.WaitGroup
+	for i := 0; i < increments; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			incrementRequest(t, handler, "hits", `{"delta":1}`)
+		}()
+	}
+	wg.Wait()
+
+	stored, err := testdb.GetKeyValueDirect(ctx, pool, "hits")
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%d", increments), stored)
+}
+
+// TestIncrementValue_DatabaseError tests the error path when the database is unavailable
+func TestIncrementValue_DatabaseError(t *testing.T) {
+	closedPool, err := createClosedPool()
+	assert.NoError(t, err)
+
+	handler := NewKeyValueHandler(db.New(closedPool), config.Default().Encryption, config.KeyValue{})
+
+	rec := incrementRequest(t, handler, "counter", `{"delta":1}`)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "KV_207_FAILED_INCREMENT")
+}
+
+// ============================================================================
+// LISTING AND PREFIX TESTS
+// ============================================================================
+
+// listValuesRequest calls ListValues with the given raw query string
+func listValuesRequest(t *testing.T, handler *KeyValueHandler, query string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/key-value?"+query, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	assert.NoError(t, handler.ListValues(c))
+
+	var response map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &response)
+	return rec, response
+}
+
+// TestListValues_PrefixAndPagination tests prefix filtering and keyset pagination
+func TestListValues_PrefixAndPagination(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	for _, key := range []string{"feature.a", "feature.b", "feature.c", "other.x"} {
+		assert.NoError(t, testdb.InsertKeyValueDirect(ctx, pool, key, "v-"+key))
+	}
+	assert.NoError(t, testdb.InsertKeyValueWithExpiryDirect(ctx, pool, "feature.expired", "v", time.Now().Add(-time.Minute)))
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+
+	rec, page := listValuesRequest(t, handler, "prefix=feature.&limit=2")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	items := page["items"].([]interface{})
+	assert.Len(t, items, 2)
+	assert.Equal(t, "feature.a", items[0].(map[string]interface{})["key"])
+	assert.Equal(t, "feature.b", items[1].(map[string]interface{})["key"])
+	cursor, ok := page["next_cursor"].(string)
+	assert.True(t, ok, "First page should include next_cursor")
+
+	rec, page = listValuesRequest(t, handler, "prefix=feature.&limit=2&cursor="+cursor)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	items = page["items"].([]interface{})
+	assert.Len(t, items, 1, "Expired keys must not be listed")
+	assert.Equal(t, "feature.c", items[0].(map[string]interface{})["key"])
+	assert.NotContains(t, page, "next_cursor")
+}
+
+// TestListValues_KeysOnly tests that keys_only omits values
+func TestListValues_KeysOnly(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+	assert.NoError(t, testdb.InsertKeyValueDirect(ctx, pool, "cfg.timeout", "30"))
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+
+	rec, page := listValuesRequest(t, handler, "prefix=cfg.&keys_only=true")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	item := page["items"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "cfg.timeout", item["key"])
+	assert.NotContains(t, item, "value")
+	assert.Contains(t, item, "version")
+}
+
+// TestListValues_PrefixWithWildcards tests that LIKE wildcards in the prefix are matched literally
+func TestListValues_PrefixWithWildcards(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+	assert.NoError(t, testdb.InsertKeyValueDirect(ctx, pool, "a%b", "1"))
+	assert.NoError(t, testdb.InsertKeyValueDirect(ctx, pool, "axb", "2"))
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+
+	_, page := listValuesRequest(t, handler, "prefix=a%25")
+	items := page["items"].([]interface{})
+	assert.Len(t, items, 1)
+	assert.Equal(t, "a%b", items[0].(map[string]interface{})["key"])
+}
+
+// TestListValues_InvalidParams tests validation of list parameters
+func TestListValues_InvalidParams(t *testing.T) {
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+
+	for _, query := range []string{"limit=0", "limit=1001", "limit=abc", "cursor=!!!", "keys_only=maybe"} {
+		t.Run(query, func(t *testing.T) {
+			rec, _ := listValuesRequest(t, handler, query)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), "KV_006_INVALID_LIST_PARAMS")
+		})
+	}
+}
+
+// TestListValues_DatabaseError tests the error path when the database is unavailable
+func TestListValues_DatabaseError(t *testing.T) {
+	closedPool, err := createClosedPool()
+	assert.NoError(t, err)
+
+	handler := NewKeyValueHandler(db.New(closedPool), config.Default().Encryption, config.KeyValue{})
+
+	rec, _ := listValuesRequest(t, handler, "prefix=x")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "KV_208_FAILED_LIST")
+}
+
+// TestDeleteByPrefix tests confirmed prefix deletion
+func TestDeleteByPrefix(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	for _, key := range []string{"tmp.1", "tmp.2", "keep.1"} {
+		assert.NoError(t, testdb.InsertKeyValueDirect(ctx, pool, key, "v"))
+	}
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+
+	deleteByPrefix := func(query string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodDelete, "/api/key-value?"+query, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		assert.NoError(t, handler.DeleteByPrefix(c))
+		return rec
+	}
+
+	// Missing, mismatched or empty confirmation is rejected
+	for _, query := range []string{"prefix=tmp.", "prefix=tmp.&confirm=tmp", "prefix=&confirm="} {
+		rec := deleteByPrefix(query)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "KV_007_DELETE_NOT_CONFIRMED")
+	}
+
+	rec := deleteByPrefix("prefix=tmp.&confirm=tmp.")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"deleted":2`)
+
+	value, err := testdb.GetKeyValueDirect(ctx, pool, "keep.1")
+	assert.NoError(t, err)
+	assert.Equal(t, "v", value)
+}
+
+// TestCursorRoundTrip tests that cursors decode back to the original key
+func TestCursorRoundTrip(t *testing.T) {
+	for _, key := range []string{"feature.a", "unicode-日本", "with/slash?and=query"} {
+		decoded, err := decodeCursor(encodeCursor(key))
+		assert.NoError(t, err)
+		assert.Equal(t, key, decoded)
+	}
+}
+
+// ============================================================================
+// NAMESPACE AND ENCRYPTION TESTS
+// ============================================================================
+
+// namespacedRequest runs a key-value handler as the principal owning namespace
+func namespacedRequest(t *testing.T, namespace, method, target, jsonBody, key string, handle func(echo.Context) error) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(method, target, strings.NewReader(jsonBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req = req.WithContext(auth.ContextWithPrincipal(req.Context(), auth.Principal{ID: namespace, Namespace: namespace}))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if key != "" {
+		c.SetParamNames("key")
+		c.SetParamValues(key)
+	}
+	assert.NoError(t, handle(c))
+	return rec
+}
+
+// TestNamespaces_IsolateKeys tests that principals cannot see each other's keys
+func TestNamespaces_IsolateKeys(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+
+	rec := namespacedRequest(t, "team-a", http.MethodPost, "/api/key-value", `{"key":"shared","value":"a-value"}`, "", handler.SetValue)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	// The same key is independent in another namespace
+	rec = namespacedRequest(t, "team-b", http.MethodGet, "/api/key-value/shared", "", "shared", handler.GetValue)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = namespacedRequest(t, "team-b", http.MethodPost, "/api/key-value", `{"key":"shared","value":"b-value"}`, "", handler.SetValue)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = namespacedRequest(t, "team-a", http.MethodGet, "/api/key-value/shared", "", "shared", handler.GetValue)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"value":"a-value"`)
+
+	// Deletes and prefix deletes only affect the caller's namespace
+	rec = namespacedRequest(t, "team-b", http.MethodDelete, "/api/key-value?prefix=sh&confirm=sh", "", "", handler.DeleteByPrefix)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"deleted":1`)
+
+	rec = namespacedRequest(t, "team-a", http.MethodGet, "/api/key-value?prefix=sh", "", "", handler.ListValues)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"key":"shared"`)
+}
+
+// TestEncryption_ValuesEncryptedAtRest tests that values are stored encrypted when enabled
+func TestEncryption_ValuesEncryptedAtRest(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{EncryptValues: true})
+
+	rec := setValueRequest(t, handler, `{"key":"secret","value":"s3cr3t"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"value":"s3cr3t"`)
+
+	value, encryptedValue, err := testdb.GetStoredKeyValueDirect(ctx, pool, auth.DefaultNamespace, "secret")
+	assert.NoError(t, err)
+	assert.Empty(t, value, "Plaintext column must be empty for encrypted values")
+	assert.NotEmpty(t, encryptedValue)
+	assert.NotContains(t, string(encryptedValue), "s3cr3t")
+
+	// Counters keep working on encrypted values
+	rec = incrementRequest(t, handler, "counter", `{"delta":5}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = incrementRequest(t, handler, "counter", `{"delta":2}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"value":"7"`)
+
+	// Encrypted values stay readable after encryption is turned off
+	plainHandler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{EncryptValues: false})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/key-value/secret", nil)
+	rec = httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("key")
+	c.SetParamValues("secret")
+	assert.NoError(t, plainHandler.GetValue(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"value":"s3cr3t"`)
+}
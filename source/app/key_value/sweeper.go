@@ -0,0 +1,73 @@
+package key_value
+
+import (
+	"context"
+	"time"
+
+	db "person-service/internal/db/generated"
+	"person-service/logging"
+)
+
+const (
+	// DefaultSweepInterval is how often expired keys are removed when not configured
+	DefaultSweepInterval = time.Minute
+
+	// sweepBatchSize bounds the number of rows deleted per statement so the
+	// sweeper never holds long locks on the key_value table
+	sweepBatchSize = 500
+)
+
+// ExpirySweeper periodically deletes expired key-value entries.
+// Expired entries are already hidden from reads; the sweeper only reclaims storage.
+type ExpirySweeper struct {
+	queries  *db.Queries
+	interval time.Duration
+}
+
+// NewExpirySweeper creates a new instance of ExpirySweeper with injected queries
+func NewExpirySweeper(queries *db.Queries, interval time.Duration) *ExpirySweeper {
+	if interval <= 0 {
+		interval = DefaultSweepInterval
+	}
+	return &ExpirySweeper{
+		queries:  queries,
+		interval: interval,
+	}
+}
+
+// Run sweeps expired entries every interval until the context is cancelled
+func (s *ExpirySweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Sweep(ctx); err != nil && ctx.Err() == nil {
+				logging.Warn("Key-value expiry sweep failed", "error", err)
+			}
+		}
+	}
+}
+
+// Sweep deletes all currently expired entries in batches and returns the number removed
+func (s *ExpirySweeper) Sweep(ctx context.Context) (int64, error) {
+	var total int64
+	for {
+		deleted, err := s.queries.DeleteExpiredKeyValues(ctx, sweepBatchSize)
+		if err != nil {
+			return total, err
+		}
+		total += deleted
+		if deleted < sweepBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		logging.Debug("Swept expired key-value entries", "count", total)
+	}
+	return total, nil
+}
@@ -0,0 +1,63 @@
+package key_value
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	db "person-service/internal/db/generated"
+	"person-service/internal/testdb"
+)
+
+func TestNewExpirySweeper_DefaultInterval(t *testing.T) {
+	sweeper := NewExpirySweeper(db.New(pool), 0)
+	assert.Equal(t, DefaultSweepInterval, sweeper.interval)
+}
+
+func TestExpirySweeper_Sweep(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	err = testdb.InsertKeyValueWithExpiryDirect(ctx, pool, "expired-1", "v", time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+	err = testdb.InsertKeyValueWithExpiryDirect(ctx, pool, "expired-2", "v", time.Now().Add(-time.Second))
+	assert.NoError(t, err)
+	err = testdb.InsertKeyValueWithExpiryDirect(ctx, pool, "future", "v", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	err = testdb.InsertKeyValueDirect(ctx, pool, "forever", "v")
+	assert.NoError(t, err)
+
+	sweeper := NewExpirySweeper(db.New(pool), time.Minute)
+	deleted, err := sweeper.Sweep(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	exists, err := testdb.GetKeyValueDirect(ctx, pool, "future")
+	assert.NoError(t, err)
+	assert.Equal(t, "v", exists)
+	_, err = testdb.GetKeyValueDirect(ctx, pool, "forever")
+	assert.NoError(t, err)
+	_, err = testdb.GetKeyValueDirect(ctx, pool, "expired-1")
+	assert.Error(t, err)
+}
+
+func TestExpirySweeper_RunStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sweeper := NewExpirySweeper(db.New(pool), 10*time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		sweeper.Run(ctx)
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("sweeper did not stop after context cancellation")
+	}
+}
@@ -0,0 +1,188 @@
+package key_value
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"person-service/logging"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// watchChannel is the Postgres NOTIFY channel written by the key_value_notify trigger
+	watchChannel = "key_value_changes"
+
+	// watchReconnectDelay is how long the listener waits before reconnecting after a failure
+	watchReconnectDelay = 2 * time.Second
+)
+
+// keyValueChange is the payload of a key_value_changes notification.
+// Key is nil when the key was too long to fit in the payload.
+type keyValueChange struct {
+	Namespace string  `json:"namespace"`
+	Key       *string `json:"key"`
+}
+
+// watcher is a single subscription to changes of a key or of every key under a prefix
+type watcher struct {
+	namespace string
+	key       string
+	prefix    bool
+	notify    chan struct{}
+}
+
+// matches reports whether the change affects the keys this watcher observes
+func (w *watcher) matches(change keyValueChange) bool {
+	if w.namespace != change.Namespace {
+		return false
+	}
+	if change.Key == nil {
+		return true
+	}
+	if w.prefix {
+		return strings.HasPrefix(*change.Key, w.key)
+	}
+	return *change.Key == w.key
+}
+
+// WatchHub fans out key-value change notifications to long-polling requests.
+// A single Listen loop per process holds the LISTEN connection; watchers only
+// receive a wake-up signal and re-read the current state themselves.
+type WatchHub struct {
+	mu        sync.Mutex
+	watchers  map[*watcher]struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewWatchHub creates a new instance of WatchHub with no watchers
+func NewWatchHub() *WatchHub {
+	return &WatchHub{
+		watchers: make(map[*watcher]struct{}),
+		closed:   make(chan struct{}),
+	}
+}
+
+// Close ends every watch, current and future, so long-polling requests
+// return at once and the server can drain. Register it with
+// http.Server.RegisterOnShutdown. It is safe to call more than once.
+func (h *WatchHub) Close() {
+	h.closeOnce.Do(func() { close(h.closed) })
+}
+
+// Done returns a channel that is closed once Close has been called
+func (h *WatchHub) Done() <-chan struct{} {
+	return h.closed
+}
+
+// Subscribe registers interest in a key, or in every key starting with key when
+// prefix is true. The returned channel receives a signal after each matching
+// change; the returned function must be called to unsubscribe.
+func (h *WatchHub) Subscribe(namespace, key string, prefix bool) (<-chan struct{}, func()) {
+	w := &watcher{
+		namespace: namespace,
+		key:       key,
+		prefix:    prefix,
+		notify:    make(chan struct{}, 1),
+	}
+
+	h.mu.Lock()
+	h.watchers[w] = struct{}{}
+	h.mu.Unlock()
+
+	return w.notify, func() {
+		h.mu.Lock()
+		delete(h.watchers, w)
+		h.mu.Unlock()
+	}
+}
+
+// publish wakes every watcher affected by the change
+func (h *WatchHub) publish(change keyValueChange) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for w := range h.watchers {
+		if w.matches(change) {
+			wake(w)
+		}
+	}
+}
+
+// wakeAll wakes every watcher so it re-reads the current state,
+// used when notifications may have been missed
+func (h *WatchHub) wakeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for w := range h.watchers {
+		wake(w)
+	}
+}
+
+// wake signals a watcher without blocking; a pending signal is enough
+func wake(w *watcher) {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Listen receives key-value change notifications from Postgres until the
+// context is cancelled, reconnecting after failures
+func (h *WatchHub) Listen(ctx context.Context, pool *pgxpool.Pool) {
+	for {
+		err := h.listen(ctx, pool)
+		if ctx.Err() != nil {
+			return
+		}
+		logging.Warn("Key-value watch listener disconnected, reconnecting", "error", err)
+
+		// Changes may have been missed while disconnected
+		h.wakeAll()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchReconnectDelay):
+		}
+	}
+}
+
+// listen holds a dedicated connection on the watch channel and dispatches notifications
+func (h *WatchHub) listen(ctx context.Context, pool *pgxpool.Pool) error {
+	pooled, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Take the connection out of the pool so LISTEN state never leaks to other queries
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+watchChannel); err != nil {
+		return err
+	}
+	logging.Debug("Key-value watch listener connected")
+
+	// Changes made before LISTEN took effect are not delivered
+	h.wakeAll()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var change keyValueChange
+		if err := json.Unmarshal([]byte(notification.Payload), &change); err != nil {
+			logging.Warn("Ignoring malformed key-value change notification", "error", err)
+			continue
+		}
+		h.publish(change)
+	}
+}
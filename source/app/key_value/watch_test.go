@@ -0,0 +1,241 @@
+package key_value
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"person-service/config"
+	db "person-service/internal/db/generated"
+	"person-service/internal/testdb"
+)
+
+func TestWatchHub_PublishMatchesKeyAndPrefix(t *testing.T) {
+	hub := NewWatchHub()
+
+	keyUpdates, unsubscribeKey := hub.Subscribe("default", "flags.a", false)
+	defer unsubscribeKey()
+	prefixUpdates, unsubscribePrefix := hub.Subscribe("default", "flags.", true)
+	defer unsubscribePrefix()
+	otherUpdates, unsubscribeOther := hub.Subscribe("team-b", "flags.a", false)
+	defer unsubscribeOther()
+
+	key := "flags.b"
+	hub.publish(keyValueChange{Namespace: "default", Key: &key})
+
+	assert.Len(t, keyUpdates, 0, "Exact watcher must ignore other keys")
+	assert.Len(t, prefixUpdates, 1, "Prefix watcher must see keys under its prefix")
+	assert.Len(t, otherUpdates, 0, "Watchers in other namespaces must not be woken")
+
+	// A change without a key wakes every watcher in the namespace
+	hub.publish(keyValueChange{Namespace: "default"})
+	assert.Len(t, keyUpdates, 1)
+}
+
+func TestWatchHub_Unsubscribe(t *testing.T) {
+	hub := NewWatchHub()
+
+	updates, unsubscribe := hub.Subscribe("default", "k", false)
+	unsubscribe()
+
+	hub.wakeAll()
+	assert.Len(t, updates, 0)
+	assert.Empty(t, hub.watchers)
+}
+
+// watchRequest calls GetValue for key with the given query string
+func watchRequest(t *testing.T, handler *KeyValueHandler, key, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/key-value/"+key+"?"+query, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("key")
+	c.SetParamValues(key)
+	assert.NoError(t, handler.GetValue(c))
+	return rec
+}
+
+// startWatchListener runs the handler's watch listener for the duration of the test
+func startWatchListener(t *testing.T, handler *KeyValueHandler) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go handler.WatchHub().Listen(ctx, pool)
+
+	// Give the listener time to issue LISTEN
+	time.Sleep(200 * time.Millisecond)
+}
+
+func TestGetValue_WatchReturnsOnChange(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+	assert.NoError(t, testdb.InsertKeyValueDirect(ctx, pool, "flag", "off"))
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+	startWatchListener(t, handler)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- watchRequest(t, handler, "flag", "wait=10s&since_version=1")
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	rec := setValueRequest(t, handler, `{"key":"flag","value":"on"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	select {
+	case rec := <-done:
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, "on", response["value"])
+		assert.Equal(t, float64(2), response["version"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch did not return after the key changed")
+	}
+}
+
+func TestGetValue_WatchTimesOutWithNotModified(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+	assert.NoError(t, testdb.InsertKeyValueDirect(ctx, pool, "quiet", "v"))
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+
+	start := time.Now()
+	rec := watchRequest(t, handler, "quiet", "wait=1s&since_version=1")
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+}
+
+func TestGetValue_SinceVersionAlreadyStale(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+	assert.NoError(t, testdb.InsertKeyValueDirect(ctx, pool, "stale", "v"))
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+
+	// A different version returns immediately without waiting
+	rec := watchRequest(t, handler, "stale", "wait=30s&since_version=7")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// since_version without wait is a conditional read
+	rec = watchRequest(t, handler, "stale", "since_version=1")
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	// A deleted key counts as a change
+	rec = watchRequest(t, handler, "missing", "wait=30s&since_version=3")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestGetValue_WatchInvalidParams(t *testing.T) {
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+
+	for _, query := range []string{"wait=abc", "wait=500ms", "wait=2m", "since_version=-1", "since_version=x"} {
+		t.Run(query, func(t *testing.T) {
+			rec := watchRequest(t, handler, "k", query)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), "KV_008_INVALID_WATCH_PARAMS")
+		})
+	}
+}
+
+func TestListValues_WatchPrefix(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+	assert.NoError(t, testdb.InsertKeyValueDirect(ctx, pool, "flags.a", "1"))
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+	startWatchListener(t, handler)
+
+	_, page := listValuesRequest(t, handler, "prefix=flags.")
+	etag, ok := page["etag"].(string)
+	assert.True(t, ok)
+
+	// An unchanged page is not modified
+	rec, _ := listValuesRequest(t, handler, "prefix=flags.&since_etag="+etag)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	done := make(chan map[string]interface{})
+	go func() {
+		_, page := listValuesRequest(t, handler, "prefix=flags.&wait=10s&since_etag="+etag)
+		done <- page
+	}()
+
+	// Changes outside the prefix do not end the watch
+	time.Sleep(200 * time.Millisecond)
+	setValueRequest(t, handler, `{"key":"other.a","value":"1"}`)
+	setValueRequest(t, handler, `{"key":"flags.b","value":"2"}`)
+
+	select {
+	case page := <-done:
+		assert.Len(t, page["items"], 2)
+		assert.NotEqual(t, etag, page["etag"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("Prefix watch did not return after a key under the prefix changed")
+	}
+}
+
+func TestWatchHub_Close(t *testing.T) {
+	hub := NewWatchHub()
+	_, unsubscribe := hub.Subscribe("default", "k", false)
+	defer unsubscribe()
+
+	hub.Close()
+	hub.Close()
+
+	select {
+	case <-hub.Done():
+	default:
+		t.Fatal("Done must be closed after Close")
+	}
+}
+
+func TestGetValue_WatchEndsOnServerShutdown(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+	assert.NoError(t, testdb.InsertKeyValueDirect(ctx, pool, "draining", "v"))
+
+	handler := NewKeyValueHandler(db.New(pool), config.Default().Encryption, config.KeyValue{})
+	e := echo.New()
+	e.GET("/api/key-value/:key", handler.GetValue)
+	server := httptest.NewServer(e)
+	defer server.Close()
+	server.Config.RegisterOnShutdown(handler.WatchHub().Close)
+
+	done := make(chan int)
+	go func() {
+		resp, err := http.Get(server.URL + "/api/key-value/draining?wait=60s&since_version=1")
+		if !assert.NoError(t, err) {
+			done <- 0
+			return
+		}
+		resp.Body.Close()
+		done <- resp.StatusCode
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	// The open watch must not hold the shutdown until the drain timeout
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	assert.NoError(t, server.Config.Shutdown(shutdownCtx))
+
+	select {
+	case code := <-done:
+		assert.Equal(t, http.StatusNotModified, code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch did not return after shutdown")
+	}
+}
@@ -0,0 +1,37 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// HTTPServer returns a component serving server on server.Addr, with TLS when
+// server.TLSConfig is set. It is ready once the listener is bound, so a port
+// already in use fails startup. Stop stops accepting connections and waits for
+// in-flight requests to complete.
+func HTTPServer(name string, server *http.Server) Component {
+	return Component{
+		Name: name,
+		Start: func(ctx context.Context, ready func()) error {
+			listener, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
+			ready()
+
+			// Certificates come from TLSConfig, so no files are passed
+			if server.TLSConfig != nil {
+				err = server.ServeTLS(listener, "", "")
+			} else {
+				err = server.Serve(listener)
+			}
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		},
+		Stop: server.Shutdown,
+	}
+}
@@ -0,0 +1,232 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	errs "person-service/errors"
+	"person-service/logging"
+)
+
+// DefaultDrainTimeout bounds shutdown when no drain timeout is configured
+const DefaultDrainTimeout = 10 * time.Second
+
+// Component is a part of the service that runs from startup until shutdown,
+// such as the HTTP server, the database pool or a background worker
+type Component struct {
+	// Name identifies the component in logs
+	Name string
+
+	// Start runs the component until ctx is cancelled or Stop is called. It
+	// calls ready once the component accepts work. An error returned before
+	// shutdown starts is fatal and shuts the service down.
+	Start func(ctx context.Context, ready func()) error
+
+	// Stop asks the component to finish its work before ctx expires. It is
+	// optional; the context passed to Start is cancelled right after it.
+	Stop func(ctx context.Context) error
+}
+
+// Manager starts components in the order they were added, waiting for each to
+// become ready before starting the next, and stops them in reverse order so
+// the HTTP server drains before the workers and the pool it depends on.
+type Manager struct {
+	drainTimeout   time.Duration
+	readinessDelay time.Duration
+	components     []Component
+	onShutdown     []func()
+}
+
+// NewManager creates a new instance of Manager. Shutdown of all components
+// must complete within drainTimeout; zero uses DefaultDrainTimeout.
+func NewManager(drainTimeout time.Duration) *Manager {
+	if drainTimeout <= 0 {
+		drainTimeout = DefaultDrainTimeout
+	}
+	return &Manager{
+		drainTimeout: drainTimeout,
+	}
+}
+
+// SetReadinessDelay sets how long shutdown waits after the shutdown hooks
+// before stopping components, so load balancers see readiness fail while the
+// listener still accepts requests. The delay is not part of the drain timeout.
+func (m *Manager) SetReadinessDelay(delay time.Duration) {
+	m.readinessDelay = delay
+}
+
+// Add registers a component. It must be called before Run.
+func (m *Manager) Add(component Component) {
+	m.components = append(m.components, component)
+}
+
+// AddWorker registers a background worker that runs until its context is
+// cancelled. It is ready as soon as it is started.
+func (m *Manager) AddWorker(name string, run func(ctx context.Context)) {
+	m.Add(Component{
+		Name: name,
+		Start: func(ctx context.Context, ready func()) error {
+			ready()
+			run(ctx)
+			return nil
+		},
+	})
+}
+
+// AddCloser registers a resource that is already open, such as a connection
+// pool, so it is closed in order on shutdown
+func (m *Manager) AddCloser(name string, close func(ctx context.Context) error) {
+	m.Add(Component{
+		Name: name,
+		Start: func(ctx context.Context, ready func()) error {
+			ready()
+			<-ctx.Done()
+			return nil
+		},
+		Stop: close,
+	})
+}
+
+// OnShutdown registers a hook that runs when shutdown starts, before any
+// component is stopped
+func (m *Manager) OnShutdown(hook func()) {
+	m.onShutdown = append(m.onShutdown, hook)
+}
+
+// running tracks a started component
+type running struct {
+	component Component
+	cancel    context.CancelFunc
+	ready     chan struct{}
+	done      chan struct{}
+	err       error
+	fatal     bool
+}
+
+// Run starts the components and blocks until ctx is cancelled or a component
+// fails, then stops them. Failures are logged; the returned error is non-nil
+// when a component failed or did not stop within the drain timeout.
+func (m *Manager) Run(ctx context.Context) error {
+	var stopping atomic.Bool
+	failures := make(chan error, len(m.components))
+
+	var started []*running
+	var cause error
+	for _, component := range m.components {
+		r := m.start(component, &stopping, failures)
+		started = append(started, r)
+
+		select {
+		case <-r.ready:
+		case <-r.done:
+			// Components may finish without waiting for shutdown
+			if r.err != nil {
+				cause = fmt.Errorf("%s: %w", component.Name, r.err)
+			}
+		case cause = <-failures:
+		case <-ctx.Done():
+		}
+		if cause != nil || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if cause == nil && ctx.Err() == nil {
+		logging.Info("Service ready", "components", len(started))
+		select {
+		case cause = <-failures:
+		case <-ctx.Done():
+		}
+	}
+
+	if cause != nil {
+		logging.Error("Component failed, shutting down",
+			"error", cause,
+			"error_code", errs.ErrFailedStartServer)
+	}
+	stopping.Store(true)
+	if err := m.shutdown(started); err != nil {
+		return errors.Join(cause, err)
+	}
+	return cause
+}
+
+// start runs component in its own goroutine with a context cancelled on stop
+func (m *Manager) start(component Component, stopping *atomic.Bool, failures chan<- error) *running {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &running{
+		component: component,
+		cancel:    cancel,
+		ready:     make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	var readyOnce sync.Once
+	ready := func() {
+		readyOnce.Do(func() { close(r.ready) })
+	}
+
+	logging.Debug("Starting component", "component", component.Name)
+	go func() {
+		defer close(r.done)
+		r.err = component.Start(ctx, ready)
+		if r.err != nil && !stopping.Load() {
+			r.fatal = true
+			failures <- fmt.Errorf("%s: %w", component.Name, r.err)
+		}
+	}()
+	return r
+}
+
+// shutdown runs the shutdown hooks, waits for the readiness delay and stops
+// started components in reverse order, sharing one drain timeout between them
+func (m *Manager) shutdown(started []*running) error {
+	logging.Info("Shutting down", "drain_timeout", m.drainTimeout)
+	for _, hook := range m.onShutdown {
+		hook()
+	}
+	if m.readinessDelay > 0 {
+		logging.Info("Waiting for load balancers to stop routing", "readiness_delay", m.readinessDelay)
+		time.Sleep(m.readinessDelay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.drainTimeout)
+	defer cancel()
+
+	var failed []error
+	for i := len(started) - 1; i >= 0; i-- {
+		r := started[i]
+		name := r.component.Name
+
+		if r.component.Stop != nil {
+			if err := r.component.Stop(ctx); err != nil {
+				failed = append(failed, m.stopFailed(name, err))
+			}
+		}
+		r.cancel()
+
+		select {
+		case <-r.done:
+			if r.err != nil && !r.fatal && !errors.Is(r.err, context.Canceled) {
+				failed = append(failed, m.stopFailed(name, r.err))
+			}
+		case <-ctx.Done():
+			failed = append(failed, m.stopFailed(name, fmt.Errorf("did not stop within %s", m.drainTimeout)))
+		}
+		logging.Debug("Stopped component", "component", name)
+	}
+	return errors.Join(failed...)
+}
+
+// stopFailed logs that component name failed to stop cleanly
+func (m *Manager) stopFailed(name string, err error) error {
+	logging.Error("Component failed to stop",
+		"component", name,
+		"error", err,
+		"error_code", errs.ErrFailedShutdownServer)
+	return fmt.Errorf("%s: %w", name, err)
+}
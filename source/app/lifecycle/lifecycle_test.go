@@ -0,0 +1,297 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder collects lifecycle events from several goroutines
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.events...)
+}
+
+func TestManager_StartsInOrderAndStopsInReverse(t *testing.T) {
+	events := &recorder{}
+	m := NewManager(time.Second)
+
+	component := func(name string) Component {
+		return Component{
+			Name: name,
+			Start: func(ctx context.Context, ready func()) error {
+				events.add("start " + name)
+				ready()
+				<-ctx.Done()
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				events.add("stop " + name)
+				return nil
+			},
+		}
+	}
+	m.Add(component("database"))
+	m.Add(component("server"))
+	m.AddWorker("worker", func(ctx context.Context) {
+		events.add("start worker")
+		<-ctx.Done()
+		events.add("worker done")
+	})
+	m.OnShutdown(func() { events.add("shutdown hook") })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+
+	assert.Eventually(t, func() bool { return len(events.list()) == 3 }, time.Second, time.Millisecond)
+	cancel()
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, []string{
+		"start database",
+		"start server",
+		"start worker",
+		"shutdown hook",
+		"worker done",
+		"stop server",
+		"stop database",
+	}, events.list())
+}
+
+func TestManager_WaitsForReadyBeforeStartingNext(t *testing.T) {
+	events := &recorder{}
+	m := NewManager(time.Second)
+	m.Add(Component{
+		Name: "slow",
+		Start: func(ctx context.Context, ready func()) error {
+			time.Sleep(20 * time.Millisecond)
+			events.add("slow ready")
+			ready()
+			<-ctx.Done()
+			return nil
+		},
+	})
+	m.AddWorker("next", func(ctx context.Context) {
+		events.add("next started")
+		<-ctx.Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+
+	assert.Eventually(t, func() bool { return len(events.list()) == 2 }, time.Second, time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, []string{"slow ready", "next started"}, events.list())
+}
+
+func TestManager_StartupFailureStopsStartedComponents(t *testing.T) {
+	var stopped bool
+	var startedAfter bool
+	m := NewManager(time.Second)
+	m.AddCloser("database", func(ctx context.Context) error {
+		stopped = true
+		return nil
+	})
+	m.Add(Component{
+		Name: "server",
+		Start: func(ctx context.Context, ready func()) error {
+			return errors.New("address in use")
+		},
+	})
+	m.AddWorker("worker", func(ctx context.Context) {
+		startedAfter = true
+	})
+
+	err := m.Run(context.Background())
+
+	assert.ErrorContains(t, err, "server: address in use")
+	assert.True(t, stopped, "components started before the failure must be stopped")
+	assert.False(t, startedAfter, "components after the failure must not start")
+}
+
+func TestManager_FatalErrorShutsDown(t *testing.T) {
+	var stopped bool
+	m := NewManager(time.Second)
+	m.AddCloser("database", func(ctx context.Context) error {
+		stopped = true
+		return nil
+	})
+	m.Add(Component{
+		Name: "server",
+		Start: func(ctx context.Context, ready func()) error {
+			ready()
+			time.Sleep(10 * time.Millisecond)
+			return errors.New("listener closed")
+		},
+	})
+
+	done := make(chan error)
+	go func() { done <- m.Run(context.Background()) }()
+
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "server: listener closed")
+		assert.True(t, stopped)
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after a component failed")
+	}
+}
+
+func TestManager_DrainTimeout(t *testing.T) {
+	m := NewManager(20 * time.Millisecond)
+	m.AddWorker("stuck", func(ctx context.Context) {
+		// Ignores cancellation for longer than the drain timeout
+		time.Sleep(time.Second)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "stuck: did not stop within")
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Run did not return after the drain timeout")
+	}
+}
+
+func TestManager_StopError(t *testing.T) {
+	m := NewManager(time.Second)
+	m.AddCloser("tracing", func(ctx context.Context) error {
+		return errors.New("export failed")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := m.Run(ctx)
+	assert.ErrorContains(t, err, "tracing: export failed")
+}
+
+func TestManager_ReadinessDelay(t *testing.T) {
+	m := NewManager(time.Second)
+	m.SetReadinessDelay(100 * time.Millisecond)
+
+	var hookAt, stopAt time.Time
+	m.Add(Component{
+		Name: "server",
+		Start: func(ctx context.Context, ready func()) error {
+			ready()
+			<-ctx.Done()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			stopAt = time.Now()
+			return nil
+		},
+	})
+	m.OnShutdown(func() { hookAt = time.Now() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.NoError(t, m.Run(ctx))
+	assert.GreaterOrEqual(t, stopAt.Sub(hookAt), 100*time.Millisecond,
+		"Components are stopped only after the readiness delay")
+}
+
+func TestNewManager_DefaultDrainTimeout(t *testing.T) {
+	assert.Equal(t, DefaultDrainTimeout, NewManager(0).drainTimeout)
+}
+
+// freeAddr returns a local address nothing listens on
+func freeAddr(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+	return addr
+}
+
+func TestHTTPServer_DrainsInFlightRequests(t *testing.T) {
+	addr := freeAddr(t)
+	inFlight := make(chan struct{})
+	server := &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(inFlight)
+			time.Sleep(50 * time.Millisecond)
+			w.Write([]byte("done"))
+		}),
+	}
+	m := NewManager(time.Second)
+	m.Add(HTTPServer("http", server))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+
+	// The listener is bound once the server is ready
+	var resp *http.Response
+	var reqErr error
+	requestDone := make(chan struct{})
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	}, time.Second, time.Millisecond)
+	go func() {
+		defer close(requestDone)
+		resp, reqErr = http.Get("http://" + addr)
+	}()
+
+	<-inFlight
+	cancel()
+	<-requestDone
+
+	assert.NoError(t, reqErr)
+	if assert.NotNil(t, resp) {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "done", string(body))
+	}
+	assert.NoError(t, <-done)
+
+	// No longer accepting connections
+	_, err := net.Dial("tcp", addr)
+	assert.Error(t, err)
+}
+
+func TestHTTPServer_AddressInUse(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	m := NewManager(time.Second)
+	m.Add(HTTPServer("http", &http.Server{Addr: listener.Addr().String()}))
+
+	err = m.Run(context.Background())
+	assert.ErrorContains(t, err, "http:")
+}
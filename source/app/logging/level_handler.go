@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"net/http"
+
+	"person-service/auth"
+	errs "person-service/errors"
+
+	"github.com/labstack/echo/v4"
+)
+
+// LevelRequest represents the request body for changing the log level
+type LevelRequest struct {
+	Level string `json:"level"`
+}
+
+// LevelHandler handles reading and changing the log level at runtime
+type LevelHandler struct{}
+
+// NewLevelHandler creates a new instance of LevelHandler
+func NewLevelHandler() *LevelHandler {
+	return &LevelHandler{}
+}
+
+// GetLevel handles GET /admin/log-level - returns the minimum level logged
+func (h *LevelHandler) GetLevel(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"level": Level().String(),
+	})
+}
+
+// SetLevel handles PUT /admin/log-level - changes the minimum level logged.
+// The change applies to this instance only and lasts until it restarts.
+func (h *LevelHandler) SetLevel(c echo.Context) error {
+	var req LevelRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+			Message:   "Invalid request body",
+			ErrorCode: errs.ErrInvalidLogLevel,
+		})
+	}
+
+	newLevel, err := ParseLevel(req.Level)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+			Message:   "level must be one of debug, info, warn or error",
+			ErrorCode: errs.ErrInvalidLogLevel,
+		})
+	}
+
+	ctx := c.Request().Context()
+	previous := Level()
+	SetLevel(newLevel)
+
+	// Logged at warn so the change is visible at every level
+	principal, _ := auth.PrincipalFromContext(ctx)
+	WarnContext(ctx, "Log level changed",
+		"previous_level", previous.String(),
+		"level", newLevel.String(),
+		"principal_id", principal.ID)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"level": newLevel.String(),
+	})
+}
@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelHandler(t *testing.T) {
+	Init()
+	defer SetLevel(slog.LevelInfo)
+
+	e := echo.New()
+	handler := NewLevelHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/log-level", nil)
+	rec := httptest.NewRecorder()
+	assert.NoError(t, handler.GetLevel(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"INFO"}`, rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(`{"level":"debug"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	assert.NoError(t, handler.SetLevel(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"DEBUG"}`, rec.Body.String())
+	assert.Equal(t, slog.LevelDebug, Level())
+}
+
+func TestLevelHandler_InvalidLevel(t *testing.T) {
+	Init()
+	defer SetLevel(slog.LevelInfo)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(`{"level":"verbose"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	assert.NoError(t, NewLevelHandler().SetLevel(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "LOG_001_INVALID_LOG_LEVEL")
+	assert.Equal(t, slog.LevelInfo, Level())
+}
@@ -6,11 +6,21 @@ import (
 	"log/slog"
 	"os"
 	"person-service/tracing"
+	"strings"
 )
 
 const (
 	// GCPTraceField is the field name GCP Cloud Logging uses for trace correlation
 	GCPTraceField = "logging.googleapis.com/trace"
+
+	// GCPSpanIDField is the field name GCP Cloud Logging uses for span correlation
+	GCPSpanIDField = "logging.googleapis.com/spanId"
+
+	// GCPTraceSampledField is the field name GCP Cloud Logging uses for the sampling decision
+	GCPTraceSampledField = "logging.googleapis.com/trace_sampled"
+
+	// GCPSeverityField is the field name GCP Cloud Logging reads the log level from
+	GCPSeverityField = "severity"
 )
 
 var (
@@ -19,20 +29,104 @@ var (
 
 	// gcpProjectID is the GCP project ID for trace correlation
 	gcpProjectID string
+
+	// level is the minimum level logged; it can change at runtime
+	level = new(slog.LevelVar)
 )
 
-// Init initializes the global structured logger with JSON output.
-// Should be called once at application startup.
+// Options configures the global structured logger
+type Options struct {
+	// Level is the minimum level logged
+	Level slog.Level
+
+	// Format is json, the default, with GCP severity names, or text
+	Format string
+
+	// RedactKeys are attribute keys redacted in addition to DefaultSensitiveKeys
+	RedactKeys []string
+
+	// GCPProjectID formats trace IDs for GCP Cloud Logging correlation
+	GCPProjectID string
+}
+
+// Init initializes the global structured logger with the default options:
+// JSON at info level with the built-in sensitive keys redacted. Should be
+// called once at application startup, before the configuration is loaded.
 func Init() {
-	gcpProjectID = os.Getenv("GCP_PROJECT_ID")
+	// The default options are always valid
+	_ = Configure(Options{Level: slog.LevelInfo})
+}
 
-	// Create JSON handler for GCP Cloud Logging compatibility
-	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	})
+// Configure replaces the global structured logger. Values of the sensitive
+// keys are redacted, as are emails and phone numbers.
+func Configure(opts Options) error {
+	var handler slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "text":
+		handler = slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+			Level: level,
+		})
+	case "", "json":
+		// Create JSON handler for GCP Cloud Logging compatibility
+		handler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+			Level:       level,
+			ReplaceAttr: gcpSeverity,
+		})
+	default:
+		return fmt.Errorf("unknown log format %q", opts.Format)
+	}
 
-	defaultLogger = slog.New(handler)
+	gcpProjectID = opts.GCPProjectID
+	level.Set(opts.Level)
+	keys := append(append([]string{}, DefaultSensitiveKeys...), opts.RedactKeys...)
+	defaultLogger = slog.New(NewRedactingHandler(handler, keys))
 	slog.SetDefault(defaultLogger)
+	return nil
+}
+
+// ParseLevel parses a log level name such as debug, info, warn or error
+func ParseLevel(name string) (slog.Level, error) {
+	var parsed slog.Level
+	if err := parsed.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q: use debug, info, warn or error", name)
+	}
+	return parsed, nil
+}
+
+// Level returns the minimum level logged
+func Level() slog.Level {
+	return level.Level()
+}
+
+// SetLevel changes the minimum level logged by loggers created with Init or Configure
+func SetLevel(newLevel slog.Level) {
+	level.Set(newLevel)
+}
+
+// gcpSeverity renames the level of JSON entries to the severity field GCP
+// Cloud Logging reads, with its level names
+func gcpSeverity(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 || a.Key != slog.LevelKey {
+		return a
+	}
+
+	entryLevel, _ := a.Value.Any().(slog.Level)
+	severity := "DEBUG"
+	switch {
+	case entryLevel >= slog.LevelError:
+		severity = "ERROR"
+	case entryLevel >= slog.LevelWarn:
+		severity = "WARNING"
+	case entryLevel >= slog.LevelInfo:
+		severity = "INFO"
+	}
+	return slog.String(GCPSeverityField, severity)
+}
+
+// SetLogger replaces the default logger instance, for example to capture
+// output in tests.
+func SetLogger(logger *slog.Logger) {
+	defaultLogger = logger
 }
 
 // Logger returns the default logger instance.
@@ -44,7 +138,8 @@ func Logger() *slog.Logger {
 }
 
 // LoggerFromContext returns a logger with the trace ID from context attached.
-// The trace ID is formatted for GCP Cloud Logging correlation.
+// The trace ID is formatted for GCP Cloud Logging correlation; the ID and
+// sampling decision of the active span are attached too when there is one.
 func LoggerFromContext(ctx context.Context) *slog.Logger {
 	logger := Logger()
 
@@ -62,6 +157,11 @@ func LoggerFromContext(ctx context.Context) *slog.Logger {
 		traceValue = traceID
 	}
 
+	if spanID := tracing.SpanIDFromContext(ctx); spanID != "" {
+		return logger.With(GCPTraceField, traceValue,
+			GCPSpanIDField, spanID,
+			GCPTraceSampledField, tracing.SampledFromContext(ctx))
+	}
 	return logger.With(GCPTraceField, traceValue)
 }
 
@@ -1,12 +1,15 @@
 package logging
 
 import (
+	"bytes"
 	"context"
+	"log/slog"
 	"os"
 	"person-service/tracing"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestInit(t *testing.T) {
@@ -19,17 +22,77 @@ func TestInit(t *testing.T) {
 	assert.NotNil(t, defaultLogger)
 }
 
-func TestInit_WithGCPProjectID(t *testing.T) {
-	os.Setenv("GCP_PROJECT_ID", "test-project")
-	defer os.Unsetenv("GCP_PROJECT_ID")
+func TestConfigure_WithGCPProjectID(t *testing.T) {
+	defer func() { gcpProjectID = "" }()
 
 	defaultLogger = nil
-	Init()
+	err := Configure(Options{GCPProjectID: "test-project"})
 
+	assert.NoError(t, err)
 	assert.NotNil(t, defaultLogger)
 	assert.Equal(t, "test-project", gcpProjectID)
 }
 
+func TestConfigure_WithLevel(t *testing.T) {
+	defer SetLevel(slog.LevelInfo)
+
+	defaultLogger = nil
+	err := Configure(Options{Level: slog.LevelDebug, Format: "text"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, Level())
+	assert.True(t, Logger().Enabled(context.Background(), slog.LevelDebug))
+}
+
+func TestConfigure_InvalidFormat(t *testing.T) {
+	Init()
+	previous := defaultLogger
+
+	err := Configure(Options{Level: slog.LevelDebug, Format: "xml"})
+
+	// The logger in use is kept
+	assert.ErrorContains(t, err, "xml")
+	assert.Same(t, previous, defaultLogger)
+	assert.Equal(t, slog.LevelInfo, Level())
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, expected := range map[string]slog.Level{
+		"debug": slog.LevelDebug,
+		"INFO":  slog.LevelInfo,
+		"warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+	} {
+		parsed, err := ParseLevel(name)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, parsed)
+	}
+
+	_, err := ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestGCPSeverity(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		Level:       slog.LevelDebug,
+		ReplaceAttr: gcpSeverity,
+	}))
+
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn", slog.Group("request", "level", "kept"))
+	logger.Error("error")
+
+	out := buf.String()
+	assert.Contains(t, out, `"severity":"DEBUG"`)
+	assert.Contains(t, out, `"severity":"INFO"`)
+	assert.Contains(t, out, `"severity":"WARNING"`)
+	assert.Contains(t, out, `"severity":"ERROR"`)
+	assert.Contains(t, out, `"request":{"level":"kept"}`)
+	assert.NotContains(t, out, `"level":"INFO"`)
+}
+
 func TestLogger_InitializesOnNil(t *testing.T) {
 	defaultLogger = nil
 
@@ -66,10 +129,9 @@ func TestLoggerFromContext_WithTraceID(t *testing.T) {
 }
 
 func TestLoggerFromContext_WithGCPProject(t *testing.T) {
-	os.Setenv("GCP_PROJECT_ID", "my-project")
-	defer os.Unsetenv("GCP_PROJECT_ID")
 	defaultLogger = nil
-	Init()
+	assert.NoError(t, Configure(Options{GCPProjectID: "my-project"}))
+	defer func() { gcpProjectID = "" }()
 
 	ctx := tracing.ContextWithTraceID(context.Background(), "test-trace-456")
 
@@ -77,6 +139,22 @@ func TestLoggerFromContext_WithGCPProject(t *testing.T) {
 	assert.NotNil(t, logger)
 }
 
+func TestLoggerFromContext_WithSpan(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger = slog.New(slog.NewJSONHandler(&buf, nil))
+	defer func() { defaultLogger = nil }()
+	gcpProjectID = ""
+
+	spanContext := tracing.ParseGCPSpanContext("105445aa7843bc8bf206b12000100000/1;o=1")
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), spanContext)
+	ctx = tracing.ContextWithTraceID(ctx, spanContext.TraceID().String())
+
+	LoggerFromContext(ctx).Info("test")
+	assert.Contains(t, buf.String(), `"logging.googleapis.com/trace":"105445aa7843bc8bf206b12000100000"`)
+	assert.Contains(t, buf.String(), `"logging.googleapis.com/spanId":"0000000000000001"`)
+	assert.Contains(t, buf.String(), `"logging.googleapis.com/trace_sampled":true`)
+}
+
 func TestInfo(t *testing.T) {
 	Init()
 	// Should not panic
@@ -0,0 +1,160 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// RedactedValue replaces the values of sensitive keys
+const RedactedValue = "[REDACTED]"
+
+// Replacements of personal data found in logged text
+const (
+	RedactedEmail = "[REDACTED_EMAIL]"
+	RedactedPhone = "[REDACTED_PHONE]"
+)
+
+// DefaultSensitiveKeys are attribute keys whose values are always redacted
+var DefaultSensitiveKeys = []string{
+	"attribute_value",
+	"password",
+	"secret",
+	"token",
+	"api_key",
+	"authorization",
+	"enc_key",
+	"encryption_key",
+}
+
+var (
+	// emailPattern matches email addresses
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+
+	// phonePattern matches phone numbers: a + country code followed by digit
+	// groups, an area code in parentheses, or three digit groups separated by
+	// spaces or dashes. Bare digit runs are not matched so numeric IDs are
+	// kept, and dots are not separators so IP addresses are kept.
+	phonePattern = regexp.MustCompile(`\+\d{1,3}(?:[\s-]?\(\d{1,4}\))?(?:[\s-]?\d{2,4}){2,4}\b|` +
+		`\(\d{1,4}\)[\s-]?\d{3,4}[\s-]?\d{3,4}\b|` +
+		`\b\d{2,4}[\s-]\d{3,4}[\s-]\d{3,4}\b`)
+)
+
+// Scrub replaces emails and phone numbers in s
+func Scrub(s string) string {
+	s = emailPattern.ReplaceAllString(s, RedactedEmail)
+	return scrubPhones(s)
+}
+
+// scrubPhones replaces the phone numbers in s. Matches joined to a longer
+// token by a dash, such as the groups of a UUID, are left alone.
+func scrubPhones(s string) string {
+	var scrubbed strings.Builder
+	last := 0
+	for _, match := range phonePattern.FindAllStringIndex(s, -1) {
+		start, end := match[0], match[1]
+		if (start > 0 && isTokenChar(s[start-1])) || (end < len(s) && isTokenChar(s[end])) {
+			continue
+		}
+		scrubbed.WriteString(s[last:start])
+		scrubbed.WriteString(RedactedPhone)
+		last = end
+	}
+	if last == 0 {
+		return s
+	}
+	scrubbed.WriteString(s[last:])
+	return scrubbed.String()
+}
+
+// isTokenChar reports whether c continues an identifier next to a match
+func isTokenChar(c byte) bool {
+	return c == '-' || c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// RedactingHandler is a slog.Handler that redacts values of sensitive keys
+// and scrubs emails and phone numbers from messages and text values before
+// passing records to the wrapped handler. Keys are matched case-insensitively,
+// also inside groups.
+type RedactingHandler struct {
+	next slog.Handler
+	keys map[string]bool
+}
+
+// NewRedactingHandler creates a new instance of RedactingHandler wrapping next
+func NewRedactingHandler(next slog.Handler, keys []string) *RedactingHandler {
+	sensitive := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		sensitive[strings.ToLower(key)] = true
+	}
+
+	return &RedactingHandler{
+		next: next,
+		keys: sensitive,
+	}
+}
+
+// Enabled implements slog.Handler
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h *RedactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, Scrub(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.redact(attr))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+// WithAttrs implements slog.Handler
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.redact(attr)
+	}
+	return &RedactingHandler{
+		next: h.next.WithAttrs(redacted),
+		keys: h.keys,
+	}
+}
+
+// WithGroup implements slog.Handler
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{
+		next: h.next.WithGroup(name),
+		keys: h.keys,
+	}
+}
+
+// redact returns attr with a sensitive value replaced and text values scrubbed
+func (h *RedactingHandler) redact(attr slog.Attr) slog.Attr {
+	if h.keys[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, RedactedValue)
+	}
+
+	attr.Value = attr.Value.Resolve()
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		attr.Value = slog.StringValue(Scrub(attr.Value.String()))
+	case slog.KindGroup:
+		group := attr.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, member := range group {
+			redacted[i] = h.redact(member)
+		}
+		attr.Value = slog.GroupValue(redacted...)
+	case slog.KindAny:
+		// Errors and other values are logged as text; keep them as they are
+		// unless the text holds personal data
+		text := fmt.Sprint(attr.Value.Any())
+		if scrubbed := Scrub(text); scrubbed != text {
+			attr.Value = slog.StringValue(scrubbed)
+		}
+	}
+	return attr
+}
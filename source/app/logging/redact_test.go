@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScrub(t *testing.T) {
+	assert.Equal(t, "contact [REDACTED_EMAIL] now", Scrub("contact jane.doe+crm@example.co.uk now"))
+	assert.Equal(t, "call [REDACTED_PHONE]", Scrub("call +1 415-555-0132"))
+	assert.Equal(t, "call [REDACTED_PHONE]", Scrub("call (030) 1234 5678"))
+	assert.Equal(t, "call [REDACTED_PHONE]", Scrub("call 06 1234 5678"))
+	assert.Equal(t, "call [REDACTED_PHONE]", Scrub("call +31612345678"))
+	assert.Equal(t, "call [REDACTED_PHONE] or [REDACTED_PHONE]", Scrub("call 415-555-0132 or +44 20 7946 0958"))
+
+	// IDs, versions and short numbers are left alone
+	for _, text := range []string{
+		"105445aa7843bc8bf206b12000100000",
+		"5b7c1f0e-1d2a-4c55-9f7a-0c1d2e3f4a5b",
+		"550e8400-e29b-41d4-a716-446655440000",
+		"12345678-1234-4234-8234-123456789012",
+		"person 12345678-1234-4234-8234-123456789012 updated",
+		"order 1234567",
+		"credential 0612345678",
+		"2026-10-18",
+		"version 42 of 1000",
+		"192.168.100.200",
+		"projects/my-project/traces/105445aa7843bc8bf206b12000100000",
+	} {
+		assert.Equal(t, text, Scrub(text))
+	}
+}
+
+func TestRedactingHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewRedactingHandler(slog.NewJSONHandler(&buf, nil), []string{"SSN", "password"}))
+
+	logger.With("password", "hunter2").WithGroup("request").Info("Lookup for jane@example.com",
+		"ssn", "123-45-6789",
+		"note", "reach me at +44 20 7946 0958",
+		"error", errors.New("duplicate key jane@example.com"),
+		"keys", []string{"email"},
+		slog.Group("attribute", "SSN", "987-65-4321", "key", "ssn"),
+		"count", 3)
+
+	out := buf.String()
+	assert.NotContains(t, out, "hunter2")
+	assert.NotContains(t, out, "123-45-6789")
+	assert.NotContains(t, out, "987-65-4321")
+	assert.NotContains(t, out, "jane@example.com")
+	assert.NotContains(t, out, "7946")
+	assert.Contains(t, out, `"msg":"Lookup for [REDACTED_EMAIL]"`)
+	assert.Contains(t, out, `"password":"[REDACTED]"`)
+	assert.Contains(t, out, `"ssn":"[REDACTED]"`)
+	assert.Contains(t, out, `"error":"duplicate key [REDACTED_EMAIL]"`)
+	assert.Contains(t, out, `"keys":["email"]`)
+	assert.Contains(t, out, `"key":"ssn"`)
+	assert.Contains(t, out, `"count":3`)
+}
+
+func TestRedactingHandler_Enabled(t *testing.T) {
+	handler := NewRedactingHandler(slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelWarn}), nil)
+
+	assert.False(t, handler.Enabled(context.Background(), slog.LevelInfo))
+	assert.True(t, handler.Enabled(context.Background(), slog.LevelError))
+}
+
+func TestConfigure_RedactKeys(t *testing.T) {
+	defer Init()
+
+	assert.NoError(t, Configure(Options{RedactKeys: []string{"SSN", "date_of_birth"}}))
+
+	handler, ok := defaultLogger.Handler().(*RedactingHandler)
+	if assert.True(t, ok) {
+		assert.True(t, handler.keys["password"])
+		assert.True(t, handler.keys["ssn"])
+		assert.True(t, handler.keys["date_of_birth"])
+		assert.Len(t, handler.keys, len(DefaultSensitiveKeys)+2)
+	}
+}
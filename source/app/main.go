@@ -2,25 +2,42 @@ package main
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
 
+	"person-service/auth"
+	"person-service/config"
+	"person-service/credentials"
 	errs "person-service/errors"
 	health "person-service/healthcheck"
 	dbpkg "person-service/internal/db"
 	db "person-service/internal/db/generated"
 	key_value "person-service/key_value"
+	"person-service/lifecycle"
 	"person-service/logging"
+	"person-service/metrics"
 	"person-service/middleware"
+	"person-service/mtls"
+	"person-service/oidc"
 	person "person-service/person"
 	person_attributes "person-service/person_attributes"
+	"person-service/policy"
+	"person-service/purpose"
+	"person-service/ratelimit"
+	"person-service/redaction"
+	"person-service/signing"
+	"person-service/tenancy"
+	"person-service/tracing"
 )
 
 // Version is set at build time via ldflags
@@ -30,26 +47,66 @@ var Version = "dev"
 // MAIN - Application Entry Point
 // ============================================================================
 
-func setupDb(port string) (*db.Queries, *pgxpool.Pool) {
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		logging.Error("DATABASE_URL environment variable is not set",
-			"error_code", errs.ErrDatabaseURLNotSet)
-		os.Exit(1)
-	}
+// setupDb connects to the database, prepares the schema and returns the
+// queries and the pool. With ExpectSchemaVersion set it only checks the schema
+// version; otherwise it runs the migrations unless MigrateOnStart is turned
+// off. It exits when the database cannot be reached, a migration fails or the
+// schema is dirty or at another version than expected.
+func setupDb(cfg config.Database) (*db.Queries, *pgxpool.Pool) {
+	pool := connectDb(cfg)
 
-	// Validate port
-	if _, err := strconv.Atoi(port); err != nil {
-		logging.Error("Invalid PORT value",
+	// Waiting for the migration lock is bounded as well
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.MigrationLockTimeout)
+	defer cancel()
+
+	// Prepare the schema before creating queries
+	var err error
+	switch {
+	case cfg.ExpectSchemaVersion > 0:
+		logging.Info("Checking schema version; migrations are applied separately",
+			"expected", cfg.ExpectSchemaVersion)
+		err = dbpkg.CheckSchemaVersion(ctx, pool, uint(cfg.ExpectSchemaVersion))
+	case cfg.MigrateOnStart:
+		err = dbpkg.RunMigrations(ctx, cfg.URL)
+	default:
+		logging.Info("Skipping migrations on start; apply them with the migrate command")
+		// A dirty schema is refused in every mode
+		if version, dirty, versionErr := dbpkg.CurrentVersion(ctx, pool); versionErr == nil && dirty {
+			err = &dbpkg.DirtyError{Version: version}
+		}
+	}
+	if err != nil {
+		logging.Error("Database schema is not ready",
 			"error", err,
-			"error_code", errs.ErrInvalidPort)
+			"error_code", schemaErrorCode(err))
 		os.Exit(1)
 	}
 
+	queries := db.New(pool)
+	return queries, pool
+}
+
+// schemaErrorCode returns the error code of a setupDb schema error
+func schemaErrorCode(err error) string {
+	var dirtyErr *dbpkg.DirtyError
+	var mismatchErr *dbpkg.VersionMismatchError
+	switch {
+	case errors.As(err, &dirtyErr):
+		return errs.ErrMigrationDirty
+	case errors.As(err, &mismatchErr):
+		return errs.ErrSchemaVersionMismatch
+	default:
+		return errs.ErrMigrationFailed
+	}
+}
+
+// connectDb creates the connection pool and pings the database. It exits when
+// the database cannot be reached.
+func connectDb(cfg config.Database) *pgxpool.Pool {
 	logging.Info("Connecting to database")
 
 	// Configure connection pool
-	config, err := pgxpool.ParseConfig(databaseURL)
+	poolConfig, err := pgxpool.ParseConfig(cfg.URL)
 	if err != nil {
 		logging.Error("Failed to parse database URL",
 			"error", err,
@@ -58,17 +115,20 @@ func setupDb(port string) (*db.Queries, *pgxpool.Pool) {
 	}
 
 	// Set connection pool parameters
-	config.MaxConns = 25
-	config.MinConns = 5
-	config.MaxConnLifetime = 5 * time.Minute
-	config.MaxConnIdleTime = 1 * time.Minute
-	config.HealthCheckPeriod = 1 * time.Minute
+	poolConfig.MaxConns = cfg.MaxConns
+	poolConfig.MinConns = cfg.MinConns
+	poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+
+	// Trace every query as a child span of the request that runs it
+	poolConfig.ConnConfig.Tracer = tracing.NewQueryTracer()
 
 	// Create connection pool with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
 	defer cancel()
 
-	pool, err := pgxpool.NewWithConfig(ctx, config)
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		logging.Error("Failed to create connection pool",
 			"error", err,
@@ -87,39 +147,357 @@ func setupDb(port string) (*db.Queries, *pgxpool.Pool) {
 	}
 
 	logging.Debug("Database ping successful")
+	return pool
+}
+
+// setupTokenVerifier builds the JWT verifier. It returns nil when JWT
+// authentication is not configured and exits when the configuration is invalid.
+func setupTokenVerifier(cfg config.JWT) middleware.TokenVerifier {
+	if cfg.JWKS == "" {
+		return nil
+	}
 
-	// Run migrations before creating queries
-	// Convert postgres:// to pgx5:// for golang-migrate compatibility
-	migrateURL := databaseURL
-	if len(migrateURL) >= 11 && migrateURL[:11] == "postgres://" {
-		migrateURL = "pgx5://" + migrateURL[11:]
-	} else if len(migrateURL) >= 13 && migrateURL[:13] == "postgresql://" {
-		migrateURL = "pgx5://" + migrateURL[13:]
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	verifier, err := oidc.NewVerifier(ctx, oidc.Config{
+		JWKS:           cfg.JWKS,
+		Issuer:         cfg.Issuer,
+		Audience:       cfg.Audience,
+		ScopesClaim:    cfg.ScopesClaim,
+		ScopePrefix:    cfg.ScopePrefix,
+		NamespaceClaim: cfg.NamespaceClaim,
+		TenantClaim:    cfg.TenantClaim,
+		ClockSkew:      cfg.ClockSkew,
+	})
+	if err != nil {
+		logging.Error("Failed to set up JWT authentication",
+			"error", err,
+			"error_code", errs.ErrInvalidJWTConfig)
+		os.Exit(1)
 	}
 
-	if err := dbpkg.RunMigrations(ctx, pool, migrateURL); err != nil {
-		logging.Error("Database migration failed",
-			"error", err)
+	logging.Info("JWT authentication enabled")
+	return verifier
+}
+
+// setupTLS loads the TLS certificates. It returns nil when TLS is not
+// configured and exits when the files cannot be loaded.
+func setupTLS(cfg config.TLS) *mtls.Reloader {
+	if !cfg.Enabled() {
+		return nil
+	}
+
+	reloader, err := mtls.NewReloader(mtls.Config{
+		CertFile:          cfg.CertFile,
+		KeyFile:           cfg.KeyFile,
+		ClientCAFile:      cfg.ClientCAFile,
+		IdentityFile:      cfg.IdentityFile,
+		RequireClientCert: cfg.RequireClientCert(),
+	})
+	if err != nil {
+		logging.Error("Failed to set up TLS",
+			"error", err,
+			"error_code", errs.ErrInvalidTLSConfig)
 		os.Exit(1)
 	}
 
-	queries := db.New(pool)
-	return queries, pool
+	logging.Info("TLS enabled",
+		"client_auth", cfg.ClientCAFile != "",
+		"require_client_cert", cfg.RequireClientCert())
+	return reloader
+}
+
+// signableRoutes are the route group names accepted in REQUEST_SIGNING_ROUTES
+var signableRoutes = []string{"admin", "key-value", "person", "attributes"}
+
+// setupRequestSigning builds the request signature verifier and returns it
+// with the set of route groups that must be signed. Signing is disabled when
+// no route group is listed; invalid settings stop the application.
+func setupRequestSigning(lc *lifecycle.Manager, cfg config.Signing, queries *db.Queries) (*signing.Verifier, map[string]bool) {
+	signedRoutes := make(map[string]bool)
+	for _, name := range cfg.Routes {
+		if !slices.Contains(signableRoutes, name) {
+			logging.Error("Unknown route group in REQUEST_SIGNING_ROUTES",
+				"route_group", name,
+				"error_code", errs.ErrInvalidSigningConfig)
+			os.Exit(1)
+		}
+		signedRoutes[name] = true
+	}
+	if len(signedRoutes) == 0 {
+		return nil, signedRoutes
+	}
+
+	secrets, err := signing.ParseSecrets(cfg.Keys)
+	if err != nil {
+		logging.Error("Invalid REQUEST_SIGNING_KEYS",
+			"error", err,
+			"error_code", errs.ErrInvalidSigningConfig)
+		os.Exit(1)
+	}
+	principals, err := signing.ParsePrincipals(cfg.Principals, secrets)
+	if err != nil {
+		logging.Error("Invalid REQUEST_SIGNING_PRINCIPALS",
+			"error", err,
+			"error_code", errs.ErrInvalidSigningConfig)
+		os.Exit(1)
+	}
+
+	// Nonces live in Postgres by default so replays are caught across instances
+	var nonces signing.NonceStore
+	if cfg.NonceStore == "memory" {
+		nonces = signing.NewMemoryNonceStore()
+	} else {
+		postgresNonces := signing.NewPostgresNonceStore(queries)
+		lc.AddWorker("nonce-sweeper", func(ctx context.Context) {
+			postgresNonces.Run(ctx, cfg.Window)
+		})
+		nonces = postgresNonces
+	}
+
+	logging.Info("Request signing enabled",
+		"route_groups", cfg.Routes,
+		"window", cfg.Window)
+	return signing.NewVerifier(secrets, principals, cfg.Window, nonces), signedRoutes
+}
+
+// setupRateLimiting builds the per-class rate limiter. It returns nil when no
+// limit is set; invalid limits stop the application.
+func setupRateLimiting(lc *lifecycle.Manager, cfg config.RateLimit, queries *db.Queries) *ratelimit.Limiter {
+	configured := map[ratelimit.Class]string{
+		ratelimit.ClassRead:   cfg.Read,
+		ratelimit.ClassWrite:  cfg.Write,
+		ratelimit.ClassSearch: cfg.Search,
+	}
+	limits := make(map[ratelimit.Class]ratelimit.Limit)
+	for _, class := range ratelimit.Classes {
+		raw := configured[class]
+		if raw == "" {
+			continue
+		}
+		limit, err := ratelimit.ParseLimit(raw)
+		if err != nil {
+			logging.Error("Invalid RATE_LIMIT_"+strings.ToUpper(string(class)),
+				"error", err,
+				"error_code", errs.ErrInvalidRateLimitConfig)
+			os.Exit(1)
+		}
+		limits[class] = limit
+	}
+	if len(limits) == 0 {
+		return nil
+	}
+
+	// Buckets live in memory by default; Postgres keeps limits consistent across instances
+	var backend ratelimit.Backend
+	var postgresBackend *ratelimit.PostgresBackend
+	if cfg.Backend == "postgres" {
+		postgresBackend = ratelimit.NewPostgresBackend(queries)
+		backend = postgresBackend
+	} else {
+		backend = ratelimit.NewMemoryBackend()
+	}
+
+	limiter := ratelimit.NewLimiter(backend, limits)
+	if postgresBackend != nil {
+		// Buckets idle for the longest period are full and can be dropped
+		lc.AddWorker("rate-limit-sweeper", func(ctx context.Context) {
+			postgresBackend.Run(ctx, time.Minute, limiter.MaxPeriod())
+		})
+	}
+	logging.Info("Rate limiting enabled",
+		"read", cfg.Read,
+		"write", cfg.Write,
+		"search", cfg.Search)
+	return limiter
+}
+
+// setupAttributePolicy loads the attribute access policy. It returns nil when
+// no policy file is configured and exits when the policy is invalid.
+func setupAttributePolicy(cfg config.Access) *policy.Policy {
+	if cfg.AttributePolicyFile == "" {
+		return nil
+	}
+
+	attributePolicy, err := policy.Load(cfg.AttributePolicyFile)
+	if err != nil {
+		logging.Error("Failed to load attribute policy",
+			"error", err,
+			"error_code", errs.ErrInvalidAttributePolicy)
+		os.Exit(1)
+	}
+	logging.Info("Attribute access policy enabled")
+	return attributePolicy
+}
+
+// setupRedactionProfiles loads the redaction profiles callers can select with
+// ?view= or that credentials are bound to. The built-in profiles are used when
+// no profiles file is configured; it exits when the profiles are invalid.
+func setupRedactionProfiles(cfg config.Access) *redaction.Profiles {
+	if cfg.RedactionProfilesFile == "" {
+		return redaction.Default()
+	}
+
+	profiles, err := redaction.Load(cfg.RedactionProfilesFile)
+	if err != nil {
+		logging.Error("Failed to load redaction profiles",
+			"error", err,
+			"error_code", errs.ErrInvalidRedactionProfiles)
+		os.Exit(1)
+	}
+	return profiles
+}
+
+// setupPurposeRegistry builds the registry of purposes of use callers may
+// declare. It returns nil when no purpose is configured and exits when one is invalid.
+func setupPurposeRegistry(cfg config.Access) *purpose.Registry {
+	if len(cfg.Purposes) == 0 {
+		return nil
+	}
+
+	registry, err := purpose.NewRegistry(cfg.Purposes)
+	if err != nil {
+		logging.Error("Invalid PURPOSE_REGISTRY",
+			"error", err,
+			"error_code", errs.ErrInvalidPurposeConfig)
+		os.Exit(1)
+	}
+	logging.Info("Purpose of use enforcement enabled", "purposes", registry.Purposes())
+	return registry
+}
+
+// setupTenantQueries returns the queries used for tenant data. With
+// TenantRLS enabled every statement also sets the request's tenant so the
+// row-level security policies enforce isolation in Postgres. The database role
+// must not be a superuser or have BYPASSRLS for the policies to apply.
+func setupTenantQueries(cfg config.Database, pool *pgxpool.Pool, queries *db.Queries) *db.Queries {
+	if !cfg.TenantRLS {
+		return queries
+	}
+
+	logging.Info("Tenant row-level security enabled")
+	return db.New(tenancy.NewDB(pool))
+}
+
+// setupTracing installs OpenTelemetry span export when an OTLP endpoint is
+// configured. It returns the function that flushes pending spans on shutdown
+// and exits when the configuration is invalid.
+func setupTracing() func(context.Context) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	shutdown, err := tracing.Setup(ctx, Version)
+	if err != nil {
+		logging.Error("Failed to set up tracing",
+			"error", err,
+			"error_code", errs.ErrInvalidTracingConfig)
+		os.Exit(1)
+	}
+	return shutdown
+}
+
+// setupAccessLog builds the access log middleware. It returns nil when the
+// access log is turned off.
+func setupAccessLog(cfg config.AccessLog) echo.MiddlewareFunc {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	accessLogConfig := middleware.DefaultAccessLogConfig()
+	accessLogConfig.SlowThreshold = cfg.SlowThreshold
+	accessLogConfig.HealthSampleEvery = cfg.HealthSampleEvery
+	return middleware.AccessLog(accessLogConfig)
+}
+
+// requireSignature enforces request signing on group when name is one of signedRoutes
+func requireSignature(group *echo.Group, name string, verifier *signing.Verifier, signedRoutes map[string]bool) {
+	if signedRoutes[name] {
+		group.Use(middleware.RequireSignature(verifier))
+	}
 }
 
 func main() {
 	// Initialize structured logging
 	logging.Init()
 
-	logging.Info("Application starting")
+	// The first argument selects the command; without one the server starts
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		printUsage(os.Stdout)
+		return
+	}
+	cmd, ok := findCommand(name)
+	if !ok {
+		logging.Error("Unknown command",
+			"command", name,
+			"error_code", errs.ErrInvalidUsage)
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+
+	// Load and validate the configuration once; it is passed to each component
+	load := config.Load
+	if cmd.databaseOnly {
+		load = config.LoadDatabase
+	}
+	cfg, err := load()
+	if err != nil {
+		logging.Error("Invalid configuration",
+			"error", err,
+			"error_code", errs.ErrInvalidConfig)
+		os.Exit(1)
+	}
+	if err := logging.Configure(logging.Options{
+		Level:        cfg.Logging.LogLevel(),
+		Format:       cfg.Logging.Format,
+		RedactKeys:   cfg.Logging.RedactKeys,
+		GCPProjectID: cfg.Logging.GCPProjectID,
+	}); err != nil {
+		logging.Error("Invalid logging configuration",
+			"error", err,
+			"error_code", errs.ErrInvalidLogConfig)
+		os.Exit(1)
+	}
 
-	// Load configuration from environment variables
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "3000"
+	// Commands stop on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	os.Exit(runCommand(ctx, cmd, cfg, args))
+}
+
+// serve runs the HTTP server and the background workers until ctx is done or
+// a component fails
+func serve(ctx context.Context, cfg config.Config, args []string) error {
+	if err := parseFlags("serve", args, nil); err != nil {
+		return err
 	}
 
-	queries, _ := setupDb(port)
+	logging.Info("Application starting")
+
+	// Components are stopped in reverse order: the HTTP server drains first,
+	// then the background workers, then trace export and the database pool
+	lc := lifecycle.NewManager(cfg.Server.ShutdownTimeout)
+	lc.SetReadinessDelay(cfg.Server.ReadinessDelay)
+
+	shutdownTracing := setupTracing()
+	lc.AddCloser("tracing", func(ctx context.Context) error {
+		if err := shutdownTracing(ctx); err != nil {
+			logging.Error("Failed to flush traces",
+				"error", err,
+				"error_code", errs.ErrFailedFlushTraces)
+		}
+		return nil
+	})
+
+	queries, pool := setupDb(cfg.Database)
+	lc.AddCloser("database", func(ctx context.Context) error {
+		pool.Close()
+		return nil
+	})
 
 	logging.Info("Database connection successful")
 
@@ -128,79 +506,158 @@ func main() {
 	e.HideBanner = true
 	e.HidePort = true
 
-	// Apply trace middleware globally (must be first to capture all requests)
+	// Apply trace middleware globally (must be first to capture all requests).
+	// It starts the server span of each request; queries become its children.
 	e.Use(middleware.TraceMiddleware())
 
+	// Record request metrics by route template for /metrics
+	e.Use(middleware.Metrics())
+
+	// Log every request with the trace of the request
+	if accessLog := setupAccessLog(cfg.AccessLog); accessLog != nil {
+		e.Use(accessLog)
+	}
+	metrics.Registry.MustRegister(metrics.NewPoolCollector(pool))
+
+	// Readiness also requires the expected schema and a working encryption key
 	healthHandler := health.NewHealthCheckHandler(queries)
-	keyValueHandler := key_value.NewKeyValueHandler(queries)
-	personAttributesHandler := person_attributes.NewPersonAttributesHandler(queries)
+	healthHandler.AddMigrationCheck(health.NewMigrationCheck(pool, uint(cfg.Database.ExpectSchemaVersion)))
+	encryptionCanary := health.NewEncryptionCanary(queries, cfg.Encryption)
+	if err := encryptionCanary.Ensure(context.Background()); err != nil {
+		logging.Warn("Failed to write encryption canary",
+			"error", err,
+			"error_code", errs.ErrFailedEnsureCanary)
+	}
+	healthHandler.AddCheck("encryption", encryptionCanary.Check)
+	keyValueHandler := key_value.NewKeyValueHandler(queries, cfg.Encryption, cfg.KeyValue)
+	tenantQueries := setupTenantQueries(cfg.Database, pool, queries)
+	personAttributesHandler := person_attributes.NewPersonAttributesHandler(tenantQueries, cfg.Encryption)
+	personAttributesHandler.SetPolicy(setupAttributePolicy(cfg.Access))
+	redactionProfiles := setupRedactionProfiles(cfg.Access)
+	personAttributesHandler.SetRedactionProfiles(redactionProfiles)
+	credentialsHandler := credentials.NewCredentialsHandler(queries)
+	credentialsHandler.SetRedactionProfiles(redactionProfiles)
+	// Serve TLS when configured; trusted client certificates map to principals
+	tlsReloader := setupTLS(cfg.TLS)
+	var certificateMapper middleware.CertificateMapper
+	if tlsReloader != nil {
+		certificateMapper = tlsReloader
+	}
+	authenticator := middleware.NewAuthenticator(credentials.NewStore(queries), setupTokenVerifier(cfg.JWT), certificateMapper)
 
 	// Setup routes
 	e.GET("/health", healthHandler.Check)
+	e.GET("/livez", healthHandler.Livez)
+	e.GET("/readyz", healthHandler.Readyz)
 	e.GET("/version", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]string{"version": Version})
 	})
 
-	// Key-value API routes
-	e.POST("/api/key-value", keyValueHandler.SetValue)
-	e.GET("/api/key-value/:key", keyValueHandler.GetValue)
-	e.DELETE("/api/key-value/:key", keyValueHandler.DeleteValue)
-
-	// Person CRUD API routes - protected with Bearer token middleware
-	personHandler := person.NewPersonHandler(queries)
-	personGroup := e.Group("/api/person", middleware.BearerMiddleware())
-	personGroup.POST("", personHandler.CreatePerson)
-	personGroup.GET("/:id", personHandler.GetPerson)
-	personGroup.PATCH("/:id", personHandler.UpdatePerson)
-	personGroup.DELETE("/:id", personHandler.DeletePerson)
-
-	// Person attributes API routes - protected with API key middleware
-	personAttributesGroup := e.Group("/persons", middleware.APIKeyMiddleware())
-	personAttributesGroup.POST("/:personId/attributes", personAttributesHandler.CreateAttribute)
-	personAttributesGroup.PUT("/:personId/attributes", personAttributesHandler.CreateAttribute)
-	personAttributesGroup.GET("/:personId/attributes", personAttributesHandler.GetAllAttributes)
-	personAttributesGroup.GET("/:personId/attributes/:attributeId", personAttributesHandler.GetAttribute)
-	personAttributesGroup.PUT("/:personId/attributes/:attributeId", personAttributesHandler.UpdateAttribute)
-	personAttributesGroup.DELETE("/:personId/attributes/:attributeId", personAttributesHandler.DeleteAttribute)
+	// Remove expired key-value entries in the background
+	lc.AddWorker("kv-expiry-sweeper", key_value.NewExpirySweeper(queries, cfg.KeyValue.SweepInterval).Run)
 
-	// Configure server
-	e.Server = &http.Server{
-		Addr:         ":" + port,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
-	}
+	// Wake long-polling key-value watches on database change notifications
+	lc.AddWorker("kv-watch-listener", func(ctx context.Context) {
+		keyValueHandler.WatchHub().Listen(ctx, pool)
+	})
 
-	logging.Info("Server starting", "port", port)
+	// Reload bootstrap API keys on SIGHUP
+	lc.AddWorker("api-key-reloader", authenticator.ReloadOnSignal)
 
-	// Start server in goroutine
-	go func() {
-		if err := e.Start(e.Server.Addr); err != nil && err != http.ErrServerClosed {
-			logging.Error("Server error",
-				"error", err,
-				"error_code", errs.ErrFailedStartServer)
-		}
-	}()
+	// Reload TLS certificates when the files change or on SIGHUP
+	if tlsReloader != nil {
+		lc.AddWorker("tls-reloader", func(ctx context.Context) {
+			tlsReloader.Run(ctx, mtls.DefaultReloadInterval)
+		})
+	}
 
-	// Give server time to start
-	time.Sleep(100 * time.Millisecond)
+	// Require signed requests on the configured route groups
+	signatureVerifier, signedRoutes := setupRequestSigning(lc, cfg.Signing, queries)
+
+	// Limit request rates per principal and route class when limits are set
+	limiter := setupRateLimiting(lc, cfg.RateLimit, queries)
+	limitRead := middleware.RateLimit(limiter, ratelimit.ClassRead)
+	limitWrite := middleware.RateLimit(limiter, ratelimit.ClassWrite)
+	limitSearch := middleware.RateLimit(limiter, ratelimit.ClassSearch)
+
+	// API credential admin routes - require the admin scope
+	adminGroup := e.Group("/admin/api-keys", authenticator.Middleware(), middleware.RequireScope(auth.ScopeAdmin))
+	requireSignature(adminGroup, "admin", signatureVerifier, signedRoutes)
+	adminGroup.POST("", credentialsHandler.CreateCredential, limitWrite)
+	adminGroup.GET("", credentialsHandler.ListCredentials, limitSearch)
+	adminGroup.DELETE("/:id", credentialsHandler.RevokeCredential, limitWrite)
+
+	// Log level admin routes - change the level of this instance at runtime
+	logLevelHandler := logging.NewLevelHandler()
+	logLevelGroup := e.Group("/admin/log-level", authenticator.Middleware(), middleware.RequireScope(auth.ScopeAdmin))
+	requireSignature(logLevelGroup, "admin", signatureVerifier, signedRoutes)
+	logLevelGroup.GET("", logLevelHandler.GetLevel, limitRead)
+	logLevelGroup.PUT("", logLevelHandler.SetLevel, limitWrite)
+
+	// Health details route - per-check latency and errors, admin only
+	e.GET("/health/details", healthHandler.Details, authenticator.Middleware(), middleware.RequireScope(auth.ScopeAdmin), limitRead)
+
+	// Metrics route - scraped with a credential granted the metrics:read scope
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()), authenticator.Middleware(), middleware.RequireScope(auth.ScopeMetricsRead))
+
+	// Key-value API routes - protected with API key authentication, scoped to the key's namespace
+	keyValueGroup := e.Group("/api/key-value", authenticator.Middleware())
+	requireSignature(keyValueGroup, "key-value", signatureVerifier, signedRoutes)
+	keyValueGroup.POST("", keyValueHandler.SetValue, middleware.RequireScope(auth.ScopeKVWrite), limitWrite)
+	keyValueGroup.GET("", keyValueHandler.ListValues, middleware.RequireScope(auth.ScopeKVRead), limitSearch)
+	keyValueGroup.DELETE("", keyValueHandler.DeleteByPrefix, middleware.RequireScope(auth.ScopeKVWrite), limitWrite)
+	keyValueGroup.GET("/:key", keyValueHandler.GetValue, middleware.RequireScope(auth.ScopeKVRead), limitRead)
+	keyValueGroup.DELETE("/:key", keyValueHandler.DeleteValue, middleware.RequireScope(auth.ScopeKVWrite), limitWrite)
+	keyValueGroup.POST("/:key/increment", keyValueHandler.IncrementValue, middleware.RequireScope(auth.ScopeKVWrite), limitWrite)
+
+	// Require a registered purpose of use on PII routes when purposes are configured
+	requirePurpose := middleware.RequirePurpose(setupPurposeRegistry(cfg.Access), purpose.NewAuditor(queries, cfg.Encryption))
+
+	// Person CRUD API routes - protected with API key authentication
+	personHandler := person.NewPersonHandler(tenantQueries)
+	personGroup := e.Group("/api/person", authenticator.Middleware())
+	requireSignature(personGroup, "person", signatureVerifier, signedRoutes)
+	personGroup.POST("", personHandler.CreatePerson, middleware.RequireScope(auth.ScopePersonWrite), limitWrite, requirePurpose)
+	personGroup.GET("/:id", personHandler.GetPerson, middleware.RequireScope(auth.ScopePersonRead), limitRead, requirePurpose)
+	personGroup.PATCH("/:id", personHandler.UpdatePerson, middleware.RequireScope(auth.ScopePersonWrite), limitWrite, requirePurpose)
+	personGroup.DELETE("/:id", personHandler.DeletePerson, middleware.RequireScope(auth.ScopePersonWrite), limitWrite, requirePurpose)
+
+	// Person attributes API routes - protected with API key authentication
+	personAttributesGroup := e.Group("/persons", authenticator.Middleware())
+	requireSignature(personAttributesGroup, "attributes", signatureVerifier, signedRoutes)
+	personAttributesGroup.POST("/:personId/attributes", personAttributesHandler.CreateAttribute, middleware.RequireScope(auth.ScopeAttributesWrite), limitWrite, requirePurpose)
+	personAttributesGroup.PUT("/:personId/attributes", personAttributesHandler.CreateAttribute, middleware.RequireScope(auth.ScopeAttributesWrite), limitWrite, requirePurpose)
+	personAttributesGroup.GET("/:personId/attributes", personAttributesHandler.GetAllAttributes, middleware.RequireScope(auth.ScopeAttributesRead), limitRead, requirePurpose)
+	personAttributesGroup.GET("/:personId/attributes/:attributeId", personAttributesHandler.GetAttribute, middleware.RequireScope(auth.ScopeAttributesRead), limitRead, requirePurpose)
+	personAttributesGroup.PUT("/:personId/attributes/:attributeId", personAttributesHandler.UpdateAttribute, middleware.RequireScope(auth.ScopeAttributesWrite), limitWrite, requirePurpose)
+	personAttributesGroup.DELETE("/:personId/attributes/:attributeId", personAttributesHandler.DeleteAttribute, middleware.RequireScope(auth.ScopeAttributesWrite), limitWrite, requirePurpose)
 
-	logging.Info("Server ready and listening", "port", port)
+	// Configure server
+	e.Server = &http.Server{
+		Addr:         ":" + strconv.Itoa(cfg.Server.Port),
+		Handler:      e,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+	if tlsReloader != nil {
+		e.Server.TLSConfig = tlsReloader.TLSConfig()
+	}
+	// End long-polling watches when the server drains, since they would
+	// otherwise hold their connections past the drain timeout
+	e.Server.RegisterOnShutdown(keyValueHandler.WatchHub().Close)
+	lc.Add(lifecycle.HTTPServer("http-server", e.Server))
 
-	// Wait for interrupt signal to gracefully shutdown the server with
-	// a timeout of 10 seconds.
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-	<-quit
+	// Fail readiness first so load balancers stop routing to this instance
+	// during the readiness delay, before the listener closes
+	lc.OnShutdown(healthHandler.SetShuttingDown)
 
-	logging.Info("Shutting down server")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	if err := e.Shutdown(ctx); err != nil {
-		logging.Error("Server shutdown failed",
-			"error", err,
-			"error_code", errs.ErrFailedShutdownServer)
-		os.Exit(1)
+	logging.Info("Server starting", "port", cfg.Server.Port, "tls", tlsReloader != nil)
+
+	// Run until SIGINT or SIGTERM, or until a component fails
+	if err := lc.Run(ctx); err != nil {
+		return err
 	}
 	logging.Info("Server gracefully stopped")
+	return nil
 }
@@ -0,0 +1,309 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"person-service/config"
+	"person-service/logging"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// DefaultBatchSize is how many rows RotateKeys re-encrypts per statement
+	DefaultBatchSize = 500
+
+	// pgcryptoErrorCode is the SQLSTATE pgcrypto raises when a value cannot be decrypted
+	pgcryptoErrorCode = "39000"
+
+	// maxReportedRows caps how many failing rows VerifyEncryption lists per table
+	maxReportedRows = 20
+)
+
+// encryptedTable describes a table holding values encrypted with
+// pgp_sym_encrypt and the version of the key used in key_version. Rows of
+// key_value encrypted before key versions were recorded have a NULL
+// key_version and are treated as encryption.UnversionedVersion().
+type encryptedTable struct {
+	name string
+	// label is an SQL expression identifying a row in reports
+	label string
+	// columns are the encrypted columns; NULL values are left as they are
+	columns []string
+}
+
+// encryptedTables lists every table with values encrypted at rest
+var encryptedTables = []encryptedTable{
+	{name: "person_attributes", label: "id::text", columns: []string{"encrypted_value"}},
+	{name: "person_images", label: "id::text", columns: []string{"encrypted_image_data"}},
+	{name: "request_log", label: "tenant_id || '/' || trace_id", columns: []string{"encrypted_request_body", "encrypted_response_body"}},
+	{name: "key_value", label: "namespace || '/' || key", columns: []string{"encrypted_value"}},
+	{name: "encryption_canary", label: "id::text", columns: []string{"encrypted_value"}},
+}
+
+// RotationResult counts the rows of a table moved from one key version to the
+// current one
+type RotationResult struct {
+	Table       string `json:"table"`
+	FromVersion int64  `json:"from_version"`
+	ToVersion   int64  `json:"to_version"`
+	Rows        int64  `json:"rows"`
+}
+
+// RotateKeys re-encrypts every value stored with a key version other than the
+// current one with the current key, batchSize rows per statement, and records
+// the current key version on every re-encrypted row. The keys of
+// all versions found must be configured; this is checked for every table
+// before any row is changed. With dryRun only the rows to re-encrypt are
+// counted.
+func RotateKeys(ctx context.Context, pool *pgxpool.Pool, encryption config.Encryption, batchSize int, dryRun bool) ([]RotationResult, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	type rotation struct {
+		table  encryptedTable
+		result RotationResult
+	}
+	var pending []rotation
+	for _, table := range encryptedTables {
+		counts, err := countByVersion(ctx, pool, table, encryption.UnversionedVersion())
+		if err != nil {
+			return nil, err
+		}
+		for _, count := range counts {
+			if count.version == encryption.KeyVersion {
+				continue
+			}
+			if _, ok := encryption.KeyFor(count.version); !ok {
+				return nil, fmt.Errorf("%s has %d rows with key version %d but ENCRYPTION_KEY_%d is not set",
+					table.name, count.rows, count.version, count.version)
+			}
+			pending = append(pending, rotation{table: table, result: RotationResult{
+				Table:       table.name,
+				FromVersion: count.version,
+				ToVersion:   encryption.KeyVersion,
+				Rows:        count.rows,
+			}})
+		}
+	}
+
+	results := make([]RotationResult, 0, len(pending))
+	for _, next := range pending {
+		result := next.result
+		if dryRun {
+			results = append(results, result)
+			continue
+		}
+
+		oldKey, _ := encryption.KeyFor(result.FromVersion)
+		rows, err := reencrypt(ctx, pool, next.table, result.FromVersion, oldKey, encryption, batchSize)
+		result.Rows = rows
+		results = append(results, result)
+		if err != nil {
+			return results, fmt.Errorf("failed to re-encrypt %s from key version %d: %w", result.Table, result.FromVersion, err)
+		}
+		logging.InfoContext(ctx, "Re-encrypted rows with the current key",
+			"table", result.Table,
+			"from_version", result.FromVersion,
+			"to_version", result.ToVersion,
+			"rows", rows)
+	}
+	return results, nil
+}
+
+// reencrypt moves the rows of table with key version from to the current key
+// in batches until none is left
+func reencrypt(ctx context.Context, pool *pgxpool.Pool, table encryptedTable, from int64, oldKey string, encryption config.Encryption, batchSize int) (int64, error) {
+	assignments := make([]string, 0, len(table.columns)+1)
+	for _, column := range table.columns {
+		assignments = append(assignments, fmt.Sprintf("%s = pgp_sym_encrypt(pgp_sym_decrypt(%s, $1::text), $2::text)", column, column))
+	}
+	assignments = append(assignments, "key_version = $3")
+	sql := fmt.Sprintf(`UPDATE %s SET %s
+		WHERE ctid = ANY(ARRAY(SELECT ctid FROM %s WHERE %s LIMIT $5))`,
+		table.name, strings.Join(assignments, ", "), table.name,
+		hasKeyVersion(table, encryption.UnversionedVersion(), "$4"))
+
+	var total int64
+	for {
+		tag, err := pool.Exec(ctx, sql, oldKey, encryption.Key, encryption.KeyVersion, from, batchSize)
+		if err != nil {
+			return total, err
+		}
+		total += tag.RowsAffected()
+		if tag.RowsAffected() == 0 {
+			return total, nil
+		}
+	}
+}
+
+// VerifyResult reports the rows of a table that do not decrypt with the key
+// of their key version
+type VerifyResult struct {
+	Table  string `json:"table"`
+	Rows   int64  `json:"rows"`
+	Failed int64  `json:"failed"`
+	// FailedRows identifies up to the first 20 failing rows
+	FailedRows []string `json:"failed_rows,omitempty"`
+}
+
+// VerifyEncryption checks that every encrypted value decrypts with the key of
+// its key version. Rows whose key version has no configured key fail too.
+func VerifyEncryption(ctx context.Context, pool *pgxpool.Pool, encryption config.Encryption) ([]VerifyResult, error) {
+	results := make([]VerifyResult, 0, len(encryptedTables))
+	for _, table := range encryptedTables {
+		unversioned := encryption.UnversionedVersion()
+		counts, err := countByVersion(ctx, pool, table, unversioned)
+		if err != nil {
+			return results, err
+		}
+
+		result := VerifyResult{Table: table.name}
+		for _, count := range counts {
+			result.Rows += count.rows
+			key, ok := encryption.KeyFor(count.version)
+			if ok {
+				// Check every row of the version in one statement and only
+				// look for the failing rows when it fails
+				if err := decryptAll(ctx, pool, table, count.version, unversioned, key); err == nil {
+					continue
+				} else if !isDecryptError(err) {
+					return results, fmt.Errorf("failed to verify %s: %w", table.name, err)
+				}
+			}
+
+			failed, err := failingRows(ctx, pool, table, count.version, unversioned, key, ok)
+			if err != nil {
+				return results, fmt.Errorf("failed to verify %s: %w", table.name, err)
+			}
+			result.Failed += int64(len(failed))
+			for _, label := range failed {
+				if len(result.FailedRows) < maxReportedRows {
+					result.FailedRows = append(result.FailedRows, fmt.Sprintf("%s (key version %d)", label, count.version))
+				}
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// versionCount is the number of rows of a table encrypted with a key version
+type versionCount struct {
+	version int64
+	rows    int64
+}
+
+// keyVersionExpression evaluates to the key version of a row of table, which
+// is unversioned for encrypted rows stored without one
+func keyVersionExpression(unversioned int64) string {
+	return fmt.Sprintf("COALESCE(key_version, %d)", unversioned)
+}
+
+// isEncrypted is an SQL condition matching the rows of table holding an
+// encrypted value
+func isEncrypted(table encryptedTable) string {
+	return fmt.Sprintf("(key_version IS NOT NULL OR num_nonnulls(%s) > 0)", strings.Join(table.columns, ", "))
+}
+
+// hasKeyVersion is an SQL condition matching the encrypted rows of table with
+// the key version in parameter param
+func hasKeyVersion(table encryptedTable, unversioned int64, param string) string {
+	return fmt.Sprintf("%s = %s AND %s", keyVersionExpression(unversioned), param, isEncrypted(table))
+}
+
+// countByVersion counts the encrypted rows of table per key version
+func countByVersion(ctx context.Context, pool *pgxpool.Pool, table encryptedTable, unversioned int64) ([]versionCount, error) {
+	version := keyVersionExpression(unversioned)
+	rows, err := pool.Query(ctx, fmt.Sprintf(
+		"SELECT %s, count(*) FROM %s WHERE %s GROUP BY 1 ORDER BY 1",
+		version, table.name, isEncrypted(table)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count %s by key version: %w", table.name, err)
+	}
+	defer rows.Close()
+
+	var counts []versionCount
+	for rows.Next() {
+		var count versionCount
+		if err := rows.Scan(&count.version, &count.rows); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+// decryptExpression decrypts every encrypted column of table with the key in
+// parameter $1 and evaluates to a value so that no column is skipped
+func decryptExpression(table encryptedTable) string {
+	decrypted := make([]string, 0, len(table.columns))
+	for _, column := range table.columns {
+		decrypted = append(decrypted, fmt.Sprintf("pgp_sym_decrypt(%s, $1::text)", column))
+	}
+	return "num_nulls(" + strings.Join(decrypted, ", ") + ")"
+}
+
+// decryptAll decrypts every row of table with key version
+func decryptAll(ctx context.Context, pool *pgxpool.Pool, table encryptedTable, version, unversioned int64, key string) error {
+	var count int64
+	return pool.QueryRow(ctx, fmt.Sprintf("SELECT count(*) FROM %s WHERE %s AND %s >= 0",
+		table.name, hasKeyVersion(table, unversioned, "$2"), decryptExpression(table)), key, version).Scan(&count)
+}
+
+// failingRows returns the labels of the rows of table with key version that
+// do not decrypt with key, or of all of them when no key is configured
+func failingRows(ctx context.Context, pool *pgxpool.Pool, table encryptedTable, version, unversioned int64, key string, hasKey bool) ([]string, error) {
+	rows, err := pool.Query(ctx, fmt.Sprintf("SELECT ctid::text, %s FROM %s WHERE %s",
+		table.label, table.name, hasKeyVersion(table, unversioned, "$1")), version)
+	if err != nil {
+		return nil, err
+	}
+	type row struct{ ctid, label string }
+	var candidates []row
+	for rows.Next() {
+		var candidate row
+		if err := rows.Scan(&candidate.ctid, &candidate.label); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		candidates = append(candidates, candidate)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !hasKey {
+		labels := make([]string, 0, len(candidates))
+		for _, candidate := range candidates {
+			labels = append(labels, candidate.label)
+		}
+		return labels, nil
+	}
+
+	check := fmt.Sprintf("SELECT %s FROM %s WHERE ctid = $2::tid", decryptExpression(table), table.name)
+	var failed []string
+	for _, candidate := range candidates {
+		var nulls int
+		err := pool.QueryRow(ctx, check, key, candidate.ctid).Scan(&nulls)
+		if isDecryptError(err) {
+			failed = append(failed, candidate.label)
+		} else if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+	return failed, nil
+}
+
+// isDecryptError reports whether err is pgcrypto's "Wrong key or corrupt
+// data" rather than a failure of the statement or the connection
+func isDecryptError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgcryptoErrorCode
+}
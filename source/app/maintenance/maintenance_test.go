@@ -0,0 +1,241 @@
+package maintenance
+
+import (
+	"context"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"person-service/config"
+	db "person-service/internal/db/generated"
+	"person-service/internal/testdb"
+)
+
+var pool *pgxpool.Pool
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+	var err error
+	pool, err = testdb.GetPool(ctx)
+	if err != nil {
+		log.Fatalf("Failed to get pool: %v", err)
+	}
+	if err := testdb.RunMigrations(ctx, pool); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+	os.Exit(m.Run())
+}
+
+// keyring returns an encryption config with keys for versions 1 and 2 that
+// encrypts with version
+func keyring(version int64) config.Encryption {
+	keys := map[int64]string{1: "first-key", 2: "second-key"}
+	return config.Encryption{Key: keys[version], KeyVersion: version, Keys: keys}
+}
+
+// insertAttribute stores an attribute encrypted with key under key version
+func insertAttribute(t *testing.T, ctx context.Context, personID, attributeKey, value, key string, version int64) {
+	_, err := pool.Exec(ctx, `
+		INSERT INTO person_attributes (person_id, attribute_key, encrypted_value, key_version)
+		VALUES ($1::uuid, $2, pgp_sym_encrypt($3, $4), $5)
+	`, personID, attributeKey, value, key, version)
+	require.NoError(t, err)
+}
+
+// decryptAttribute reads an attribute back with key
+func decryptAttribute(t *testing.T, ctx context.Context, attributeKey, key string) (string, int64) {
+	var value string
+	var version int64
+	err := pool.QueryRow(ctx, `
+		SELECT pgp_sym_decrypt(encrypted_value, $2), key_version FROM person_attributes WHERE attribute_key = $1
+	`, attributeKey, key).Scan(&value, &version)
+	require.NoError(t, err)
+	return value, version
+}
+
+func TestRotateKeys_ReencryptsOlderVersions(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, testdb.TruncateTables(ctx, pool))
+
+	personID, err := testdb.CreatePerson(ctx, pool, "", "rotate-1")
+	require.NoError(t, err)
+	insertAttribute(t, ctx, personID, "first_name", "Ada", "first-key", 1)
+	insertAttribute(t, ctx, personID, "last_name", "Lovelace", "second-key", 2)
+
+	results, err := RotateKeys(ctx, pool, keyring(2), 1, false)
+	require.NoError(t, err)
+	assert.Equal(t, []RotationResult{
+		{Table: "person_attributes", FromVersion: 1, ToVersion: 2, Rows: 1},
+	}, results)
+
+	value, version := decryptAttribute(t, ctx, "first_name", "second-key")
+	assert.Equal(t, "Ada", value)
+	assert.Equal(t, int64(2), version)
+
+	// Running again finds nothing to rotate
+	results, err = RotateKeys(ctx, pool, keyring(2), 1, false)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestRotateKeys_DryRun(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, testdb.TruncateTables(ctx, pool))
+
+	personID, err := testdb.CreatePerson(ctx, pool, "", "rotate-2")
+	require.NoError(t, err)
+	insertAttribute(t, ctx, personID, "first_name", "Grace", "first-key", 1)
+
+	results, err := RotateKeys(ctx, pool, keyring(2), DefaultBatchSize, true)
+	require.NoError(t, err)
+	assert.Equal(t, []RotationResult{
+		{Table: "person_attributes", FromVersion: 1, ToVersion: 2, Rows: 1},
+	}, results)
+
+	_, version := decryptAttribute(t, ctx, "first_name", "first-key")
+	assert.Equal(t, int64(1), version)
+}
+
+func TestRotateKeys_MissingOldKey(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, testdb.TruncateTables(ctx, pool))
+
+	personID, err := testdb.CreatePerson(ctx, pool, "", "rotate-3")
+	require.NoError(t, err)
+	insertAttribute(t, ctx, personID, "first_name", "Alan", "first-key", 1)
+
+	encryption := config.Encryption{Key: "second-key", KeyVersion: 2, Keys: map[int64]string{2: "second-key"}}
+	_, err = RotateKeys(ctx, pool, encryption, DefaultBatchSize, false)
+	assert.ErrorContains(t, err, "ENCRYPTION_KEY_1 is not set")
+
+	_, version := decryptAttribute(t, ctx, "first_name", "first-key")
+	assert.Equal(t, int64(1), version)
+}
+
+// insertUnversionedValue stores a key-value entry encrypted with key and no key version
+func insertUnversionedValue(t *testing.T, ctx context.Context, key, value, encryptionKey string) {
+	_, err := pool.Exec(ctx, `
+		INSERT INTO key_value (key, value, encrypted_value) VALUES ($1, '', pgp_sym_encrypt($2, $3))
+	`, key, value, encryptionKey)
+	require.NoError(t, err)
+}
+
+func TestRotateKeys_UnversionedKeyValue(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, testdb.TruncateTables(ctx, pool))
+
+	insertUnversionedValue(t, ctx, "legacy", "secret", "first-key")
+	_, err := pool.Exec(ctx, "INSERT INTO key_value (key, value) VALUES ('plain', 'visible')")
+	require.NoError(t, err)
+
+	// Without an unversioned key version the entry counts as the current version
+	results, err := RotateKeys(ctx, pool, keyring(2), DefaultBatchSize, true)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	encryption := keyring(2)
+	encryption.UnversionedKeyVersion = 1
+	results, err = RotateKeys(ctx, pool, encryption, DefaultBatchSize, false)
+	require.NoError(t, err)
+	assert.Equal(t, []RotationResult{
+		{Table: "key_value", FromVersion: 1, ToVersion: 2, Rows: 1},
+	}, results)
+
+	var value string
+	var version int64
+	require.NoError(t, pool.QueryRow(ctx, `
+		SELECT pgp_sym_decrypt(encrypted_value, 'second-key'), key_version FROM key_value WHERE key = 'legacy'
+	`).Scan(&value, &version))
+	assert.Equal(t, "secret", value)
+	assert.Equal(t, int64(2), version)
+
+	// Plain text entries are not encrypted by rotation
+	var plainVersion *int64
+	require.NoError(t, pool.QueryRow(ctx, "SELECT key_version FROM key_value WHERE key = 'plain'").Scan(&plainVersion))
+	assert.Nil(t, plainVersion)
+}
+
+func TestVerifyEncryption_UnversionedKeyValue(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, testdb.TruncateTables(ctx, pool))
+
+	insertUnversionedValue(t, ctx, "legacy", "secret", "first-key")
+
+	// The entry only decrypts when resolved to the key it was encrypted with
+	results, err := VerifyEncryption(ctx, pool, keyring(2))
+	require.NoError(t, err)
+	for _, result := range results {
+		if result.Table == "key_value" {
+			assert.Equal(t, int64(1), result.Failed)
+		}
+	}
+
+	encryption := keyring(2)
+	encryption.UnversionedKeyVersion = 1
+	results, err = VerifyEncryption(ctx, pool, encryption)
+	require.NoError(t, err)
+	for _, result := range results {
+		assert.Zero(t, result.Failed, result.Table)
+	}
+}
+
+func TestVerifyEncryption_ReportsWrongKey(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, testdb.TruncateTables(ctx, pool))
+
+	personID, err := testdb.CreatePerson(ctx, pool, "", "verify-1")
+	require.NoError(t, err)
+	insertAttribute(t, ctx, personID, "first_name", "Edsger", "first-key", 1)
+	insertAttribute(t, ctx, personID, "last_name", "Dijkstra", "unknown-key", 1)
+
+	results, err := VerifyEncryption(ctx, pool, keyring(1))
+	require.NoError(t, err)
+
+	for _, result := range results {
+		if result.Table != "person_attributes" {
+			assert.Zero(t, result.Failed, result.Table)
+			continue
+		}
+		assert.Equal(t, int64(2), result.Rows)
+		assert.Equal(t, int64(1), result.Failed)
+		assert.Len(t, result.FailedRows, 1)
+	}
+}
+
+func TestVerifyEncryption_AllDecrypt(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, testdb.TruncateTables(ctx, pool))
+
+	personID, err := testdb.CreatePerson(ctx, pool, "", "verify-2")
+	require.NoError(t, err)
+	insertAttribute(t, ctx, personID, "first_name", "Barbara", "first-key", 1)
+	insertAttribute(t, ctx, personID, "last_name", "Liskov", "second-key", 2)
+
+	results, err := VerifyEncryption(ctx, pool, keyring(2))
+	require.NoError(t, err)
+	for _, result := range results {
+		assert.Zero(t, result.Failed, result.Table)
+	}
+}
+
+func TestSeed_Idempotent(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, testdb.TruncateTables(ctx, pool))
+	queries := db.New(pool)
+
+	result, err := Seed(ctx, queries, keyring(1), "default", 3)
+	require.NoError(t, err)
+	assert.Equal(t, SeedResult{Tenant: "default", Created: 3}, result)
+
+	result, err = Seed(ctx, queries, keyring(1), "default", 5)
+	require.NoError(t, err)
+	assert.Equal(t, SeedResult{Tenant: "default", Created: 2, Existing: 3}, result)
+
+	var attributes int
+	require.NoError(t, pool.QueryRow(ctx, "SELECT count(*) FROM person_attributes").Scan(&attributes))
+	assert.Equal(t, 20, attributes)
+}
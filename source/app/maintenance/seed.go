@@ -0,0 +1,84 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"person-service/config"
+	db "person-service/internal/db/generated"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// seedFirstNames and seedLastNames are combined into the names of seeded persons
+var (
+	seedFirstNames = []string{"Ada", "Grace", "Alan", "Edsger", "Barbara", "Donald", "Frances", "Ken"}
+	seedLastNames  = []string{"Lovelace", "Hopper", "Turing", "Dijkstra", "Liskov", "Knuth", "Allen", "Thompson"}
+)
+
+// SeedResult counts the persons Seed created and those that already existed
+type SeedResult struct {
+	Tenant   string `json:"tenant"`
+	Created  int    `json:"created"`
+	Existing int    `json:"existing"`
+}
+
+// Seed creates count persons with fictitious names, emails and phone numbers
+// in tenant for local development and demos. Persons are identified by the
+// client IDs seed-0001, seed-0002 and so on, so running it again only adds
+// the missing ones. Attribute values are encrypted with the current key.
+func Seed(ctx context.Context, queries *db.Queries, encryption config.Encryption, tenant string, count int) (SeedResult, error) {
+	result := SeedResult{Tenant: tenant}
+	for i := 1; i <= count; i++ {
+		clientID := fmt.Sprintf("seed-%04d", i)
+		_, err := queries.GetPersonByClientId(ctx, db.GetPersonByClientIdParams{
+			TenantID: tenant,
+			ClientID: clientID,
+		})
+		if err == nil {
+			result.Existing++
+			continue
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return result, fmt.Errorf("failed to look up %s: %w", clientID, err)
+		}
+
+		person, err := queries.CreatePerson(ctx, db.CreatePersonParams{
+			TenantID: tenant,
+			ClientID: clientID,
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to create %s: %w", clientID, err)
+		}
+
+		for _, attribute := range seedAttributes(i) {
+			_, err := queries.CreateOrUpdatePersonAttribute(ctx, db.CreateOrUpdatePersonAttributeParams{
+				TenantID:       tenant,
+				PersonID:       person.ID,
+				AttributeKey:   attribute[0],
+				AttributeValue: attribute[1],
+				EncKey:         encryption.Key,
+				KeyVersion:     encryption.KeyVersion,
+			})
+			if err != nil {
+				return result, fmt.Errorf("failed to set %s of %s: %w", attribute[0], clientID, err)
+			}
+		}
+		result.Created++
+	}
+	return result, nil
+}
+
+// seedAttributes returns the attribute keys and values of the i-th seeded person
+func seedAttributes(i int) [][2]string {
+	first := seedFirstNames[(i-1)%len(seedFirstNames)]
+	last := seedLastNames[((i-1)/len(seedFirstNames))%len(seedLastNames)]
+	return [][2]string{
+		{"first_name", first},
+		{"last_name", last},
+		{"email", fmt.Sprintf("%s.%s.%d@example.com", strings.ToLower(first), strings.ToLower(last), i)},
+		{"phone", fmt.Sprintf("+1555%07d", i)},
+	}
+}
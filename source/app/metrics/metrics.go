@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric exported by the service
+const namespace = "person_service"
+
+// Operations of encryption metrics
+const (
+	OperationEncrypt = "encrypt"
+	OperationDecrypt = "decrypt"
+)
+
+// Resources of encryption metrics
+const (
+	ResourceAttribute  = "attribute"
+	ResourceKeyValue   = "key_value"
+	ResourceRequestLog = "request_log"
+)
+
+// Sources of audit log write failures
+const (
+	AuditSourceAttribute       = "attribute"
+	AuditSourceAttributePolicy = "attribute_policy"
+	AuditSourcePurpose         = "purpose"
+)
+
+// Registry holds every metric served on /metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by method, route template and status class.",
+	}, []string{"method", "route", "status_class"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by method, route template and status class.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status_class"})
+
+	cryptoOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "crypto_operations_total",
+		Help:      "Values encrypted or decrypted, by operation and resource.",
+	}, []string{"operation", "resource"})
+
+	auditLogWriteFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audit_log_write_failures_total",
+		Help:      "Request log entries that could not be written, by source.",
+	}, []string{"source"})
+
+	migrationVersion = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "migration_version",
+		Help:      "Version of the last applied schema migration, as last read by the readiness check.",
+	})
+
+	migrationDirty = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "migration_dirty",
+		Help:      "1 when the last schema migration failed part way, otherwise 0.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		cryptoOperations,
+		auditLogWriteFailures,
+		migrationVersion,
+		migrationDirty,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// StatusClass returns the class of an HTTP status code, such as 2xx
+func StatusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// ObserveRequest records a handled HTTP request. route must be the route
+// template, such as /persons/:personId/attributes, so raw IDs do not end up
+// in label values.
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	class := StatusClass(status)
+	httpRequests.WithLabelValues(method, route, class).Inc()
+	httpRequestDuration.WithLabelValues(method, route, class).Observe(duration.Seconds())
+}
+
+// RecordEncryption counts n values of resource encrypted
+func RecordEncryption(resource string, n int) {
+	cryptoOperations.WithLabelValues(OperationEncrypt, resource).Add(float64(n))
+}
+
+// RecordDecryption counts n values of resource decrypted
+func RecordDecryption(resource string, n int) {
+	cryptoOperations.WithLabelValues(OperationDecrypt, resource).Add(float64(n))
+}
+
+// RecordAuditFailure counts a request log entry from source that could not be written
+func RecordAuditFailure(source string) {
+	auditLogWriteFailures.WithLabelValues(source).Inc()
+}
+
+// RecordSchemaVersion sets the schema migration version and dirty flag. The
+// readiness check records them whenever it reads them, so scrapes do not
+// query the database.
+func RecordSchemaVersion(version uint, dirty bool) {
+	migrationVersion.Set(float64(version))
+	dirtyValue := 0.0
+	if dirty {
+		dirtyValue = 1
+	}
+	migrationDirty.Set(dirtyValue)
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolCollector exports connection pool statistics of a pgx pool. Values are
+// read from the pool on each scrape without querying the database.
+type PoolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns *prometheus.Desc
+	idleConns     *prometheus.Desc
+	totalConns    *prometheus.Desc
+	maxConns      *prometheus.Desc
+	acquireCount  *prometheus.Desc
+	emptyAcquire  *prometheus.Desc
+	acquireWait   *prometheus.Desc
+}
+
+// NewPoolCollector creates a new instance of PoolCollector for pool
+func NewPoolCollector(pool *pgxpool.Pool) *PoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db", name), help, nil, nil)
+	}
+
+	return &PoolCollector{
+		pool:          pool,
+		acquiredConns: desc("pool_acquired_conns", "Connections currently acquired from the pool."),
+		idleConns:     desc("pool_idle_conns", "Idle connections in the pool."),
+		totalConns:    desc("pool_total_conns", "Connections in the pool, acquired, idle or being opened."),
+		maxConns:      desc("pool_max_conns", "Maximum size of the pool."),
+		acquireCount:  desc("pool_acquires_total", "Connections acquired from the pool."),
+		emptyAcquire:  desc("pool_empty_acquires_total", "Acquires that had to wait for a connection because none was idle."),
+		acquireWait:   desc("pool_acquire_wait_seconds_total", "Time spent waiting to acquire connections."),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (p *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.acquiredConns
+	ch <- p.idleConns
+	ch <- p.totalConns
+	ch <- p.maxConns
+	ch <- p.acquireCount
+	ch <- p.emptyAcquire
+	ch <- p.acquireWait
+}
+
+// Collect implements prometheus.Collector
+func (p *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := p.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(p.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(p.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(p.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(p.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(p.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(p.emptyAcquire, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(p.acquireWait, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+
+}
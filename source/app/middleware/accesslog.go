@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"person-service/auth"
+	"person-service/logging"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AccessLogConfig configures the access log middleware
+type AccessLogConfig struct {
+	// SlowThreshold logs requests taking at least this long as warnings; zero disables it
+	SlowThreshold time.Duration
+
+	// HealthRoutes are probe routes whose successful requests are sampled
+	HealthRoutes []string
+
+	// HealthSampleEvery logs one in this many successful probe requests; zero logs none
+	HealthSampleEvery int
+}
+
+// DefaultAccessLogConfig returns the access log settings used when none are configured
+func DefaultAccessLogConfig() AccessLogConfig {
+	return AccessLogConfig{
+		SlowThreshold:     time.Second,
+		HealthRoutes:      []string{"/health", "/livez", "/readyz", "/metrics"},
+		HealthSampleEvery: 100,
+	}
+}
+
+// AccessLog creates a middleware that writes one structured log entry per
+// request with the method, route template, status, latency, caller and
+// sizes. Entries carry the trace of the request, so it must run after
+// TraceMiddleware. Request and response bodies, raw paths and query strings
+// are never logged since they can hold PII; the route template identifies
+// the endpoint instead. Server errors are logged as errors and slow requests
+// as warnings, and successful probe requests are sampled.
+func AccessLog(config AccessLogConfig) echo.MiddlewareFunc {
+	var probes atomic.Uint64
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			latency := time.Since(start)
+
+			status := responseStatus(c, err)
+			route := c.Path()
+			if route == "" {
+				route = unmatchedRoute
+			}
+
+			slow := config.SlowThreshold > 0 && latency >= config.SlowThreshold
+			if status < http.StatusInternalServerError && !slow && slices.Contains(config.HealthRoutes, route) {
+				if config.HealthSampleEvery <= 0 || (probes.Add(1)-1)%uint64(config.HealthSampleEvery) != 0 {
+					return err
+				}
+			}
+
+			// The request in c carries the principal set by authentication
+			req := c.Request()
+			principal, _ := auth.PrincipalFromContext(req.Context())
+			caller := principal.Name
+			if caller == "" {
+				caller = "anonymous"
+			}
+
+			level := slog.LevelInfo
+			switch {
+			case status >= http.StatusInternalServerError:
+				level = slog.LevelError
+			case slow:
+				level = slog.LevelWarn
+			}
+
+			logging.LoggerFromContext(req.Context()).LogAttrs(req.Context(), level, "HTTP request",
+				slog.String("method", req.Method),
+				slog.String("route", route),
+				slog.Int("status", status),
+				slog.Float64("latency_ms", float64(latency.Microseconds())/1000),
+				slog.Bool("slow", slow),
+				slog.String("caller", caller),
+				slog.String("principal_id", principal.ID),
+				slog.String("remote_ip", c.RealIP()),
+				slog.Int64("bytes_in", max(req.ContentLength, 0)),
+				slog.Int64("bytes_out", c.Response().Size),
+			)
+			return err
+		}
+	}
+}
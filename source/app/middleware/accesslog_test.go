@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"person-service/auth"
+	"person-service/logging"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// captureLogs sends log output to the returned buffer for the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	logging.SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { logging.SetLogger(nil) })
+	return &buf
+}
+
+// logEntries decodes the JSON log lines in buf
+func logEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func newAccessLogServer(config AccessLogConfig) *echo.Echo {
+	e := echo.New()
+	e.Use(TraceMiddleware())
+	e.Use(AccessLog(config))
+	e.GET("/health", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+	e.POST("/persons/:personId/attributes", func(c echo.Context) error {
+		principal := auth.Principal{ID: "cred-1", Name: "crm"}
+		c.SetRequest(c.Request().WithContext(auth.ContextWithPrincipal(c.Request().Context(), principal)))
+		return c.JSON(http.StatusCreated, map[string]string{"key": "ssn", "value": "123-45-6789"})
+	})
+	e.GET("/slow", func(c echo.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/fail", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusInternalServerError, "boom")
+	})
+	return e
+}
+
+func TestAccessLog(t *testing.T) {
+	buf := captureLogs(t)
+	e := newAccessLogServer(DefaultAccessLogConfig())
+
+	body := `{"key":"ssn","value":"123-45-6789","meta":{"caller":"crm","reason":"kyc_verification"}}`
+	req := httptest.NewRequest(http.MethodPost, "/persons/5b7c1f0e-1d2a-4c55-9f7a-0c1d2e3f4a5b/attributes?view=full", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	entries := logEntries(t, buf)
+	assert.Len(t, entries, 1)
+	entry := entries[0]
+	assert.Equal(t, "HTTP request", entry["msg"])
+	assert.Equal(t, "INFO", entry["level"])
+	assert.Equal(t, "POST", entry["method"])
+	assert.Equal(t, "/persons/:personId/attributes", entry["route"])
+	assert.Equal(t, float64(http.StatusCreated), entry["status"])
+	assert.Equal(t, "crm", entry["caller"])
+	assert.Equal(t, "cred-1", entry["principal_id"])
+	assert.Equal(t, float64(len(body)), entry["bytes_in"])
+	assert.Equal(t, float64(rec.Body.Len()), entry["bytes_out"])
+	assert.Contains(t, entry, "latency_ms")
+
+	// Entries carry the trace of the request for GCP correlation
+	assert.Equal(t, rec.Header().Get(TraceIDResponseHeader), entry[logging.GCPTraceField])
+
+	// Bodies, raw paths and query strings never reach the log
+	assert.NotContains(t, buf.String(), "123-45-6789")
+	assert.NotContains(t, buf.String(), "5b7c1f0e")
+	assert.NotContains(t, buf.String(), "view=full")
+}
+
+func TestAccessLog_Levels(t *testing.T) {
+	buf := captureLogs(t)
+	config := DefaultAccessLogConfig()
+	config.SlowThreshold = 10 * time.Millisecond
+	e := newAccessLogServer(config)
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	entries := logEntries(t, buf)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "WARN", entries[0]["level"])
+	assert.Equal(t, true, entries[0]["slow"])
+	assert.Equal(t, "anonymous", entries[0]["caller"])
+	assert.Equal(t, "ERROR", entries[1]["level"])
+	assert.Equal(t, float64(http.StatusInternalServerError), entries[1]["status"])
+}
+
+func TestAccessLog_SamplesHealthChecks(t *testing.T) {
+	buf := captureLogs(t)
+	config := DefaultAccessLogConfig()
+	config.HealthSampleEvery = 3
+	e := newAccessLogServer(config)
+
+	for i := 0; i < 7; i++ {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	}
+
+	// The 1st, 4th and 7th probe are logged
+	assert.Len(t, logEntries(t, buf), 3)
+
+	buf.Reset()
+	config.HealthSampleEvery = 0
+	e = newAccessLogServer(config)
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Empty(t, logEntries(t, buf))
+}
@@ -0,0 +1,296 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+
+	"person-service/auth"
+	"person-service/config"
+	"person-service/credentials"
+	errs "person-service/errors"
+	"person-service/logging"
+	"person-service/oidc"
+
+	"github.com/labstack/echo/v4"
+)
+
+// API key format: person-service-key-<UUID>
+// UUID format: 8-4-4-4-12 hexadecimal characters
+var apiKeyPattern = regexp.MustCompile(`^person-service-key-[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// CredentialLookup resolves an API key to the principal of a stored credential.
+// It returns credentials.ErrInvalidCredential when the key is unknown, revoked or expired.
+type CredentialLookup interface {
+	Authenticate(ctx context.Context, key string) (auth.Principal, error)
+}
+
+// TokenVerifier validates a signed bearer token and returns its principal
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (auth.Principal, error)
+}
+
+// CertificateMapper maps a verified client certificate to a principal and
+// reports false when the certificate has no configured identity
+type CertificateMapper interface {
+	Principal(cert *x509.Certificate) (auth.Principal, bool)
+}
+
+// envKey is a bootstrap key loaded from the environment, kept only as a hash
+type envKey struct {
+	hash      [sha256.Size]byte
+	principal auth.Principal
+}
+
+// Authenticator verifies API keys sent in the x-api-key header or as an
+// Authorization: Bearer token, JWT bearer tokens when a verifier is set and
+// verified TLS client certificates when a certificate mapper is set. The blue
+// and green bootstrap keys are loaded once and replaced by Reload; any other
+// key is resolved through lookup.
+type Authenticator struct {
+	lookup       CredentialLookup
+	verifier     TokenVerifier
+	certificates CertificateMapper
+
+	mu   sync.RWMutex
+	keys []envKey
+}
+
+// NewAuthenticator creates a new instance of Authenticator and loads the
+// bootstrap keys. lookup may be nil when only bootstrap keys are accepted,
+// verifier may be nil when JWTs are not accepted and certificates may be nil
+// when client certificates are not accepted.
+func NewAuthenticator(lookup CredentialLookup, verifier TokenVerifier, certificates CertificateMapper) *Authenticator {
+	a := &Authenticator{
+		lookup:       lookup,
+		verifier:     verifier,
+		certificates: certificates,
+	}
+	if err := a.Reload(); err != nil {
+		logging.Error("Failed to load API keys", "error", err)
+	}
+	return a
+}
+
+// Reload reads the bootstrap keys again with config.LoadBootstrapKeys, so
+// mounted secrets can be rotated without a restart. Keys that do not match
+// the expected format are ignored. On error the previous keys stay active.
+func (a *Authenticator) Reload() error {
+	bootstrapKeys, err := config.LoadBootstrapKeys()
+	if err != nil {
+		return err
+	}
+
+	var keys []envKey
+	for _, bootstrapKey := range bootstrapKeys {
+		if !apiKeyPattern.MatchString(bootstrapKey.Key) {
+			continue
+		}
+		keys = append(keys, envKey{
+			hash:      sha256.Sum256([]byte(bootstrapKey.Key)),
+			principal: envKeyPrincipal(bootstrapKey),
+		})
+	}
+
+	a.mu.Lock()
+	a.keys = keys
+	a.mu.Unlock()
+
+	logging.Info("API keys loaded", "bootstrap_keys", len(keys))
+	return nil
+}
+
+// ReloadOnSignal reloads the bootstrap keys on every SIGHUP until the context is cancelled
+func (a *Authenticator) ReloadOnSignal(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := a.Reload(); err != nil {
+				logging.Error("Failed to reload API keys, keeping previous keys", "error", err)
+			}
+		}
+	}
+}
+
+// Middleware creates a middleware that authenticates the request and stores
+// the caller's principal in the request context
+func (a *Authenticator) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal, status, errResponse := a.authenticateRequest(c.Request())
+			if errResponse != nil {
+				return c.JSON(status, errResponse)
+			}
+
+			// Make the caller identity available to handlers and audit logging
+			ctx := auth.ContextWithPrincipal(c.Request().Context(), principal)
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
+
+// authenticateRequest resolves a mapped client certificate first, then the
+// x-api-key header or, when that header is absent, the Authorization: Bearer
+// header. Bearer tokens may be API keys or, when a verifier is configured, JWTs.
+func (a *Authenticator) authenticateRequest(r *http.Request) (auth.Principal, int, *errs.ErrorResponse) {
+	ctx := r.Context()
+
+	// The TLS handshake has already verified the chain against the client CA bundle
+	var unmappedCertificate bool
+	if a.certificates != nil && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		if principal, ok := a.certificates.Principal(r.TLS.VerifiedChains[0][0]); ok {
+			return principal, http.StatusOK, nil
+		}
+		unmappedCertificate = true
+	}
+
+	if apiKey := r.Header.Get("x-api-key"); apiKey != "" {
+		if !apiKeyPattern.MatchString(apiKey) {
+			return auth.Principal{}, http.StatusUnauthorized, &errs.ErrorResponse{
+				Message:   "Invalid API key format",
+				ErrorCode: errs.ErrInvalidAPIKeyFormat,
+			}
+		}
+		return a.authenticate(ctx, apiKey)
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" && unmappedCertificate {
+		return auth.Principal{}, http.StatusUnauthorized, &errs.ErrorResponse{
+			Message:   "Client certificate is not mapped to an identity",
+			ErrorCode: errs.ErrUnknownClientCertificate,
+		}
+	}
+	if authHeader == "" {
+		return auth.Principal{}, http.StatusUnauthorized, &errs.ErrorResponse{
+			Message:   "Missing required header \"x-api-key\" or \"Authorization\"",
+			ErrorCode: errs.ErrMissingAPIKey,
+		}
+	}
+
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return auth.Principal{}, http.StatusUnauthorized, &errs.ErrorResponse{
+			Message:   "Invalid Authorization header format, expected Bearer token",
+			ErrorCode: errs.ErrInvalidBearerFormat,
+		}
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if apiKeyPattern.MatchString(token) {
+		return a.authenticate(ctx, token)
+	}
+
+	if a.verifier != nil && oidc.LooksLikeJWT(token) {
+		principal, err := a.verifier.Verify(ctx, token)
+		if err != nil {
+			logging.DebugContext(ctx, "Rejected bearer JWT", "error", err)
+			return auth.Principal{}, http.StatusUnauthorized, &errs.ErrorResponse{
+				Message:   "Invalid token",
+				ErrorCode: errs.ErrInvalidToken,
+			}
+		}
+		return principal, http.StatusOK, nil
+	}
+
+	return auth.Principal{}, http.StatusUnauthorized, &errs.ErrorResponse{
+		Message:   "Invalid token format",
+		ErrorCode: errs.ErrInvalidBearerFormat,
+	}
+}
+
+// authenticate resolves a well-formed key to a principal. On failure it
+// returns the HTTP status and error response to send.
+func (a *Authenticator) authenticate(ctx context.Context, key string) (auth.Principal, int, *errs.ErrorResponse) {
+	principal, matched, configured := a.matchEnvKey(key)
+	if matched {
+		return principal, http.StatusOK, nil
+	}
+
+	// If no key source is configured, reject the request
+	if a.lookup == nil {
+		if !configured {
+			return auth.Principal{}, http.StatusServiceUnavailable, &errs.ErrorResponse{
+				Message:   "API keys are not properly configured",
+				ErrorCode: errs.ErrAPIKeysNotConfigured,
+			}
+		}
+		return auth.Principal{}, http.StatusUnauthorized, &errs.ErrorResponse{
+			Message:   "Invalid API key",
+			ErrorCode: errs.ErrInvalidAPIKey,
+		}
+	}
+
+	principal, err := a.lookup.Authenticate(ctx, key)
+	if errors.Is(err, credentials.ErrInvalidCredential) {
+		return auth.Principal{}, http.StatusUnauthorized, &errs.ErrorResponse{
+			Message:   "Invalid API key",
+			ErrorCode: errs.ErrInvalidAPIKey,
+		}
+	}
+	if err != nil {
+		logging.ErrorContext(ctx, "Failed to look up API credential", "error", err)
+		return auth.Principal{}, http.StatusInternalServerError, &errs.ErrorResponse{
+			Message:   "Failed to verify API key",
+			ErrorCode: errs.ErrCredentialLookupFailed,
+		}
+	}
+	return principal, http.StatusOK, nil
+}
+
+// matchEnvKey compares key with every bootstrap key in constant time and
+// reports the matching principal and whether any bootstrap key is configured
+func (a *Authenticator) matchEnvKey(key string) (principal auth.Principal, matched, configured bool) {
+	hash := sha256.Sum256([]byte(key))
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	// Every key is compared so the time taken does not reveal which one matched
+	for _, k := range a.keys {
+		if subtle.ConstantTimeCompare(hash[:], k.hash[:]) == 1 {
+			principal = k.principal
+			matched = true
+		}
+	}
+	return principal, matched, len(a.keys) > 0
+}
+
+// envKeyPrincipal builds the principal for a blue or green bootstrap key.
+// Its key-value namespace falls back to auth.DefaultNamespace and its tenant
+// to auth.DefaultTenant when not configured.
+// Bootstrap keys are granted every scope so they can issue the first credentials.
+func envKeyPrincipal(key config.BootstrapKey) auth.Principal {
+	namespace := key.Namespace
+	if namespace == "" {
+		namespace = auth.DefaultNamespace
+	}
+	tenant := key.Tenant
+	if tenant == "" {
+		tenant = auth.DefaultTenant
+	}
+	return auth.Principal{
+		ID:        key.Name,
+		Source:    auth.SourceBootstrapKey,
+		Name:      key.Name,
+		Namespace: namespace,
+		Tenant:    tenant,
+		Scopes:    auth.AllScopes,
+	}
+}
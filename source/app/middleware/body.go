@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MaxBufferedBodySize caps the request bodies middleware reads before the handler
+const MaxBufferedBodySize = 1 << 20
+
+// bufferBody reads the request body up to MaxBufferedBodySize and restores it
+// for the handler. It reports whether the body was too large.
+func bufferBody(c echo.Context) ([]byte, bool, error) {
+	req := c.Request()
+	body, err := io.ReadAll(http.MaxBytesReader(c.Response(), req.Body, MaxBufferedBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		return nil, errors.As(err, &tooLarge), err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, false, nil
+}
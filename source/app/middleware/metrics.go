@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"person-service/metrics"
+
+	"github.com/labstack/echo/v4"
+)
+
+// unmatchedRoute labels requests that matched no route, so unknown paths do
+// not create new label values. Route templates come from registered routes
+// only, which keeps the label set bounded.
+const unmatchedRoute = "unmatched"
+
+// Metrics creates a middleware that records request counts and latency by
+// method, route template and status class. It must be registered with Use so
+// the route is already resolved.
+func Metrics() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			route := c.Path()
+			if route == "" {
+				route = unmatchedRoute
+			}
+			metrics.ObserveRequest(c.Request().Method, route, responseStatus(c, err), time.Since(start))
+			return err
+		}
+	}
+}
+
+// responseStatus returns the status the request is answered with. Errors
+// returned to echo are written after the middleware chain, so their status is
+// derived the way the default error handler does.
+func responseStatus(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"person-service/metrics"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// scrapeMetrics returns the current metrics exposition
+func scrapeMetrics(t *testing.T) string {
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
+
+func TestMetrics(t *testing.T) {
+	e := echo.New()
+	e.Use(Metrics())
+	e.GET("/persons/:personId/attributes", func(c echo.Context) error {
+		return c.String(http.StatusOK, "OK")
+	})
+	e.DELETE("/persons/:personId/attributes", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusConflict, "conflict")
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/persons/5b7c1f0e-1d2a-4c55-9f7a-0c1d2e3f4a5b/attributes", nil),
+		httptest.NewRequest(http.MethodDelete, "/persons/5b7c1f0e-1d2a-4c55-9f7a-0c1d2e3f4a5b/attributes", nil),
+		httptest.NewRequest(http.MethodGet, "/no-such-route/42", nil),
+	} {
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	body := scrapeMetrics(t)
+
+	// Requests are labelled with the route template, never the raw path
+	assert.Contains(t, body, `person_service_http_requests_total{method="GET",route="/persons/:personId/attributes",status_class="2xx"}`)
+	assert.Contains(t, body, `person_service_http_request_duration_seconds_count{method="GET",route="/persons/:personId/attributes",status_class="2xx"}`)
+	assert.NotContains(t, body, "5b7c1f0e")
+
+	// Errors returned to echo are counted with the status they are answered with
+	assert.Contains(t, body, `person_service_http_requests_total{method="DELETE",route="/persons/:personId/attributes",status_class="4xx"}`)
+
+	// Unknown paths share a single label value
+	assert.Contains(t, body, `route="unmatched",status_class="4xx"`)
+	assert.NotContains(t, body, "no-such-route")
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"person-service/auth"
+	errs "person-service/errors"
+	"person-service/logging"
+	"person-service/metrics"
+	"person-service/purpose"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AccessRecorder records PII access made under a declared purpose
+type AccessRecorder interface {
+	Record(ctx context.Context, principal auth.Principal, access purpose.Access) error
+}
+
+// purposeBody is the part of a JSON request body that can declare the purpose
+type purposeBody struct {
+	Meta *struct {
+		Reason string `json:"reason"`
+	} `json:"meta"`
+}
+
+// RequirePurpose creates a middleware that rejects PII requests without a
+// registered purpose of use the principal may declare. The purpose is read
+// from the X-Purpose header, or from meta.reason of a JSON body of up to
+// MaxBufferedBodySize, which is restored for the handler. Every handled request is recorded with its
+// purpose. It must run after authentication; a nil registry lets every request through.
+func RequirePurpose(registry *purpose.Registry, recorder AccessRecorder) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if registry == nil {
+			return next
+		}
+
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := req.Context()
+
+			declared := req.Header.Get(purpose.Header)
+			if declared == "" && req.Body != nil && req.ContentLength != 0 {
+				body, tooLarge, err := bufferBody(c)
+				if tooLarge {
+					return c.JSON(http.StatusRequestEntityTooLarge, errs.ErrorResponse{
+						Message:   "Request body is too large",
+						ErrorCode: errs.ErrPurposeBodyTooLarge,
+					})
+				}
+				if err != nil {
+					return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+						Message:   "Failed to read request body",
+						ErrorCode: errs.ErrMissingPurpose,
+					})
+				}
+
+				var parsed purposeBody
+				if json.Unmarshal(body, &parsed) == nil && parsed.Meta != nil {
+					declared = parsed.Meta.Reason
+				}
+			}
+
+			if declared == "" {
+				return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+					Message:   "A purpose of use is required in the " + purpose.Header + " header or meta.reason",
+					ErrorCode: errs.ErrMissingPurpose,
+				})
+			}
+			if !registry.Has(declared) {
+				return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+					Message:   "Unknown purpose of use \"" + declared + "\"",
+					ErrorCode: errs.ErrUnknownPurpose,
+				})
+			}
+
+			principal, _ := auth.PrincipalFromContext(ctx)
+			if !registry.Allows(principal, declared) {
+				return c.JSON(http.StatusForbidden, errs.ErrorResponse{
+					Message:   "API key may not declare purpose \"" + declared + "\"",
+					ErrorCode: errs.ErrPurposeNotAllowed,
+				})
+			}
+
+			ctx = purpose.ContextWithPurpose(ctx, declared)
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+
+			// The response is already decided, so recording failures are only logged
+			if recorder != nil {
+				access := purpose.Access{
+					Purpose: declared,
+					Method:  req.Method,
+					Path:    req.URL.Path,
+					Status:  responseStatus(c, err),
+				}
+				if recordErr := recorder.Record(ctx, principal, access); recordErr != nil {
+					metrics.RecordAuditFailure(metrics.AuditSourcePurpose)
+					logging.WarnContext(ctx, "Failed to record purpose of use",
+						"error", recordErr,
+						"error_code", errs.ErrFailedRecordPurpose)
+				}
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"person-service/auth"
+	"person-service/purpose"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingRecorder keeps the accesses it is asked to record
+type recordingRecorder struct {
+	accesses []purpose.Access
+}
+
+func (r *recordingRecorder) Record(ctx context.Context, principal auth.Principal, access purpose.Access) error {
+	r.accesses = append(r.accesses, access)
+	return nil
+}
+
+func TestRequirePurpose(t *testing.T) {
+	registry, err := purpose.NewRegistry([]string{"kyc_verification", "customer_support"})
+	assert.NoError(t, err)
+	recorder := &recordingRecorder{}
+
+	var seenPurpose, seenBody string
+	handler := RequirePurpose(registry, recorder)(func(c echo.Context) error {
+		seenPurpose = purpose.FromContext(c.Request().Context())
+		body, _ := io.ReadAll(c.Request().Body)
+		seenBody = string(body)
+		return c.String(http.StatusOK, "OK")
+	})
+
+	serve := func(header, body string, principal auth.Principal) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/persons/1/attributes", strings.NewReader(body))
+		if header != "" {
+			req.Header.Set(purpose.Header, header)
+		}
+		req = req.WithContext(auth.ContextWithPrincipal(req.Context(), principal))
+		rec := httptest.NewRecorder()
+		_ = handler(e.NewContext(req, rec))
+		return rec
+	}
+
+	support := auth.Principal{ID: "support", Purposes: []string{"customer_support"}}
+
+	rec := serve("customer_support", "", support)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "customer_support", seenPurpose)
+
+	// meta.reason declares the purpose when the header is absent and the body stays readable
+	body := `{"key":"email","value":"a@b.c","meta":{"caller":"crm","reason":"kyc_verification"}}`
+	rec = serve("", body, auth.Principal{ID: "bootstrap"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "kyc_verification", seenPurpose)
+	assert.Equal(t, body, seenBody)
+
+	rec = serve("", `{"key":"email"}`, support)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "PU_001_MISSING_PURPOSE")
+
+	rec = serve("marketing", "", support)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "PU_002_UNKNOWN_PURPOSE")
+
+	rec = serve("kyc_verification", "", support)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "PU_101_PURPOSE_NOT_ALLOWED")
+
+	// Only handled requests are recorded
+	assert.Equal(t, []purpose.Access{
+		{Purpose: "customer_support", Method: http.MethodPost, Path: "/persons/1/attributes", Status: http.StatusOK},
+		{Purpose: "kyc_verification", Method: http.MethodPost, Path: "/persons/1/attributes", Status: http.StatusOK},
+	}, recorder.accesses)
+}
+
+func TestRequirePurpose_BodyTooLarge(t *testing.T) {
+	registry, err := purpose.NewRegistry([]string{"kyc_verification"})
+	assert.NoError(t, err)
+	handler := RequirePurpose(registry, nil)(func(c echo.Context) error {
+		return c.String(http.StatusOK, "OK")
+	})
+
+	e := echo.New()
+	body := `{"meta":{"reason":"kyc_verification"},"value":"` + strings.Repeat("x", MaxBufferedBodySize) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/persons/1/attributes", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	assert.NoError(t, handler(e.NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Contains(t, rec.Body.String(), "PU_004_BODY_TOO_LARGE")
+}
+
+func TestRequirePurpose_RecordsErrorStatus(t *testing.T) {
+	registry, err := purpose.NewRegistry([]string{"kyc_verification"})
+	assert.NoError(t, err)
+	recorder := &recordingRecorder{}
+	handler := RequirePurpose(registry, recorder)(func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound, "not found")
+	})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/persons/1", nil)
+	req.Header.Set(purpose.Header, "kyc_verification")
+	_ = handler(e.NewContext(req, httptest.NewRecorder()))
+
+	// The error has not been written yet, so the status comes from it
+	assert.Equal(t, []purpose.Access{
+		{Purpose: "kyc_verification", Method: http.MethodGet, Path: "/persons/1", Status: http.StatusNotFound},
+	}, recorder.accesses)
+}
+
+func TestRequirePurpose_NilRegistry(t *testing.T) {
+	handler := RequirePurpose(nil, nil)(func(c echo.Context) error {
+		return c.String(http.StatusOK, "OK")
+	})
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	err := handler(e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"person-service/auth"
+	errs "person-service/errors"
+	"person-service/logging"
+	"person-service/ratelimit"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RateLimit creates a middleware that takes a token from the caller's bucket
+// for class and rejects the request with 429 when the bucket is empty. It must
+// run after authentication; callers are keyed by principal source and ID, and
+// unauthenticated callers are limited by client IP.
+// A nil limiter or a class without a limit lets every request through. When
+// the backend fails, requests are allowed so an outage does not block the API.
+func RateLimit(limiter *ratelimit.Limiter, class ratelimit.Class) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if limiter == nil || !limiter.Limits(class) {
+			return next
+		}
+
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+
+			caller := "ip:" + c.RealIP()
+			if principal, ok := auth.PrincipalFromContext(ctx); ok {
+				caller = principal.Source + ":" + principal.ID
+			}
+
+			result, err := limiter.Allow(ctx, class, caller)
+			if err != nil {
+				logging.WarnContext(ctx, "Failed to check rate limit, allowing request",
+					"error", err,
+					"error_code", errs.ErrFailedCheckRateLimit)
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+
+			if !result.Allowed {
+				header.Set("Retry-After", ceilSeconds(result.RetryAfter))
+				return c.JSON(http.StatusTooManyRequests, errs.ErrorResponse{
+					Message:   "Rate limit exceeded",
+					ErrorCode: errs.ErrRateLimitExceeded,
+				})
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// ceilSeconds formats d as whole seconds, rounded up
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
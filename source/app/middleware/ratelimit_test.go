@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"person-service/auth"
+	"person-service/ratelimit"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingBackend always returns an error
+type failingBackend struct{}
+
+func (failingBackend) Take(ctx context.Context, key string, limit ratelimit.Limit) (float64, bool, error) {
+	return 0, false, errors.New("connection refused")
+}
+
+func serveRateLimited(handler echo.HandlerFunc, principalID string) *httptest.ResponseRecorder {
+	return serveRateLimitedAs(handler, auth.Principal{ID: principalID, Source: auth.SourceCredential})
+}
+
+func serveRateLimitedAs(handler echo.HandlerFunc, principal auth.Principal) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(auth.ContextWithPrincipal(req.Context(), principal))
+	rec := httptest.NewRecorder()
+	_ = handler(e.NewContext(req, rec))
+	return rec
+}
+
+func TestRateLimit(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryBackend(), map[ratelimit.Class]ratelimit.Limit{
+		ratelimit.ClassWrite: {Requests: 2, Period: time.Minute},
+	})
+	handler := RateLimit(limiter, ratelimit.ClassWrite)(func(c echo.Context) error {
+		return c.String(http.StatusOK, "OK")
+	})
+
+	rec := serveRateLimited(handler, "billing")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", rec.Header().Get("RateLimit-Reset"))
+	assert.Empty(t, rec.Header().Get("Retry-After"))
+
+	rec = serveRateLimited(handler, "billing")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+
+	rec = serveRateLimited(handler, "billing")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Contains(t, rec.Body.String(), "RL_001_RATE_LIMIT_EXCEEDED")
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+
+	// Each principal has its own bucket
+	rec = serveRateLimited(handler, "reports")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// The same ID from another source does not share the bucket
+	rec = serveRateLimitedAs(handler, auth.Principal{ID: "billing", Source: auth.SourceJWT})
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryBackend(), map[ratelimit.Class]ratelimit.Limit{
+		ratelimit.ClassWrite: {Requests: 1, Period: time.Minute},
+	})
+	next := func(c echo.Context) error {
+		return c.String(http.StatusOK, "OK")
+	}
+
+	for _, handler := range []echo.HandlerFunc{
+		RateLimit(nil, ratelimit.ClassWrite)(next),
+		RateLimit(limiter, ratelimit.ClassRead)(next),
+	} {
+		for i := 0; i < 3; i++ {
+			rec := serveRateLimited(handler, "billing")
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+		}
+	}
+}
+
+func TestRateLimit_BackendFailureAllowsRequest(t *testing.T) {
+	limiter := ratelimit.NewLimiter(failingBackend{}, map[ratelimit.Class]ratelimit.Limit{
+		ratelimit.ClassRead: {Requests: 1, Period: time.Minute},
+	})
+	handler := RateLimit(limiter, ratelimit.ClassRead)(func(c echo.Context) error {
+		return c.String(http.StatusOK, "OK")
+	})
+
+	rec := serveRateLimited(handler, "billing")
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
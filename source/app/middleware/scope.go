@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"person-service/auth"
+	errs "person-service/errors"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RequireScope creates a middleware that rejects requests whose principal was
+// not granted scope. It must run after Authenticator.Middleware.
+func RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal, ok := auth.PrincipalFromContext(c.Request().Context())
+			if !ok || !principal.HasScope(scope) {
+				return c.JSON(http.StatusForbidden, errs.ErrorResponse{
+					Message:   "API key is missing required scope \"" + scope + "\"",
+					ErrorCode: errs.ErrInsufficientScope,
+				})
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"person-service/auth"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireScope(t *testing.T) {
+	os.Setenv("PERSON_API_KEY_BLUE", validAPIKeyBlue)
+	defer os.Unsetenv("PERSON_API_KEY_BLUE")
+
+	e := echo.New()
+	e.GET("/read", func(c echo.Context) error {
+		return c.String(http.StatusOK, "OK")
+	}, NewAuthenticator(fakeLookup{}, nil, nil).Middleware(), RequireScope(auth.ScopePersonRead))
+	e.POST("/write", func(c echo.Context) error {
+		return c.String(http.StatusOK, "OK")
+	}, NewAuthenticator(fakeLookup{}, nil, nil).Middleware(), RequireScope(auth.ScopePersonWrite))
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		key      string
+		expected int
+	}{
+		{"granted scope", http.MethodGet, "/read", storedAPIKey, http.StatusOK},
+		{"missing scope", http.MethodPost, "/write", storedAPIKey, http.StatusForbidden},
+		{"environment key has every scope", http.MethodPost, "/write", validAPIKeyBlue, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("x-api-key", tt.key)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expected, rec.Code)
+			if tt.expected == http.StatusForbidden {
+				assert.Contains(t, rec.Body.String(), "API_008_INSUFFICIENT_SCOPE")
+			}
+		})
+	}
+}
+
+func TestRequireScope_NoPrincipal(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	handler := RequireScope(auth.ScopeAdmin)(func(c echo.Context) error {
+		return c.String(http.StatusOK, "OK")
+	})
+
+	err := handler(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"person-service/auth"
+	errs "person-service/errors"
+	"person-service/logging"
+	"person-service/signing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RequireSignature creates a middleware that rejects requests without a valid
+// HMAC signature from a key bound to the authenticated principal, a fresh
+// timestamp and an unused nonce. The body, up to MaxBufferedBodySize, is read
+// to verify its hash and restored for the handler.
+func RequireSignature(verifier *signing.Verifier) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := req.Context()
+
+			body, tooLarge, err := bufferBody(c)
+			if tooLarge {
+				return c.JSON(http.StatusRequestEntityTooLarge, errs.ErrorResponse{
+					Message:   "Request body is too large",
+					ErrorCode: errs.ErrSignedBodyTooLarge,
+				})
+			}
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+					Message:   "Failed to read request body",
+					ErrorCode: errs.ErrMissingSignature,
+				})
+			}
+
+			principal, _ := auth.PrincipalFromContext(ctx)
+			keyID, err := verifier.Verify(ctx, req, body, principal)
+			switch {
+			case err == nil:
+				logging.DebugContext(ctx, "Request signature verified", "signing_key_id", keyID)
+				return next(c)
+			case errors.Is(err, signing.ErrMissingHeaders):
+				return c.JSON(http.StatusUnauthorized, errs.ErrorResponse{
+					Message:   "Missing or malformed request signature headers",
+					ErrorCode: errs.ErrMissingSignature,
+				})
+			case errors.Is(err, signing.ErrInvalidSignature):
+				return c.JSON(http.StatusUnauthorized, errs.ErrorResponse{
+					Message:   "Invalid request signature",
+					ErrorCode: errs.ErrInvalidSignature,
+				})
+			case errors.Is(err, signing.ErrPrincipalMismatch):
+				logging.WarnContext(ctx, "Signing key used by another principal",
+					"signing_key_id", req.Header.Get(signing.HeaderKeyID),
+					"principal_source", principal.Source,
+					"principal_id", principal.ID)
+				return c.JSON(http.StatusUnauthorized, errs.ErrorResponse{
+					Message:   "Request signing key does not belong to the authenticated principal",
+					ErrorCode: errs.ErrSigningKeyPrincipalMismatch,
+				})
+			case errors.Is(err, signing.ErrTimestampOutOfWindow):
+				return c.JSON(http.StatusUnauthorized, errs.ErrorResponse{
+					Message:   "Request signature timestamp is outside the allowed window",
+					ErrorCode: errs.ErrSignatureExpired,
+				})
+			case errors.Is(err, signing.ErrNonceReused):
+				return c.JSON(http.StatusUnauthorized, errs.ErrorResponse{
+					Message:   "Request signature nonce was already used",
+					ErrorCode: errs.ErrSignatureNonceReused,
+				})
+			default:
+				logging.ErrorContext(ctx, "Failed to verify request signature", "error", err)
+				return c.JSON(http.StatusInternalServerError, errs.ErrorResponse{
+					Message:   "Failed to verify request signature",
+					ErrorCode: errs.ErrFailedRecordNonce,
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"person-service/auth"
+	"person-service/signing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSigningSecret = "0123456789abcdef0123456789abcdef"
+
+func TestRequireSignature(t *testing.T) {
+	verifier := signing.NewVerifier(map[string][]byte{"billing": []byte(testSigningSecret)},
+		map[string]signing.Binding{"billing": {Source: auth.SourceJWT, PrincipalID: "billing-service"}}, time.Minute, signing.NewMemoryNonceStore())
+
+	e := echo.New()
+	var received string
+	e.POST("/signed", func(c echo.Context) error {
+		var body map[string]string
+		if err := c.Bind(&body); err != nil {
+			return err
+		}
+		received = body["value"]
+		return c.String(http.StatusOK, "OK")
+	}, RequireSignature(verifier))
+
+	body := `{"value":"signed"}`
+	newRequestAs := func(principal auth.Principal, nonce string, signedAt time.Time) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/signed", strings.NewReader(body))
+		req = req.WithContext(auth.ContextWithPrincipal(req.Context(), principal))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+		req.Header.Set(signing.HeaderKeyID, "billing")
+		req.Header.Set(signing.HeaderTimestamp, timestamp)
+		req.Header.Set(signing.HeaderNonce, nonce)
+		req.Header.Set(signing.HeaderSignature,
+			signing.Sign([]byte(testSigningSecret), http.MethodPost, "/signed", timestamp, nonce, []byte(body)))
+		return req
+	}
+	newRequest := func(nonce string, signedAt time.Time) *http.Request {
+		return newRequestAs(auth.Principal{ID: "billing-service", Source: auth.SourceJWT}, nonce, signedAt)
+	}
+
+	// The handler still receives the body after verification
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, newRequest("middleware-nonce-1", time.Now()))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "signed", received)
+
+	tests := []struct {
+		name      string
+		req       *http.Request
+		errorCode string
+	}{
+		{"replayed nonce", newRequest("middleware-nonce-1", time.Now()), "SIG_004_NONCE_REUSED"},
+		{"stale timestamp", newRequest("middleware-nonce-2", time.Now().Add(-time.Hour)), "SIG_003_TIMESTAMP_OUT_OF_WINDOW"},
+		{"key of another principal", newRequestAs(auth.Principal{ID: "reports-service", Source: auth.SourceJWT}, "middleware-nonce-4", time.Now()), "SIG_006_KEY_PRINCIPAL_MISMATCH"},
+		{"same id from another source", newRequestAs(auth.Principal{ID: "billing-service", Source: auth.SourceCertificate}, "middleware-nonce-5", time.Now()), "SIG_006_KEY_PRINCIPAL_MISMATCH"},
+		{"unsigned", httptest.NewRequest(http.MethodPost, "/signed", strings.NewReader(body)), "SIG_001_MISSING_SIGNATURE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, tt.req)
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.errorCode)
+		})
+	}
+
+	// A tampered signature is rejected
+	req := newRequest("middleware-nonce-3", time.Now())
+	req.Header.Set(signing.HeaderSignature, strings.Repeat("0", 64))
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "SIG_002_INVALID_SIGNATURE")
+
+	// Bodies are only buffered up to the cap
+	req = httptest.NewRequest(http.MethodPost, "/signed", strings.NewReader(strings.Repeat("x", MaxBufferedBodySize+1)))
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Contains(t, rec.Body.String(), "SIG_007_BODY_TOO_LARGE")
+}
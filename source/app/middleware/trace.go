@@ -1,15 +1,21 @@
 package middleware
 
 import (
+	"net/http"
+	"strings"
+
 	"person-service/tracing"
 
-	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	// GCPTraceHeader is the header name for GCP Cloud Trace context
-	GCPTraceHeader = "X-Cloud-Trace-Context"
+	GCPTraceHeader = tracing.GCPTraceHeader
 
 	// TraceIDResponseHeader is the header returned to clients for debugging
 	TraceIDResponseHeader = "X-Trace-ID"
@@ -18,32 +24,91 @@ const (
 	EchoTraceIDKey = "trace-id"
 )
 
-// TraceMiddleware extracts the trace ID from GCP Load Balancer headers
-// and propagates it through the request context.
-// If no trace header is present, generates a UUID as fallback (for local dev).
+// Span attributes identifying the person a request is about
+const (
+	PersonIDAttribute    = attribute.Key("person.id")
+	AttributeIDAttribute = attribute.Key("person.attribute.id")
+)
+
+// TraceMiddleware starts a server span for every request and propagates the
+// trace ID through the request context. A trace from the W3C traceparent,
+// B3 or GCP Load Balancer headers is continued, so spans and logs share its
+// trace ID. If no span can be recorded and no trace header is present, random
+// W3C IDs are used as fallback (for local dev). The span's traceparent is
+// returned to the client. It must be registered with Use so the route is
+// resolved.
 func TraceMiddleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			// Extract trace ID from GCP header
-			gcpTraceHeader := c.Request().Header.Get(GCPTraceHeader)
-			traceID := tracing.ParseGCPTraceHeader(gcpTraceHeader)
+			req := c.Request()
+			ctx := req.Context()
+
+			// Extract trace context from traceparent, B3 or GCP headers
+			ctx = tracing.Extract(ctx, req.Header)
 
-			// Generate fallback UUID if no trace header present (local dev)
-			if traceID == "" {
-				traceID = uuid.New().String()
+			route := c.Path()
+			spanName := req.Method
+			if route != "" {
+				spanName += " " + route
 			}
+			ctx, span := tracing.Tracer().Start(ctx, spanName,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(requestAttributes(c, route)...))
+			defer span.End()
+
+			// Generate fallback IDs if no trace is available (local dev)
+			if !span.SpanContext().IsValid() {
+				ctx = trace.ContextWithSpanContext(ctx, tracing.NewLocalSpanContext())
+			}
+			traceID := trace.SpanContextFromContext(ctx).TraceID().String()
 
 			// Store trace ID in Echo context for easy access in handlers
 			c.Set(EchoTraceIDKey, traceID)
 
-			// Add trace ID to response header for debugging
+			// Add trace ID and trace context to response headers for debugging
 			c.Response().Header().Set(TraceIDResponseHeader, traceID)
+			tracing.Inject(ctx, c.Response().Header())
 
 			// Create new Go context with trace ID for downstream propagation
-			ctx := tracing.ContextWithTraceID(c.Request().Context(), traceID)
-			c.SetRequest(c.Request().WithContext(ctx))
+			ctx = tracing.ContextWithTraceID(ctx, traceID)
+			c.SetRequest(req.WithContext(ctx))
 
-			return next(c)
+			err := next(c)
+
+			status := responseStatus(c, err)
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if err != nil {
+				span.RecordError(err)
+			}
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			return err
 		}
 	}
 }
+
+// requestAttributes returns the span attributes of a request. Path IDs are
+// recorded as attributes so the span name stays the route template.
+func requestAttributes(c echo.Context, route string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(c.Request().Method),
+		semconv.URLPath(c.Request().URL.Path),
+	}
+	if route != "" {
+		attrs = append(attrs, semconv.HTTPRoute(route))
+	}
+
+	// Person routes name the person ID :id, attribute routes :personId
+	personID := c.Param("personId")
+	if personID == "" && strings.HasPrefix(route, "/api/person/") {
+		personID = c.Param("id")
+	}
+	if personID != "" {
+		attrs = append(attrs, PersonIDAttribute.String(personID))
+	}
+	if attributeID := c.Param("attributeId"); attributeID != "" {
+		attrs = append(attrs, AttributeIDAttribute.String(attributeID))
+	}
+	return attrs
+}
@@ -8,6 +8,13 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestTraceMiddleware_WithGCPHeader(t *testing.T) {
@@ -45,10 +52,10 @@ func TestTraceMiddleware_WithoutGCPHeader(t *testing.T) {
 
 	middleware := TraceMiddleware()
 	handler := middleware(func(c echo.Context) error {
-		// Verify a UUID was generated as fallback
+		// Verify a W3C trace ID was generated as fallback
 		traceID := c.Get(EchoTraceIDKey).(string)
 		assert.NotEmpty(t, traceID)
-		assert.Len(t, traceID, 36) // UUID format: 8-4-4-4-12
+		assert.Regexp(t, "^[0-9a-f]{32}$", traceID)
 
 		// Verify it's in the response header too
 		assert.Equal(t, traceID, rec.Header().Get(TraceIDResponseHeader))
@@ -75,10 +82,10 @@ func TestTraceMiddleware_EmptyGCPHeader(t *testing.T) {
 
 	middleware := TraceMiddleware()
 	handler := middleware(func(c echo.Context) error {
-		// Empty header should generate UUID fallback
+		// Empty header should generate W3C trace ID fallback
 		traceID := c.Get(EchoTraceIDKey).(string)
 		assert.NotEmpty(t, traceID)
-		assert.Len(t, traceID, 36)
+		assert.Regexp(t, "^[0-9a-f]{32}$", traceID)
 		return c.String(http.StatusOK, "OK")
 	})
 
@@ -86,6 +93,71 @@ func TestTraceMiddleware_EmptyGCPHeader(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestTraceMiddleware_W3CTraceparent(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(tracing.TracestateHeader, "vendor=value")
+	// traceparent is preferred over the other formats
+	req.Header.Set(GCPTraceHeader, "105445aa7843bc8bf206b12000100000/1;o=1")
+	req.Header.Set("b3", "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	handler := TraceMiddleware()(func(c echo.Context) error {
+		ctx := c.Request().Context()
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tracing.TraceIDFromContext(ctx))
+		assert.Equal(t, "00f067aa0ba902b7", tracing.SpanIDFromContext(ctx))
+		assert.True(t, tracing.SampledFromContext(ctx))
+		return c.String(http.StatusOK, "OK")
+	})
+
+	assert.NoError(t, handler(c))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", rec.Header().Get(TraceIDResponseHeader))
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", rec.Header().Get(tracing.TraceparentHeader))
+	assert.Equal(t, "vendor=value", rec.Header().Get(tracing.TracestateHeader))
+}
+
+func TestTraceMiddleware_B3(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-B3-TraceId", "80f198ee56343ba864fe8b2a57d3eff7")
+	req.Header.Set("X-B3-SpanId", "e457b5a2e4d86bd1")
+	req.Header.Set("X-B3-Sampled", "0")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	handler := TraceMiddleware()(func(c echo.Context) error {
+		ctx := c.Request().Context()
+		assert.Equal(t, "80f198ee56343ba864fe8b2a57d3eff7", tracing.TraceIDFromContext(ctx))
+		assert.False(t, tracing.SampledFromContext(ctx))
+		return c.String(http.StatusOK, "OK")
+	})
+
+	assert.NoError(t, handler(c))
+	assert.Equal(t, "00-80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-00", rec.Header().Get(tracing.TraceparentHeader))
+}
+
+func TestTraceMiddleware_InvalidTraceparent(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	var traceID string
+	handler := TraceMiddleware()(func(c echo.Context) error {
+		traceID = tracing.TraceIDFromContext(c.Request().Context())
+		return c.String(http.StatusOK, "OK")
+	})
+
+	// An all-zero trace ID is rejected and a new trace is started
+	assert.NoError(t, handler(c))
+	assert.Regexp(t, "^[0-9a-f]{32}$", traceID)
+	assert.NotEqual(t, "00000000000000000000000000000000", traceID)
+	assert.Contains(t, rec.Header().Get(tracing.TraceparentHeader), traceID)
+}
+
 func TestTraceMiddleware_PropagatesNextError(t *testing.T) {
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -101,3 +173,47 @@ func TestTraceMiddleware_PropagatesNextError(t *testing.T) {
 	err := handler(c)
 	assert.Equal(t, expectedErr, err)
 }
+
+func TestTraceMiddleware_RecordsServerSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	e := echo.New()
+	e.Use(TraceMiddleware())
+	var ctxTraceID, ctxSpanID string
+	e.GET("/persons/:personId/attributes/:attributeId", func(c echo.Context) error {
+		ctxTraceID = tracing.TraceIDFromContext(c.Request().Context())
+		ctxSpanID = tracing.SpanIDFromContext(c.Request().Context())
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "unavailable")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/persons/5b7c1f0e-1d2a-4c55-9f7a-0c1d2e3f4a5b/attributes/42", nil)
+	req.Header.Set(GCPTraceHeader, "105445aa7843bc8bf206b12000100000/1;o=1")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /persons/:personId/attributes/:attributeId", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, codes.Error, span.Status().Code)
+
+	// The load balancer's trace is continued and shared with logs and clients
+	assert.Equal(t, "105445aa7843bc8bf206b12000100000", span.SpanContext().TraceID().String())
+	assert.Equal(t, "0000000000000001", span.Parent().SpanID().String())
+	assert.Equal(t, "105445aa7843bc8bf206b12000100000", ctxTraceID)
+	assert.Equal(t, span.SpanContext().SpanID().String(), ctxSpanID)
+	assert.Equal(t, "105445aa7843bc8bf206b12000100000", rec.Header().Get(TraceIDResponseHeader))
+
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, attr := range span.Attributes() {
+		attrs[attr.Key] = attr.Value
+	}
+	assert.Equal(t, "/persons/:personId/attributes/:attributeId", attrs[semconv.HTTPRouteKey].AsString())
+	assert.Equal(t, "5b7c1f0e-1d2a-4c55-9f7a-0c1d2e3f4a5b", attrs[PersonIDAttribute].AsString())
+	assert.Equal(t, "42", attrs[AttributeIDAttribute].AsString())
+	assert.Equal(t, int64(http.StatusServiceUnavailable), attrs[semconv.HTTPResponseStatusCodeKey].AsInt64())
+}
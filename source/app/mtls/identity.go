@@ -0,0 +1,98 @@
+package mtls
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"person-service/auth"
+)
+
+// Identity maps a client certificate to a principal. Match is compared exactly
+// with the certificate's URI SANs (such as SPIFFE IDs), then its DNS SANs and
+// finally its subject common name.
+type Identity struct {
+	Match     string   `json:"match"`
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace"`
+	Tenant    string   `json:"tenant"`
+	Scopes    []string `json:"scopes"`
+}
+
+// loadIdentities reads and validates a JSON array of identities
+func loadIdentities(path string) ([]Identity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client identity file: %w", err)
+	}
+
+	var identities []Identity
+	if err := json.Unmarshal(data, &identities); err != nil {
+		return nil, fmt.Errorf("failed to parse client identity file: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i, identity := range identities {
+		if identity.Match == "" {
+			return nil, fmt.Errorf("client identity %d has no match", i)
+		}
+		if seen[identity.Match] {
+			return nil, fmt.Errorf("duplicate client identity %q", identity.Match)
+		}
+		seen[identity.Match] = true
+		for _, scope := range identity.Scopes {
+			if !auth.IsValidScope(scope) {
+				return nil, fmt.Errorf("client identity %q has unknown scope %q", identity.Match, scope)
+			}
+		}
+	}
+	return identities, nil
+}
+
+// certificateNames lists the names a certificate can be matched by, in order of precedence
+func certificateNames(cert *x509.Certificate) []string {
+	var names []string
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	names = append(names, cert.DNSNames...)
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	return names
+}
+
+// Principal maps a verified client certificate to the principal of the first
+// matching identity. It reports false when no identity matches.
+func (r *Reloader) Principal(cert *x509.Certificate) (auth.Principal, bool) {
+	identities := r.current().identities
+	for _, name := range certificateNames(cert) {
+		for _, identity := range identities {
+			if identity.Match != name {
+				continue
+			}
+			namespace := identity.Namespace
+			if namespace == "" {
+				namespace = auth.DefaultNamespace
+			}
+			tenant := identity.Tenant
+			if tenant == "" {
+				tenant = auth.DefaultTenant
+			}
+			displayName := identity.Name
+			if displayName == "" {
+				displayName = name
+			}
+			return auth.Principal{
+				ID:        name,
+				Source:    auth.SourceCertificate,
+				Name:      displayName,
+				Namespace: namespace,
+				Tenant:    tenant,
+				Scopes:    identity.Scopes,
+			}, true
+		}
+	}
+	return auth.Principal{}, false
+}
@@ -0,0 +1,179 @@
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"person-service/logging"
+)
+
+// DefaultReloadInterval is how often certificate files are checked for changes
+const DefaultReloadInterval = 30 * time.Second
+
+// Config configures TLS serving. ClientCAFile enables client certificates and
+// IdentityFile maps them to principals.
+type Config struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+	IdentityFile string
+	// RequireClientCert rejects handshakes without a verified client certificate.
+	// When false, clients without a certificate can still authenticate with API keys.
+	RequireClientCert bool
+}
+
+// tlsState is the material loaded from disk, swapped atomically on reload
+type tlsState struct {
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+	identities  []Identity
+	modTimes    map[string]time.Time
+}
+
+// Reloader serves TLS with certificates, client CAs and client identities
+// that are reloaded from disk when the files change or on SIGHUP
+type Reloader struct {
+	config Config
+
+	mu    sync.RWMutex
+	state *tlsState
+}
+
+// NewReloader creates a new instance of Reloader and loads all files once
+func NewReloader(config Config) (*Reloader, error) {
+	r := &Reloader{
+		config: config,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads all configured files again. On error the previous material stays active.
+func (r *Reloader) Reload() error {
+	state := &tlsState{
+		modTimes: r.modTimes(),
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	state.certificate = &certificate
+
+	if r.config.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA bundle: %w", err)
+		}
+		state.clientCAs = x509.NewCertPool()
+		if !state.clientCAs.AppendCertsFromPEM(pem) {
+			return errors.New("client CA bundle contains no certificates")
+		}
+	}
+
+	if r.config.IdentityFile != "" {
+		state.identities, err = loadIdentities(r.config.IdentityFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	r.state = state
+	r.mu.Unlock()
+
+	logging.Info("TLS material loaded",
+		"client_auth", r.config.ClientCAFile != "",
+		"client_identities", len(state.identities))
+	return nil
+}
+
+// TLSConfig returns a server configuration that always uses the latest loaded material
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			state := r.current()
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*state.certificate},
+			}
+			if state.clientCAs != nil {
+				config.ClientCAs = state.clientCAs
+				config.ClientAuth = tls.VerifyClientCertIfGiven
+				if r.config.RequireClientCert {
+					config.ClientAuth = tls.RequireAndVerifyClientCert
+				}
+			}
+			return config, nil
+		},
+	}
+}
+
+// Run reloads the TLS material when any file changes, checking every interval,
+// and on SIGHUP until the context is cancelled
+func (r *Reloader) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+		case <-hup:
+		}
+
+		if err := r.Reload(); err != nil {
+			logging.Error("Failed to reload TLS material, keeping previous certificates", "error", err)
+		}
+	}
+}
+
+// current returns the active TLS material
+func (r *Reloader) current() *tlsState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.state
+}
+
+// changed reports whether any configured file has a different modification time than at the last reload
+func (r *Reloader) changed() bool {
+	loaded := r.current().modTimes
+	for path, modTime := range r.modTimes() {
+		if !loaded[path].Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// modTimes returns the modification time of every configured file that exists
+func (r *Reloader) modTimes() map[string]time.Time {
+	times := make(map[string]time.Time)
+	for _, path := range []string{r.config.CertFile, r.config.KeyFile, r.config.ClientCAFile, r.config.IdentityFile} {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			times[path] = info.ModTime()
+		}
+	}
+	return times
+}
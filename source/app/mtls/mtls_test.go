@@ -0,0 +1,308 @@
+package mtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"person-service/auth"
+)
+
+// testCA is a locally generated certificate authority
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue signs a leaf certificate and returns it with its PEM certificate and key
+func (ca testCA) issue(t *testing.T, template *x509.Certificate) (*x509.Certificate, []byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	assert.NoError(t, err)
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	return cert,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// serverCert issues a localhost server certificate with the given common name
+func (ca testCA) serverCert(t *testing.T, commonName string) ([]byte, []byte) {
+	t.Helper()
+	_, certPEM, keyPEM := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: commonName},
+		DNSNames:    []string{"localhost"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	return certPEM, keyPEM
+}
+
+// clientCert issues a client certificate usable with tls.Config
+func (ca testCA) clientCert(t *testing.T, template *x509.Certificate) tls.Certificate {
+	t.Helper()
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	_, certPEM, keyPEM := ca.issue(t, template)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	assert.NoError(t, err)
+	return cert
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	assert.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+// newTestConfig writes a server certificate, the CA bundle and identities to a temporary directory
+func newTestConfig(t *testing.T, ca testCA, identities string) Config {
+	t.Helper()
+	dir := t.TempDir()
+	certPEM, keyPEM := ca.serverCert(t, "server-1")
+	return Config{
+		CertFile:          writeFile(t, dir, "tls.crt", certPEM),
+		KeyFile:           writeFile(t, dir, "tls.key", keyPEM),
+		ClientCAFile:      writeFile(t, dir, "ca.crt", ca.pem),
+		IdentityFile:      writeFile(t, dir, "identities.json", []byte(identities)),
+		RequireClientCert: true,
+	}
+}
+
+// startServer serves the reloader's TLS configuration and reports the verified client certificate's common name
+func startServer(t *testing.T, reloader *Reloader) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.TLS.VerifiedChains[0][0].Subject.CommonName)
+	}))
+	server.TLS = reloader.TLSConfig()
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newClient(ca testCA, cert *tls.Certificate) *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	config := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+	if cert != nil {
+		config.Certificates = []tls.Certificate{*cert}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+}
+
+func TestReloader_MutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	reloader, err := NewReloader(newTestConfig(t, ca, `[]`))
+	assert.NoError(t, err)
+	server := startServer(t, reloader)
+
+	clientCert := ca.clientCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "billing"}})
+	resp, err := newClient(ca, &clientCert).Get(server.URL)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "billing", string(body))
+
+	// Clients without a certificate are rejected during the handshake
+	_, err = newClient(ca, nil).Get(server.URL)
+	assert.Error(t, err)
+
+	// Certificates from another CA are rejected as well
+	otherCert := newTestCA(t).clientCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "billing"}})
+	_, err = newClient(ca, &otherCert).Get(server.URL)
+	assert.Error(t, err)
+}
+
+func TestReloader_OptionalClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	config := newTestConfig(t, ca, `[]`)
+	config.RequireClientCert = false
+	reloader, err := NewReloader(config)
+	assert.NoError(t, err)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server.TLS = reloader.TLSConfig()
+	server.StartTLS()
+	defer server.Close()
+
+	resp, err := newClient(ca, nil).Get(server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestReloader_ReloadsChangedCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	config := newTestConfig(t, ca, `[]`)
+	reloader, err := NewReloader(config)
+	assert.NoError(t, err)
+	assert.False(t, reloader.changed())
+	server := startServer(t, reloader)
+
+	// Each call uses a new client so every request makes a new handshake
+	serverName := func() string {
+		clientCert := ca.clientCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "billing"}})
+		client := newClient(ca, &clientCert)
+		resp, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	assert.Equal(t, "server-1", serverName())
+
+	// A rotated certificate is served after reload without restarting the listener
+	certPEM, keyPEM := ca.serverCert(t, "server-2")
+	assert.NoError(t, os.WriteFile(config.CertFile, certPEM, 0o600))
+	assert.NoError(t, os.WriteFile(config.KeyFile, keyPEM, 0o600))
+	future := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(config.CertFile, future, future))
+	assert.True(t, reloader.changed())
+	assert.NoError(t, reloader.Reload())
+	assert.Equal(t, "server-2", serverName())
+
+	// Broken files keep the previous material
+	assert.NoError(t, os.WriteFile(config.KeyFile, []byte("not a key"), 0o600))
+	assert.Error(t, reloader.Reload())
+	assert.Equal(t, "server-2", serverName())
+}
+
+func TestReloader_Principal(t *testing.T) {
+	ca := newTestCA(t)
+	reloader, err := NewReloader(newTestConfig(t, ca, `[
+		{"match": "spiffe://cluster.local/ns/billing/sa/billing", "name": "billing", "namespace": "billing", "tenant": "acme", "scopes": ["person:read"]},
+		{"match": "reports.internal", "scopes": ["kv:read"]},
+		{"match": "legacy-client", "scopes": ["kv:write"]}
+	]`))
+	assert.NoError(t, err)
+
+	spiffeID, _ := url.Parse("spiffe://cluster.local/ns/billing/sa/billing")
+	issued := func(template *x509.Certificate) *x509.Certificate {
+		cert, _, _ := ca.issue(t, template)
+		return cert
+	}
+
+	tests := []struct {
+		name     string
+		cert     *x509.Certificate
+		expected auth.Principal
+		matched  bool
+	}{
+		{
+			name: "URI SAN takes precedence over common name",
+			cert: issued(&x509.Certificate{Subject: pkix.Name{CommonName: "legacy-client"}, URIs: []*url.URL{spiffeID}}),
+			expected: auth.Principal{
+				ID:        "spiffe://cluster.local/ns/billing/sa/billing",
+				Source:    auth.SourceCertificate,
+				Name:      "billing",
+				Namespace: "billing",
+				Tenant:    "acme",
+				Scopes:    []string{auth.ScopePersonRead},
+			},
+			matched: true,
+		},
+		{
+			name: "DNS SAN",
+			cert: issued(&x509.Certificate{DNSNames: []string{"reports.internal"}}),
+			expected: auth.Principal{
+				ID:        "reports.internal",
+				Source:    auth.SourceCertificate,
+				Name:      "reports.internal",
+				Namespace: auth.DefaultNamespace,
+				Tenant:    auth.DefaultTenant,
+				Scopes:    []string{auth.ScopeKVRead},
+			},
+			matched: true,
+		},
+		{
+			name: "common name",
+			cert: issued(&x509.Certificate{Subject: pkix.Name{CommonName: "legacy-client"}}),
+			expected: auth.Principal{
+				ID:        "legacy-client",
+				Source:    auth.SourceCertificate,
+				Name:      "legacy-client",
+				Namespace: auth.DefaultNamespace,
+				Tenant:    auth.DefaultTenant,
+				Scopes:    []string{auth.ScopeKVWrite},
+			},
+			matched: true,
+		},
+		{
+			name: "unknown certificate",
+			cert: issued(&x509.Certificate{Subject: pkix.Name{CommonName: "someone-else"}}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, ok := reloader.Principal(tt.cert)
+			assert.Equal(t, tt.matched, ok)
+			assert.Equal(t, tt.expected, principal)
+		})
+	}
+}
+
+func TestLoadIdentities_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"malformed", `{"match": "billing"}`},
+		{"missing match", `[{"scopes": ["kv:read"]}]`},
+		{"duplicate match", `[{"match": "billing"}, {"match": "billing"}]`},
+		{"unknown scope", `[{"match": "billing", "scopes": ["everything"]}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadIdentities(writeFile(t, dir, "identities.json", []byte(tt.content)))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"person-service/logging"
+)
+
+const (
+	// minRefreshInterval limits how often an unknown key id triggers a JWKS reload
+	minRefreshInterval = time.Minute
+
+	// fetchTimeout bounds a single JWKS download
+	fetchTimeout = 10 * time.Second
+
+	// maxJWKSSize bounds the size of a JWKS document
+	maxJWKSSize = 1 << 20
+)
+
+// jsonWebKey is the subset of RFC 7517 fields needed for RSA and P-256 signing keys
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// KeySet holds the public keys of a JWKS loaded from a file path or an http(s) URL
+type KeySet struct {
+	source string
+	client *http.Client
+
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	lastRefresh time.Time
+}
+
+// LoadKeySet creates a KeySet from source and loads it once
+func LoadKeySet(ctx context.Context, source string) (*KeySet, error) {
+	ks := &KeySet{
+		source: source,
+		client: &http.Client{Timeout: fetchTimeout},
+	}
+	if err := ks.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Refresh reloads the key set from its source. On error the previous keys stay active.
+func (ks *KeySet) Refresh(ctx context.Context) error {
+	data, err := ks.read(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS from %s: %w", ks.source, err)
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse JWKS from %s: %w", ks.source, err)
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.lastRefresh = time.Now()
+	ks.mu.Unlock()
+
+	logging.Debug("JWKS loaded", "source", ks.source, "keys", len(keys))
+	return nil
+}
+
+// key returns the key with the given id. An unknown id triggers a refresh,
+// at most once per minRefreshInterval, so rotated keys are picked up.
+func (ks *KeySet) key(ctx context.Context, kid string) (crypto.PublicKey, bool) {
+	ks.mu.RLock()
+	key, ok := ks.keys[kid]
+	stale := time.Since(ks.lastRefresh) >= minRefreshInterval
+	ks.mu.RUnlock()
+
+	if ok || !stale {
+		return key, ok
+	}
+
+	if err := ks.Refresh(ctx); err != nil {
+		logging.WarnContext(ctx, "Failed to refresh JWKS", "error", err)
+		// Do not retry on every request while the source is failing
+		ks.mu.Lock()
+		ks.lastRefresh = time.Now()
+		ks.mu.Unlock()
+		return nil, false
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, ok = ks.keys[kid]
+	return key, ok
+}
+
+// read returns the raw JWKS document from a URL or a file
+func (ks *KeySet) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(ks.source, "http://") && !strings.HasPrefix(ks.source, "https://") {
+		return os.ReadFile(ks.source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+}
+
+// parseJWKS decodes the signing keys of a JWKS document, skipping keys of
+// unsupported types and logging keys it cannot use, such as other curves or
+// RSA keys under 2048 bits, so one such key does not break the whole set. It
+// fails only when no usable key is left. Keys without a kid are stored under
+// the empty id.
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(doc.Keys))
+	for _, jwk := range doc.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		var err error
+		switch jwk.Kty {
+		case "RSA":
+			key, err = parseRSAKey(jwk)
+		case "EC":
+			key, err = parseECKey(jwk)
+		default:
+			continue
+		}
+		if err != nil {
+			logging.Warn("Skipping unusable JWKS key", "kid", jwk.Kid, "kty", jwk.Kty, "error", err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no supported signing keys")
+	}
+	return keys, nil
+}
+
+// parseRSAKey builds an RSA public key from its modulus and exponent
+func parseRSAKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
+	}
+
+	key := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}
+	if key.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must be at least 2048 bits")
+	}
+	return key, nil
+}
+
+// parseECKey builds a P-256 public key from its coordinates, rejecting points off the curve
+func parseECKey(jwk jsonWebKey) (*ecdsa.PublicKey, error) {
+	if jwk.Crv != "P-256" {
+		return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil || len(x) != 32 {
+		return nil, errors.New("invalid x coordinate")
+	}
+	y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil || len(y) != 32 {
+		return nil, errors.New("invalid y coordinate")
+	}
+
+	// ecdh validates that the point is on the curve
+	point := append(append([]byte{4}, x...), y...)
+	if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+		return nil, fmt.Errorf("invalid point: %w", err)
+	}
+
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}, nil
+}
@@ -0,0 +1,259 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"person-service/auth"
+)
+
+const (
+	// DefaultClockSkew is the leeway applied to exp, nbf and iat checks
+	DefaultClockSkew = time.Minute
+
+	// DefaultScopesClaim is the claim holding the caller's scopes, as in RFC 8693
+	DefaultScopesClaim = "scope"
+)
+
+// ErrInvalidToken is returned when a token fails parsing, signature or claims validation
+var ErrInvalidToken = errors.New("invalid token")
+
+// Config configures JWT validation
+type Config struct {
+	// JWKS is a file path or http(s) URL of the JSON Web Key Set
+	JWKS string
+	// Issuer must equal the token's iss claim
+	Issuer string
+	// Audience must be one of the token's aud claim values
+	Audience string
+	// ClockSkew is the leeway applied to time-based claims
+	ClockSkew time.Duration
+	// ScopesClaim names the claim holding scopes, as a space-separated string or an array
+	ScopesClaim string
+	// ScopePrefix is stripped from claim scopes; scopes without it are ignored when set
+	ScopePrefix string
+	// NamespaceClaim optionally names the claim holding the key-value namespace
+	NamespaceClaim string
+	// TenantClaim optionally names the claim holding the tenant
+	TenantClaim string
+}
+
+// Verifier validates RS256 and ES256 signed JWTs against a JWKS
+type Verifier struct {
+	config Config
+	keys   *KeySet
+}
+
+// NewVerifier creates a new instance of Verifier and loads its key set
+func NewVerifier(ctx context.Context, config Config) (*Verifier, error) {
+	if config.Issuer == "" || config.Audience == "" {
+		return nil, errors.New("JWT issuer and audience are required")
+	}
+	if config.ClockSkew == 0 {
+		config.ClockSkew = DefaultClockSkew
+	}
+	if config.ScopesClaim == "" {
+		config.ScopesClaim = DefaultScopesClaim
+	}
+
+	keys, err := LoadKeySet(ctx, config.JWKS)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Verifier{
+		config: config,
+		keys:   keys,
+	}, nil
+}
+
+// LooksLikeJWT reports whether token has the three-segment compact JWS shape
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Verify validates token and returns the principal described by its claims.
+// All validation failures wrap ErrInvalidToken.
+func (v *Verifier) Verify(ctx context.Context, token string) (auth.Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return auth.Principal{}, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return auth.Principal{}, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+
+	key, ok := v.keys.key(ctx, header.Kid)
+	if !ok {
+		return auth.Principal{}, fmt.Errorf("%w: unknown key id %q", ErrInvalidToken, header.Kid)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return auth.Principal{}, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return auth.Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return auth.Principal{}, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+	if err := v.validateClaims(claims, time.Now()); err != nil {
+		return auth.Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	subject, _ := claims["sub"].(string)
+	namespace := auth.DefaultNamespace
+	if v.config.NamespaceClaim != "" {
+		if claimed, _ := claims[v.config.NamespaceClaim].(string); claimed != "" {
+			namespace = claimed
+		}
+	}
+	tenant := auth.DefaultTenant
+	if v.config.TenantClaim != "" {
+		if claimed, _ := claims[v.config.TenantClaim].(string); claimed != "" {
+			tenant = claimed
+		}
+	}
+
+	return auth.Principal{
+		ID:        subject,
+		Source:    auth.SourceJWT,
+		Name:      subject,
+		Namespace: namespace,
+		Tenant:    tenant,
+		Scopes:    v.scopes(claims[v.config.ScopesClaim]),
+	}, nil
+}
+
+// validateClaims checks issuer, audience, subject and the time-based claims
+func (v *Verifier) validateClaims(claims map[string]interface{}, now time.Time) error {
+	if iss, _ := claims["iss"].(string); iss != v.config.Issuer {
+		return fmt.Errorf("unexpected issuer %q", iss)
+	}
+	if !hasAudience(claims["aud"], v.config.Audience) {
+		return errors.New("audience mismatch")
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return errors.New("missing subject")
+	}
+
+	skew := v.config.ClockSkew
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("missing expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(skew)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(skew).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token not yet valid")
+	}
+	if iat, ok := claims["iat"].(float64); ok && now.Add(skew).Before(time.Unix(int64(iat), 0)) {
+		return errors.New("token issued in the future")
+	}
+	return nil
+}
+
+// scopes maps a scopes claim to known service scopes, dropping everything else
+func (v *Verifier) scopes(claim interface{}) []string {
+	var raw []string
+	switch value := claim.(type) {
+	case string:
+		raw = strings.Fields(value)
+	case []interface{}:
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				raw = append(raw, s)
+			}
+		}
+	}
+
+	scopes := make([]string, 0, len(raw))
+	for _, scope := range raw {
+		if v.config.ScopePrefix != "" {
+			if !strings.HasPrefix(scope, v.config.ScopePrefix) {
+				continue
+			}
+			scope = strings.TrimPrefix(scope, v.config.ScopePrefix)
+		}
+		if auth.IsValidScope(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// hasAudience reports whether an aud claim, a string or an array, contains audience
+func hasAudience(claim interface{}, audience string) bool {
+	switch value := claim.(type) {
+	case string:
+		return value == audience
+	case []interface{}:
+		for _, item := range value {
+			if item == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// verifySignature checks a JWS signature. The algorithm must match the key
+// type so a token cannot choose a weaker verification path.
+func verifySignature(alg string, key crypto.PublicKey, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("algorithm does not match key type")
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("signature verification failed")
+		}
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("algorithm does not match key type")
+		}
+		if len(signature) != 64 {
+			return errors.New("malformed ES256 signature")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			return errors.New("signature verification failed")
+		}
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	return nil
+}
+
+// decodeSegment decodes a base64url JSON segment of a compact JWS
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
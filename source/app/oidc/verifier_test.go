@@ -0,0 +1,322 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"person-service/auth"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "person-service"
+)
+
+// testKeys holds locally generated signing keys and their JWKS document
+type testKeys struct {
+	rsa  *rsa.PrivateKey
+	ec   *ecdsa.PrivateKey
+	jwks []byte
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	jwks, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": "rsa-1",
+				"use": "sig",
+				"n":   b64(rsaKey.N.Bytes()),
+				"e":   b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "EC",
+				"kid": "ec-1",
+				"crv": "P-256",
+				"x":   b64(ecKey.X.FillBytes(make([]byte, 32))),
+				"y":   b64(ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	return testKeys{rsa: rsaKey, ec: ecKey, jwks: jwks}
+}
+
+// writeJWKS writes the key set to a temporary file and returns its path
+func (k testKeys) writeJWKS(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, k.jwks, 0o600))
+	return path
+}
+
+// sign creates a compact JWS with the given algorithm, key id and claims
+func (k testKeys) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	assert.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	assert.NoError(t, err)
+
+	signingInput := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch alg {
+	case "RS256":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k.rsa, crypto.SHA256, digest[:])
+		assert.NoError(t, err)
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, k.ec, digest[:])
+		assert.NoError(t, err)
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signingInput + "." + b64(signature)
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// validClaims returns claims that pass validation with the test configuration
+func validClaims() map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss":   testIssuer,
+		"aud":   testAudience,
+		"sub":   "billing-service",
+		"exp":   now.Add(time.Hour).Unix(),
+		"iat":   now.Unix(),
+		"scope": "person:read kv:write openid",
+	}
+}
+
+func newTestVerifier(t *testing.T, keys testKeys, config Config) *Verifier {
+	t.Helper()
+	config.JWKS = keys.writeJWKS(t)
+	config.Issuer = testIssuer
+	config.Audience = testAudience
+	verifier, err := NewVerifier(context.Background(), config)
+	assert.NoError(t, err)
+	return verifier
+}
+
+func TestVerify_ValidTokens(t *testing.T) {
+	keys := newTestKeys(t)
+	verifier := newTestVerifier(t, keys, Config{})
+
+	for _, tc := range []struct{ alg, kid string }{{"RS256", "rsa-1"}, {"ES256", "ec-1"}} {
+		t.Run(tc.alg, func(t *testing.T) {
+			principal, err := verifier.Verify(context.Background(), keys.sign(t, tc.alg, tc.kid, validClaims()))
+			assert.NoError(t, err)
+			assert.Equal(t, "billing-service", principal.ID)
+			assert.Equal(t, auth.DefaultNamespace, principal.Namespace)
+			assert.Equal(t, []string{auth.ScopePersonRead, auth.ScopeKVWrite}, principal.Scopes)
+		})
+	}
+}
+
+func TestVerify_RejectsInvalidTokens(t *testing.T) {
+	keys := newTestKeys(t)
+	verifier := newTestVerifier(t, keys, Config{ClockSkew: 30 * time.Second})
+
+	withClaim := func(name string, value interface{}) map[string]interface{} {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tamperedPayload := keys.sign(t, "RS256", "rsa-1", validClaims())
+	otherPayload := b64([]byte(`{"iss":"` + testIssuer + `","aud":"` + testAudience + `","sub":"admin","exp":9999999999}`))
+	parts := strings.Split(tamperedPayload, ".")
+	tamperedPayload = parts[0] + "." + otherPayload + "." + parts[2]
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"wrong issuer", keys.sign(t, "RS256", "rsa-1", withClaim("iss", "https://evil.example.com"))},
+		{"wrong audience", keys.sign(t, "RS256", "rsa-1", withClaim("aud", "other-service"))},
+		{"missing subject", keys.sign(t, "RS256", "rsa-1", withClaim("sub", nil))},
+		{"missing expiry", keys.sign(t, "RS256", "rsa-1", withClaim("exp", nil))},
+		{"expired beyond skew", keys.sign(t, "RS256", "rsa-1", withClaim("exp", time.Now().Add(-time.Minute).Unix()))},
+		{"not yet valid", keys.sign(t, "RS256", "rsa-1", withClaim("nbf", time.Now().Add(time.Minute).Unix()))},
+		{"unknown key id", keys.sign(t, "RS256", "rsa-2", validClaims())},
+		{"algorithm does not match key", keys.sign(t, "ES256", "rsa-1", validClaims())},
+		{"unsigned", b64([]byte(`{"alg":"none","kid":"rsa-1"}`)) + "." + b64([]byte(`{}`)) + "."},
+		{"tampered payload", tamperedPayload},
+		{"malformed", "not.a.jwt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.Verify(context.Background(), tt.token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestVerify_ClockSkew(t *testing.T) {
+	keys := newTestKeys(t)
+	verifier := newTestVerifier(t, keys, Config{ClockSkew: time.Minute})
+
+	claims := validClaims()
+	claims["exp"] = time.Now().Add(-30 * time.Second).Unix()
+	claims["nbf"] = time.Now().Add(30 * time.Second).Unix()
+
+	_, err := verifier.Verify(context.Background(), keys.sign(t, "RS256", "rsa-1", claims))
+	assert.NoError(t, err)
+}
+
+func TestVerify_ClaimsMapping(t *testing.T) {
+	keys := newTestKeys(t)
+	verifier := newTestVerifier(t, keys, Config{
+		ScopesClaim:    "scp",
+		ScopePrefix:    "person-service/",
+		NamespaceClaim: "tenant",
+		TenantClaim:    "org",
+	})
+
+	claims := validClaims()
+	claims["aud"] = []string{"other-service", testAudience}
+	claims["scp"] = []string{"person-service/attributes:read", "attributes:write", "person-service/unknown"}
+	claims["tenant"] = "team-a"
+	claims["org"] = "acme"
+
+	principal, err := verifier.Verify(context.Background(), keys.sign(t, "ES256", "ec-1", claims))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{auth.ScopeAttributesRead}, principal.Scopes)
+	assert.Equal(t, "team-a", principal.Namespace)
+	assert.Equal(t, "acme", principal.Tenant)
+}
+
+func TestKeySet_RefreshesFromURLOnUnknownKey(t *testing.T) {
+	oldKeys := newTestKeys(t)
+	newKeys := newTestKeys(t)
+
+	var current atomic.Pointer[[]byte]
+	current.Store(&oldKeys.jwks)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(*current.Load())
+	}))
+	defer server.Close()
+
+	verifier, err := NewVerifier(context.Background(), Config{
+		JWKS:     server.URL,
+		Issuer:   testIssuer,
+		Audience: testAudience,
+	})
+	assert.NoError(t, err)
+
+	// Rotate the key under the same id; the cached key no longer verifies
+	current.Store(&newKeys.jwks)
+	token := newKeys.sign(t, "RS256", "rsa-1", validClaims())
+	_, err = verifier.Verify(context.Background(), token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// A key id the cache does not know triggers a reload once the set is stale
+	verifier.keys.lastRefresh = time.Now().Add(-minRefreshInterval)
+	_, err = verifier.Verify(context.Background(), newKeys.sign(t, "RS256", "rsa-9", validClaims()))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = verifier.Verify(context.Background(), token)
+	assert.NoError(t, err)
+}
+
+func TestNewVerifier_Config(t *testing.T) {
+	keys := newTestKeys(t)
+	jwks := keys.writeJWKS(t)
+
+	_, err := NewVerifier(context.Background(), Config{JWKS: jwks})
+	assert.Error(t, err, "Issuer and audience are required")
+
+	verifier, err := NewVerifier(context.Background(), Config{JWKS: jwks, Issuer: testIssuer, Audience: testAudience})
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultClockSkew, verifier.config.ClockSkew)
+	assert.Equal(t, DefaultScopesClaim, verifier.config.ScopesClaim)
+
+	verifier, err = NewVerifier(context.Background(), Config{JWKS: jwks, Issuer: testIssuer, Audience: testAudience, ClockSkew: 2 * time.Minute})
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, verifier.config.ClockSkew)
+}
+
+func TestParseJWKS_RejectsWeakKeys(t *testing.T) {
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+
+	jwks, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "weak",
+			"n":   b64(weak.N.Bytes()),
+			"e":   b64(big.NewInt(int64(weak.E)).Bytes()),
+		}},
+	})
+	assert.NoError(t, err)
+
+	_, err = parseJWKS(jwks)
+	assert.Error(t, err)
+}
+
+func TestParseJWKS_SkipsUnusableKeys(t *testing.T) {
+	keys := newTestKeys(t)
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+
+	var doc struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	assert.NoError(t, json.Unmarshal(keys.jwks, &doc))
+	doc.Keys = append(doc.Keys,
+		map[string]string{
+			"kty": "RSA",
+			"kid": "weak",
+			"n":   b64(weak.N.Bytes()),
+			"e":   b64(big.NewInt(int64(weak.E)).Bytes()),
+		},
+		map[string]string{
+			"kty": "EC",
+			"kid": "p384",
+			"crv": "P-384",
+			"x":   b64(make([]byte, 48)),
+			"y":   b64(make([]byte, 48)),
+		},
+	)
+	jwks, err := json.Marshal(doc)
+	assert.NoError(t, err)
+
+	parsed, err := parseJWKS(jwks)
+	assert.NoError(t, err)
+	assert.Len(t, parsed, 2)
+	assert.Contains(t, parsed, "rsa-1")
+	assert.Contains(t, parsed, "ec-1")
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"person-service/auth"
 	errs "person-service/errors"
 	db "person-service/internal/db/generated"
 
@@ -67,8 +68,12 @@ func (h *PersonHandler) CreatePerson(c echo.Context) error {
 	}
 
 	ctx := c.Request().Context()
+	tenant := auth.TenantFromContext(ctx)
 
-	person, err := h.queries.CreatePerson(ctx, req.ClientID)
+	person, err := h.queries.CreatePerson(ctx, db.CreatePersonParams{
+		TenantID: tenant,
+		ClientID: req.ClientID,
+	})
 	if err != nil {
 		if isDuplicateKeyError(err) {
 			return c.JSON(http.StatusConflict, errs.ErrorResponse{
@@ -102,8 +107,12 @@ func (h *PersonHandler) GetPerson(c echo.Context) error {
 	}
 
 	ctx := c.Request().Context()
+	tenant := auth.TenantFromContext(ctx)
 
-	person, err := h.queries.GetPersonById(ctx, personID)
+	person, err := h.queries.GetPersonById(ctx, db.GetPersonByIdParams{
+		TenantID: tenant,
+		ID:       personID,
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return c.JSON(http.StatusNotFound, errs.ErrorResponse{
@@ -152,9 +161,13 @@ func (h *PersonHandler) UpdatePerson(c echo.Context) error {
 	}
 
 	ctx := c.Request().Context()
+	tenant := auth.TenantFromContext(ctx)
 
 	// Verify person exists
-	_, err = h.queries.GetPersonById(ctx, personID)
+	_, err = h.queries.GetPersonById(ctx, db.GetPersonByIdParams{
+		TenantID: tenant,
+		ID:       personID,
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return c.JSON(http.StatusNotFound, errs.ErrorResponse{
@@ -171,6 +184,7 @@ func (h *PersonHandler) UpdatePerson(c echo.Context) error {
 	// Update client_id
 	err = h.queries.UpdatePersonClientId(ctx, db.UpdatePersonClientIdParams{
 		NewClientID: req.ClientID,
+		TenantID:    tenant,
 		ID:          personID,
 	})
 	if err != nil {
@@ -187,7 +201,10 @@ func (h *PersonHandler) UpdatePerson(c echo.Context) error {
 	}
 
 	// Fetch updated person
-	updated, err := h.queries.GetPersonById(ctx, personID)
+	updated, err := h.queries.GetPersonById(ctx, db.GetPersonByIdParams{
+		TenantID: tenant,
+		ID:       personID,
+	})
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, errs.ErrorResponse{
 			Message:   "Failed to retrieve updated person",
@@ -215,9 +232,13 @@ func (h *PersonHandler) DeletePerson(c echo.Context) error {
 	}
 
 	ctx := c.Request().Context()
+	tenant := auth.TenantFromContext(ctx)
 
 	// Verify person exists
-	_, err = h.queries.GetPersonById(ctx, personID)
+	_, err = h.queries.GetPersonById(ctx, db.GetPersonByIdParams{
+		TenantID: tenant,
+		ID:       personID,
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return c.JSON(http.StatusNotFound, errs.ErrorResponse{
@@ -232,7 +253,10 @@ func (h *PersonHandler) DeletePerson(c echo.Context) error {
 	}
 
 	// Soft delete
-	err = h.queries.SoftDeletePerson(ctx, personID)
+	err = h.queries.SoftDeletePerson(ctx, db.SoftDeletePersonParams{
+		TenantID: tenant,
+		ID:       personID,
+	})
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, errs.ErrorResponse{
 			Message:   "Failed to delete person",
@@ -250,6 +274,7 @@ func buildPersonResponse(p db.Person) map[string]interface{} {
 	resp := map[string]interface{}{
 		"id":        formatUUID(p.ID),
 		"client_id": p.ClientID,
+		"tenant_id": p.TenantID,
 	}
 	if p.CreatedAt.Valid {
 		resp["created_at"] = p.CreatedAt.Time
@@ -4,10 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
+	"person-service/audit"
+	"person-service/auth"
+	"person-service/config"
 	errs "person-service/errors"
 	db "person-service/internal/db/generated"
 	"person-service/logging"
+	"person-service/metrics"
+	"person-service/policy"
+	"person-service/redaction"
 	"strconv"
 	"strings"
 
@@ -43,19 +48,20 @@ type PersonAttributesHandler struct {
 	queries       *db.Queries
 	encryptionKey string
 	keyVersion    int64
+	policy        *policy.Policy
+	profiles      *redaction.Profiles
+	audit         *audit.Writer
 }
 
 // NewPersonAttributesHandler creates a new instance of PersonAttributesHandler
-func NewPersonAttributesHandler(queries *db.Queries) *PersonAttributesHandler {
-	encryptionKey := os.Getenv("ENCRYPTION_KEY_1")
-	if encryptionKey == "" {
-		encryptionKey = "default-key-for-dev"
-	}
-
+// that encrypts attribute values with the key in encryption
+func NewPersonAttributesHandler(queries *db.Queries, encryption config.Encryption) *PersonAttributesHandler {
 	return &PersonAttributesHandler{
 		queries:       queries,
-		encryptionKey: encryptionKey,
-		keyVersion:    1,
+		encryptionKey: encryption.Key,
+		keyVersion:    encryption.KeyVersion,
+		profiles:      redaction.Default(),
+		audit:         audit.NewWriter(queries, encryption),
 	}
 }
 
@@ -108,9 +114,23 @@ func (h *PersonAttributesHandler) CreateAttribute(c echo.Context) error {
 
 	// Use request context for trace propagation
 	ctx := c.Request().Context()
+	tenant := auth.TenantFromContext(ctx)
+
+	// Resolve the view before the attribute is stored so an invalid one is rejected up front
+	principal, _ := auth.PrincipalFromContext(ctx)
+	profile, ok := h.redactionProfile(c, principal)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+			Message:   "Unknown view",
+			ErrorCode: errs.ErrInvalidView,
+		})
+	}
 
 	// Check if person exists
-	_, err = h.queries.GetPersonById(ctx, personID)
+	_, err = h.queries.GetPersonById(ctx, db.GetPersonByIdParams{
+		TenantID: tenant,
+		ID:       personID,
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return c.JSON(http.StatusNotFound, errs.ErrorResponse{
@@ -126,6 +146,7 @@ func (h *PersonAttributesHandler) CreateAttribute(c echo.Context) error {
 
 	// Create or update the attribute
 	_, err = h.queries.CreateOrUpdatePersonAttribute(ctx, db.CreateOrUpdatePersonAttributeParams{
+		TenantID:       tenant,
 		PersonID:       personID,
 		AttributeKey:   req.Key,
 		AttributeValue: req.Value,
@@ -140,6 +161,7 @@ func (h *PersonAttributesHandler) CreateAttribute(c echo.Context) error {
 			ErrorCode: errs.ErrFailedCreateAttribute,
 		})
 	}
+	metrics.RecordEncryption(metrics.ResourceAttribute, 1)
 
 	// Log the request to audit log (request_log table)
 	if req.Meta != nil && req.Meta.TraceID != "" {
@@ -147,23 +169,40 @@ func (h *PersonAttributesHandler) CreateAttribute(c echo.Context) error {
 		requestBody := fmt.Sprintf(`{"key":"%s","value":"%s"}`, req.Key, req.Value)
 		responseBody := "" // Will be populated after getting the attribute
 
+		// Record which authenticated principal made the request
+		var principalID pgtype.Text
+		if principal, ok := auth.PrincipalFromContext(ctx); ok {
+			principalID = pgtype.Text{String: principal.ID, Valid: true}
+		}
+
 		_, logErr := h.queries.InsertRequestLog(ctx, db.InsertRequestLogParams{
+			TenantID:              tenant,
 			TraceID:               req.Meta.TraceID,
 			CallerInfo:                req.Meta.Caller,
+			PrincipalID:           principalID,
 			Reason:                req.Meta.Reason,
 			EncryptedRequestBody:  requestBody,
 			EncryptedResponseBody: responseBody,
 			EncKey:                h.encryptionKey,
 			KeyVersion:            h.keyVersion,
+			Purpose:               purposeText(ctx),
 		})
 
 		// Note: If InsertRequestLog fails, we still continue successfully
 		// because audit logging should not block the main operation
-		_ = logErr
+		if logErr != nil {
+			metrics.RecordAuditFailure(metrics.AuditSourceAttribute)
+			logging.WarnContext(ctx, "Failed to write audit log",
+				"error", logErr,
+				"error_code", errs.ErrFailedAuditLog)
+		} else {
+			metrics.RecordEncryption(metrics.ResourceRequestLog, 1)
+		}
 	}
 
 	// Get the created attribute with decrypted value
 	attribute, err := h.queries.GetPersonAttribute(ctx, db.GetPersonAttributeParams{
+		TenantID:     tenant,
 		PersonID:     personID,
 		AttributeKey: req.Key,
 		EncKey:       h.encryptionKey,
@@ -175,6 +214,7 @@ func (h *PersonAttributesHandler) CreateAttribute(c echo.Context) error {
 			ErrorCode: errs.ErrFailedRetrieveAttribute,
 		})
 	}
+	metrics.RecordDecryption(metrics.ResourceAttribute, 1)
 
 	// Build response
 	response := map[string]interface{}{
@@ -191,6 +231,10 @@ func (h *PersonAttributesHandler) CreateAttribute(c echo.Context) error {
 		response["updatedAt"] = attribute.UpdatedAt.Time
 	}
 
+	var denied []string
+	h.applyPolicy(principal, profile, response, &denied)
+	h.auditDeniedAttributes(ctx, principal, personID, denied)
+
 	// Always return 201 Created for this endpoint, even if it's an upsert
 	// This is because from the client's perspective, they're creating/setting an attribute
 	return c.JSON(http.StatusCreated, response)
@@ -212,9 +256,22 @@ func (h *PersonAttributesHandler) GetAllAttributes(c echo.Context) error {
 
 	// Use request context for trace propagation
 	ctx := c.Request().Context()
+	tenant := auth.TenantFromContext(ctx)
+
+	principal, _ := auth.PrincipalFromContext(ctx)
+	profile, ok := h.redactionProfile(c, principal)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+			Message:   "Unknown view",
+			ErrorCode: errs.ErrInvalidView,
+		})
+	}
 
 	// Check if person exists
-	_, err = h.queries.GetPersonById(ctx, personID)
+	_, err = h.queries.GetPersonById(ctx, db.GetPersonByIdParams{
+		TenantID: tenant,
+		ID:       personID,
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return c.JSON(http.StatusNotFound, errs.ErrorResponse{
@@ -230,6 +287,7 @@ func (h *PersonAttributesHandler) GetAllAttributes(c echo.Context) error {
 
 	// Get all attributes for the person
 	attributes, err := h.queries.GetAllPersonAttributes(ctx, db.GetAllPersonAttributesParams{
+		TenantID: tenant,
 		PersonID: personID,
 		EncKey:   h.encryptionKey,
 	})
@@ -240,8 +298,10 @@ func (h *PersonAttributesHandler) GetAllAttributes(c echo.Context) error {
 			ErrorCode: errs.ErrFailedRetrieveAttributes,
 		})
 	}
+	metrics.RecordDecryption(metrics.ResourceAttribute, len(attributes))
 
-	// Build response array
+	// Build response array, leaving out values the caller may not read
+	var denied []string
 	response := make([]map[string]interface{}, 0, len(attributes))
 	for _, attr := range attributes {
 		item := map[string]interface{}{
@@ -256,8 +316,11 @@ func (h *PersonAttributesHandler) GetAllAttributes(c echo.Context) error {
 		if attr.UpdatedAt.Valid {
 			item["updatedAt"] = attr.UpdatedAt.Time
 		}
-		response = append(response, item)
+		if h.applyPolicy(principal, profile, item, &denied) {
+			response = append(response, item)
+		}
 	}
+	h.auditDeniedAttributes(ctx, principal, personID, denied)
 
 	return c.JSON(http.StatusOK, response)
 }
@@ -287,9 +350,22 @@ func (h *PersonAttributesHandler) GetAttribute(c echo.Context) error {
 
 	// Use request context for trace propagation
 	ctx := c.Request().Context()
+	tenant := auth.TenantFromContext(ctx)
+
+	principal, _ := auth.PrincipalFromContext(ctx)
+	profile, ok := h.redactionProfile(c, principal)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+			Message:   "Unknown view",
+			ErrorCode: errs.ErrInvalidView,
+		})
+	}
 
 	// Check if person exists
-	_, err = h.queries.GetPersonById(ctx, personID)
+	_, err = h.queries.GetPersonById(ctx, db.GetPersonByIdParams{
+		TenantID: tenant,
+		ID:       personID,
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return c.JSON(http.StatusNotFound, errs.ErrorResponse{
@@ -305,6 +381,7 @@ func (h *PersonAttributesHandler) GetAttribute(c echo.Context) error {
 
 	// Get all attributes and find the one with matching ID
 	attributes, err := h.queries.GetAllPersonAttributes(ctx, db.GetAllPersonAttributesParams{
+		TenantID: tenant,
 		PersonID: personID,
 		EncKey:   h.encryptionKey,
 	})
@@ -315,6 +392,7 @@ func (h *PersonAttributesHandler) GetAttribute(c echo.Context) error {
 			ErrorCode: errs.ErrFailedRetrieveAttributes,
 		})
 	}
+	metrics.RecordDecryption(metrics.ResourceAttribute, len(attributes))
 
 	// Find the attribute with matching ID
 	var foundAttr *db.GetAllPersonAttributesRow
@@ -346,6 +424,10 @@ func (h *PersonAttributesHandler) GetAttribute(c echo.Context) error {
 		response["updatedAt"] = foundAttr.UpdatedAt.Time
 	}
 
+	var denied []string
+	h.applyPolicy(principal, profile, response, &denied)
+	h.auditDeniedAttributes(ctx, principal, personID, denied)
+
 	return c.JSON(http.StatusOK, response)
 }
 
@@ -391,9 +473,23 @@ func (h *PersonAttributesHandler) UpdateAttribute(c echo.Context) error {
 
 	// Use request context for trace propagation
 	ctx := c.Request().Context()
+	tenant := auth.TenantFromContext(ctx)
+
+	// Resolve the view before the attribute is changed so an invalid one is rejected up front
+	principal, _ := auth.PrincipalFromContext(ctx)
+	profile, ok := h.redactionProfile(c, principal)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, errs.ErrorResponse{
+			Message:   "Unknown view",
+			ErrorCode: errs.ErrInvalidView,
+		})
+	}
 
 	// Check if person exists
-	_, err = h.queries.GetPersonById(ctx, personID)
+	_, err = h.queries.GetPersonById(ctx, db.GetPersonByIdParams{
+		TenantID: tenant,
+		ID:       personID,
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return c.JSON(http.StatusNotFound, errs.ErrorResponse{
@@ -409,6 +505,7 @@ func (h *PersonAttributesHandler) UpdateAttribute(c echo.Context) error {
 
 	// Get all attributes and find the one with matching ID to get the key and current version
 	attributes, err := h.queries.GetAllPersonAttributes(ctx, db.GetAllPersonAttributesParams{
+		TenantID: tenant,
 		PersonID: personID,
 		EncKey:   h.encryptionKey,
 	})
@@ -419,6 +516,7 @@ func (h *PersonAttributesHandler) UpdateAttribute(c echo.Context) error {
 			ErrorCode: errs.ErrFailedRetrieveAttributes,
 		})
 	}
+	metrics.RecordDecryption(metrics.ResourceAttribute, len(attributes))
 
 	// Find the attribute with matching ID
 	var existingAttr *db.GetAllPersonAttributesRow
@@ -445,6 +543,7 @@ func (h *PersonAttributesHandler) UpdateAttribute(c echo.Context) error {
 	// If the key changed, we need to delete the old one first
 	if req.Key != "" && req.Key != existingAttr.AttributeKey {
 		err = h.queries.DeletePersonAttribute(ctx, db.DeletePersonAttributeParams{
+			TenantID:     tenant,
 			PersonID:     personID,
 			AttributeKey: existingAttr.AttributeKey,
 		})
@@ -457,6 +556,7 @@ func (h *PersonAttributesHandler) UpdateAttribute(c echo.Context) error {
 
 		// Key changed: create new attribute (no version check since it's a new key)
 		_, err = h.queries.CreateOrUpdatePersonAttribute(ctx, db.CreateOrUpdatePersonAttributeParams{
+			TenantID:       tenant,
 			PersonID:       personID,
 			AttributeKey:   keyToUse,
 			AttributeValue: req.Value,
@@ -466,6 +566,7 @@ func (h *PersonAttributesHandler) UpdateAttribute(c echo.Context) error {
 	} else if req.Version != nil {
 		// Version provided: use optimistic locking
 		_, err = h.queries.UpdatePersonAttributeWithVersion(ctx, db.UpdatePersonAttributeWithVersionParams{
+			TenantID:        tenant,
 			PersonID:        personID,
 			AttributeKey:    keyToUse,
 			AttributeValue:  req.Value,
@@ -482,6 +583,7 @@ func (h *PersonAttributesHandler) UpdateAttribute(c echo.Context) error {
 	} else {
 		// No version provided: update without version check (backward compatible)
 		_, err = h.queries.CreateOrUpdatePersonAttribute(ctx, db.CreateOrUpdatePersonAttributeParams{
+			TenantID:       tenant,
 			PersonID:       personID,
 			AttributeKey:   keyToUse,
 			AttributeValue: req.Value,
@@ -496,9 +598,11 @@ func (h *PersonAttributesHandler) UpdateAttribute(c echo.Context) error {
 			ErrorCode: errs.ErrFailedUpdateAttribute,
 		})
 	}
+	metrics.RecordEncryption(metrics.ResourceAttribute, 1)
 
 	// Get the updated attribute
 	attribute, err := h.queries.GetPersonAttribute(ctx, db.GetPersonAttributeParams{
+		TenantID:     tenant,
 		PersonID:     personID,
 		AttributeKey: keyToUse,
 		EncKey:       h.encryptionKey,
@@ -510,6 +614,7 @@ func (h *PersonAttributesHandler) UpdateAttribute(c echo.Context) error {
 			ErrorCode: errs.ErrFailedRetrieveUpdatedAttr,
 		})
 	}
+	metrics.RecordDecryption(metrics.ResourceAttribute, 1)
 
 	// Build response
 	response := map[string]interface{}{
@@ -525,6 +630,10 @@ func (h *PersonAttributesHandler) UpdateAttribute(c echo.Context) error {
 		response["updatedAt"] = attribute.UpdatedAt.Time
 	}
 
+	var denied []string
+	h.applyPolicy(principal, profile, response, &denied)
+	h.auditDeniedAttributes(ctx, principal, personID, denied)
+
 	return c.JSON(http.StatusOK, response)
 }
 
@@ -553,9 +662,13 @@ func (h *PersonAttributesHandler) DeleteAttribute(c echo.Context) error {
 
 	// Use request context for trace propagation
 	ctx := c.Request().Context()
+	tenant := auth.TenantFromContext(ctx)
 
 	// Check if person exists
-	_, err = h.queries.GetPersonById(ctx, personID)
+	_, err = h.queries.GetPersonById(ctx, db.GetPersonByIdParams{
+		TenantID: tenant,
+		ID:       personID,
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return c.JSON(http.StatusNotFound, errs.ErrorResponse{
@@ -571,6 +684,7 @@ func (h *PersonAttributesHandler) DeleteAttribute(c echo.Context) error {
 
 	// Get all attributes and find the one with matching ID to get the key
 	attributes, err := h.queries.GetAllPersonAttributes(ctx, db.GetAllPersonAttributesParams{
+		TenantID: tenant,
 		PersonID: personID,
 		EncKey:   h.encryptionKey,
 	})
@@ -581,6 +695,7 @@ func (h *PersonAttributesHandler) DeleteAttribute(c echo.Context) error {
 			ErrorCode: errs.ErrFailedRetrieveAttributes,
 		})
 	}
+	metrics.RecordDecryption(metrics.ResourceAttribute, len(attributes))
 
 	// Find the attribute with matching ID
 	var keyToDelete string
@@ -602,6 +717,7 @@ func (h *PersonAttributesHandler) DeleteAttribute(c echo.Context) error {
 
 	// Delete the attribute
 	err = h.queries.DeletePersonAttribute(ctx, db.DeletePersonAttributeParams{
+		TenantID:     tenant,
 		PersonID:     personID,
 		AttributeKey: keyToDelete,
 	})
@@ -16,14 +16,20 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 
+	"person-service/auth"
+	"person-service/config"
 	db "person-service/internal/db/generated"
 	"person-service/internal/testdb"
+	"person-service/policy"
 )
 
 var pool *pgxpool.Pool
 
 const testEncryptionKey = "test-encryption-key-32bytes!!"
 
+// testEncryption is the encryption configuration the handlers under test use
+var testEncryption = config.Encryption{Key: testEncryptionKey, KeyVersion: 1}
+
 func TestMain(m *testing.M) {
 	ctx := context.Background()
 	var err error
@@ -35,9 +41,6 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
-	// Set up environment variable for encryption key
-	os.Setenv("ENCRYPTION_KEY_1", testEncryptionKey)
-
 	os.Exit(m.Run())
 }
 
@@ -69,38 +72,31 @@ func getTestAttribute(ctx context.Context, personID, key string) (string, error)
 
 func TestNewPersonAttributesHandler(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 	assert.NotNil(t, handler)
 	assert.Equal(t, queries, handler.queries)
 	assert.Equal(t, testEncryptionKey, handler.encryptionKey)
 	assert.Equal(t, int64(1), handler.keyVersion)
 }
 
-func TestNewPersonAttributesHandler_WithEnvVar(t *testing.T) {
-	// Set environment variable
-	os.Setenv("ENCRYPTION_KEY_1", "test-key")
-	defer os.Setenv("ENCRYPTION_KEY_1", testEncryptionKey)
-
+func TestNewPersonAttributesHandler_WithEncryptionConfig(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, config.Encryption{Key: "test-key", KeyVersion: 2})
 	assert.NotNil(t, handler)
 	assert.Equal(t, "test-key", handler.encryptionKey)
+	assert.Equal(t, int64(2), handler.keyVersion)
 }
 
 func TestNewPersonAttributesHandler_DefaultKey(t *testing.T) {
-	// Unset environment variable to test default key
-	os.Unsetenv("ENCRYPTION_KEY_1")
-	defer os.Setenv("ENCRYPTION_KEY_1", testEncryptionKey)
-
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, config.Default().Encryption)
 	assert.NotNil(t, handler)
 	assert.Equal(t, "default-key-for-dev", handler.encryptionKey)
 }
 
 func TestCreateAttribute_InvalidUUID(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"key":"email","value":"test@example.com","meta":{"caller":"test","reason":"testing","traceId":"123"}}`
@@ -120,7 +116,7 @@ func TestCreateAttribute_InvalidUUID(t *testing.T) {
 
 func TestCreateAttribute_InvalidJSON(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{invalid-json}`
@@ -140,7 +136,7 @@ func TestCreateAttribute_InvalidJSON(t *testing.T) {
 
 func TestCreateAttribute_EmptyKey(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"key":"","value":"test@example.com","meta":{"caller":"test","reason":"testing","traceId":"123"}}`
@@ -160,7 +156,7 @@ func TestCreateAttribute_EmptyKey(t *testing.T) {
 
 func TestCreateAttribute_MissingMeta(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"key":"email","value":"test@example.com"}`
@@ -180,7 +176,7 @@ func TestCreateAttribute_MissingMeta(t *testing.T) {
 
 func TestGetAllAttributes_InvalidUUID(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/persons/invalid-uuid/attributes", nil)
@@ -198,7 +194,7 @@ func TestGetAllAttributes_InvalidUUID(t *testing.T) {
 
 func TestGetAttribute_InvalidPersonUUID(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/persons/invalid-uuid/attributes/1", nil)
@@ -216,7 +212,7 @@ func TestGetAttribute_InvalidPersonUUID(t *testing.T) {
 
 func TestGetAttribute_InvalidAttributeID(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/persons/123e4567-e89b-12d3-a456-426614174000/attributes/invalid", nil)
@@ -234,7 +230,7 @@ func TestGetAttribute_InvalidAttributeID(t *testing.T) {
 
 func TestUpdateAttribute_InvalidPersonUUID(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"value":"updated@example.com"}`
@@ -254,7 +250,7 @@ func TestUpdateAttribute_InvalidPersonUUID(t *testing.T) {
 
 func TestUpdateAttribute_InvalidAttributeID(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"value":"updated@example.com"}`
@@ -274,7 +270,7 @@ func TestUpdateAttribute_InvalidAttributeID(t *testing.T) {
 
 func TestUpdateAttribute_InvalidJSON(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{invalid-json}`
@@ -294,7 +290,7 @@ func TestUpdateAttribute_InvalidJSON(t *testing.T) {
 
 func TestDeleteAttribute_InvalidPersonUUID(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodDelete, "/persons/invalid-uuid/attributes/1", nil)
@@ -312,7 +308,7 @@ func TestDeleteAttribute_InvalidPersonUUID(t *testing.T) {
 
 func TestDeleteAttribute_InvalidAttributeID(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodDelete, "/persons/123e4567-e89b-12d3-a456-426614174000/attributes/invalid", nil)
@@ -334,7 +330,7 @@ func TestCreateAttribute_PersonNotFound(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"key":"email","value":"test@example.com","meta":{"caller":"test","reason":"testing","traceId":"123"}}`
@@ -357,7 +353,7 @@ func TestGetAllAttributes_PersonNotFound(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/persons/123e4567-e89b-12d3-a456-426614174000/attributes", nil)
@@ -378,7 +374,7 @@ func TestGetAttribute_PersonNotFound(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/persons/123e4567-e89b-12d3-a456-426614174000/attributes/1", nil)
@@ -399,7 +395,7 @@ func TestUpdateAttribute_PersonNotFound(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"value":"updated@example.com"}`
@@ -422,7 +418,7 @@ func TestDeleteAttribute_PersonNotFound(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodDelete, "/persons/123e4567-e89b-12d3-a456-426614174000/attributes/1", nil)
@@ -447,7 +443,7 @@ func TestCreateAttribute_SuccessWithoutTraceID(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"key":"email","value":"test@example.com","meta":{"caller":"test","reason":"testing"}}`
@@ -475,7 +471,7 @@ func TestGetAllAttributes_Success(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/persons/"+personID+"/attributes", nil)
@@ -500,7 +496,7 @@ func TestGetAttribute_NotFound(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/persons/"+personID+"/attributes/999", nil)
@@ -526,7 +522,7 @@ func TestUpdateAttribute_NotFound(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"value":"updated@example.com"}`
@@ -554,7 +550,7 @@ func TestDeleteAttribute_NotFound(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodDelete, "/persons/"+personID+"/attributes/999", nil)
@@ -575,7 +571,7 @@ func TestGetAllAttributes_DatabaseError(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(closedPool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/persons/123e4567-e89b-12d3-a456-426614174000/attributes", nil)
@@ -595,7 +591,7 @@ func TestGetAttribute_DatabaseError(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(closedPool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/persons/123e4567-e89b-12d3-a456-426614174000/attributes/1", nil)
@@ -615,7 +611,7 @@ func TestUpdateAttribute_DatabaseError(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(closedPool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"value":"updated@example.com"}`
@@ -637,7 +633,7 @@ func TestDeleteAttribute_DatabaseError(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(closedPool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodDelete, "/persons/123e4567-e89b-12d3-a456-426614174000/attributes/1", nil)
@@ -665,7 +661,7 @@ func TestUpdateAttribute_Success(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"value":"updated@example.com"}`
@@ -695,7 +691,7 @@ func TestUpdateAttribute_WithKeyChange(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"key":"newkey","value":"updated@example.com"}`
@@ -725,7 +721,7 @@ func TestDeleteAttribute_Success(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/persons/%s/attributes/%d", personID, attrID), nil)
@@ -754,7 +750,7 @@ func TestGetAttribute_Success(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/persons/%s/attributes/%d", personID, attrID), nil)
@@ -784,7 +780,7 @@ func TestGetAllAttributes_WithResults(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/persons/"+personID+"/attributes", nil)
@@ -809,7 +805,7 @@ func TestCreateAttribute_SuccessWithTraceID(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"key":"email","value":"test@example.com","meta":{"caller":"test","reason":"testing","traceId":"trace123"}}`
@@ -831,7 +827,7 @@ func TestCreateAttribute_DatabaseErrorOnCreate(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(closedPool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"key":"email","value":"test@example.com","meta":{"caller":"test","reason":"testing","traceId":"123"}}`
@@ -861,7 +857,7 @@ func TestUpdateAttribute_WithoutKeyProvided(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"value":"new-value","meta":{"caller":"test","reason":"testing","traceId":"123"}}`
@@ -890,7 +886,7 @@ func TestCreateAttribute_WithEmptyValue(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"key":"empty-value-key","value":"","meta":{"caller":"test","reason":"testing","traceId":"trace-empty"}}`
@@ -922,7 +918,7 @@ func TestUpdateAttribute_SameKeyAsExisting(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	// Update with same key explicitly provided
@@ -957,7 +953,7 @@ func TestUpdateAttribute_EmptyKeyPreservesOriginal(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	// Update with empty key - should preserve the original key
@@ -1004,7 +1000,7 @@ func TestGetAttribute_WithTimestamps(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/persons/%s/attributes/%d", personID, attrID), nil)
@@ -1034,7 +1030,7 @@ func TestGetAllAttributes_WithTimestamps(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/persons/"+personID+"/attributes", nil)
@@ -1061,7 +1057,7 @@ func TestCreateAttribute_WithUpdatedAt(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"key":"updated-key","value":"updated-value","meta":{"caller":"test","reason":"testing","traceId":"trace-updated"}}`
@@ -1093,7 +1089,7 @@ func TestUpdateAttribute_ResponseContainsTimestamps(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"value":"new-value"}`
@@ -1199,7 +1195,7 @@ func TestGetAttribute_CrossPersonAccessDenied(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	// Try to access person A's attribute via person B's endpoint
 	e := echo.New()
@@ -1234,7 +1230,7 @@ func TestUpdateAttribute_CrossPersonAccessDenied(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	// Try to update person A's attribute via person B's endpoint
 	e := echo.New()
@@ -1276,7 +1272,7 @@ func TestDeleteAttribute_CrossPersonAccessDenied(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	// Try to delete person A's attribute via person B's endpoint
 	e := echo.New()
@@ -1400,7 +1396,7 @@ func TestConcurrentAttributeCreation_SamePerson(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	numGoroutines := 10
 	var wg sync.WaitGroup
@@ -1474,7 +1470,7 @@ func TestConcurrentAttributeUpsert_SameKey(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	numGoroutines := 5
 	var wg sync.WaitGroup
@@ -1541,7 +1537,7 @@ func TestConcurrentReadWrite_Attributes(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	numReaders := 5
 	numWriters := 3
@@ -1604,7 +1600,7 @@ func TestCreateAttribute_LongKey(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	// Create a long key (citext has no explicit limit but test reasonable boundary)
 	longKey := strings.Repeat("a", 255)
@@ -1636,7 +1632,7 @@ func TestCreateAttribute_LongValue(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	// Create a long value (encrypted values stored as BYTEA should handle large data)
 	longValue := strings.Repeat("x", 10000)
@@ -1665,7 +1661,7 @@ func TestCreateAttribute_UnicodeCharacters(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	testCases := []struct {
 		name  string
@@ -1711,7 +1707,7 @@ func TestCreateAttribute_SpecialCharactersInKey(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	testCases := []struct {
 		name string
@@ -1752,7 +1748,7 @@ func TestCreateAttribute_CaseInsensitiveKey(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 
@@ -1794,7 +1790,7 @@ func TestMultipleAttributesPerPerson(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	numAttributes := 50 // Test with many attributes
 
@@ -1846,7 +1842,7 @@ func TestCreateAttribute_ResponseSchema(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"key":"schema-key","value":"schema-value","meta":{"caller":"test","reason":"schema-test","traceId":"schema-trace"}}`
@@ -1894,7 +1890,7 @@ func TestGetAllAttributes_ResponseSchema(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/persons/"+personID+"/attributes", nil)
@@ -1924,7 +1920,7 @@ func TestGetAllAttributes_ResponseSchema(t *testing.T) {
 // TestErrorResponse_Schema validates error response format consistency
 func TestErrorResponse_Schema(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	testCases := []struct {
 		name           string
@@ -2002,7 +1998,7 @@ func TestContentType_JSON(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"key":"ct-key","value":"ct-value","meta":{"caller":"test","reason":"content-type-test","traceId":"ct-trace"}}`
@@ -2054,7 +2050,7 @@ func TestCascadeDelete_PersonWithAttributes(t *testing.T) {
 
 	// Verify person cannot access attributes through API
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/persons/"+personID+"/attributes", nil)
@@ -2078,7 +2074,7 @@ func TestIdempotency_SameTraceID(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	// Create attribute with specific trace_id
 	traceID := "idempotent-trace-12345"
@@ -2149,7 +2145,7 @@ func TestAuditLog_WithTraceID(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	// Create attribute with traceID
 	traceID := "audit-test-trace-999"
@@ -2180,6 +2176,38 @@ func TestAuditLog_WithTraceID(t *testing.T) {
 	assert.Equal(t, "test-reason", reason)
 }
 
+// TestAuditLog_RecordsPrincipal verifies that the authenticated principal is stored with the audit log
+func TestAuditLog_RecordsPrincipal(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	personID, err := createTestPerson(ctx, "audit-principal-test")
+	assert.NoError(t, err)
+
+	handler := NewPersonAttributesHandler(db.New(pool), testEncryption)
+
+	traceID := "audit-principal-trace"
+	e := echo.New()
+	jsonBody := fmt.Sprintf(`{"key":"audit-key","value":"audit-value","meta":{"caller":"test-caller","reason":"test-reason","traceId":"%s"}}`, traceID)
+	req := httptest.NewRequest(http.MethodPut, "/persons/"+personID+"/attributes", strings.NewReader(jsonBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req = req.WithContext(auth.ContextWithPrincipal(req.Context(), auth.Principal{ID: "blue"}))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("personId")
+	c.SetParamValues(personID)
+
+	err = handler.CreateAttribute(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	var principalID string
+	err = pool.QueryRow(ctx, "SELECT principal_id FROM request_log WHERE trace_id = $1", traceID).Scan(&principalID)
+	assert.NoError(t, err)
+	assert.Equal(t, "blue", principalID)
+}
+
 // TestAuditLog_WithoutTraceID verifies that audit log is NOT created when traceID is empty
 func TestAuditLog_WithoutTraceID(t *testing.T) {
 	ctx := context.Background()
@@ -2190,7 +2218,7 @@ func TestAuditLog_WithoutTraceID(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	// Get initial count
 	var initialCount int
@@ -2232,7 +2260,7 @@ func TestUpdateAttribute_KeyRename(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	// Update attribute with a new key (rename)
 	e := echo.New()
@@ -2279,7 +2307,7 @@ func TestUpdateAttribute_SameKey(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	// Update attribute with the SAME key (just change value)
 	e := echo.New()
@@ -2318,7 +2346,7 @@ func TestUpdateAttribute_SameKey(t *testing.T) {
 
 func TestCreateAttribute_MetaEmptyCaller(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"key":"email","value":"test@example.com","meta":{"caller":"","reason":"testing","traceId":"123"}}`
@@ -2338,7 +2366,7 @@ func TestCreateAttribute_MetaEmptyCaller(t *testing.T) {
 
 func TestCreateAttribute_MetaEmptyReason(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"key":"email","value":"test@example.com","meta":{"caller":"test","reason":"","traceId":"123"}}`
@@ -2358,7 +2386,7 @@ func TestCreateAttribute_MetaEmptyReason(t *testing.T) {
 
 func TestUpdateAttribute_EmptyValue(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"value":""}`
@@ -2378,7 +2406,7 @@ func TestUpdateAttribute_EmptyValue(t *testing.T) {
 
 func TestUpdateAttribute_WhitespaceOnlyValue(t *testing.T) {
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	e := echo.New()
 	jsonBody := `{"value":"   "}`
@@ -2409,7 +2437,7 @@ func TestUpdateAttribute_WithVersionOptimisticLocking(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	// Get the current version
 	var currentVersion int64
@@ -2445,7 +2473,7 @@ func TestUpdateAttribute_VersionConflict(t *testing.T) {
 	assert.NoError(t, err)
 
 	queries := db.New(pool)
-	handler := NewPersonAttributesHandler(queries)
+	handler := NewPersonAttributesHandler(queries, testEncryption)
 
 	// Use a wrong version to trigger conflict
 	wrongVersion := int64(999)
@@ -2465,3 +2493,137 @@ func TestUpdateAttribute_VersionConflict(t *testing.T) {
 	assert.Equal(t, http.StatusConflict, rec.Code)
 	assert.Contains(t, rec.Body.String(), "Version conflict")
 }
+
+func TestGetAllAttributes_OtherTenant(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	// The person belongs to the default tenant
+	personID, err := createTestPerson(ctx, "test-client-tenant")
+	assert.NoError(t, err)
+	_, err = createTestAttribute(ctx, personID, "email", "user@example.com")
+	assert.NoError(t, err)
+
+	handler := NewPersonAttributesHandler(db.New(pool), testEncryption)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/persons/"+personID+"/attributes", nil)
+	req = req.WithContext(auth.ContextWithPrincipal(req.Context(), auth.Principal{ID: "acme", Tenant: "acme"}))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("personId")
+	c.SetParamValues(personID)
+
+	err = handler.GetAllAttributes(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, rec.Code, "Persons of other tenants must not be visible")
+}
+
+func TestGetAllAttributes_AppliesPolicy(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	personID, err := createTestPerson(ctx, "test-client-policy")
+	assert.NoError(t, err)
+	for key, value := range map[string]string{"email": "user@example.com", "national_id": "123-45-6789", "salary": "85000"} {
+		_, err = createTestAttribute(ctx, personID, key, value)
+		assert.NoError(t, err)
+	}
+
+	attributePolicy, err := policy.New([]policy.Rule{
+		{Pattern: "national_id", Scope: "attributes:read:pii"},
+		{Pattern: "salary*", Scope: "attributes:read:payroll", Action: policy.ActionHide},
+	})
+	assert.NoError(t, err)
+	handler := NewPersonAttributesHandler(db.New(pool), testEncryption)
+	handler.SetPolicy(attributePolicy)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/persons/"+personID+"/attributes", nil)
+	req = req.WithContext(auth.ContextWithPrincipal(req.Context(), auth.Principal{
+		ID:     "support",
+		Name:   "support",
+		Scopes: []string{auth.ScopeAttributesRead},
+	}))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("personId")
+	c.SetParamValues(personID)
+
+	err = handler.GetAllAttributes(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	values := make(map[string]interface{})
+	for _, item := range response {
+		values[item["key"].(string)] = item["value"]
+	}
+	assert.Equal(t, map[string]interface{}{
+		"email":       "user@example.com",
+		"national_id": "****6789",
+	}, values, "Masked values keep their last characters and hidden attributes are left out")
+
+	// The denial is recorded in the audit log
+	var principalID, reason string
+	err = pool.QueryRow(ctx, `SELECT principal_id, reason FROM request_log`).Scan(&principalID, &reason)
+	assert.NoError(t, err)
+	assert.Equal(t, "support", principalID)
+	assert.Equal(t, "attribute_policy_denied", reason)
+}
+
+func TestGetAllAttributes_MaskedView(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	personID, err := createTestPerson(ctx, "test-client-masked")
+	assert.NoError(t, err)
+	_, err = createTestAttribute(ctx, personID, "email", "jane@example.com")
+	assert.NoError(t, err)
+	_, err = createTestAttribute(ctx, personID, "phone", "+1 555 123 4567")
+	assert.NoError(t, err)
+
+	handler := NewPersonAttributesHandler(db.New(pool), testEncryption)
+	e := echo.New()
+
+	get := func(query string, principal auth.Principal) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodGet, "/persons/"+personID+"/attributes"+query, nil)
+		req = req.WithContext(auth.ContextWithPrincipal(req.Context(), principal))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("personId")
+		c.SetParamValues(personID)
+		assert.NoError(t, handler.GetAllAttributes(c))
+
+		values := make(map[string]interface{})
+		var response []map[string]interface{}
+		if json.Unmarshal(rec.Body.Bytes(), &response) == nil {
+			for _, item := range response {
+				values[item["key"].(string)] = item["value"]
+			}
+		}
+		return rec, values
+	}
+
+	rec, values := get("?view=masked", auth.Principal{ID: "support"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, map[string]interface{}{"email": "j****@example.com", "phone": "****4567"}, values)
+
+	rec, values = get("", auth.Principal{ID: "support"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "jane@example.com", values["email"], "The full view is the default")
+
+	rec, _ = get("?view=unknown", auth.Principal{ID: "support"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "PA_009_INVALID_VIEW")
+
+	// A credential bound to a profile cannot ask for the full view
+	rec, values = get("?view=full", auth.Principal{ID: "support", RedactionProfile: "masked"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "****4567", values["phone"])
+}
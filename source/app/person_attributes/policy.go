@@ -0,0 +1,108 @@
+package person_attributes
+
+import (
+	"context"
+
+	"person-service/audit"
+	"person-service/auth"
+	errs "person-service/errors"
+	"person-service/logging"
+	"person-service/metrics"
+	"person-service/policy"
+	"person-service/purpose"
+	"person-service/redaction"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+// deniedAuditReason is the request log reason of attribute reads withheld by the policy
+const deniedAuditReason = "attribute_policy_denied"
+
+// purposeText returns the declared purpose of use of the request for the
+// request log, or NULL when none was declared
+func purposeText(ctx context.Context) pgtype.Text {
+	declared := purpose.FromContext(ctx)
+	return pgtype.Text{String: declared, Valid: declared != ""}
+}
+
+// SetPolicy sets the attribute policy applied to values in responses.
+// Without a policy every value is returned to callers allowed on the route.
+func (h *PersonAttributesHandler) SetPolicy(attributePolicy *policy.Policy) {
+	h.policy = attributePolicy
+}
+
+// SetRedactionProfiles sets the redaction profiles callers can select.
+// The built-in full and masked profiles are used until it is called.
+func (h *PersonAttributesHandler) SetRedactionProfiles(profiles *redaction.Profiles) {
+	h.profiles = profiles
+}
+
+// redactionProfile returns the redaction profile of the request: the
+// credential's profile when it has one, otherwise the view query parameter,
+// which defaults to the full view. It returns false for an unknown view.
+func (h *PersonAttributesHandler) redactionProfile(c echo.Context, principal auth.Principal) (string, bool) {
+	if principal.RedactionProfile != "" {
+		return principal.RedactionProfile, true
+	}
+
+	view := c.QueryParam("view")
+	if view == "" {
+		return redaction.ProfileFull, true
+	}
+	return view, h.profiles.Has(view)
+}
+
+// applyPolicy masks or removes the value of an attribute response the caller
+// may not read and appends its key to denied. Values the caller may read are
+// redacted with profile. It returns false when the attribute must be left out
+// of list responses.
+func (h *PersonAttributesHandler) applyPolicy(principal auth.Principal, profile string, item map[string]interface{}, denied *[]string) bool {
+	key, _ := item["key"].(string)
+	decision := h.policy.Check(principal, key)
+	if decision.Allowed {
+		value, _ := item["value"].(string)
+		if redacted, changed := h.profiles.Redact(profile, key, value); changed {
+			item["value"] = redacted
+			item["redacted"] = true
+		}
+		return true
+	}
+
+	*denied = append(*denied, key)
+	if decision.Action == policy.ActionHide {
+		delete(item, "value")
+		return false
+	}
+
+	value, _ := item["value"].(string)
+	item["value"] = policy.Mask(value)
+	item["masked"] = true
+	return true
+}
+
+// auditDeniedAttributes records in the request log which attribute values of
+// the person were withheld from the caller. Failures are logged and do not
+// fail the request.
+func (h *PersonAttributesHandler) auditDeniedAttributes(ctx context.Context, principal auth.Principal, personID pgtype.UUID, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	err := h.audit.Write(ctx, principal, audit.Entry{
+		Kind:    "policy",
+		Reason:  deniedAuditReason,
+		Purpose: purpose.FromContext(ctx),
+		Details: map[string]interface{}{
+			"person_id": uuid.UUID(personID.Bytes).String(),
+			"keys":      keys,
+		},
+	})
+	if err != nil {
+		metrics.RecordAuditFailure(metrics.AuditSourceAttributePolicy)
+		logging.WarnContext(ctx, "Failed to record denied attribute access",
+			"error", err,
+			"error_code", errs.ErrFailedAuditLog)
+	}
+}
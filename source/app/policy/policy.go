@@ -0,0 +1,123 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+
+	"person-service/auth"
+)
+
+const (
+	// maskVisibleChars is the number of trailing characters a masked value keeps
+	maskVisibleChars = 4
+
+	// maskPrefix replaces the rest of a masked value
+	maskPrefix = "****"
+)
+
+// Action is what happens to an attribute value the caller may not read
+type Action string
+
+// Actions applied to protected attribute values
+const (
+	// ActionMask replaces all but the last characters of the value, as in ****1234
+	ActionMask Action = "mask"
+	// ActionHide leaves the value out of responses entirely
+	ActionHide Action = "hide"
+)
+
+// Rule protects the attributes whose key matches Pattern. Pattern is an exact
+// key or a glob such as salary_* and Scope is the scope needed to read them.
+type Rule struct {
+	Pattern string `json:"pattern"`
+	Scope   string `json:"scope"`
+	Action  Action `json:"action"`
+}
+
+// Decision is the outcome of checking one attribute against the policy
+type Decision struct {
+	Allowed bool
+	// Action applies to the value when the read is not allowed
+	Action Action
+	// Scope is the scope the caller was missing
+	Scope string
+}
+
+// Policy maps attribute keys to the scopes required to read their values.
+// The first rule matching a key applies; keys without a rule are readable by
+// every caller allowed on the route. A nil Policy allows everything.
+type Policy struct {
+	rules []Rule
+}
+
+// New creates a new instance of Policy after validating rules
+func New(rules []Rule) (*Policy, error) {
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Pattern == "" {
+			return nil, fmt.Errorf("rule %d: pattern is required", i)
+		}
+		if _, err := path.Match(rule.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("rule %d: invalid pattern %q: %w", i, rule.Pattern, err)
+		}
+		if !auth.IsValidScope(rule.Scope) {
+			return nil, fmt.Errorf("rule %d: unknown scope %q", i, rule.Scope)
+		}
+		switch rule.Action {
+		case "":
+			rule.Action = ActionMask
+		case ActionMask, ActionHide:
+		default:
+			return nil, fmt.Errorf("rule %d: unknown action %q", i, rule.Action)
+		}
+	}
+
+	return &Policy{
+		rules: rules,
+	}, nil
+}
+
+// Load reads a policy from a JSON array of rules
+func Load(file string) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attribute policy: %w", err)
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse attribute policy: %w", err)
+	}
+	return New(rules)
+}
+
+// Check decides whether principal may read the value of the attribute key
+func (p *Policy) Check(principal auth.Principal, key string) Decision {
+	if p == nil {
+		return Decision{Allowed: true}
+	}
+
+	for _, rule := range p.rules {
+		if matched, _ := path.Match(rule.Pattern, key); !matched {
+			continue
+		}
+		if principal.HasScope(rule.Scope) {
+			return Decision{Allowed: true}
+		}
+		return Decision{Action: rule.Action, Scope: rule.Scope}
+	}
+	return Decision{Allowed: true}
+}
+
+// Mask replaces value with asterisks followed by its last characters, as in
+// ****1234. The mask has a fixed width so it does not reveal the length, and
+// values too short to keep any characters are masked completely.
+func Mask(value string) string {
+	runes := []rune(value)
+	if len(runes) <= maskVisibleChars {
+		return maskPrefix
+	}
+	return maskPrefix + string(runes[len(runes)-maskVisibleChars:])
+}
@@ -0,0 +1,68 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"person-service/auth"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	policy, err := New([]Rule{
+		{Pattern: "national_id", Scope: "attributes:read:pii"},
+		{Pattern: "salary*", Scope: "attributes:read:payroll", Action: ActionHide},
+		{Pattern: "*", Scope: auth.ScopeAttributesRead},
+	})
+	assert.NoError(t, err)
+
+	support := auth.Principal{ID: "support", Scopes: []string{auth.ScopeAttributesRead}}
+	payroll := auth.Principal{ID: "payroll", Scopes: []string{auth.ScopeAttributesRead, "attributes:read:payroll"}}
+
+	assert.Equal(t, Decision{Action: ActionMask, Scope: "attributes:read:pii"}, policy.Check(support, "national_id"))
+	assert.Equal(t, Decision{Action: ActionHide, Scope: "attributes:read:payroll"}, policy.Check(support, "salary_2024"))
+	assert.True(t, policy.Check(payroll, "salary_2024").Allowed)
+	assert.True(t, policy.Check(support, "email").Allowed)
+	assert.False(t, policy.Check(auth.Principal{}, "email").Allowed, "The first matching rule applies")
+
+	var none *Policy
+	assert.True(t, none.Check(auth.Principal{}, "national_id").Allowed, "A nil policy allows everything")
+}
+
+func TestNew_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"missing pattern", Rule{Scope: auth.ScopeAttributesRead}},
+		{"invalid pattern", Rule{Pattern: "[", Scope: auth.ScopeAttributesRead}},
+		{"unknown scope", Rule{Pattern: "ssn", Scope: "pii"}},
+		{"unknown action", Rule{Pattern: "ssn", Scope: auth.ScopeAttributesRead, Action: "drop"}},
+	}
+	for _, tt := range tests {
+		_, err := New([]Rule{tt.rule})
+		assert.Error(t, err, tt.name)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policy.json")
+	assert.NoError(t, os.WriteFile(file, []byte(`[{"pattern": "ssn", "scope": "attributes:read:pii"}]`), 0o600))
+
+	policy, err := Load(file)
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{{Pattern: "ssn", Scope: "attributes:read:pii", Action: ActionMask}}, policy.rules)
+
+	assert.NoError(t, os.WriteFile(file, []byte(`{"pattern": "ssn"}`), 0o600))
+	_, err = Load(file)
+	assert.Error(t, err)
+}
+
+func TestMask(t *testing.T) {
+	assert.Equal(t, "****1234", Mask("123-45-1234"))
+	assert.Equal(t, "****ümlä", Mask("naïve ümlä"))
+	assert.Equal(t, "****", Mask("1234"))
+	assert.Equal(t, "****", Mask(""))
+}
@@ -0,0 +1,45 @@
+package purpose
+
+import (
+	"context"
+
+	"person-service/audit"
+	"person-service/auth"
+	"person-service/config"
+	db "person-service/internal/db/generated"
+)
+
+// Access describes a PII request made under a declared purpose
+type Access struct {
+	Purpose string
+	Method  string
+	Path    string
+	Status  int
+}
+
+// Auditor records PII access and its purpose in the request log
+type Auditor struct {
+	writer *audit.Writer
+}
+
+// NewAuditor creates a new instance of Auditor with injected queries that
+// encrypts request log entries with the key in encryption
+func NewAuditor(queries *db.Queries, encryption config.Encryption) *Auditor {
+	return &Auditor{
+		writer: audit.NewWriter(queries, encryption),
+	}
+}
+
+// Record adds a request log entry for access by principal
+func (a *Auditor) Record(ctx context.Context, principal auth.Principal, access Access) error {
+	return a.writer.Write(ctx, principal, audit.Entry{
+		Kind:    "purpose",
+		Reason:  access.Purpose,
+		Purpose: access.Purpose,
+		Details: map[string]interface{}{
+			"method": access.Method,
+			"path":   access.Path,
+			"status": access.Status,
+		},
+	})
+}
@@ -0,0 +1,82 @@
+package purpose
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"person-service/auth"
+)
+
+// Header carries the declared purpose of use of a request
+const Header = "X-Purpose"
+
+// contextKey is a custom type for context keys to avoid collisions
+type contextKey string
+
+// purposeKey is the context key for storing the declared purpose
+const purposeKey contextKey = "purpose"
+
+// namePattern restricts purpose names to lowercase identifiers such as kyc_verification
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// IsValidName reports whether name is a well-formed purpose name
+func IsValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Registry lists the purposes of use callers may declare for PII access
+type Registry struct {
+	purposes []string
+}
+
+// NewRegistry creates a new instance of Registry after validating names
+func NewRegistry(purposes []string) (*Registry, error) {
+	if len(purposes) == 0 {
+		return nil, fmt.Errorf("purpose registry is empty")
+	}
+	for _, name := range purposes {
+		if !IsValidName(name) {
+			return nil, fmt.Errorf("invalid purpose %q: use lowercase letters, digits and underscores", name)
+		}
+	}
+
+	return &Registry{
+		purposes: purposes,
+	}, nil
+}
+
+// Purposes returns the registered purposes
+func (r *Registry) Purposes() []string {
+	return r.purposes
+}
+
+// Has reports whether purpose is registered
+func (r *Registry) Has(purpose string) bool {
+	return slices.Contains(r.purposes, purpose)
+}
+
+// Allows reports whether principal may declare purpose. It must be registered
+// and linked to the principal's credential; principals without linked
+// purposes may declare every registered purpose.
+func (r *Registry) Allows(principal auth.Principal, purpose string) bool {
+	if !r.Has(purpose) {
+		return false
+	}
+	return len(principal.Purposes) == 0 || slices.Contains(principal.Purposes, purpose)
+}
+
+// ContextWithPurpose creates a new context with the declared purpose stored
+func ContextWithPurpose(ctx context.Context, purpose string) context.Context {
+	return context.WithValue(ctx, purposeKey, purpose)
+}
+
+// FromContext returns the declared purpose stored in the context, or an empty string
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	purpose, _ := ctx.Value(purposeKey).(string)
+	return purpose
+}
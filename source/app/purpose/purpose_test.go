@@ -0,0 +1,41 @@
+package purpose
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"person-service/auth"
+)
+
+func TestNewRegistry(t *testing.T) {
+	registry, err := NewRegistry([]string{"kyc_verification", "customer_support"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"kyc_verification", "customer_support"}, registry.Purposes())
+
+	_, err = NewRegistry(nil)
+	assert.Error(t, err)
+
+	_, err = NewRegistry([]string{"KYC Verification"})
+	assert.Error(t, err)
+}
+
+func TestRegistry_Allows(t *testing.T) {
+	registry, err := NewRegistry([]string{"kyc_verification", "customer_support"})
+	assert.NoError(t, err)
+
+	assert.True(t, registry.Allows(auth.Principal{}, "kyc_verification"), "Principals without linked purposes may declare any registered one")
+	assert.False(t, registry.Allows(auth.Principal{}, "marketing"))
+
+	support := auth.Principal{Purposes: []string{"customer_support", "marketing"}}
+	assert.True(t, registry.Allows(support, "customer_support"))
+	assert.False(t, registry.Allows(support, "kyc_verification"))
+	assert.False(t, registry.Allows(support, "marketing"), "Linked purposes must also be registered")
+}
+
+func TestContextWithPurpose(t *testing.T) {
+	ctx := ContextWithPurpose(context.Background(), "kyc_verification")
+	assert.Equal(t, "kyc_verification", FromContext(ctx))
+	assert.Empty(t, FromContext(context.Background()))
+}
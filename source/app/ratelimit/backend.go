@@ -0,0 +1,161 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	db "person-service/internal/db/generated"
+	"person-service/logging"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// memoryPruneInterval is the minimum time between prunes of the in-memory backend
+	memoryPruneInterval = time.Minute
+
+	// sweepBatchSize bounds the number of bucket rows deleted per statement
+	sweepBatchSize = 500
+)
+
+// memoryBucket is a token bucket held in process memory
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+// MemoryBackend keeps token buckets in process memory. Each instance limits
+// independently, so it suits single-instance deployments.
+type MemoryBackend struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// NewMemoryBackend creates a new instance of MemoryBackend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		buckets:   make(map[string]*memoryBucket),
+		lastPrune: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Take refills the bucket for key and takes one token when available
+func (b *MemoryBackend) Take(ctx context.Context, key string, limit Limit) (float64, bool, error) {
+	now := b.now()
+	capacity := float64(limit.Requests)
+	rate := limit.refillPerSecond()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Buckets that have refilled completely hold no state worth keeping
+	if now.Sub(b.lastPrune) >= memoryPruneInterval {
+		for stored, bucket := range b.buckets {
+			if !bucket.fullAt.After(now) {
+				delete(b.buckets, stored)
+			}
+		}
+		b.lastPrune = now
+	}
+
+	bucket, ok := b.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: capacity, updatedAt: now}
+		b.buckets[key] = bucket
+	}
+	bucket.tokens = min(capacity, bucket.tokens+now.Sub(bucket.updatedAt).Seconds()*rate)
+	bucket.updatedAt = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+	bucket.fullAt = now.Add(seconds((capacity - bucket.tokens) / rate))
+	return bucket.tokens, allowed, nil
+}
+
+// PostgresBackend keeps token buckets in the rate_limit_bucket table so all
+// instances share the same limits
+type PostgresBackend struct {
+	queries *db.Queries
+}
+
+// NewPostgresBackend creates a new instance of PostgresBackend with injected queries
+func NewPostgresBackend(queries *db.Queries) *PostgresBackend {
+	return &PostgresBackend{
+		queries: queries,
+	}
+}
+
+// Take refills the bucket for key and takes one token when available
+func (b *PostgresBackend) Take(ctx context.Context, key string, limit Limit) (float64, bool, error) {
+	tokens, err := b.queries.TakeRateLimitToken(ctx, db.TakeRateLimitTokenParams{
+		BucketKey:       key,
+		Capacity:        float64(limit.Requests),
+		RefillPerSecond: limit.refillPerSecond(),
+	})
+	if err == nil {
+		return tokens, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, err
+	}
+
+	// The bucket is empty; read its level to tell the client when to retry
+	tokens, err = b.queries.GetRateLimitTokens(ctx, db.GetRateLimitTokensParams{
+		Capacity:        float64(limit.Requests),
+		RefillPerSecond: limit.refillPerSecond(),
+		BucketKey:       key,
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return tokens, false, nil
+}
+
+// Run sweeps buckets idle for longer than idleAfter every interval until the context is cancelled
+func (b *PostgresBackend) Run(ctx context.Context, interval, idleAfter time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := b.Sweep(ctx, time.Now().Add(-idleAfter)); err != nil && ctx.Err() == nil {
+				logging.Warn("Rate limit bucket sweep failed", "error", err)
+			}
+		}
+	}
+}
+
+// Sweep deletes buckets not used since idleBefore in batches and returns the number removed
+func (b *PostgresBackend) Sweep(ctx context.Context, idleBefore time.Time) (int64, error) {
+	var total int64
+	for {
+		deleted, err := b.queries.DeleteIdleRateLimitBuckets(ctx, db.DeleteIdleRateLimitBucketsParams{
+			IdleBefore: pgtype.Timestamptz{Time: idleBefore, Valid: true},
+			BatchSize:  sweepBatchSize,
+		})
+		if err != nil {
+			return total, err
+		}
+		total += deleted
+		if deleted < sweepBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		logging.Debug("Swept idle rate limit buckets", "count", total)
+	}
+	return total, nil
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+
+	db "person-service/internal/db/generated"
+	"person-service/internal/testdb"
+)
+
+var pool *pgxpool.Pool
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+	var err error
+	pool, err = testdb.GetPool(ctx)
+	if err != nil {
+		log.Fatalf("Failed to get pool: %v", err)
+	}
+	if err := testdb.RunMigrations(ctx, pool); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestPostgresBackend_Take(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	backend := NewPostgresBackend(db.New(pool))
+	limit := Limit{Requests: 2, Period: time.Hour}
+
+	tokens, allowed, err := backend.Take(ctx, "write:billing", limit)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	assert.InDelta(t, 1, tokens, 0.01)
+
+	_, allowed, err = backend.Take(ctx, "write:billing", limit)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	tokens, allowed, err = backend.Take(ctx, "write:billing", limit)
+	assert.NoError(t, err)
+	assert.False(t, allowed, "An empty bucket must reject the request")
+	assert.Less(t, tokens, 1.0)
+
+	_, allowed, err = backend.Take(ctx, "write:reports", limit)
+	assert.NoError(t, err)
+	assert.True(t, allowed, "Buckets are scoped to their key")
+}
+
+func TestPostgresBackend_Refills(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	backend := NewPostgresBackend(db.New(pool))
+	limit := Limit{Requests: 1, Period: time.Hour}
+
+	_, allowed, err := backend.Take(ctx, "read:billing", limit)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	// Pretend the bucket was last used a full period ago
+	_, err = pool.Exec(ctx, "UPDATE rate_limit_bucket SET updated_at = updated_at - interval '1 hour'")
+	assert.NoError(t, err)
+
+	_, allowed, err = backend.Take(ctx, "read:billing", limit)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+func TestPostgresBackend_Sweep(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	backend := NewPostgresBackend(db.New(pool))
+	limit := Limit{Requests: 5, Period: time.Minute}
+
+	for _, key := range []string{"read:billing", "read:reports"} {
+		_, _, err := backend.Take(ctx, key, limit)
+		assert.NoError(t, err)
+	}
+	_, err = pool.Exec(ctx, "UPDATE rate_limit_bucket SET updated_at = updated_at - interval '2 minutes' WHERE bucket_key = 'read:billing'")
+	assert.NoError(t, err)
+
+	deleted, err := backend.Sweep(ctx, time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	var remaining int
+	err = pool.QueryRow(ctx, "SELECT count(*) FROM rate_limit_bucket").Scan(&remaining)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, remaining)
+}
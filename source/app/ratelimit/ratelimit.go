@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Class groups routes that share a rate limit
+type Class string
+
+// Route classes with separate limits per principal
+const (
+	ClassRead   Class = "read"
+	ClassWrite  Class = "write"
+	ClassSearch Class = "search"
+)
+
+// Classes lists every route class, in the order they are documented
+var Classes = []Class{ClassRead, ClassWrite, ClassSearch}
+
+// Limit is a token bucket holding Requests tokens that refills completely over Period
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit parses a limit written as <requests>/<period>, where period is
+// s, m, h or a duration such as 30s
+func ParseLimit(raw string) (Limit, error) {
+	requests, period, ok := strings.Cut(strings.TrimSpace(raw), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <requests>/<period>", raw)
+	}
+
+	count, err := strconv.Atoi(requests)
+	if err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive integer", raw)
+	}
+
+	var duration time.Duration
+	switch period {
+	case "s":
+		duration = time.Second
+	case "m":
+		duration = time.Minute
+	case "h":
+		duration = time.Hour
+	default:
+		duration, err = time.ParseDuration(period)
+		if err != nil || duration <= 0 {
+			return Limit{}, fmt.Errorf("invalid rate limit %q: unknown period %q", raw, period)
+		}
+	}
+
+	return Limit{Requests: count, Period: duration}, nil
+}
+
+// refillPerSecond returns the number of tokens added to the bucket each second
+func (l Limit) refillPerSecond() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Backend stores token buckets
+type Backend interface {
+	// Take refills the bucket for key and takes one token when available. It
+	// returns the tokens left and whether a token was taken.
+	Take(ctx context.Context, key string, limit Limit) (float64, bool, error)
+}
+
+// Result describes the state of a bucket after a request
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next token is available when the request was not allowed
+	RetryAfter time.Duration
+}
+
+// Limiter applies per-class limits to the buckets of each principal
+type Limiter struct {
+	backend Backend
+	limits  map[Class]Limit
+}
+
+// NewLimiter creates a new instance of Limiter. Classes without a limit are not limited.
+func NewLimiter(backend Backend, limits map[Class]Limit) *Limiter {
+	return &Limiter{
+		backend: backend,
+		limits:  limits,
+	}
+}
+
+// Limits reports whether requests of class are limited
+func (l *Limiter) Limits(class Class) bool {
+	_, ok := l.limits[class]
+	return ok
+}
+
+// MaxPeriod returns the longest refill period, after which every idle bucket is full
+func (l *Limiter) MaxPeriod() time.Duration {
+	var longest time.Duration
+	for _, limit := range l.limits {
+		longest = max(longest, limit.Period)
+	}
+	return longest
+}
+
+// Allow takes a token from the bucket of principalID for class
+func (l *Limiter) Allow(ctx context.Context, class Class, principalID string) (Result, error) {
+	limit, ok := l.limits[class]
+	if !ok {
+		return Result{Allowed: true}, nil
+	}
+
+	tokens, allowed, err := l.backend.Take(ctx, string(class)+":"+principalID, limit)
+	if err != nil {
+		return Result{}, err
+	}
+
+	rate := limit.refillPerSecond()
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: max(0, int(math.Floor(tokens))),
+		Reset:     seconds((float64(limit.Requests) - tokens) / rate),
+	}
+	if !allowed {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return result, nil
+}
+
+// seconds converts a non-negative number of seconds to a duration, rounded to
+// the millisecond to hide floating point error
+func seconds(s float64) time.Duration {
+	return time.Duration(max(0, s) * float64(time.Second)).Round(time.Millisecond)
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected Limit
+	}{
+		{"10/s", Limit{Requests: 10, Period: time.Second}},
+		{"600/m", Limit{Requests: 600, Period: time.Minute}},
+		{"5000/h", Limit{Requests: 5000, Period: time.Hour}},
+		{" 50/30s ", Limit{Requests: 50, Period: 30 * time.Second}},
+	}
+	for _, tt := range tests {
+		limit, err := ParseLimit(tt.raw)
+		assert.NoError(t, err, tt.raw)
+		assert.Equal(t, tt.expected, limit, tt.raw)
+	}
+
+	for _, raw := range []string{"", "10", "0/s", "-1/m", "ten/s", "10/d", "10/-5s"} {
+		_, err := ParseLimit(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+// newTestMemoryBackend returns a memory backend with a clock the test controls
+func newTestMemoryBackend() (*MemoryBackend, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	backend := NewMemoryBackend()
+	backend.lastPrune = now
+	backend.now = func() time.Time { return now }
+	return backend, &now
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	ctx := context.Background()
+	backend, now := newTestMemoryBackend()
+	limiter := NewLimiter(backend, map[Class]Limit{
+		ClassWrite: {Requests: 2, Period: 10 * time.Second},
+	})
+
+	result, err := limiter.Allow(ctx, ClassWrite, "billing")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Limit)
+	assert.Equal(t, 1, result.Remaining)
+	assert.Equal(t, 5*time.Second, result.Reset)
+
+	result, err = limiter.Allow(ctx, ClassWrite, "billing")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	result, err = limiter.Allow(ctx, ClassWrite, "billing")
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 5*time.Second, result.RetryAfter)
+	assert.Equal(t, 10*time.Second, result.Reset)
+
+	// Other principals and classes have their own buckets
+	result, err = limiter.Allow(ctx, ClassWrite, "reports")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	result, err = limiter.Allow(ctx, ClassRead, "billing")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed, "Classes without a limit are not limited")
+
+	// Tokens refill over time
+	*now = now.Add(3 * time.Second)
+	result, err = limiter.Allow(ctx, ClassWrite, "billing")
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 2*time.Second, result.RetryAfter)
+
+	*now = now.Add(2 * time.Second)
+	result, err = limiter.Allow(ctx, ClassWrite, "billing")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestMemoryBackend_PrunesFullBuckets(t *testing.T) {
+	ctx := context.Background()
+	backend, now := newTestMemoryBackend()
+	limit := Limit{Requests: 5, Period: time.Second}
+
+	_, _, err := backend.Take(ctx, "write:billing", limit)
+	assert.NoError(t, err)
+	assert.Len(t, backend.buckets, 1)
+
+	*now = now.Add(memoryPruneInterval)
+	_, _, err = backend.Take(ctx, "write:reports", limit)
+	assert.NoError(t, err)
+	assert.Len(t, backend.buckets, 1, "Refilled buckets are dropped")
+	assert.Contains(t, backend.buckets, "write:reports")
+}
+
+func TestLimiter_MaxPeriod(t *testing.T) {
+	limiter := NewLimiter(NewMemoryBackend(), map[Class]Limit{
+		ClassRead:   {Requests: 100, Period: time.Minute},
+		ClassSearch: {Requests: 100, Period: time.Hour},
+	})
+	assert.Equal(t, time.Hour, limiter.MaxPeriod())
+	assert.True(t, limiter.Limits(ClassSearch))
+	assert.False(t, limiter.Limits(ClassWrite))
+}
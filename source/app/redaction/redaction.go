@@ -0,0 +1,187 @@
+package redaction
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	// ProfileFull returns values unchanged
+	ProfileFull = "full"
+
+	// ProfileMasked is the profile support tooling asks for with ?view=masked
+	ProfileMasked = "masked"
+
+	// defaultKeep is the number of characters kept by keep_prefix and keep_suffix rules without a count
+	defaultKeep = 4
+
+	// maskPrefix replaces the hidden part of a masked value
+	maskPrefix = "****"
+
+	// redactedValue replaces fully redacted values
+	redactedValue = "[REDACTED]"
+
+	// hashLength is the number of hex characters of the SHA-256 digest shown by hash rules
+	hashLength = 16
+)
+
+// Strategy is how a rule rewrites a value
+type Strategy string
+
+// Masking strategies of redaction rules
+const (
+	// StrategyKeepPrefix keeps the first Keep characters, as in 4111****
+	StrategyKeepPrefix Strategy = "keep_prefix"
+	// StrategyKeepSuffix keeps the last Keep characters, as in ****4567
+	StrategyKeepSuffix Strategy = "keep_suffix"
+	// StrategyEmail keeps the first character of the local part and the domain, as in j****@example.com
+	StrategyEmail Strategy = "email"
+	// StrategyHash replaces the value with a truncated SHA-256 digest, so equal values still match
+	StrategyHash Strategy = "hash"
+	// StrategyRedact replaces the whole value
+	StrategyRedact Strategy = "redact"
+)
+
+// Rule rewrites the values of attributes whose key matches Pattern, an exact
+// key or a glob such as phone_*. Keep applies to keep_prefix and keep_suffix.
+type Rule struct {
+	Pattern  string   `json:"pattern"`
+	Strategy Strategy `json:"strategy"`
+	Keep     int      `json:"keep,omitempty"`
+}
+
+// defaultMaskedRules make up the masked profile when it is not configured
+var defaultMaskedRules = []Rule{
+	{Pattern: "*email*", Strategy: StrategyEmail},
+	{Pattern: "*", Strategy: StrategyKeepSuffix, Keep: defaultKeep},
+}
+
+// Profiles holds the named redaction profiles. In each profile the first rule
+// matching a key applies and keys without a rule are returned unchanged. The
+// full profile is always available and never redacts.
+type Profiles struct {
+	profiles map[string][]Rule
+}
+
+// New creates a new instance of Profiles after validating the rules of each
+// profile. The masked profile falls back to built-in rules when not configured.
+func New(profiles map[string][]Rule) (*Profiles, error) {
+	validated := map[string][]Rule{
+		ProfileFull:   nil,
+		ProfileMasked: defaultMaskedRules,
+	}
+	for name, rules := range profiles {
+		if name == "" || name == ProfileFull {
+			return nil, fmt.Errorf("invalid redaction profile name %q", name)
+		}
+		for i, rule := range rules {
+			if err := validateRule(rule); err != nil {
+				return nil, fmt.Errorf("profile %q rule %d: %w", name, i, err)
+			}
+			if rule.Keep == 0 {
+				rules[i].Keep = defaultKeep
+			}
+		}
+		validated[name] = rules
+	}
+
+	return &Profiles{
+		profiles: validated,
+	}, nil
+}
+
+// validateRule checks the pattern, strategy and count of rule
+func validateRule(rule Rule) error {
+	if rule.Pattern == "" {
+		return fmt.Errorf("pattern is required")
+	}
+	if _, err := path.Match(rule.Pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", rule.Pattern, err)
+	}
+	switch rule.Strategy {
+	case StrategyKeepPrefix, StrategyKeepSuffix, StrategyEmail, StrategyHash, StrategyRedact:
+	default:
+		return fmt.Errorf("unknown strategy %q", rule.Strategy)
+	}
+	if rule.Keep < 0 {
+		return fmt.Errorf("keep must not be negative")
+	}
+	return nil
+}
+
+// Default returns the built-in full and masked profiles
+func Default() *Profiles {
+	profiles, _ := New(nil)
+	return profiles
+}
+
+// Load reads profiles from a JSON object mapping profile names to arrays of rules
+func Load(file string) (*Profiles, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction profiles: %w", err)
+	}
+
+	var profiles map[string][]Rule
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction profiles: %w", err)
+	}
+	return New(profiles)
+}
+
+// Has reports whether profile is defined
+func (p *Profiles) Has(profile string) bool {
+	_, ok := p.profiles[profile]
+	return ok
+}
+
+// Redact rewrites the value of the attribute key for profile and reports
+// whether it changed. Unknown profiles redact every value so a misconfigured
+// credential never sees plaintext.
+func (p *Profiles) Redact(profile, key, value string) (string, bool) {
+	rules, ok := p.profiles[profile]
+	if !ok {
+		return redactedValue, true
+	}
+
+	for _, rule := range rules {
+		if matched, _ := path.Match(rule.Pattern, key); matched {
+			return rule.apply(value), true
+		}
+	}
+	return value, false
+}
+
+// apply rewrites value with the strategy of the rule
+func (r Rule) apply(value string) string {
+	switch r.Strategy {
+	case StrategyKeepPrefix:
+		runes := []rune(value)
+		if len(runes) <= r.Keep {
+			return maskPrefix
+		}
+		return string(runes[:r.Keep]) + maskPrefix
+	case StrategyKeepSuffix:
+		runes := []rune(value)
+		if len(runes) <= r.Keep {
+			return maskPrefix
+		}
+		return maskPrefix + string(runes[len(runes)-r.Keep:])
+	case StrategyEmail:
+		local, domain, ok := strings.Cut(value, "@")
+		if !ok || local == "" {
+			return maskPrefix
+		}
+		return string([]rune(local)[:1]) + maskPrefix + "@" + domain
+	case StrategyHash:
+		sum := sha256.Sum256([]byte(value))
+		return "sha256:" + hex.EncodeToString(sum[:])[:hashLength]
+	default:
+		return redactedValue
+	}
+}
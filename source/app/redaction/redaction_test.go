@@ -0,0 +1,91 @@
+package redaction
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRule_Apply(t *testing.T) {
+	tests := []struct {
+		rule     Rule
+		value    string
+		expected string
+	}{
+		{Rule{Strategy: StrategyKeepSuffix, Keep: 4}, "+1 555 123 4567", "****4567"},
+		{Rule{Strategy: StrategyKeepSuffix, Keep: 4}, "123", "****"},
+		{Rule{Strategy: StrategyKeepPrefix, Keep: 2}, "DE89370400440532013000", "DE****"},
+		{Rule{Strategy: StrategyEmail}, "jane.doe@example.com", "j****@example.com"},
+		{Rule{Strategy: StrategyEmail}, "not-an-email", "****"},
+		{Rule{Strategy: StrategyHash}, "secret", "sha256:2bb80d537b1da3e3"},
+		{Rule{Strategy: StrategyRedact}, "secret", "[REDACTED]"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.rule.apply(tt.value), string(tt.rule.Strategy)+" "+tt.value)
+	}
+}
+
+func TestProfiles_Redact(t *testing.T) {
+	profiles, err := New(map[string][]Rule{
+		"support": {
+			{Pattern: "phone*", Strategy: StrategyKeepSuffix},
+			{Pattern: "national_id", Strategy: StrategyRedact},
+		},
+	})
+	assert.NoError(t, err)
+
+	value, changed := profiles.Redact("support", "phone_mobile", "+1 555 123 4567")
+	assert.True(t, changed)
+	assert.Equal(t, "****4567", value, "keep defaults to 4 characters")
+
+	value, changed = profiles.Redact("support", "nickname", "JD")
+	assert.False(t, changed, "Keys without a rule are returned unchanged")
+	assert.Equal(t, "JD", value)
+
+	value, changed = profiles.Redact(ProfileFull, "national_id", "123-45-6789")
+	assert.False(t, changed)
+	assert.Equal(t, "123-45-6789", value)
+
+	value, _ = profiles.Redact(ProfileMasked, "email", "jane@example.com")
+	assert.Equal(t, "j****@example.com", value, "The masked profile has built-in rules")
+
+	value, changed = profiles.Redact("unknown", "nickname", "JD")
+	assert.True(t, changed)
+	assert.Equal(t, "[REDACTED]", value, "Unknown profiles redact everything")
+
+	assert.True(t, profiles.Has("support"))
+	assert.False(t, profiles.Has("unknown"))
+}
+
+func TestNew_Validation(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles map[string][]Rule
+	}{
+		{"full is reserved", map[string][]Rule{ProfileFull: {{Pattern: "*", Strategy: StrategyRedact}}}},
+		{"missing pattern", map[string][]Rule{"support": {{Strategy: StrategyRedact}}}},
+		{"invalid pattern", map[string][]Rule{"support": {{Pattern: "[", Strategy: StrategyRedact}}}},
+		{"unknown strategy", map[string][]Rule{"support": {{Pattern: "*", Strategy: "blur"}}}},
+		{"negative keep", map[string][]Rule{"support": {{Pattern: "*", Strategy: StrategyKeepSuffix, Keep: -1}}}},
+	}
+	for _, tt := range tests {
+		_, err := New(tt.profiles)
+		assert.Error(t, err, tt.name)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "profiles.json")
+	assert.NoError(t, os.WriteFile(file, []byte(`{"masked": [{"pattern": "*", "strategy": "hash"}]}`), 0o600))
+
+	profiles, err := Load(file)
+	assert.NoError(t, err)
+	value, _ := profiles.Redact(ProfileMasked, "email", "secret")
+	assert.Equal(t, "sha256:2bb80d537b1da3e3", value, "Configured profiles replace the built-in rules")
+
+	assert.NoError(t, os.WriteFile(file, []byte(`[]`), 0o600))
+	_, err = Load(file)
+	assert.Error(t, err)
+}
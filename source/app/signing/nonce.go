@@ -0,0 +1,123 @@
+package signing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	db "person-service/internal/db/generated"
+	"person-service/logging"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// memoryPruneInterval is the minimum time between prunes of the in-memory store
+	memoryPruneInterval = time.Minute
+
+	// sweepBatchSize bounds the number of nonce rows deleted per statement
+	sweepBatchSize = 500
+)
+
+// MemoryNonceStore keeps nonces in process memory. Replays are only detected
+// by the instance that saw the first request, so it suits single-instance deployments.
+type MemoryNonceStore struct {
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	lastPrune time.Time
+}
+
+// NewMemoryNonceStore creates a new instance of MemoryNonceStore
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{
+		nonces:    make(map[string]time.Time),
+		lastPrune: time.Now(),
+	}
+}
+
+// Use records the nonce and reports false when it was already used and has not expired
+func (s *MemoryNonceStore) Use(ctx context.Context, keyID, nonce string, expiresAt time.Time) (bool, error) {
+	now := time.Now()
+	id := keyID + "\x00" + nonce
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastPrune) >= memoryPruneInterval {
+		for stored, expiry := range s.nonces {
+			if !expiry.After(now) {
+				delete(s.nonces, stored)
+			}
+		}
+		s.lastPrune = now
+	}
+
+	if expiry, ok := s.nonces[id]; ok && expiry.After(now) {
+		return false, nil
+	}
+	s.nonces[id] = expiresAt
+	return true, nil
+}
+
+// PostgresNonceStore keeps nonces in the request_nonce table so replays are
+// detected across all instances
+type PostgresNonceStore struct {
+	queries *db.Queries
+}
+
+// NewPostgresNonceStore creates a new instance of PostgresNonceStore with injected queries
+func NewPostgresNonceStore(queries *db.Queries) *PostgresNonceStore {
+	return &PostgresNonceStore{
+		queries: queries,
+	}
+}
+
+// Use records the nonce and reports false when it was already used and has not expired
+func (s *PostgresNonceStore) Use(ctx context.Context, keyID, nonce string, expiresAt time.Time) (bool, error) {
+	recorded, err := s.queries.UseRequestNonce(ctx, db.UseRequestNonceParams{
+		KeyID:     keyID,
+		Nonce:     nonce,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		return false, err
+	}
+	return recorded == 1, nil
+}
+
+// Run sweeps expired nonces every interval until the context is cancelled
+func (s *PostgresNonceStore) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Sweep(ctx); err != nil && ctx.Err() == nil {
+				logging.Warn("Request nonce sweep failed", "error", err)
+			}
+		}
+	}
+}
+
+// Sweep deletes all expired nonces in batches and returns the number removed
+func (s *PostgresNonceStore) Sweep(ctx context.Context) (int64, error) {
+	var total int64
+	for {
+		deleted, err := s.queries.DeleteExpiredRequestNonces(ctx, sweepBatchSize)
+		if err != nil {
+			return total, err
+		}
+		total += deleted
+		if deleted < sweepBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		logging.Debug("Swept expired request nonces", "count", total)
+	}
+	return total, nil
+}
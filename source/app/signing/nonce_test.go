@@ -0,0 +1,74 @@
+package signing
+
+import (
+	"context"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+
+	db "person-service/internal/db/generated"
+	"person-service/internal/testdb"
+)
+
+var pool *pgxpool.Pool
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+	var err error
+	pool, err = testdb.GetPool(ctx)
+	if err != nil {
+		log.Fatalf("Failed to get pool: %v", err)
+	}
+	if err := testdb.RunMigrations(ctx, pool); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestPostgresNonceStore_Use(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	store := NewPostgresNonceStore(db.New(pool))
+
+	fresh, err := store.Use(ctx, "billing", "nonce-1", time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, fresh)
+
+	fresh, err = store.Use(ctx, "billing", "nonce-1", time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, fresh, "A recorded nonce must be rejected until it expires")
+
+	fresh, err = store.Use(ctx, "reports", "nonce-1", time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, fresh, "Nonces are scoped to the signing key")
+}
+
+func TestPostgresNonceStore_ExpiredNonce(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	store := NewPostgresNonceStore(db.New(pool))
+
+	fresh, err := store.Use(ctx, "billing", "old", time.Now().Add(-time.Second))
+	assert.NoError(t, err)
+	assert.True(t, fresh)
+
+	// An expired nonce can be recorded again
+	fresh, err = store.Use(ctx, "billing", "old", time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, fresh)
+
+	_, err = store.Use(ctx, "billing", "stale", time.Now().Add(-time.Second))
+	assert.NoError(t, err)
+
+	deleted, err := store.Sweep(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
@@ -0,0 +1,202 @@
+package signing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"person-service/auth"
+)
+
+// Headers carrying the request signature
+const (
+	HeaderKeyID     = "X-Signature-Key-Id"
+	HeaderTimestamp = "X-Signature-Timestamp"
+	HeaderNonce     = "X-Signature-Nonce"
+	HeaderSignature = "X-Signature"
+)
+
+const (
+	// DefaultWindow is how far a signature timestamp may be from the server clock
+	DefaultWindow = 5 * time.Minute
+
+	// Nonces must carry enough randomness to be unique per key
+	minNonceLength = 16
+	maxNonceLength = 128
+)
+
+var (
+	// ErrMissingHeaders is returned when any signature header is absent or malformed
+	ErrMissingHeaders = errors.New("missing or malformed signature headers")
+	// ErrInvalidSignature is returned for an unknown key id or a signature mismatch
+	ErrInvalidSignature = errors.New("invalid request signature")
+	// ErrTimestampOutOfWindow is returned when the signature timestamp is too old or too far ahead
+	ErrTimestampOutOfWindow = errors.New("signature timestamp outside the allowed window")
+	// ErrNonceReused is returned when the nonce was already used with the same key
+	ErrNonceReused = errors.New("signature nonce already used")
+	// ErrPrincipalMismatch is returned when the signing key is bound to another principal
+	ErrPrincipalMismatch = errors.New("signing key does not belong to the authenticated principal")
+)
+
+// NonceStore remembers nonces until they expire
+type NonceStore interface {
+	// Use records the nonce and reports false when it was already used and has not expired
+	Use(ctx context.Context, keyID, nonce string, expiresAt time.Time) (bool, error)
+}
+
+// Binding names the principal allowed to sign with a key. Principal IDs are
+// only unique within a source, so both must match.
+type Binding struct {
+	Source      string
+	PrincipalID string
+}
+
+// Verifier checks HMAC-SHA256 request signatures made with shared secrets
+type Verifier struct {
+	secrets    map[string][]byte
+	principals map[string]Binding
+	window     time.Duration
+	nonces     NonceStore
+	now        func() time.Time
+}
+
+// NewVerifier creates a new instance of Verifier for the given key id to
+// secret map and key id to principal bindings
+func NewVerifier(secrets map[string][]byte, principals map[string]Binding, window time.Duration, nonces NonceStore) *Verifier {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Verifier{
+		secrets:    secrets,
+		principals: principals,
+		window:     window,
+		nonces:     nonces,
+		now:        time.Now,
+	}
+}
+
+// ParseSecrets parses a comma-separated list of key_id:secret pairs
+func ParseSecrets(raw string) (map[string][]byte, error) {
+	secrets := make(map[string][]byte)
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		keyID, secret, ok := strings.Cut(pair, ":")
+		if !ok || keyID == "" || len(secret) < 32 {
+			return nil, fmt.Errorf("invalid signing key %q: expected key_id:secret with a secret of at least 32 characters", keyID)
+		}
+		secrets[keyID] = []byte(secret)
+	}
+	if len(secrets) == 0 {
+		return nil, errors.New("no signing keys configured")
+	}
+	return secrets, nil
+}
+
+// ParsePrincipals parses a comma-separated list of key_id:source:principal_id
+// bindings and checks that every key in secrets is bound to a principal. The
+// principal id may itself contain colons, as SPIFFE IDs do.
+func ParsePrincipals(raw string, secrets map[string][]byte) (map[string]Binding, error) {
+	principals := make(map[string]Binding)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		keyID, principal, _ := strings.Cut(entry, ":")
+		source, principalID, _ := strings.Cut(principal, ":")
+		if keyID == "" || !auth.IsValidSource(source) || principalID == "" {
+			return nil, fmt.Errorf("invalid signing key binding %q: expected key_id:source:principal_id", entry)
+		}
+		principals[keyID] = Binding{Source: source, PrincipalID: principalID}
+	}
+	for keyID := range secrets {
+		if _, ok := principals[keyID]; !ok {
+			return nil, fmt.Errorf("signing key %q is not bound to a principal", keyID)
+		}
+	}
+	return principals, nil
+}
+
+// StringToSign builds the canonical string covered by the signature:
+// method, request URI, timestamp, nonce and the hex SHA-256 of the body, one per line
+func StringToSign(method, requestURI, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		requestURI,
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+}
+
+// Sign returns the hex HMAC-SHA256 signature of a request, as clients compute it
+func Sign(secret []byte, method, requestURI, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(StringToSign(method, requestURI, timestamp, nonce, body)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature headers of r against its body, checks that the
+// signing key is bound to principal and records the nonce. It returns the
+// signing key id. Errors other than the Err* values above come from the nonce
+// store.
+func (v *Verifier) Verify(ctx context.Context, r *http.Request, body []byte, principal auth.Principal) (string, error) {
+	keyID := r.Header.Get(HeaderKeyID)
+	timestamp := r.Header.Get(HeaderTimestamp)
+	nonce := r.Header.Get(HeaderNonce)
+	signature := r.Header.Get(HeaderSignature)
+	if keyID == "" || timestamp == "" || signature == "" ||
+		len(nonce) < minNonceLength || len(nonce) > maxNonceLength {
+		return "", ErrMissingHeaders
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", ErrMissingHeaders
+	}
+	signedAt := time.Unix(seconds, 0)
+
+	secret, ok := v.secrets[keyID]
+	if !ok {
+		return "", ErrInvalidSignature
+	}
+
+	expected := Sign(secret, r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return "", ErrInvalidSignature
+	}
+
+	// A valid signature only vouches for the request when the key belongs to its caller
+	owner, ok := v.principals[keyID]
+	if !ok || owner.Source != principal.Source || owner.PrincipalID != principal.ID {
+		return "", ErrPrincipalMismatch
+	}
+
+	// Only authentic requests are checked for freshness so forged requests cannot burn nonces
+	now := v.now()
+	if signedAt.Before(now.Add(-v.window)) || signedAt.After(now.Add(v.window)) {
+		return "", ErrTimestampOutOfWindow
+	}
+
+	// A nonce only needs remembering while its timestamp is inside the window
+	fresh, err := v.nonces.Use(ctx, keyID, nonce, signedAt.Add(v.window))
+	if err != nil {
+		return "", fmt.Errorf("failed to record signature nonce: %w", err)
+	}
+	if !fresh {
+		return "", ErrNonceReused
+	}
+
+	return keyID, nil
+}
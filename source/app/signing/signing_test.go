@@ -0,0 +1,186 @@
+package signing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"person-service/auth"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// testPrincipal is the principal the billing key is bound to
+var testPrincipal = auth.Principal{ID: "billing-service", Source: auth.SourceJWT}
+
+// signedRequest builds a request signed with testSecret at the given time
+func signedRequest(method, target, body, nonce string, at time.Time) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	req.Header.Set(HeaderKeyID, "billing")
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, Sign([]byte(testSecret), method, req.URL.RequestURI(), timestamp, nonce, []byte(body)))
+	return req
+}
+
+func newTestVerifier() *Verifier {
+	return NewVerifier(map[string][]byte{"billing": []byte(testSecret)}, map[string]Binding{"billing": {Source: auth.SourceJWT, PrincipalID: "billing-service"}},
+		time.Minute, NewMemoryNonceStore())
+}
+
+func TestVerify_ValidSignature(t *testing.T) {
+	verifier := newTestVerifier()
+	body := `{"key":"a","value":"b"}`
+	req := signedRequest(http.MethodPost, "/api/key-value?x=1", body, "nonce-0000000001", time.Now())
+
+	keyID, err := verifier.Verify(context.Background(), req, []byte(body), testPrincipal)
+	assert.NoError(t, err)
+	assert.Equal(t, "billing", keyID)
+}
+
+func TestVerify_RejectsTampering(t *testing.T) {
+	verifier := newTestVerifier()
+	body := `{"key":"a","value":"b"}`
+
+	tests := []struct {
+		name   string
+		mutate func(req *http.Request) []byte
+	}{
+		{"different body", func(req *http.Request) []byte { return []byte(`{"key":"a","value":"c"}`) }},
+		{"different method", func(req *http.Request) []byte { req.Method = http.MethodDelete; return []byte(body) }},
+		{"different query", func(req *http.Request) []byte { req.URL.RawQuery = "x=2"; return []byte(body) }},
+		{"different timestamp", func(req *http.Request) []byte {
+			req.Header.Set(HeaderTimestamp, strconv.FormatInt(time.Now().Unix()+1, 10))
+			return []byte(body)
+		}},
+		{"unknown key", func(req *http.Request) []byte { req.Header.Set(HeaderKeyID, "other"); return []byte(body) }},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonce := "tamper-nonce-000" + strconv.Itoa(i)
+			req := signedRequest(http.MethodPost, "/api/key-value?x=1", body, nonce, time.Now())
+			_, err := verifier.Verify(context.Background(), req, tt.mutate(req), testPrincipal)
+			assert.ErrorIs(t, err, ErrInvalidSignature)
+		})
+	}
+}
+
+func TestVerify_TimestampWindow(t *testing.T) {
+	verifier := newTestVerifier()
+
+	for _, offset := range []time.Duration{-2 * time.Minute, 2 * time.Minute} {
+		req := signedRequest(http.MethodGet, "/api/person/1", "", "window-nonce-"+offset.String(), time.Now().Add(offset))
+		_, err := verifier.Verify(context.Background(), req, nil, testPrincipal)
+		assert.ErrorIs(t, err, ErrTimestampOutOfWindow)
+	}
+}
+
+func TestVerify_NonceReuse(t *testing.T) {
+	verifier := newTestVerifier()
+	req := signedRequest(http.MethodGet, "/api/person/1", "", "replayed-nonce-01", time.Now())
+
+	_, err := verifier.Verify(context.Background(), req, nil, testPrincipal)
+	assert.NoError(t, err)
+
+	_, err = verifier.Verify(context.Background(), req, nil, testPrincipal)
+	assert.ErrorIs(t, err, ErrNonceReused)
+}
+
+func TestVerify_PrincipalMismatch(t *testing.T) {
+	verifier := newTestVerifier()
+	req := signedRequest(http.MethodGet, "/api/person/1", "", "principal-nonce-1", time.Now())
+
+	// Another principal holding the secret cannot sign as the key owner
+	_, err := verifier.Verify(context.Background(), req, nil, auth.Principal{ID: "reports-service", Source: auth.SourceJWT})
+	assert.ErrorIs(t, err, ErrPrincipalMismatch)
+
+	// Nor can a principal of another source that happens to share the id
+	_, err = verifier.Verify(context.Background(), req, nil, auth.Principal{ID: "billing-service", Source: auth.SourceCertificate})
+	assert.ErrorIs(t, err, ErrPrincipalMismatch)
+
+	// The rejected request did not use up the nonce
+	_, err = verifier.Verify(context.Background(), req, nil, testPrincipal)
+	assert.NoError(t, err)
+}
+
+func TestVerify_MissingHeaders(t *testing.T) {
+	verifier := newTestVerifier()
+
+	for _, header := range []string{HeaderKeyID, HeaderTimestamp, HeaderNonce, HeaderSignature} {
+		t.Run(header, func(t *testing.T) {
+			req := signedRequest(http.MethodGet, "/", "", "missing-nonce-001", time.Now())
+			req.Header.Del(header)
+			_, err := verifier.Verify(context.Background(), req, nil, testPrincipal)
+			assert.ErrorIs(t, err, ErrMissingHeaders)
+		})
+	}
+
+	// Nonces that are too short cannot be unique
+	req := signedRequest(http.MethodGet, "/", "", "short", time.Now())
+	_, err := verifier.Verify(context.Background(), req, nil, testPrincipal)
+	assert.ErrorIs(t, err, ErrMissingHeaders)
+}
+
+func TestParseSecrets(t *testing.T) {
+	secrets, err := ParseSecrets("billing:" + testSecret + ", reports:" + testSecret + "xyz")
+	assert.NoError(t, err)
+	assert.Len(t, secrets, 2)
+	assert.Equal(t, []byte(testSecret+"xyz"), secrets["reports"])
+
+	for _, raw := range []string{"", "billing", "billing:short", ":" + testSecret} {
+		_, err := ParseSecrets(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestParsePrincipals(t *testing.T) {
+	secrets := map[string][]byte{"billing": []byte(testSecret), "reports": []byte(testSecret)}
+
+	principals, err := ParsePrincipals("billing:jwt:billing-service, reports:certificate:spiffe://cluster.local/ns/reports", secrets)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Binding{
+		"billing": {Source: auth.SourceJWT, PrincipalID: "billing-service"},
+		"reports": {Source: auth.SourceCertificate, PrincipalID: "spiffe://cluster.local/ns/reports"},
+	}, principals)
+
+	for _, raw := range []string{
+		"",
+		"billing:jwt:billing-service",
+		"billing:billing-service,reports:jwt:reports-service",
+		"billing:ldap:billing-service,reports:jwt:reports-service",
+		"billing:jwt:,reports:jwt:reports-service",
+		":jwt:x",
+	} {
+		_, err := ParsePrincipals(raw, secrets)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestMemoryNonceStore_ExpiredNonceCanBeReused(t *testing.T) {
+	store := NewMemoryNonceStore()
+	ctx := context.Background()
+
+	fresh, err := store.Use(ctx, "billing", "n", time.Now().Add(-time.Second))
+	assert.NoError(t, err)
+	assert.True(t, fresh)
+
+	fresh, err = store.Use(ctx, "billing", "n", time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, fresh, "An expired nonce no longer blocks reuse")
+
+	fresh, err = store.Use(ctx, "billing", "n", time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, fresh)
+
+	fresh, err = store.Use(ctx, "reports", "n", time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, fresh, "Nonces are scoped to the signing key")
+}
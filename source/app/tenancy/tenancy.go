@@ -0,0 +1,142 @@
+package tenancy
+
+import (
+	"context"
+
+	"person-service/auth"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// setTenantSQL scopes app.tenant_id to the transaction it runs in, so the
+// setting never leaks to the next user of a pooled connection
+const setTenantSQL = "SELECT set_config('app.tenant_id', $1, true)"
+
+// DB runs every statement with app.tenant_id set to the tenant of the request,
+// so the row-level security policies hide and reject rows of other tenants in
+// addition to the tenant filters of the queries. It satisfies the DBTX
+// interface of the generated queries.
+//
+// Statements are sent in a batch behind the setting, which Postgres runs as a
+// single implicit transaction without an extra round trip.
+type DB struct {
+	pool *pgxpool.Pool
+}
+
+// NewDB creates a new instance of DB backed by pool
+func NewDB(pool *pgxpool.Pool) *DB {
+	return &DB{
+		pool: pool,
+	}
+}
+
+// send queues the tenant setting of ctx followed by the statement queued by queue
+func (d *DB) send(ctx context.Context, queue func(batch *pgx.Batch)) pgx.BatchResults {
+	batch := &pgx.Batch{}
+	batch.Queue(setTenantSQL, auth.TenantFromContext(ctx))
+	queue(batch)
+	return d.pool.SendBatch(ctx, batch)
+}
+
+// Exec runs sql with the tenant of ctx set
+func (d *DB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	results := d.send(ctx, func(batch *pgx.Batch) { batch.Queue(sql, args...) })
+	defer results.Close()
+
+	if _, err := results.Exec(); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return results.Exec()
+}
+
+// Query runs sql with the tenant of ctx set. The connection is released when
+// the returned rows are closed.
+func (d *DB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	results := d.send(ctx, func(batch *pgx.Batch) { batch.Queue(sql, args...) })
+
+	if _, err := results.Exec(); err != nil {
+		results.Close()
+		return nil, err
+	}
+	rows, err := results.Query()
+	if err != nil {
+		results.Close()
+		return nil, err
+	}
+	return &batchRows{Rows: rows, results: results}, nil
+}
+
+// QueryRow runs sql with the tenant of ctx set. The connection is released
+// when the row is scanned.
+func (d *DB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	rows, err := d.Query(ctx, sql, args...)
+	if err != nil {
+		return errRow{err: err}
+	}
+	return batchRow{rows: rows}
+}
+
+// CopyFrom copies rows into tableName inside a transaction with the tenant of ctx set
+func (d *DB) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, setTenantSQL, auth.TenantFromContext(ctx)); err != nil {
+		return 0, err
+	}
+	copied, err := tx.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	if err != nil {
+		return 0, err
+	}
+	return copied, tx.Commit(ctx)
+}
+
+// batchRows closes the batch, releasing its connection, along with the rows
+type batchRows struct {
+	pgx.Rows
+	results pgx.BatchResults
+}
+
+// Close closes the rows and the batch they were read from
+func (r *batchRows) Close() {
+	r.Rows.Close()
+	r.results.Close()
+}
+
+// batchRow reads the first row of a batch query, matching pgx.Conn.QueryRow
+type batchRow struct {
+	rows pgx.Rows
+}
+
+// Scan reads the first row into dest and releases the connection. It returns
+// pgx.ErrNoRows when the query returned no rows.
+func (r batchRow) Scan(dest ...interface{}) error {
+	defer r.rows.Close()
+
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+	r.rows.Close()
+	return r.rows.Err()
+}
+
+// errRow is a row whose query failed
+type errRow struct {
+	err error
+}
+
+// Scan returns the error of the failed query
+func (r errRow) Scan(dest ...interface{}) error {
+	return r.err
+}
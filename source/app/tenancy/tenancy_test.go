@@ -0,0 +1,157 @@
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+
+	"person-service/auth"
+	db "person-service/internal/db/generated"
+	"person-service/internal/testdb"
+)
+
+var pool *pgxpool.Pool
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+	var err error
+	pool, err = testdb.GetPool(ctx)
+	if err != nil {
+		log.Fatalf("Failed to get pool: %v", err)
+	}
+	if err := testdb.RunMigrations(ctx, pool); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+	os.Exit(m.Run())
+}
+
+// tenantContext returns a context authenticated as a principal of tenant
+func tenantContext(tenant string) context.Context {
+	return auth.ContextWithPrincipal(context.Background(), auth.Principal{ID: tenant, Tenant: tenant})
+}
+
+func TestDB_ScopesPersonsToTenant(t *testing.T) {
+	err := testdb.TruncateTables(context.Background(), pool)
+	assert.NoError(t, err)
+
+	queries := db.New(NewDB(pool))
+	acme := tenantContext("acme")
+	globex := tenantContext("globex")
+
+	created, err := queries.CreatePerson(acme, db.CreatePersonParams{TenantID: "acme", ClientID: "client-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", created.TenantID)
+
+	// Client ids are unique per tenant only
+	_, err = queries.CreatePerson(globex, db.CreatePersonParams{TenantID: "globex", ClientID: "client-1"})
+	assert.NoError(t, err)
+	_, err = queries.CreatePerson(acme, db.CreatePersonParams{TenantID: "acme", ClientID: "client-1"})
+	assert.Error(t, err)
+
+	found, err := queries.GetPersonById(acme, db.GetPersonByIdParams{TenantID: "acme", ID: created.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, "client-1", found.ClientID)
+
+	_, err = queries.GetPersonById(globex, db.GetPersonByIdParams{TenantID: "globex", ID: created.ID})
+	assert.True(t, errors.Is(err, pgx.ErrNoRows), "Persons of other tenants must not be found")
+
+	// The tenant setting is local to each statement's transaction
+	var setting string
+	err = pool.QueryRow(context.Background(), "SELECT COALESCE(current_setting('app.tenant_id', true), '')").Scan(&setting)
+	assert.NoError(t, err)
+	assert.Empty(t, setting)
+}
+
+func TestDB_ScopesRequestLogToTenant(t *testing.T) {
+	err := testdb.TruncateTables(context.Background(), pool)
+	assert.NoError(t, err)
+
+	queries := db.New(NewDB(pool))
+	acme := tenantContext("acme")
+	globex := tenantContext("globex")
+
+	entry := func(tenant string) db.InsertRequestLogParams {
+		return db.InsertRequestLogParams{
+			TenantID:              tenant,
+			TraceID:               "trace-1",
+			CallerInfo:            tenant,
+			Reason:                "audit",
+			EncryptedRequestBody:  "{}",
+			EncryptedResponseBody: "",
+			EncKey:                "tenancy-test-key",
+			KeyVersion:            1,
+		}
+	}
+
+	// Trace ids are unique per tenant only
+	_, err = queries.InsertRequestLog(acme, entry("acme"))
+	assert.NoError(t, err)
+	_, err = queries.InsertRequestLog(globex, entry("globex"))
+	assert.NoError(t, err)
+	_, err = queries.InsertRequestLog(acme, entry("acme"))
+	assert.Error(t, err)
+
+	found, err := queries.GetRequestLogByTraceId(acme, db.GetRequestLogByTraceIdParams{TenantID: "acme", TraceID: "trace-1", EncKey: "tenancy-test-key"})
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", found.CallerInfo)
+
+	exists, err := queries.CheckTraceIdExists(tenantContext("initech"), db.CheckTraceIdExistsParams{TenantID: "initech", TraceID: "trace-1"})
+	assert.NoError(t, err)
+	assert.False(t, exists, "Audit entries of other tenants must not be found")
+}
+
+func TestRowLevelSecurity_HidesOtherTenants(t *testing.T) {
+	ctx := context.Background()
+	err := testdb.TruncateTables(ctx, pool)
+	assert.NoError(t, err)
+
+	_, err = pool.Exec(ctx, `INSERT INTO person (tenant_id, client_id) VALUES ('acme', 'a-1'), ('acme', 'a-2'), ('globex', 'g-1')`)
+	assert.NoError(t, err)
+
+	// Superusers bypass row-level security, so the policies are checked as an unprivileged role
+	_, err = pool.Exec(ctx, `
+		DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'tenancy_test') THEN
+				CREATE ROLE tenancy_test NOLOGIN;
+			END IF;
+		END $$;
+		GRANT SELECT ON person TO tenancy_test;
+	`)
+	assert.NoError(t, err)
+
+	countAs := func(tenant string) (int, error) {
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			return 0, err
+		}
+		defer tx.Rollback(ctx)
+
+		if _, err := tx.Exec(ctx, "SET LOCAL ROLE tenancy_test"); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(ctx, setTenantSQL, tenant); err != nil {
+			return 0, err
+		}
+		var count int
+		err = tx.QueryRow(ctx, "SELECT count(*) FROM person").Scan(&count)
+		return count, err
+	}
+
+	count, err := countAs("acme")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = countAs("globex")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = countAs("")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count, "Without a tenant setting every row stays visible")
+}
@@ -2,7 +2,11 @@ package tracing
 
 import (
 	"context"
+	"encoding/binary"
+	"strconv"
 	"strings"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // contextKey is a custom type for context keys to avoid collisions
@@ -30,6 +34,41 @@ func ParseGCPTraceHeader(header string) string {
 	return traceID
 }
 
+// ParseGCPSpanContext parses the X-Cloud-Trace-Context header into a remote
+// span context, so spans continue the load balancer's trace. The span ID is
+// decimal in the header. It returns an invalid span context when the header
+// does not carry a 32 hex digit trace ID and a non-zero span ID.
+func ParseGCPSpanContext(header string) trace.SpanContext {
+	traceIDPart, rest, found := strings.Cut(header, "/")
+	if !found {
+		return trace.SpanContext{}
+	}
+	traceID, err := trace.TraceIDFromHex(strings.ToLower(traceIDPart))
+	if err != nil {
+		return trace.SpanContext{}
+	}
+
+	spanIDPart, options, _ := strings.Cut(rest, ";")
+	spanIDValue, err := strconv.ParseUint(spanIDPart, 10, 64)
+	if err != nil || spanIDValue == 0 {
+		return trace.SpanContext{}
+	}
+	var spanID trace.SpanID
+	binary.BigEndian.PutUint64(spanID[:], spanIDValue)
+
+	var flags trace.TraceFlags
+	if options == "o=1" {
+		flags = trace.FlagsSampled
+	}
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: flags,
+		Remote:     true,
+	})
+}
+
 // ContextWithTraceID creates a new context with the trace ID stored.
 func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
 	return context.WithValue(ctx, TraceIDKey, traceID)
@@ -49,3 +88,18 @@ func TraceIDFromContext(ctx context.Context) string {
 
 	return traceID
 }
+
+// SpanIDFromContext returns the hex ID of the active span in the context.
+// Returns empty string if there is no valid span.
+func SpanIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return ""
+	}
+
+	return spanContext.SpanID().String()
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestParseGCPTraceHeader_Empty(t *testing.T) {
@@ -53,3 +54,42 @@ func TestTraceIDFromContext_WrongType(t *testing.T) {
 	result := TraceIDFromContext(ctx)
 	assert.Equal(t, "", result)
 }
+
+func TestParseGCPSpanContext_ValidHeader(t *testing.T) {
+	spanContext := ParseGCPSpanContext("105445AA7843BC8BF206B12000100000/1;o=1")
+
+	assert.True(t, spanContext.IsValid())
+	assert.True(t, spanContext.IsRemote())
+	assert.True(t, spanContext.IsSampled())
+	assert.Equal(t, "105445aa7843bc8bf206b12000100000", spanContext.TraceID().String())
+	assert.Equal(t, "0000000000000001", spanContext.SpanID().String())
+}
+
+func TestParseGCPSpanContext_NotSampled(t *testing.T) {
+	spanContext := ParseGCPSpanContext("105445aa7843bc8bf206b12000100000/255;o=0")
+
+	assert.True(t, spanContext.IsValid())
+	assert.False(t, spanContext.IsSampled())
+	assert.Equal(t, "00000000000000ff", spanContext.SpanID().String())
+}
+
+func TestParseGCPSpanContext_Invalid(t *testing.T) {
+	for _, header := range []string{
+		"",
+		"105445aa7843bc8bf206b12000100000",
+		"not-hex/1;o=1",
+		"105445aa7843bc8bf206b12000100000/abc;o=1",
+		"105445aa7843bc8bf206b12000100000/0;o=1",
+	} {
+		assert.False(t, ParseGCPSpanContext(header).IsValid(), header)
+	}
+}
+
+func TestSpanIDFromContext(t *testing.T) {
+	assert.Equal(t, "", SpanIDFromContext(nil))
+	assert.Equal(t, "", SpanIDFromContext(context.Background()))
+
+	spanContext := ParseGCPSpanContext("105445aa7843bc8bf206b12000100000/1;o=1")
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), spanContext)
+	assert.Equal(t, "0000000000000001", SpanIDFromContext(ctx))
+}
@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName is the default service.name of exported spans
+const ServiceName = "person-service"
+
+// Tracer returns the tracer for spans of the service. It uses the global
+// tracer provider, so spans are not exported until Setup installs one.
+func Tracer() trace.Tracer {
+	return otel.Tracer(ServiceName)
+}
+
+// Setup installs a tracer provider that exports spans over OTLP/HTTP when
+// OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set.
+// The exporter reads the other OTEL_EXPORTER_OTLP_* variables, the sampler
+// OTEL_TRACES_SAMPLER and the resource OTEL_SERVICE_NAME and
+// OTEL_RESOURCE_ATTRIBUTES. It returns a function that flushes pending spans
+// and stops the provider; without an endpoint export stays disabled. The W3C
+// trace context becomes the global propagator either way.
+func Setup(ctx context.Context, version string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(Propagator())
+
+	shutdown := func(context.Context) error { return nil }
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return shutdown, nil
+	}
+	if os.Getenv("OTEL_SDK_DISABLED") == "true" {
+		return shutdown, nil
+	}
+
+	protocol := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+	if protocol == "" {
+		protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+	if protocol != "" && protocol != "http/protobuf" {
+		return nil, fmt.Errorf("unsupported OTLP protocol %q: only http/protobuf is supported", protocol)
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	// Later detectors win, so the environment can override the service name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(ServiceName),
+			semconv.ServiceVersion(version),
+		),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
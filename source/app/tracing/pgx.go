@@ -0,0 +1,130 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// sqlcNamePrefix starts the comment sqlc puts in front of generated queries
+const sqlcNamePrefix = "-- name: "
+
+// dbSpanKey is the context key for the span started for a statement
+const dbSpanKey contextKey = "db-span"
+
+// QueryTracer creates a client span for every statement run on a pgx
+// connection, named after the sqlc query. Statements are only traced within
+// an existing trace, such as a request, so background workers do not create
+// root spans. Arguments are never recorded since they carry attribute values
+// and encryption keys.
+type QueryTracer struct{}
+
+// NewQueryTracer creates a new instance of QueryTracer
+func NewQueryTracer() *QueryTracer {
+	return &QueryTracer{}
+}
+
+// QueryName returns the sqlc query name of sql, or its first keyword for
+// statements not generated by sqlc
+func QueryName(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if rest, ok := strings.CutPrefix(sql, sqlcNamePrefix); ok {
+		if fields := strings.Fields(rest); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "query"
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// startSpan starts a database span when ctx belongs to a trace
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) context.Context {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+
+	attrs = append(attrs, semconv.DBSystemNamePostgreSQL)
+	ctx, span := Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+	return context.WithValue(ctx, dbSpanKey, span)
+}
+
+// endSpan ends the database span started by startSpan, recording err. The
+// span of the caller is left alone when none was started.
+func endSpan(ctx context.Context, err error) {
+	span, ok := ctx.Value(dbSpanKey).(trace.Span)
+	if !ok {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceQueryStart implements pgx.QueryTracer
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	name := QueryName(data.SQL)
+	return startSpan(ctx, name,
+		semconv.DBOperationName(name),
+		semconv.DBQueryText(data.SQL))
+}
+
+// TraceQueryEnd implements pgx.QueryTracer
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	endSpan(ctx, data.Err)
+}
+
+// TraceBatchStart implements pgx.BatchTracer. The span is named after the
+// first sqlc query of the batch, since batches wrap a single query with the
+// tenant setting when row-level security is enabled.
+func (t *QueryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	name := "batch"
+	if data.Batch != nil {
+		for _, query := range data.Batch.QueuedQueries {
+			if strings.HasPrefix(strings.TrimSpace(query.SQL), sqlcNamePrefix) {
+				name = QueryName(query.SQL)
+				break
+			}
+		}
+	}
+	return startSpan(ctx, name, semconv.DBOperationName(name))
+}
+
+// TraceBatchQuery implements pgx.BatchTracer
+func (t *QueryTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	span, ok := ctx.Value(dbSpanKey).(trace.Span)
+	if !ok {
+		return
+	}
+	span.AddEvent(QueryName(data.SQL), trace.WithAttributes(semconv.DBQueryText(data.SQL)))
+	if data.Err != nil {
+		span.RecordError(data.Err)
+	}
+}
+
+// TraceBatchEnd implements pgx.BatchTracer
+func (t *QueryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	endSpan(ctx, data.Err)
+}
+
+// TraceCopyFromStart implements pgx.CopyFromTracer
+func (t *QueryTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	return startSpan(ctx, "COPY "+data.TableName.Sanitize(), semconv.DBOperationName("COPY"))
+}
+
+// TraceCopyFromEnd implements pgx.CopyFromTracer
+func (t *QueryTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	endSpan(ctx, data.Err)
+}
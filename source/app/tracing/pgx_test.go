@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider that keeps ended spans for the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestQueryName(t *testing.T) {
+	assert.Equal(t, "GetKeyValue", QueryName("-- name: GetKeyValue :one\nSELECT key FROM key_value"))
+	assert.Equal(t, "SELECT", QueryName("  select set_config('app.tenant_id', $1, true)"))
+	assert.Equal(t, "query", QueryName(""))
+}
+
+func TestQueryTracer_ChildSpan(t *testing.T) {
+	recorder := recordSpans(t)
+	tracer := NewQueryTracer()
+
+	ctx, parent := Tracer().Start(context.Background(), "GET /persons/:personId/attributes")
+	sql := "-- name: GetAllPersonAttributes :many\nSELECT id FROM person_attributes WHERE person_id = $1"
+	queryCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: sql, Args: []any{"secret-key"}})
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{Err: errors.New("boom")})
+	parent.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	query := spans[0]
+	assert.Equal(t, "GetAllPersonAttributes", query.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), query.Parent().SpanID())
+	assert.Equal(t, codes.Error, query.Status().Code)
+
+	// Arguments are never recorded
+	for _, attr := range query.Attributes() {
+		assert.NotContains(t, attr.Value.Emit(), "secret-key")
+	}
+}
+
+func TestQueryTracer_WithoutTrace(t *testing.T) {
+	recorder := recordSpans(t)
+	tracer := NewQueryTracer()
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "-- name: DeleteExpiredKeyValues :execrows\nDELETE FROM key_value"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	assert.Empty(t, recorder.Ended())
+}
+
+func TestQueryTracer_Batch(t *testing.T) {
+	recorder := recordSpans(t)
+	tracer := NewQueryTracer()
+
+	batch := &pgx.Batch{}
+	batch.Queue("SELECT set_config('app.tenant_id', $1, true)", "tenant-a")
+	batch.Queue("-- name: GetPersonById :one\nSELECT id FROM person WHERE id = $1", "id")
+
+	ctx, parent := Tracer().Start(context.Background(), "GET /api/person/:id")
+	batchCtx := tracer.TraceBatchStart(ctx, nil, pgx.TraceBatchStartData{Batch: batch})
+	tracer.TraceBatchQuery(batchCtx, nil, pgx.TraceBatchQueryData{SQL: batch.QueuedQueries[0].SQL})
+	tracer.TraceBatchQuery(batchCtx, nil, pgx.TraceBatchQueryData{SQL: batch.QueuedQueries[1].SQL})
+	tracer.TraceBatchEnd(batchCtx, nil, pgx.TraceBatchEndData{})
+	parent.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "GetPersonById", spans[0].Name())
+	assert.Len(t, spans[0].Events(), 2)
+}
@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace context headers
+const (
+	// TraceparentHeader carries the W3C trace context
+	TraceparentHeader = "traceparent"
+
+	// TracestateHeader carries vendor data of the W3C trace context
+	TracestateHeader = "tracestate"
+
+	// GCPTraceHeader is the header name for GCP Cloud Trace context
+	GCPTraceHeader = "X-Cloud-Trace-Context"
+)
+
+var (
+	// w3cPropagator reads and writes traceparent and tracestate
+	w3cPropagator = propagation.TraceContext{}
+
+	// b3Propagator reads the single b3 header and the X-B3-* headers
+	b3Propagator = b3.New()
+)
+
+// Propagator returns the propagator for outgoing requests, which writes the
+// W3C trace context
+func Propagator() propagation.TextMapPropagator {
+	return w3cPropagator
+}
+
+// Extract returns ctx with the remote span context carried by header. W3C
+// traceparent is preferred when present, then B3, then X-Cloud-Trace-Context.
+// Headers with a malformed or all-zero trace ID are ignored, and ctx is
+// returned unchanged when none carries a valid trace.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	carrier := propagation.HeaderCarrier(header)
+	if header.Get(TraceparentHeader) != "" {
+		if extracted := w3cPropagator.Extract(ctx, carrier); trace.SpanContextFromContext(extracted).IsValid() {
+			return extracted
+		}
+	}
+	if extracted := b3Propagator.Extract(ctx, carrier); trace.SpanContextFromContext(extracted).IsValid() {
+		return extracted
+	}
+	if parent := ParseGCPSpanContext(header.Get(GCPTraceHeader)); parent.IsValid() {
+		return trace.ContextWithRemoteSpanContext(ctx, parent)
+	}
+	return ctx
+}
+
+// Inject writes the traceparent and tracestate of the span in ctx to header
+func Inject(ctx context.Context, header http.Header) {
+	w3cPropagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// NewLocalSpanContext returns a span context with random W3C trace and span
+// IDs that is not sampled. It identifies requests that start a trace while
+// no tracer provider records spans.
+func NewLocalSpanContext() trace.SpanContext {
+	var traceID trace.TraceID
+	var spanID trace.SpanID
+	for !traceID.IsValid() {
+		_, _ = rand.Read(traceID[:])
+	}
+	for !spanID.IsValid() {
+		_, _ = rand.Read(spanID[:])
+	}
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	})
+}
+
+// SampledFromContext reports whether the trace of the span in ctx is sampled
+func SampledFromContext(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	return trace.SpanContextFromContext(ctx).IsSampled()
+}
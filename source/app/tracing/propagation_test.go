@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestExtract_PrefersTraceparent(t *testing.T) {
+	header := http.Header{}
+	header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	header.Set("b3", "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1")
+	header.Set(GCPTraceHeader, "105445aa7843bc8bf206b12000100000/1;o=1")
+
+	spanContext := trace.SpanContextFromContext(Extract(context.Background(), header))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spanContext.SpanID().String())
+	assert.True(t, spanContext.IsSampled())
+	assert.True(t, spanContext.IsRemote())
+}
+
+func TestExtract_FallsBackOnInvalidTraceparent(t *testing.T) {
+	header := http.Header{}
+	header.Set(TraceparentHeader, "00-not-a-trace-01")
+	header.Set("b3", "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-0")
+
+	spanContext := trace.SpanContextFromContext(Extract(context.Background(), header))
+	assert.Equal(t, "80f198ee56343ba864fe8b2a57d3eff7", spanContext.TraceID().String())
+	assert.False(t, spanContext.IsSampled())
+}
+
+func TestExtract_GCPHeader(t *testing.T) {
+	header := http.Header{}
+	header.Set(GCPTraceHeader, "105445aa7843bc8bf206b12000100000/1;o=1")
+
+	spanContext := trace.SpanContextFromContext(Extract(context.Background(), header))
+	assert.Equal(t, "105445aa7843bc8bf206b12000100000", spanContext.TraceID().String())
+}
+
+func TestExtract_NoTrace(t *testing.T) {
+	header := http.Header{}
+	header.Set(GCPTraceHeader, "not-hex/1;o=1")
+
+	ctx := context.Background()
+	assert.Equal(t, ctx, Extract(ctx, header))
+}
+
+func TestInject(t *testing.T) {
+	header := http.Header{}
+	header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	header.Set(TracestateHeader, "vendor=value")
+	ctx := Extract(context.Background(), header)
+
+	response := http.Header{}
+	Inject(ctx, response)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", response.Get(TraceparentHeader))
+	assert.Equal(t, "vendor=value", response.Get(TracestateHeader))
+}
+
+func TestNewLocalSpanContext(t *testing.T) {
+	first := NewLocalSpanContext()
+	second := NewLocalSpanContext()
+
+	assert.True(t, first.IsValid())
+	assert.False(t, first.IsSampled())
+	assert.NotEqual(t, first.TraceID(), second.TraceID())
+}
+
+func TestSampledFromContext(t *testing.T) {
+	assert.False(t, SampledFromContext(nil))
+	assert.False(t, SampledFromContext(context.Background()))
+
+	spanContext := ParseGCPSpanContext("105445aa7843bc8bf206b12000100000/1;o=1")
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), spanContext)
+	assert.True(t, SampledFromContext(ctx))
+}
@@ -12,6 +12,19 @@
   const attrSection = $('attributesSection');
   const list = $('attributesList');
 
+  function viewQuery(){
+    const v = $('view').value;
+    return v ? '?view=' + encodeURIComponent(v) : '';
+  }
+
+  // Declares the purpose of use the service requires for PII access
+  function purposeHeaders(extra){
+    const headers = Object.assign({}, extra);
+    const p = $('purpose').value.trim();
+    if(p) headers['X-Purpose'] = p;
+    return headers;
+  }
+
   function showMessage(t){msg.textContent = t}
   function clearMessage(){msg.textContent = ''}
 
@@ -21,7 +34,7 @@
     const personId = $('personId').value.trim();
     if(!personId){showMessage('Enter a person ID');return}
     try{
-      const res = await fetch(apiBase() + 'persons/' + encodeURIComponent(personId) + '/attributes');
+      const res = await fetch(apiBase() + 'persons/' + encodeURIComponent(personId) + '/attributes' + viewQuery(), { headers: purposeHeaders() });
       if(!res.ok){ showMessage('Failed to load attributes: ' + res.status); attrSection.classList.remove('hidden'); return }
       const data = await res.json();
       renderAttributes(data);
@@ -39,6 +52,12 @@
       const valIn = document.createElement('input'); valIn.type = 'text'; valIn.value = a.value || '';
       const info = document.createElement('div'); info.className = 'meta'; info.textContent = 'id: ' + (a.id||'') + (a.updatedAt? ' • updated: '+a.updatedAt : '');
       const saveBtn = document.createElement('button'); saveBtn.textContent = 'Save';
+
+      // Masked values are partial; saving them would overwrite the stored value
+      if(a.masked || a.redacted){
+        valIn.readOnly = true; valIn.title = 'Masked value'; li.classList.add('redacted');
+        saveBtn.disabled = true; saveBtn.title = 'Switch to the full view to edit';
+      }
       const delBtn = document.createElement('button'); delBtn.textContent = 'Delete';
 
       saveBtn.addEventListener('click', async ()=>{
@@ -55,7 +74,7 @@
   }
 
   function metaForRequest(){
-    return { caller: 'webui', reason: 'user-action', traceId: String(Date.now()) };
+    return { caller: 'webui', reason: $('purpose').value.trim() || 'user-action', traceId: String(Date.now()) };
   }
 
   async function createAttribute(key, value){
@@ -64,7 +83,7 @@
     const body = { key, value, meta: metaForRequest() };
     try{
       const res = await fetch(apiBase() + 'persons/' + encodeURIComponent(personId) + '/attributes', {
-        method: 'POST', headers: {'Content-Type':'application/json'}, body: JSON.stringify(body)
+        method: 'POST', headers: purposeHeaders({'Content-Type':'application/json'}), body: JSON.stringify(body)
       });
       if(res.status === 201){ showMessage('Attribute created'); await loadAttributes(); return }
       const txt = await res.text(); showMessage('Create failed: ' + res.status + ' ' + txt);
@@ -77,7 +96,7 @@
     const body = { key, value, meta: metaForRequest() };
     try{
       const res = await fetch(apiBase() + 'persons/' + encodeURIComponent(personId) + '/attributes/' + attributeId, {
-        method: 'PUT', headers: {'Content-Type':'application/json'}, body: JSON.stringify(body)
+        method: 'PUT', headers: purposeHeaders({'Content-Type':'application/json'}), body: JSON.stringify(body)
       });
       if(res.ok){ showMessage('Attribute updated'); await loadAttributes(); return }
       const txt = await res.text(); showMessage('Update failed: ' + res.status + ' ' + txt);
@@ -88,13 +107,16 @@
     clearMessage();
     const personId = $('personId').value.trim();
     try{
-      const res = await fetch(apiBase() + 'persons/' + encodeURIComponent(personId) + '/attributes/' + attributeId, { method: 'DELETE' });
+      const res = await fetch(apiBase() + 'persons/' + encodeURIComponent(personId) + '/attributes/' + attributeId, { method: 'DELETE', headers: purposeHeaders() });
       if(res.ok){ showMessage('Attribute deleted'); await loadAttributes(); return }
       const txt = await res.text(); showMessage('Delete failed: ' + res.status + ' ' + txt);
     }catch(e){ showMessage('Delete error: ' + e.message) }
   }
 
   document.getElementById('loadBtn').addEventListener('click', loadAttributes);
+  document.getElementById('view').addEventListener('change', function(){
+    if($('personId').value.trim()) loadAttributes();
+  });
   document.getElementById('createForm').addEventListener('submit', function(e){
     e.preventDefault();
     const k = $('newKey').value.trim(); const v = $('newValue').value;